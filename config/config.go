@@ -1,14 +1,34 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// ProcessPluginConfig 进程外插件配置（plugins.processes中的一项）
+type ProcessPluginConfig struct {
+	Name string   // 插件名称，非空时校验插件握手返回的名称
+	Path string   // 插件可执行文件路径
+	Args []string // 启动参数
+	Env  []string // 额外的环境变量（KEY=VALUE）
+	Dir  string   // 工作目录
+}
+
+// PluginLimitsConfig 插件并发限制配置（plugins.limits.<插件名>），各项为0表示不限制
+type PluginLimitsConfig struct {
+	MaxConcurrentExecutions int // Execute的最大并发数
+	MaxConcurrentRequests   int // 插件路由的最大并发请求数
+	QueueLength             int // 达到并发上限后允许排队的调用数
+	QueueTimeout            int // 排队超时（秒）
+}
+
 // Config 应用程序配置结构
 var Config struct {
 	// 服务器配置
@@ -65,7 +85,85 @@ var Config struct {
 		Dir            string
 		WatcherEnabled bool
 		ScanInterval   int // 秒
+		Debounce       int // 文件变更的防抖间隔（毫秒），间隔内的变更合并为一批处理
 		HotReload      bool
+
+		// Storage 插件键值存储配置
+		Storage struct {
+			Backend string // 存储后端：db（默认）或redis
+			Redis   struct {
+				Addr     string
+				Password string
+				DB       int
+				Prefix   string // 键前缀
+			}
+		}
+
+		// CircuitBreaker 插件熔断器默认配置
+		CircuitBreaker struct {
+			FailureThreshold     int // 连续失败多少次后熔断，0表示不熔断
+			OpenTimeout          int // 熔断持续时间（秒），之后进入半开状态
+			HalfOpenMaxCalls     int // 半开状态允许的试探调用数
+			AutoDisableThreshold int // 连续失败多少次后自动禁用插件，0表示不自动禁用
+		}
+
+		// Trust 动态加载插件（.so）的信任策略
+		Trust struct {
+			PublicKeys []string // 受信任的ed25519公钥（base64编码）
+			DevMode    bool     // 开发模式：跳过签名校验，仅用于本地开发
+		}
+
+		// Jobs 插件异步任务队列配置
+		Jobs struct {
+			Workers      int // 每个实例的工作协程数，0表示不启动任务队列
+			PollInterval int // 没有新任务时轮询数据库的间隔（秒）
+			MaxAttempts  int // 任务默认最大执行次数（含首次执行）
+			RetryBackoff int // 首次重试的等待时间（秒），之后每次翻倍
+			Timeout      int // 单次执行的超时时间（秒）
+			Retention    int // 已结束任务的结果保留时间（小时）
+		}
+
+		// StateSync 插件启用状态的持久化与集群同步
+		StateSync struct {
+			Enabled      bool   // 是否将通过管理接口启用或禁用插件的结果保存到plugin_states表，启动时按此恢复
+			Redis        bool   // 是否通过Redis发布订阅即时广播给其他实例（使用Storage.Redis的连接配置），连接失败时只轮询数据库
+			Channel      string // Redis频道
+			PollInterval int    // 轮询数据库的间隔（秒）
+		}
+
+		// Build 插件监控器从源码编译插件（go build -buildmode=plugin）的配置
+		Build struct {
+			Enabled   bool     // 是否在插件源码变更时编译，编译产物同样需要通过Trust校验
+			Toolchain string   // go命令路径
+			Flags     []string // 附加的go build参数，如-trimpath
+			OutputDir string   // 编译产物目录，每次编译输出到<OutputDir>/<插件名>/<构建版本>/
+			Timeout   int      // 单次编译的超时时间（秒）
+			Keep      int      // 每个插件保留的构建版本数，0表示不清理
+		}
+
+		// Registry 插件仓库配置，用于搜索、安装、升级、回滚和卸载插件
+		Registry struct {
+			URL        string // 仓库地址：本地目录或HTTP(S) URL，其中包含index.json和各插件的发布包；为空时不能搜索和安装插件
+			InstallDir string // 已安装插件的目录，每个版本位于<InstallDir>/<插件名>/<版本>/
+			Timeout    int    // 从HTTP仓库下载的超时时间（秒）
+			Keep       int    // 每个插件保留的已安装版本数（包括当前版本），大于1时才能回滚
+		}
+
+		// RolePermissions 角色拥有的权限（plugins.rolePermissions.<角色>），用于校验插件路由声明的Permissions
+		// 默认为空，即任何角色都没有权限；团队角色带team:前缀，如team:owner
+		// 权限支持通配：*表示全部权限，notes:*表示notes:下的全部权限
+		RolePermissions map[string][]string
+
+		// Limits 各插件的并发限制（plugins.limits.<插件名>），键为小写的插件名
+		Limits map[string]PluginLimitsConfig
+
+		// Processes 进程外插件列表，启动时通过JSON-RPC协议加载
+		Processes []ProcessPluginConfig
+
+		// Settings 各插件的配置段（plugins.<插件名>），键为插件名
+		// 与管理器配置项（如storage、jobs）同名的插件使用plugins.settings.<插件名>，两处都有时settings中的配置项优先
+		// 注意：Viper会将键名转换为小写，插件配置项建议使用snake_case命名
+		Settings map[string]map[string]interface{}
 	}
 
 	// Prometheus配置
@@ -132,7 +230,43 @@ func resetDefaults() {
 	Config.Plugins.Dir = "./plugins"
 	Config.Plugins.WatcherEnabled = true
 	Config.Plugins.ScanInterval = 5 // 5秒
+	Config.Plugins.Debounce = 500
 	Config.Plugins.HotReload = true
+	Config.Plugins.Storage.Backend = "db"
+	Config.Plugins.Storage.Redis.Addr = "localhost:6379"
+	Config.Plugins.Storage.Redis.Password = ""
+	Config.Plugins.Storage.Redis.DB = 0
+	Config.Plugins.Storage.Redis.Prefix = "weave:plugin_kv"
+	Config.Plugins.CircuitBreaker.FailureThreshold = 5
+	Config.Plugins.CircuitBreaker.OpenTimeout = 30 // 30秒
+	Config.Plugins.CircuitBreaker.HalfOpenMaxCalls = 1
+	Config.Plugins.CircuitBreaker.AutoDisableThreshold = 0
+	Config.Plugins.Trust.PublicKeys = nil
+	Config.Plugins.Trust.DevMode = false
+	Config.Plugins.Jobs.Workers = 4
+	Config.Plugins.Jobs.PollInterval = 2
+	Config.Plugins.Jobs.MaxAttempts = 3
+	Config.Plugins.Jobs.RetryBackoff = 5
+	Config.Plugins.Jobs.Timeout = 1800 // 30分钟
+	Config.Plugins.Jobs.Retention = 24
+	Config.Plugins.StateSync.Enabled = true
+	Config.Plugins.StateSync.Redis = false
+	Config.Plugins.StateSync.Channel = "weave:plugin_state"
+	Config.Plugins.StateSync.PollInterval = 10
+	Config.Plugins.Build.Enabled = false
+	Config.Plugins.Build.Toolchain = "go"
+	Config.Plugins.Build.Flags = nil
+	Config.Plugins.Build.OutputDir = "./plugins/.build"
+	Config.Plugins.Build.Timeout = 300 // 5分钟
+	Config.Plugins.Build.Keep = 3
+	Config.Plugins.Registry.URL = ""
+	Config.Plugins.Registry.InstallDir = "./plugins/.installed"
+	Config.Plugins.Registry.Timeout = 60
+	Config.Plugins.Registry.Keep = 3
+	Config.Plugins.RolePermissions = make(map[string][]string)
+	Config.Plugins.Limits = make(map[string]PluginLimitsConfig)
+	Config.Plugins.Processes = nil
+	Config.Plugins.Settings = make(map[string]map[string]interface{})
 
 	// Prometheus配置
 	Config.Prometheus.Enabled = true
@@ -227,6 +361,66 @@ func ValidateConfig() error {
 	if Config.Plugins.ScanInterval <= 0 {
 		return fmt.Errorf("无效的插件扫描间隔: %d，必须大于0秒", Config.Plugins.ScanInterval)
 	}
+	if Config.Plugins.Debounce <= 0 {
+		return fmt.Errorf("无效的插件变更防抖间隔: %d，必须大于0毫秒", Config.Plugins.Debounce)
+	}
+
+	validStorageBackends := map[string]bool{"db": true, "redis": true}
+	if !validStorageBackends[Config.Plugins.Storage.Backend] {
+		return fmt.Errorf("不支持的插件存储后端: %s，支持的后端有: db, redis", Config.Plugins.Storage.Backend)
+	}
+
+	breaker := Config.Plugins.CircuitBreaker
+	if breaker.FailureThreshold < 0 || breaker.AutoDisableThreshold < 0 || breaker.HalfOpenMaxCalls < 0 {
+		return fmt.Errorf("插件熔断器的阈值不能为负数")
+	}
+	if breaker.FailureThreshold > 0 && breaker.OpenTimeout <= 0 {
+		return fmt.Errorf("无效的插件熔断时间: %d，必须大于0秒", breaker.OpenTimeout)
+	}
+
+	for i, key := range Config.Plugins.Trust.PublicKeys {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+		if err != nil || len(decoded) != ed25519.PublicKeySize {
+			return fmt.Errorf("第 %d 个插件签名公钥无效，必须是base64编码的ed25519公钥", i+1)
+		}
+	}
+
+	jobs := Config.Plugins.Jobs
+	if jobs.Workers < 0 {
+		return fmt.Errorf("无效的插件任务工作协程数: %d，不能为负数", jobs.Workers)
+	}
+	if jobs.Workers > 0 && (jobs.PollInterval <= 0 || jobs.MaxAttempts <= 0 || jobs.Timeout <= 0 || jobs.Retention <= 0 || jobs.RetryBackoff < 0) {
+		return fmt.Errorf("无效的插件任务队列配置: 轮询间隔、最大执行次数、超时时间和保留时间必须大于0，重试等待时间不能为负数")
+	}
+
+	if Config.Plugins.StateSync.Enabled && Config.Plugins.StateSync.PollInterval <= 0 {
+		return fmt.Errorf("无效的插件状态同步轮询间隔: %d，必须大于0", Config.Plugins.StateSync.PollInterval)
+	}
+
+	build := Config.Plugins.Build
+	if build.Enabled && (strings.TrimSpace(build.Toolchain) == "" || strings.TrimSpace(build.OutputDir) == "") {
+		return fmt.Errorf("无效的插件编译配置: 启用编译时必须指定toolchain和outputDir")
+	}
+	if build.Timeout <= 0 || build.Keep < 0 {
+		return fmt.Errorf("无效的插件编译配置: 超时时间必须大于0，保留的构建版本数不能为负数")
+	}
+
+	registry := Config.Plugins.Registry
+	if strings.TrimSpace(registry.InstallDir) == "" || registry.Timeout <= 0 || registry.Keep < 1 {
+		return fmt.Errorf("无效的插件仓库配置: 必须指定installDir，超时时间和保留的版本数必须大于0")
+	}
+
+	for name, limits := range Config.Plugins.Limits {
+		if limits.MaxConcurrentExecutions < 0 || limits.MaxConcurrentRequests < 0 || limits.QueueLength < 0 || limits.QueueTimeout < 0 {
+			return fmt.Errorf("插件 '%s' 的并发限制不能为负数", name)
+		}
+	}
+
+	for i, process := range Config.Plugins.Processes {
+		if process.Path == "" {
+			return fmt.Errorf("第 %d 个进程外插件未配置可执行文件路径", i+1)
+		}
+	}
 
 	// 8. 验证Prometheus配置
 	if Config.Prometheus.MetricsPath != "" && Config.Prometheus.MetricsPath[0] != '/' {
@@ -269,6 +463,85 @@ func mapToPrometheusConfig(configMap map[string]interface{}) {
 	}
 }
 
+// reservedPluginKeys plugins配置段中管理器配置项的键（小写），其余键视为插件配置段
+var reservedPluginKeys = map[string]bool{
+	"dir":             true,
+	"watcherenabled":  true,
+	"scaninterval":    true,
+	"debounce":        true,
+	"hotreload":       true,
+	"processes":       true,
+	"circuitbreaker":  true,
+	"limits":          true,
+	"storage":         true,
+	"trust":           true,
+	"rolepermissions": true,
+	"jobs":            true,
+	"statesync":       true,
+	"build":           true,
+	"registry":        true,
+	"settings":        true,
+}
+
+// sensitiveKeyMarkers 敏感配置项名称包含的关键字
+var sensitiveKeyMarkers = []string{"password", "secret", "token", "apikey", "api_key", "private"}
+
+// IsSensitiveKey 判断配置项名称是否为敏感信息（密码、密钥、令牌等）
+func IsSensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, marker := range sensitiveKeyMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSettings 返回隐藏了敏感配置项的副本
+func MaskSettings(settings map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			masked[key] = MaskSettings(v)
+		default:
+			if IsSensitiveKey(key) {
+				masked[key] = "***"
+			} else {
+				masked[key] = value
+			}
+		}
+	}
+	return masked
+}
+
+// loadPluginSettings 从plugins.<插件名>和plugins.settings.<插件名>配置段中读取各插件的配置
+// 与管理器配置项同名的插件只能使用后者；两处都有时逐项合并，settings中的配置项优先
+func loadPluginSettings(v *viper.Viper) {
+	for key := range v.GetStringMap("plugins") {
+		if reservedPluginKeys[key] {
+			continue
+		}
+		if section := v.GetStringMap("plugins." + key); len(section) > 0 {
+			Config.Plugins.Settings[key] = section
+		}
+	}
+	for key := range v.GetStringMap("plugins.settings") {
+		section := v.GetStringMap("plugins.settings." + key)
+		if len(section) == 0 {
+			continue
+		}
+		merged := Config.Plugins.Settings[key]
+		if merged == nil {
+			merged = make(map[string]interface{}, len(section))
+		}
+		for name, value := range section {
+			merged[name] = value
+		}
+		Config.Plugins.Settings[key] = merged
+	}
+}
+
 // SanitizeConfig 清理配置中的敏感信息，用于日志输出
 func SanitizeConfig() map[string]interface{} {
 	// 创建配置的安全副本用于日志输出
@@ -313,7 +586,32 @@ func SanitizeConfig() map[string]interface{} {
 			"Dir":            Config.Plugins.Dir,
 			"WatcherEnabled": Config.Plugins.WatcherEnabled,
 			"ScanInterval":   Config.Plugins.ScanInterval,
+			"Debounce":       Config.Plugins.Debounce,
 			"HotReload":      Config.Plugins.HotReload,
+			"Storage": map[string]interface{}{
+				"Backend":       Config.Plugins.Storage.Backend,
+				"RedisAddr":     Config.Plugins.Storage.Redis.Addr,
+				"RedisPassword": "***", // 隐藏密码
+				"RedisDB":       Config.Plugins.Storage.Redis.DB,
+			},
+			"CircuitBreaker": map[string]interface{}{
+				"FailureThreshold":     Config.Plugins.CircuitBreaker.FailureThreshold,
+				"OpenTimeout":          Config.Plugins.CircuitBreaker.OpenTimeout,
+				"HalfOpenMaxCalls":     Config.Plugins.CircuitBreaker.HalfOpenMaxCalls,
+				"AutoDisableThreshold": Config.Plugins.CircuitBreaker.AutoDisableThreshold,
+			},
+			"Trust": map[string]interface{}{
+				"PublicKeys": Config.Plugins.Trust.PublicKeys,
+				"DevMode":    Config.Plugins.Trust.DevMode,
+			},
+			"Jobs":            Config.Plugins.Jobs,
+			"StateSync":       Config.Plugins.StateSync,
+			"Build":           Config.Plugins.Build,
+			"Registry":        Config.Plugins.Registry,
+			"RolePermissions": Config.Plugins.RolePermissions,
+			"Limits":          Config.Plugins.Limits,
+			"Processes":       sanitizeProcessPlugins(),
+			"Settings":        sanitizePluginSettings(),
 		},
 		"Prometheus": map[string]interface{}{
 			"Enabled":           Config.Prometheus.Enabled,
@@ -326,6 +624,28 @@ func SanitizeConfig() map[string]interface{} {
 	return sanitized
 }
 
+// sanitizeProcessPlugins 输出进程外插件配置，环境变量可能包含凭据，不输出
+func sanitizeProcessPlugins() []map[string]interface{} {
+	sanitized := make([]map[string]interface{}, 0, len(Config.Plugins.Processes))
+	for _, process := range Config.Plugins.Processes {
+		sanitized = append(sanitized, map[string]interface{}{
+			"Name": process.Name,
+			"Path": process.Path,
+			"Args": process.Args,
+		})
+	}
+	return sanitized
+}
+
+// sanitizePluginSettings 隐藏插件配置段中的敏感信息
+func sanitizePluginSettings() map[string]interface{} {
+	sanitized := make(map[string]interface{}, len(Config.Plugins.Settings))
+	for name, settings := range Config.Plugins.Settings {
+		sanitized[name] = MaskSettings(settings)
+	}
+	return sanitized
+}
+
 // GetAbsConfigFilePath 获取配置文件的绝对路径
 func GetAbsConfigFilePath() (string, error) {
 	configPath := os.Getenv("CONFIG_PATH")
@@ -559,9 +879,123 @@ func LoadConfigWithViper() error {
 		if v.IsSet("plugins.scanInterval") {
 			Config.Plugins.ScanInterval = v.GetInt("plugins.scanInterval")
 		}
+		if v.IsSet("plugins.debounce") {
+			Config.Plugins.Debounce = v.GetInt("plugins.debounce")
+		}
 		if v.IsSet("plugins.hotReload") {
 			Config.Plugins.HotReload = convertToBool(v.Get("plugins.hotReload"))
 		}
+		if v.IsSet("plugins.storage.backend") {
+			Config.Plugins.Storage.Backend = v.GetString("plugins.storage.backend")
+		}
+		if v.IsSet("plugins.storage.redis.addr") {
+			Config.Plugins.Storage.Redis.Addr = v.GetString("plugins.storage.redis.addr")
+		}
+		if v.IsSet("plugins.storage.redis.password") {
+			Config.Plugins.Storage.Redis.Password = v.GetString("plugins.storage.redis.password")
+		}
+		if v.IsSet("plugins.storage.redis.db") {
+			Config.Plugins.Storage.Redis.DB = v.GetInt("plugins.storage.redis.db")
+		}
+		if v.IsSet("plugins.storage.redis.prefix") {
+			Config.Plugins.Storage.Redis.Prefix = v.GetString("plugins.storage.redis.prefix")
+		}
+		if v.IsSet("plugins.circuitBreaker.failureThreshold") {
+			Config.Plugins.CircuitBreaker.FailureThreshold = v.GetInt("plugins.circuitBreaker.failureThreshold")
+		}
+		if v.IsSet("plugins.circuitBreaker.openTimeout") {
+			Config.Plugins.CircuitBreaker.OpenTimeout = v.GetInt("plugins.circuitBreaker.openTimeout")
+		}
+		if v.IsSet("plugins.circuitBreaker.halfOpenMaxCalls") {
+			Config.Plugins.CircuitBreaker.HalfOpenMaxCalls = v.GetInt("plugins.circuitBreaker.halfOpenMaxCalls")
+		}
+		if v.IsSet("plugins.circuitBreaker.autoDisableThreshold") {
+			Config.Plugins.CircuitBreaker.AutoDisableThreshold = v.GetInt("plugins.circuitBreaker.autoDisableThreshold")
+		}
+		if v.IsSet("plugins.trust.publicKeys") {
+			Config.Plugins.Trust.PublicKeys = v.GetStringSlice("plugins.trust.publicKeys")
+		}
+		if v.IsSet("plugins.trust.devMode") {
+			Config.Plugins.Trust.DevMode = convertToBool(v.Get("plugins.trust.devMode"))
+		}
+		if v.IsSet("plugins.jobs.workers") {
+			Config.Plugins.Jobs.Workers = v.GetInt("plugins.jobs.workers")
+		}
+		if v.IsSet("plugins.jobs.pollInterval") {
+			Config.Plugins.Jobs.PollInterval = v.GetInt("plugins.jobs.pollInterval")
+		}
+		if v.IsSet("plugins.jobs.maxAttempts") {
+			Config.Plugins.Jobs.MaxAttempts = v.GetInt("plugins.jobs.maxAttempts")
+		}
+		if v.IsSet("plugins.jobs.retryBackoff") {
+			Config.Plugins.Jobs.RetryBackoff = v.GetInt("plugins.jobs.retryBackoff")
+		}
+		if v.IsSet("plugins.jobs.timeout") {
+			Config.Plugins.Jobs.Timeout = v.GetInt("plugins.jobs.timeout")
+		}
+		if v.IsSet("plugins.jobs.retention") {
+			Config.Plugins.Jobs.Retention = v.GetInt("plugins.jobs.retention")
+		}
+		if v.IsSet("plugins.stateSync.enabled") {
+			Config.Plugins.StateSync.Enabled = convertToBool(v.Get("plugins.stateSync.enabled"))
+		}
+		if v.IsSet("plugins.stateSync.redis") {
+			Config.Plugins.StateSync.Redis = convertToBool(v.Get("plugins.stateSync.redis"))
+		}
+		if v.IsSet("plugins.stateSync.channel") {
+			Config.Plugins.StateSync.Channel = v.GetString("plugins.stateSync.channel")
+		}
+		if v.IsSet("plugins.stateSync.pollInterval") {
+			Config.Plugins.StateSync.PollInterval = v.GetInt("plugins.stateSync.pollInterval")
+		}
+		if v.IsSet("plugins.build.enabled") {
+			Config.Plugins.Build.Enabled = convertToBool(v.Get("plugins.build.enabled"))
+		}
+		if v.IsSet("plugins.build.toolchain") {
+			Config.Plugins.Build.Toolchain = v.GetString("plugins.build.toolchain")
+		}
+		if v.IsSet("plugins.build.flags") {
+			Config.Plugins.Build.Flags = v.GetStringSlice("plugins.build.flags")
+		}
+		if v.IsSet("plugins.build.outputDir") {
+			Config.Plugins.Build.OutputDir = v.GetString("plugins.build.outputDir")
+		}
+		if v.IsSet("plugins.build.timeout") {
+			Config.Plugins.Build.Timeout = v.GetInt("plugins.build.timeout")
+		}
+		if v.IsSet("plugins.build.keep") {
+			Config.Plugins.Build.Keep = v.GetInt("plugins.build.keep")
+		}
+		if v.IsSet("plugins.registry.url") {
+			Config.Plugins.Registry.URL = v.GetString("plugins.registry.url")
+		}
+		if v.IsSet("plugins.registry.installDir") {
+			Config.Plugins.Registry.InstallDir = v.GetString("plugins.registry.installDir")
+		}
+		if v.IsSet("plugins.registry.timeout") {
+			Config.Plugins.Registry.Timeout = v.GetInt("plugins.registry.timeout")
+		}
+		if v.IsSet("plugins.registry.keep") {
+			Config.Plugins.Registry.Keep = v.GetInt("plugins.registry.keep")
+		}
+		if v.IsSet("plugins.rolePermissions") {
+			rolePermissions := make(map[string][]string)
+			if err := v.UnmarshalKey("plugins.rolePermissions", &rolePermissions); err != nil {
+				return fmt.Errorf("解析插件角色权限配置失败: %w", err)
+			}
+			Config.Plugins.RolePermissions = rolePermissions
+		}
+		if v.IsSet("plugins.limits") {
+			if err := v.UnmarshalKey("plugins.limits", &Config.Plugins.Limits); err != nil {
+				return fmt.Errorf("解析插件并发限制配置失败: %w", err)
+			}
+		}
+		if v.IsSet("plugins.processes") {
+			if err := v.UnmarshalKey("plugins.processes", &Config.Plugins.Processes); err != nil {
+				return fmt.Errorf("解析进程外插件配置失败: %w", err)
+			}
+		}
+		loadPluginSettings(v)
 		if v.IsSet("prometheus.enabled") {
 			Config.Prometheus.Enabled = convertToBool(v.Get("prometheus.enabled"))
 		}
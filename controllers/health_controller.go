@@ -152,7 +152,10 @@ func (hc *HealthController) PluginHealthCheck(c *gin.Context) {
 		metrics.RecordPluginError(pluginName, "status_not_found")
 	}
 
-	healthy := targetPluginInfo.IsEnabled && status == "enabled"
+	// 熔断器打开时插件视为不健康
+	breaker, _ := plugins.PluginManager.GetBreakerStatus(pluginName)
+
+	healthy := targetPluginInfo.IsEnabled && status == "enabled" && breaker.State != core.BreakerOpen
 	if !healthy && targetPluginInfo.IsEnabled {
 		success = false
 		metrics.RecordPluginError(pluginName, "health_check_failed")
@@ -164,11 +167,12 @@ func (hc *HealthController) PluginHealthCheck(c *gin.Context) {
 	metrics.RecordPluginExecution(pluginName, success, duration)
 
 	c.JSON(200, gin.H{
-		"name":    pluginName,
-		"version": targetPluginInfo.Plugin.Version(),
-		"enabled": targetPluginInfo.IsEnabled,
-		"status":  status,
-		"healthy": healthy,
+		"name":            pluginName,
+		"version":         targetPluginInfo.Plugin.Version(),
+		"enabled":         targetPluginInfo.IsEnabled,
+		"status":          status,
+		"healthy":         healthy,
+		"circuit_breaker": breaker,
 	})
 }
 
@@ -185,7 +189,8 @@ func checkPluginHealth() gin.H {
 			status = "not_registered"
 			metrics.RecordPluginError(plugin.Name(), "status_not_found")
 		}
-		healthy := pluginInfo.IsEnabled && status == "enabled"
+		breaker, _ := plugins.PluginManager.GetBreakerStatus(plugin.Name())
+		healthy := pluginInfo.IsEnabled && status == "enabled" && breaker.State != core.BreakerOpen
 		if !healthy && pluginInfo.IsEnabled {
 			metrics.RecordPluginError(plugin.Name(), "health_check_failed")
 		}
@@ -194,11 +199,12 @@ func checkPluginHealth() gin.H {
 		metrics.RecordPluginExecution(plugin.Name(), healthy, duration)
 
 		pluginStatuses = append(pluginStatuses, gin.H{
-			"name":    plugin.Name(),
-			"version": plugin.Version(),
-			"enabled": pluginInfo.IsEnabled,
-			"status":  status,
-			"healthy": healthy,
+			"name":            plugin.Name(),
+			"version":         plugin.Version(),
+			"enabled":         pluginInfo.IsEnabled,
+			"status":          status,
+			"healthy":         healthy,
+			"circuit_breaker": breaker.State,
 		})
 	}
 
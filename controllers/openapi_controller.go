@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"weave/pkg"
+	"weave/pkg/openapi"
+	"weave/plugins"
+	"weave/plugins/core"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// openAPISpecPath OpenAPI文档的访问路径
+const openAPISpecPath = "/openapi.json"
+
+// openAPIExcludedPrefixes 不写入文档的核心路由前缀：插件分发器（插件路由单独描述）、文档自身和监控指标
+var openAPIExcludedPrefixes = []string{"/plugins/", openAPISpecPath, "/docs", "/metrics"}
+
+// OpenAPIController OpenAPI文档控制器
+// 文档在运行时由核心路由和已启用插件的路由生成，插件注册、启用、禁用、重载或注销后重新生成
+type OpenAPIController struct {
+	router *gin.Engine
+	mu     sync.Mutex
+	cached []byte // 已生成的文档，为空时在下次请求时重新生成
+}
+
+// NewOpenAPIController 创建OpenAPI文档控制器，并订阅插件生命周期事件以便在插件变化时重新生成文档
+func NewOpenAPIController(router *gin.Engine) *OpenAPIController {
+	oc := &OpenAPIController{router: router}
+	if _, err := plugins.PluginManager.Subscribe(core.EventSourceCore+".openapi", "plugin.#", func(core.Event) { oc.Invalidate() }); err != nil {
+		pkg.Warn("订阅插件事件失败，OpenAPI文档不会随插件变化更新", zap.Error(err))
+	}
+	return oc
+}
+
+// Invalidate 丢弃已生成的文档
+func (oc *OpenAPIController) Invalidate() {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.cached = nil
+}
+
+// GetSpec 获取OpenAPI文档
+// @Summary 获取OpenAPI文档
+// @Description 获取运行时生成的OpenAPI 3文档，包含核心接口和已启用插件的接口
+// @Tags 文档
+// @Success 200 {object} openapi.Document
+// @Router /openapi.json [get]
+func (oc *OpenAPIController) GetSpec(c *gin.Context) {
+	spec, err := oc.spec()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成OpenAPI文档失败: " + err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// GetDocsPage 获取API文档页面
+// @Summary 获取API文档页面
+// @Description 渲染OpenAPI文档的HTML页面
+// @Tags 文档
+// @Success 200 {string} string "HTML页面"
+// @Router /docs [get]
+func (oc *OpenAPIController) GetDocsPage(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(openapi.DocsPage("Weave API", openAPISpecPath)))
+}
+
+// GetDocsScript 获取文档页面使用的脚本
+// @Summary 获取文档页面脚本
+// @Description 返回服务内嵌的文档页面脚本
+// @Tags 文档
+// @Success 200 {string} string "JavaScript脚本"
+// @Router /docs/docs.js [get]
+func (oc *OpenAPIController) GetDocsScript(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", openapi.DocsScript())
+}
+
+// spec 返回已生成的文档，文档已失效时重新生成
+func (oc *OpenAPIController) spec() ([]byte, error) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if oc.cached != nil {
+		return oc.cached, nil
+	}
+	data, err := json.Marshal(BuildOpenAPIDocument(oc.router, plugins.PluginManager))
+	if err != nil {
+		return nil, err
+	}
+	oc.cached = data
+	return data, nil
+}
+
+// BuildOpenAPIDocument 根据路由引擎中的核心路由和插件管理器中的插件路由生成OpenAPI文档
+// /api/v1下的核心接口需要认证
+func BuildOpenAPIDocument(router *gin.Engine, manager *core.PluginManager) *openapi.Document {
+	builder := openapi.NewBuilder(openapi.Info{
+		Title:       "Weave API",
+		Version:     "1.0.0",
+		Description: "由核心路由和已启用插件的路由在运行时生成",
+	})
+
+	for _, route := range router.Routes() {
+		if isExcludedFromOpenAPI(route.Path) {
+			continue
+		}
+		builder.AddGinRoute(route, strings.HasPrefix(route.Path, "/api/v1"))
+	}
+	manager.DescribeRoutes(builder)
+
+	return builder.Document()
+}
+
+// isExcludedFromOpenAPI 判断核心路由是否不写入文档
+func isExcludedFromOpenAPI(path string) bool {
+	for _, prefix := range openAPIExcludedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,15 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"weave/pkg"
 	"weave/plugins"
+	"weave/plugins/core"
+	"weave/plugins/loader"
+	"weave/plugins/registry"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,8 +36,10 @@ func (pc *PluginController) GetAllPlugins(c *gin.Context) {
 			"description":  info.Plugin.Description(),
 			"version":      info.Plugin.Version(),
 			"enabled":      info.IsEnabled,
+			"state":        info.State,
 			"dependencies": info.Dependencies,
 			"conflicts":    info.Conflicts,
+			"routes":       pluginRoutesData(info),
 		}
 		response = append(response, pluginData)
 	}
@@ -38,20 +47,58 @@ func (pc *PluginController) GetAllPlugins(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// pluginRoutesData 插件路由及其访问要求，旧版插件通过RegisterRoutes注册的路由不在其中
+func pluginRoutesData(info core.PluginInfo) []map[string]interface{} {
+	routes := make([]map[string]interface{}, 0, len(info.Routes))
+	for _, route := range info.Routes {
+		routes = append(routes, map[string]interface{}{
+			"method":        route.Method,
+			"path":          fmt.Sprintf("/plugins/%s%s", info.Plugin.Name(), route.Path),
+			"description":   route.Description,
+			"auth_required": route.AuthRequired || len(route.Roles) > 0 || len(route.Permissions) > 0,
+			"roles":         route.Roles,
+			"permissions":   route.Permissions,
+		})
+	}
+	return routes
+}
+
 // EnablePlugin 启用插件
 // @Summary 启用插件
-// @Description 启用指定的插件
+// @Description 启用指定的插件。cascade=true时按依赖顺序同时启用未启用的依赖，任一失败则全部回滚；dry_run=true时只返回将受影响的插件及顺序
 // @Tags 插件管理
 // @Security BearerAuth
 // @Param name path string true "插件名称"
-// @Success 200 {object} map[string]string
+// @Param cascade query bool false "是否级联启用依赖"
+// @Param dry_run query bool false "是否只预览受影响的插件"
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /api/v1/plugins/{name}/enable [post]
 func (pc *PluginController) EnablePlugin(c *gin.Context) {
 	pluginName := c.Param("name")
+	pm := plugins.PluginManager
 
-	if err := plugins.PluginManager.EnablePlugin(pluginName); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cascade, dryRun, ok := cascadeOptions(c)
+	if !ok {
+		return
+	}
+	if dryRun {
+		steps, err := pm.PlanEnable(pluginName, cascade)
+		respondCascadePlan(c, pluginName, cascade, steps, err)
+		return
+	}
+	if cascade {
+		steps, err := pm.EnablePluginCascade(pluginName)
+		if err != nil {
+			respondPluginError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "插件启用成功", "plugin": pluginName, "steps": steps})
+		return
+	}
+
+	if err := pm.EnablePlugin(pluginName); err != nil {
+		respondPluginError(c, err)
 		return
 	}
 
@@ -60,24 +107,69 @@ func (pc *PluginController) EnablePlugin(c *gin.Context) {
 
 // DisablePlugin 禁用插件
 // @Summary 禁用插件
-// @Description 禁用指定的插件
+// @Description 禁用指定的插件。cascade=true时先禁用依赖它的已启用插件，任一失败则全部回滚；dry_run=true时只返回将受影响的插件及顺序
 // @Tags 插件管理
 // @Security BearerAuth
 // @Param name path string true "插件名称"
-// @Success 200 {object} map[string]string
+// @Param cascade query bool false "是否级联禁用依赖方"
+// @Param dry_run query bool false "是否只预览受影响的插件"
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /api/v1/plugins/{name}/disable [post]
 func (pc *PluginController) DisablePlugin(c *gin.Context) {
 	pluginName := c.Param("name")
+	pm := plugins.PluginManager
 
-	if err := plugins.PluginManager.DisablePlugin(pluginName); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cascade, dryRun, ok := cascadeOptions(c)
+	if !ok {
+		return
+	}
+	if dryRun {
+		steps, err := pm.PlanDisable(pluginName, cascade)
+		respondCascadePlan(c, pluginName, cascade, steps, err)
+		return
+	}
+	if cascade {
+		steps, err := pm.DisablePluginCascade(pluginName)
+		if err != nil {
+			respondPluginError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "插件禁用成功", "plugin": pluginName, "steps": steps})
+		return
+	}
+
+	if err := pm.DisablePlugin(pluginName); err != nil {
+		respondPluginError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "插件禁用成功", "plugin": pluginName})
 }
 
+// cascadeOptions 解析启用和禁用接口的cascade和dry_run参数，参数无效时返回400
+func cascadeOptions(c *gin.Context) (cascade, dryRun, ok bool) {
+	var err error
+	if cascade, err = strconv.ParseBool(c.DefaultQuery("cascade", "false")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cascade参数无效"})
+		return false, false, false
+	}
+	if dryRun, err = strconv.ParseBool(c.DefaultQuery("dry_run", "false")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run参数无效"})
+		return false, false, false
+	}
+	return cascade, dryRun, true
+}
+
+// respondCascadePlan 返回预览结果，计划无法执行时返回错误原因
+func respondCascadePlan(c *gin.Context, pluginName string, cascade bool, steps []core.CascadeStep, err error) {
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plugin": pluginName, "dry_run": true, "cascade": cascade, "steps": steps})
+}
+
 // ReloadPlugin 重载插件
 // @Summary 重载插件
 // @Description 重载指定的插件（先禁用再启用）
@@ -119,14 +211,643 @@ func (pc *PluginController) GetPluginStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": status, "plugin": pluginName})
 }
 
+// GetPluginHistory 获取插件状态转换历史
+// @Summary 获取插件状态转换历史
+// @Description 获取插件当前的生命周期状态及最近的状态转换记录（含失败原因），已注销或注册失败的插件同样可以查询
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/history [get]
+func (pc *PluginController) GetPluginHistory(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	history, exists := plugins.PluginManager.GetPluginHistory(pluginName)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "插件不存在", "plugin": pluginName})
+		return
+	}
+	state, _ := plugins.PluginManager.GetPluginState(pluginName)
+
+	c.JSON(http.StatusOK, gin.H{"plugin": pluginName, "state": state, "history": history})
+}
+
+// pluginBuilder 返回插件源码编译器，未启用时返回错误响应
+func pluginBuilder(c *gin.Context) (*loader.PluginBuilder, bool) {
+	builder := plugins.PluginBuilder
+	if builder == nil {
+		respondPluginError(c, pkg.NewServiceUnavailableError("插件源码编译未启用", nil))
+		return nil, false
+	}
+	return builder, true
+}
+
+// GetPluginBuilds 获取插件编译状态列表
+// @Summary 获取插件编译状态列表
+// @Description 返回插件监控器从源码编译的各插件最近一次的编译结果，包括编译器输出和产物的加载结果
+// @Tags 插件管理
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/builds [get]
+func (pc *PluginController) GetPluginBuilds(c *gin.Context) {
+	builder, ok := pluginBuilder(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"builds": builder.Statuses()})
+}
+
+// GetPluginBuild 获取插件编译状态
+// @Summary 获取插件编译状态
+// @Description 返回插件最近一次的编译结果，编译失败时output中包含编译错误
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} loader.BuildResult
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/{name}/build [get]
+func (pc *PluginController) GetPluginBuild(c *gin.Context) {
+	builder, ok := pluginBuilder(c)
+	if !ok {
+		return
+	}
+
+	pluginName := c.Param("name")
+	result, exists := builder.Status(pluginName)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "插件没有编译记录", "plugin": pluginName})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// pluginRegistry 返回插件安装器，未初始化时返回错误响应
+func pluginRegistry(c *gin.Context) (*registry.Installer, bool) {
+	installer := plugins.PluginRegistry
+	if installer == nil {
+		respondPluginError(c, pkg.NewServiceUnavailableError("插件仓库未启用", nil))
+		return nil, false
+	}
+	return installer, true
+}
+
+// installPluginRequest 安装和升级插件的请求参数
+type installPluginRequest struct {
+	Name    string `json:"name"`    // 插件名称，升级时取自路径参数
+	Version string `json:"version"` // 版本约束，如"1.2.0"、"^1.2"，为空时选择最高可用版本
+}
+
+// SearchPluginRegistry 搜索插件仓库
+// @Summary 搜索插件仓库
+// @Description 返回插件仓库中名称或描述包含q的插件及其全部版本，installed为已安装或已注册的版本
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param q query string false "搜索关键字，为空时返回全部插件"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/registry [get]
+func (pc *PluginController) SearchPluginRegistry(c *gin.Context) {
+	installer, ok := pluginRegistry(c)
+	if !ok {
+		return
+	}
+
+	results, err := installer.Search(c.Query("q"))
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"registry": installer.Registry().URL(), "plugins": results})
+}
+
+// GetInstalledPlugins 获取已安装的插件
+// @Summary 获取已安装的插件
+// @Description 返回通过插件仓库安装的插件、当前版本和可回滚的早先版本
+// @Tags 插件管理
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/installed [get]
+func (pc *PluginController) GetInstalledPlugins(c *gin.Context) {
+	installer, ok := pluginRegistry(c)
+	if !ok {
+		return
+	}
+
+	installed, err := installer.Installed()
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plugins": installed})
+}
+
+// InstallPlugin 从插件仓库安装插件
+// @Summary 从插件仓库安装插件
+// @Description 下载满足版本约束的最高版本及缺失的依赖，校验签名后按依赖顺序加载和注册，任一插件失败则全部撤销；dry_run=true时只返回安装步骤
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param request body installPluginRequest true "插件名称和版本约束"
+// @Param dry_run query bool false "是否只预览安装步骤"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/install [post]
+func (pc *PluginController) InstallPlugin(c *gin.Context) {
+	installer, ok := pluginRegistry(c)
+	if !ok {
+		return
+	}
+
+	var req installPluginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: 必须指定插件名称"})
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run参数无效"})
+		return
+	}
+	if dryRun {
+		steps, err := installer.PlanInstall(req.Name, req.Version)
+		respondInstallPlan(c, req.Name, steps, err)
+		return
+	}
+
+	result, err := installer.Install(req.Name, req.Version)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "插件安装成功", "plugin": req.Name, "steps": result.Steps})
+}
+
+// UpgradePlugin 升级已安装的插件
+// @Summary 升级已安装的插件
+// @Description 将插件升级到满足版本约束、且满足已注册插件约束的最高版本，升级后依赖它的插件按依赖顺序重新加载；dry_run=true时只返回升级步骤
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param request body installPluginRequest false "版本约束"
+// @Param dry_run query bool false "是否只预览升级步骤"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/{name}/upgrade [post]
+func (pc *PluginController) UpgradePlugin(c *gin.Context) {
+	installer, ok := pluginRegistry(c)
+	if !ok {
+		return
+	}
+
+	pluginName := c.Param("name")
+	var req installPluginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+			return
+		}
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run参数无效"})
+		return
+	}
+	if dryRun {
+		steps, err := installer.PlanUpgrade(pluginName, req.Version)
+		respondInstallPlan(c, pluginName, steps, err)
+		return
+	}
+
+	result, err := installer.Upgrade(pluginName, req.Version)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	if len(result.Steps) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "插件已是最新版本", "plugin": pluginName, "steps": result.Steps})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "插件升级成功", "plugin": pluginName, "steps": result.Steps, "reloaded": result.Reloaded})
+}
+
+// RollbackPlugin 回滚插件到上一个版本
+// @Summary 回滚插件到上一个版本
+// @Description 将通过插件仓库安装的插件切换回上一个保留的版本，回滚后依赖它的插件按依赖顺序重新加载
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/{name}/rollback [post]
+func (pc *PluginController) RollbackPlugin(c *gin.Context) {
+	installer, ok := pluginRegistry(c)
+	if !ok {
+		return
+	}
+
+	pluginName := c.Param("name")
+	result, err := installer.Rollback(pluginName)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "插件回滚成功", "plugin": pluginName, "steps": result.Steps, "reloaded": result.Reloaded})
+}
+
+// UninstallPlugin 卸载插件
+// @Summary 卸载插件
+// @Description 注销并删除通过插件仓库安装的插件，仍有插件依赖它时返回409
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/{name}/uninstall [post]
+func (pc *PluginController) UninstallPlugin(c *gin.Context) {
+	installer, ok := pluginRegistry(c)
+	if !ok {
+		return
+	}
+
+	pluginName := c.Param("name")
+	result, err := installer.Uninstall(pluginName)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "插件卸载成功", "plugin": pluginName, "steps": result.Steps})
+}
+
+// respondInstallPlan 返回安装或升级的预览结果，计划无法执行时返回错误原因
+func respondInstallPlan(c *gin.Context, pluginName string, steps []registry.Step, err error) {
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plugin": pluginName, "dry_run": true, "steps": steps})
+}
+
 // GetDependencyGraph 获取插件依赖图
 // @Summary 获取插件依赖图
-// @Description 获取所有插件的依赖关系图
+// @Description 获取所有插件的依赖关系图，包含版本约束及实际解析到的依赖版本
 // @Tags 插件管理
 // @Security BearerAuth
-// @Success 200 {object} map[string][]string
+// @Success 200 {object} map[string]core.DependencyNode
 // @Router /api/v1/plugins/dependency-graph [get]
 func (pc *PluginController) GetDependencyGraph(c *gin.Context) {
-	dependencyGraph := plugins.PluginManager.GetDependencyGraph()
+	dependencyGraph := plugins.PluginManager.GetResolvedDependencyGraph()
 	c.JSON(http.StatusOK, dependencyGraph)
 }
+
+// GetPluginConfig 获取插件配置
+// @Summary 获取插件配置
+// @Description 获取插件当前生效的配置及其JSON Schema，敏感配置项以"***"显示
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/config [get]
+func (pc *PluginController) GetPluginConfig(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	cfg, schema, err := plugins.PluginManager.GetPluginConfig(pluginName)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plugin": pluginName, "config": cfg, "schema": schema})
+}
+
+// UpdatePluginConfig 更新插件配置
+// @Summary 更新插件配置
+// @Description 按插件声明的JSON Schema校验并整体替换插件配置，保存后立即生效；值为"***"的敏感配置项保持不变
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param config body map[string]interface{} true "插件配置"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/config [put]
+func (pc *PluginController) UpdatePluginConfig(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	cfg, err := plugins.PluginManager.UpdatePluginConfig(pluginName, req, c.GetUint("user_id"))
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "插件配置更新成功", "plugin": pluginName, "config": cfg})
+}
+
+// deployCanaryRequest 部署插件灰度版本的请求体
+type deployCanaryRequest struct {
+	Manifest string            `json:"manifest" binding:"required"` // 新版本plugin.json相对插件目录的路径
+	Policy   core.CanaryPolicy `json:"policy"`                      // 灰度流量策略
+}
+
+// DeployPluginCanary 部署插件灰度版本
+// @Summary 部署插件灰度版本
+// @Description 按插件目录中的清单加载插件的新版本，与当前稳定版本并存运行，并按灰度策略分配流量
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param request body deployCanaryRequest true "新版本清单路径和灰度策略"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/plugins/{name}/canary [post]
+func (pc *PluginController) DeployPluginCanary(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	var req deployCanaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if err := plugins.DeployCanary(pluginName, req.Manifest, req.Policy); err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	status, _ := plugins.PluginManager.GetCanary(pluginName)
+	c.JSON(http.StatusOK, gin.H{"message": "灰度版本部署成功", "canary": status})
+}
+
+// GetPluginCanary 获取插件灰度发布状态
+// @Summary 获取插件灰度发布状态
+// @Description 获取插件的稳定版本、灰度版本及流量分配策略
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} core.CanaryStatus
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/canary [get]
+func (pc *PluginController) GetPluginCanary(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	status, exists := plugins.PluginManager.GetCanary(pluginName)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "插件没有灰度版本", "plugin": pluginName})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// UpdateCanaryPolicy 更新插件灰度流量策略
+// @Summary 更新插件灰度流量策略
+// @Description 更新灰度版本的流量权重、固定版本的请求头/Cookie名称及租户白名单，立即生效
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param policy body core.CanaryPolicy true "灰度策略"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/canary/policy [put]
+func (pc *PluginController) UpdateCanaryPolicy(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	var policy core.CanaryPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if err := plugins.PluginManager.SetCanaryPolicy(pluginName, policy); err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "灰度策略更新成功", "plugin": pluginName, "policy": policy})
+}
+
+// PromoteCanary 提升插件灰度版本
+// @Summary 提升插件灰度版本
+// @Description 关闭当前稳定版本，由灰度版本接管全部流量
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/canary/promote [post]
+func (pc *PluginController) PromoteCanary(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	if err := plugins.PluginManager.PromoteCanary(pluginName); err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "灰度版本已提升为稳定版本", "plugin": pluginName})
+}
+
+// RollbackCanary 回滚插件灰度版本
+// @Summary 回滚插件灰度版本
+// @Description 关闭并移除灰度版本，全部流量回到稳定版本
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/canary/rollback [post]
+func (pc *PluginController) RollbackCanary(c *gin.Context) {
+	pluginName := c.Param("name")
+
+	if err := plugins.PluginManager.RollbackCanary(pluginName); err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "灰度版本已回滚", "plugin": pluginName})
+}
+
+// enqueueJobRequest 提交插件异步任务的请求体
+type enqueueJobRequest struct {
+	Params      map[string]interface{} `json:"params"`                                  // 传给插件Execute的参数
+	MaxAttempts int                    `json:"max_attempts" binding:"omitempty,max=10"` // 最大执行次数，0表示使用默认值
+}
+
+// pluginJobs 返回插件任务队列，未启用时返回错误响应
+func pluginJobs(c *gin.Context) (*core.JobQueue, bool) {
+	queue := plugins.PluginManager.Jobs()
+	if queue == nil {
+		respondPluginError(c, pkg.NewServiceUnavailableError("插件任务队列未启用", nil))
+		return nil, false
+	}
+	return queue, true
+}
+
+// jobIDParam 解析路径中的任务ID
+func jobIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// EnqueuePluginJob 提交插件异步任务
+// @Summary 提交插件异步任务
+// @Description 将插件Execute调用放入任务队列异步执行，立即返回任务ID，之后通过任务详情接口查询进度和结果
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param job body enqueueJobRequest true "任务参数"
+// @Success 202 {object} core.Job
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/{name}/jobs [post]
+func (pc *PluginController) EnqueuePluginJob(c *gin.Context) {
+	queue, ok := pluginJobs(c)
+	if !ok {
+		return
+	}
+
+	var req enqueueJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	job, err := queue.Enqueue(core.ContextFromGin(c), c.Param("name"), req.Params, core.JobOptions{MaxAttempts: req.MaxAttempts})
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListPluginJobs 获取插件异步任务列表
+// @Summary 获取插件异步任务列表
+// @Description 按提交时间倒序返回当前租户提交的插件任务，可按状态过滤
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param status query string false "任务状态：queued、running、succeeded、failed、canceled"
+// @Param limit query int false "返回数量，默认20，最大100"
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/plugins/{name}/jobs [get]
+func (pc *PluginController) ListPluginJobs(c *gin.Context) {
+	queue, ok := pluginJobs(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	jobs, err := queue.List(core.ContextFromGin(c), c.Param("name"), core.JobStatus(c.Query("status")), limit)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plugin": c.Param("name"), "jobs": jobs})
+}
+
+// GetPluginJob 获取插件异步任务详情
+// @Summary 获取插件异步任务详情
+// @Description 返回任务的状态、进度、执行次数，以及成功后的结果或失败原因
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param id path int true "任务ID"
+// @Success 200 {object} core.Job
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/jobs/{id} [get]
+func (pc *PluginController) GetPluginJob(c *gin.Context) {
+	queue, ok := pluginJobs(c)
+	if !ok {
+		return
+	}
+	id, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := queue.Get(core.ContextFromGin(c), c.Param("name"), id)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelPluginJob 取消插件异步任务
+// @Summary 取消插件异步任务
+// @Description 排队中的任务立即取消；执行中的任务取消其执行上下文，插件需响应上下文取消
+// @Tags 插件管理
+// @Security BearerAuth
+// @Param name path string true "插件名称"
+// @Param id path int true "任务ID"
+// @Success 200 {object} core.Job
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/plugins/{name}/jobs/{id}/cancel [post]
+func (pc *PluginController) CancelPluginJob(c *gin.Context) {
+	queue, ok := pluginJobs(c)
+	if !ok {
+		return
+	}
+	id, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := queue.Cancel(core.ContextFromGin(c), c.Param("name"), id)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// respondPluginError 按错误类型返回插件管理和工作流接口的错误响应
+func respondPluginError(c *gin.Context, err error) {
+	var appErr *pkg.AppError
+	if errors.As(err, &appErr) {
+		c.JSON(pkg.GetHTTPStatus(appErr), gin.H{"code": string(appErr.Code), "error": appErr.Message})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
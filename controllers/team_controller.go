@@ -6,6 +6,8 @@ import (
 
 	"weave/models"
 	"weave/pkg"
+	"weave/plugins"
+	"weave/plugins/core"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -317,6 +319,15 @@ func (tc *TeamController) AddTeamMember(c *gin.Context) {
 		NewValue:     newMember,
 	})
 
+	// 通知订阅了团队成员事件的插件
+	plugins.PluginManager.Publish(core.TopicTeamMemberAdded, core.TeamMemberAddedEvent{
+		TeamID:   newMember.TeamID,
+		UserID:   newMember.UserID,
+		Role:     newMember.Role,
+		TenantID: newMember.TenantID,
+		AddedBy:  userID,
+	})
+
 	c.JSON(http.StatusCreated, newMember)
 }
 
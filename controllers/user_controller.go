@@ -10,6 +10,8 @@ import (
 	"weave/config"
 	"weave/models"
 	"weave/pkg"
+	"weave/plugins"
+	"weave/plugins/core"
 	"weave/services/email"
 	"weave/utils"
 
@@ -103,6 +105,12 @@ func (uc *UserController) Register(c *gin.Context) {
 		return
 	}
 
+	// 通知订阅了用户注册事件的插件
+	plugins.PluginManager.Publish(core.TopicUserRegistered, core.UserRegisteredEvent{
+		UserID:   newUser.ID,
+		TenantID: newUser.TenantID,
+	})
+
 	// 不返回密码信息
 	newUser.Password = ""
 	c.JSON(http.StatusCreated, gin.H{"message": "注册成功", "user": newUser})
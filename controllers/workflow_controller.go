@@ -0,0 +1,390 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"weave/models"
+	"weave/pkg"
+	"weave/plugins"
+	"weave/plugins/core"
+	"weave/plugins/workflow"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WorkflowController 插件工作流控制器
+type WorkflowController struct{}
+
+// workflowRequest 创建和更新工作流的请求体
+// definition可以是JSON对象，也可以是YAML或JSON格式的字符串
+type workflowRequest struct {
+	Name        string          `json:"name" binding:"required,min=1,max=100"`
+	Description string          `json:"description" binding:"max=500"`
+	Definition  json.RawMessage `json:"definition" binding:"required"`
+	Enabled     *bool           `json:"enabled"`
+}
+
+// runWorkflowRequest 运行工作流的请求体
+type runWorkflowRequest struct {
+	Input map[string]interface{} `json:"input"`
+}
+
+// definitionSource 将请求中的工作流定义转换为保存的文本，并校验定义
+func definitionSource(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	var source string
+	if len(raw) > 0 && raw[0] == '"' {
+		if err := json.Unmarshal(raw, &source); err != nil {
+			return "", err
+		}
+	} else {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, raw, "", "  "); err != nil {
+			return "", err
+		}
+		source = indented.String()
+	}
+	if _, err := workflow.ParseDefinition(source); err != nil {
+		return "", err
+	}
+	return source, nil
+}
+
+// findWorkflow 查询当前租户的工作流，失败时返回错误响应
+func findWorkflow(c *gin.Context) (*models.Workflow, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondPluginError(c, pkg.NewValidationError("无效的工作流ID", err))
+		return nil, false
+	}
+
+	var wf models.Workflow
+	if err := pkg.DB.Where("id = ? AND tenant_id = ?", id, c.GetUint("tenant_id")).First(&wf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondPluginError(c, pkg.NewNotFoundError("工作流不存在", nil))
+		} else {
+			respondPluginError(c, pkg.NewDatabaseError("查询工作流失败", err))
+		}
+		return nil, false
+	}
+	return &wf, true
+}
+
+// nameTaken 判断租户内是否已有同名工作流
+func nameTaken(tenantID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := pkg.DB.Model(&models.Workflow{}).
+		Where("tenant_id = ? AND name = ? AND id != ?", tenantID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetWorkflows 获取工作流列表
+// @Summary 获取工作流列表
+// @Description 返回当前租户的全部工作流
+// @Tags 工作流
+// @Security BearerAuth
+// @Success 200 {array} models.Workflow
+// @Router /api/v1/workflows [get]
+func (wc *WorkflowController) GetWorkflows(c *gin.Context) {
+	var workflows []models.Workflow
+	if err := pkg.DB.Where("tenant_id = ?", c.GetUint("tenant_id")).Order("id").Find(&workflows).Error; err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("查询工作流失败", err))
+		return
+	}
+	c.JSON(http.StatusOK, workflows)
+}
+
+// GetWorkflow 获取工作流详情
+// @Summary 获取工作流详情
+// @Tags 工作流
+// @Security BearerAuth
+// @Param id path int true "工作流ID"
+// @Success 200 {object} models.Workflow
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/workflows/{id} [get]
+func (wc *WorkflowController) GetWorkflow(c *gin.Context) {
+	wf, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, wf)
+}
+
+// CreateWorkflow 创建工作流
+// @Summary 创建工作流
+// @Description 保存由插件调用组成的工作流定义，定义为YAML或JSON格式，保存前校验节点依赖和表达式
+// @Tags 工作流
+// @Security BearerAuth
+// @Param workflow body workflowRequest true "工作流"
+// @Success 201 {object} models.Workflow
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/workflows [post]
+func (wc *WorkflowController) CreateWorkflow(c *gin.Context) {
+	var req workflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondPluginError(c, pkg.NewValidationError("无效的请求参数: "+err.Error(), err))
+		return
+	}
+	source, err := definitionSource(req.Definition)
+	if err != nil {
+		respondPluginError(c, pkg.NewValidationError("无效的工作流定义: "+err.Error(), err))
+		return
+	}
+
+	tenantID := c.GetUint("tenant_id")
+	if taken, err := nameTaken(tenantID, req.Name, 0); err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("查询工作流失败", err))
+		return
+	} else if taken {
+		respondPluginError(c, pkg.NewConflictError("工作流名称已存在", nil))
+		return
+	}
+
+	wf := models.Workflow{
+		TenantID:    tenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		Definition:  source,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedBy:   c.GetUint("user_id"),
+		UpdatedBy:   c.GetUint("user_id"),
+	}
+	if err := pkg.DB.Create(&wf).Error; err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("保存工作流失败", err))
+		return
+	}
+
+	_ = pkg.AuditLogFromContext(c, pkg.AuditLogOptions{
+		Action:       "create",
+		ResourceType: "workflow",
+		ResourceID:   wf.Name,
+		NewValue:     wf,
+	})
+
+	c.JSON(http.StatusCreated, wf)
+}
+
+// UpdateWorkflow 更新工作流
+// @Summary 更新工作流
+// @Description 替换工作流的名称、描述和定义，已有的运行记录不受影响
+// @Tags 工作流
+// @Security BearerAuth
+// @Param id path int true "工作流ID"
+// @Param workflow body workflowRequest true "工作流"
+// @Success 200 {object} models.Workflow
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/workflows/{id} [put]
+func (wc *WorkflowController) UpdateWorkflow(c *gin.Context) {
+	wf, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req workflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondPluginError(c, pkg.NewValidationError("无效的请求参数: "+err.Error(), err))
+		return
+	}
+	source, err := definitionSource(req.Definition)
+	if err != nil {
+		respondPluginError(c, pkg.NewValidationError("无效的工作流定义: "+err.Error(), err))
+		return
+	}
+	if req.Name != wf.Name {
+		if taken, err := nameTaken(wf.TenantID, req.Name, wf.ID); err != nil {
+			respondPluginError(c, pkg.NewDatabaseError("查询工作流失败", err))
+			return
+		} else if taken {
+			respondPluginError(c, pkg.NewConflictError("工作流名称已存在", nil))
+			return
+		}
+	}
+
+	oldValue := *wf
+	wf.Name = req.Name
+	wf.Description = req.Description
+	wf.Definition = source
+	if req.Enabled != nil {
+		wf.Enabled = *req.Enabled
+	}
+	wf.UpdatedBy = c.GetUint("user_id")
+	if err := pkg.DB.Save(wf).Error; err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("保存工作流失败", err))
+		return
+	}
+
+	_ = pkg.AuditLogFromContext(c, pkg.AuditLogOptions{
+		Action:       "update",
+		ResourceType: "workflow",
+		ResourceID:   wf.Name,
+		OldValue:     oldValue,
+		NewValue:     wf,
+	})
+
+	c.JSON(http.StatusOK, wf)
+}
+
+// DeleteWorkflow 删除工作流及其运行记录
+// @Summary 删除工作流
+// @Tags 工作流
+// @Security BearerAuth
+// @Param id path int true "工作流ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/workflows/{id} [delete]
+func (wc *WorkflowController) DeleteWorkflow(c *gin.Context) {
+	wf, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+
+	err := pkg.DB.Transaction(func(tx *gorm.DB) error {
+		runIDs := tx.Model(&models.WorkflowRun{}).Select("id").Where("workflow_id = ?", wf.ID)
+		if err := tx.Where("run_id IN (?)", runIDs).Delete(&models.WorkflowStep{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workflow_id = ?", wf.ID).Delete(&models.WorkflowRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(wf).Error
+	})
+	if err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("删除工作流失败", err))
+		return
+	}
+
+	_ = pkg.AuditLogFromContext(c, pkg.AuditLogOptions{
+		Action:       "delete",
+		ResourceType: "workflow",
+		ResourceID:   wf.Name,
+		OldValue:     wf,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "工作流已删除"})
+}
+
+// RunWorkflow 运行工作流
+// @Summary 运行工作流
+// @Description 同步执行工作流并返回运行记录，节点失败时运行状态为failed，各节点的执行过程见steps
+// @Tags 工作流
+// @Security BearerAuth
+// @Param id path int true "工作流ID"
+// @Param run body runWorkflowRequest false "运行输入"
+// @Success 200 {object} workflow.Run
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/workflows/{id}/run [post]
+func (wc *WorkflowController) RunWorkflow(c *gin.Context) {
+	wf, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+	if !wf.Enabled {
+		respondPluginError(c, pkg.NewBadRequestError("工作流已禁用", nil))
+		return
+	}
+
+	var req runWorkflowRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondPluginError(c, pkg.NewValidationError("无效的请求参数: "+err.Error(), err))
+			return
+		}
+	}
+
+	def, err := workflow.ParseDefinition(wf.Definition)
+	if err != nil {
+		respondPluginError(c, pkg.NewBadRequestError("工作流定义无效: "+err.Error(), err))
+		return
+	}
+
+	engine := workflow.NewEngine(plugins.PluginManager, workflow.NewDBRunStore(nil))
+	run, err := engine.Run(core.ContextFromGin(c), wf.ID, def, req.Input)
+	if err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("保存工作流运行记录失败", err))
+		return
+	}
+
+	_ = pkg.AuditLogFromContext(c, pkg.AuditLogOptions{
+		Action:       "run",
+		ResourceType: "workflow",
+		ResourceID:   wf.Name,
+		NewValue:     gin.H{"run_id": run.ID, "status": run.Status},
+	})
+
+	c.JSON(http.StatusOK, run)
+}
+
+// GetWorkflowRuns 获取工作流运行记录列表
+// @Summary 获取工作流运行记录列表
+// @Description 按开始时间倒序返回运行记录，不含节点执行记录
+// @Tags 工作流
+// @Security BearerAuth
+// @Param id path int true "工作流ID"
+// @Param limit query int false "返回数量，默认20，最大100"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/workflows/{id}/runs [get]
+func (wc *WorkflowController) GetWorkflowRuns(c *gin.Context) {
+	wf, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	runs, err := workflow.ListRuns(pkg.DB, wf.ID, wf.TenantID, limit)
+	if err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("查询工作流运行记录失败", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"workflow_id": wf.ID, "runs": runs})
+}
+
+// GetWorkflowRun 获取工作流运行详情
+// @Summary 获取工作流运行详情
+// @Description 返回运行记录及各节点的参数、输出、错误和执行次数
+// @Tags 工作流
+// @Security BearerAuth
+// @Param id path int true "工作流ID"
+// @Param run_id path int true "运行记录ID"
+// @Success 200 {object} workflow.Run
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/workflows/{id}/runs/{run_id} [get]
+func (wc *WorkflowController) GetWorkflowRun(c *gin.Context) {
+	wf, ok := findWorkflow(c)
+	if !ok {
+		return
+	}
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 64)
+	if err != nil {
+		respondPluginError(c, pkg.NewValidationError("无效的运行记录ID", err))
+		return
+	}
+
+	run, err := workflow.GetRun(pkg.DB, wf.ID, uint(runID), wf.TenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondPluginError(c, pkg.NewNotFoundError("运行记录不存在", nil))
+		return
+	}
+	if err != nil {
+		respondPluginError(c, pkg.NewDatabaseError("查询工作流运行记录失败", err))
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
@@ -152,6 +152,19 @@ func main() {
 
 	// 监控指标和中间件已在路由设置中配置
 
+	// 设置插件键值存储后端（需在注册插件之前）
+	if err := plugins.SetupPluginStorage(); err != nil {
+		pkg.Error("Failed to setup plugin storage", zap.Error(err))
+	}
+
+	// 设置插件熔断器默认配置
+	plugins.SetupPluginCircuitBreaker()
+
+	// 读取保存的插件启用状态并启动集群同步（需在注册插件之前）
+	if err := plugins.SetupPluginStateSync(); err != nil {
+		pkg.Error("Failed to setup plugin state sync", zap.Error(err))
+	}
+
 	// 注册插件
 	registerPlugins(router)
 
@@ -164,6 +177,9 @@ func main() {
 		pkg.Error("Failed to initialize plugin system", zap.Error(err))
 	}
 
+	// 启动插件异步任务队列
+	plugins.StartPluginJobs()
+
 	// 启动服务器
 	port := config.Config.Server.Port
 	instanceID := config.Config.Server.InstanceID
@@ -196,6 +212,26 @@ func main() {
 	// 停止插件监控器
 	plugins.PluginManager.StopPluginWatcher()
 
+	// 停止插件启用状态同步
+	plugins.StopPluginStateSync()
+
+	// 停止插件任务队列，超时未结束的任务会在下次启动时重新执行
+	jobsCtx, jobsCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := plugins.StopPluginJobs(jobsCtx); err != nil {
+		pkg.Error("Plugin job queue shutdown error", zap.Error(err))
+	}
+	jobsCancel()
+
+	// 按依赖关系逆序关闭全部插件（先等待进行中的插件请求结束）
+	pluginCtx, pluginCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if _, err := plugins.PluginManager.ShutdownAll(pluginCtx); err != nil {
+		pkg.Error("Plugin shutdown error", zap.Error(err))
+	}
+	pluginCancel()
+
+	// 结束进程外插件进程
+	plugins.UnloadProcessPlugins()
+
 	// 创建超时上下文，用于优雅关闭服务器和数据库
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -264,6 +300,12 @@ func registerPlugins(router *gin.Engine) {
 		pkg.Info("Successfully registered plugin", zap.String("plugin", sampleDependentPlugin.Name()))
 	}
 
+	// 注册配置中的进程外插件
+	plugins.LoadProcessPlugins()
+
+	// 注册通过插件仓库安装的插件
+	plugins.LoadInstalledPlugins()
+
 	// 所有插件注册完成，输出确认日志
 	pkg.Info("插件已全部注册运行成功")
 }
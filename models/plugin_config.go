@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PluginConfig 插件配置模型
+// 保存通过管理接口修改的插件配置（JSON格式），优先级高于配置文件中的plugins.<插件名>配置段
+type PluginConfig struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PluginName string    `gorm:"size:100;uniqueIndex;not null" json:"plugin_name"` // 插件名称
+	Config     string    `gorm:"type:text" json:"config"`                          // 插件配置（JSON格式）
+	UpdatedBy  uint      `json:"updated_by"`                                       // 最后修改配置的用户ID
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PluginConfig) TableName() string {
+	return "plugin_configs"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// PluginJob 插件异步任务模型
+// 保存通过任务队列异步执行的插件Execute调用，多个实例共享同一张表，按状态条件更新领取任务
+type PluginJob struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	PluginName      string     `gorm:"size:100;not null;index" json:"plugin_name"`                 // 插件名称
+	Params          string     `gorm:"type:text" json:"params"`                                    // 执行参数（JSON格式）
+	Status          string     `gorm:"size:20;not null;index:idx_plugin_jobs_claim" json:"status"` // 任务状态：queued、running、succeeded、failed、canceled
+	Progress        int        `gorm:"not null;default:0" json:"progress"`                         // 进度（0-100）
+	ProgressMessage string     `gorm:"size:255" json:"progress_message"`                           // 进度说明
+	Result          string     `gorm:"type:longtext" json:"result"`                                // 执行结果（JSON格式）
+	Error           string     `gorm:"type:text" json:"error"`                                     // 最近一次失败的错误信息
+	Attempts        int        `gorm:"not null;default:0" json:"attempts"`                         // 已执行次数
+	MaxAttempts     int        `gorm:"not null;default:1" json:"max_attempts"`                     // 最大执行次数
+	CancelRequested bool       `gorm:"not null;default:false" json:"cancel_requested"`             // 运行中的任务是否已请求取消
+	UserID          uint       `json:"user_id"`                                                    // 提交任务的用户ID
+	TenantID        uint       `gorm:"index" json:"tenant_id"`                                     // 提交任务的租户ID
+	RequestID       string     `gorm:"size:64" json:"request_id"`                                  // 提交任务的请求ID
+	NextRunAt       time.Time  `gorm:"index:idx_plugin_jobs_claim" json:"next_run_at"`             // 最早可执行时间，重试时按退避时间推迟
+	StartedAt       *time.Time `json:"started_at"`                                                 // 最近一次开始执行的时间
+	FinishedAt      *time.Time `json:"finished_at"`                                                // 结束时间
+	ExpiresAt       *time.Time `gorm:"index" json:"expires_at"`                                    // 结果保留截止时间，之后任务记录被清理
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PluginJob) TableName() string {
+	return "plugin_jobs"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// PluginKV 插件键值存储模型
+// 插件通过core.PluginStorage读写，按插件名称和租户隔离，无需为简单状态单独建表
+type PluginKV struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	PluginName string     `gorm:"size:100;not null;uniqueIndex:idx_plugin_kv_key" json:"plugin_name"` // 插件名称
+	TenantID   uint       `gorm:"not null;default:0;uniqueIndex:idx_plugin_kv_key" json:"tenant_id"`  // 租户ID，0表示全局
+	StorageKey string     `gorm:"size:255;not null;uniqueIndex:idx_plugin_kv_key" json:"key"`         // 存储键
+	Value      []byte     `json:"value"`                                                              // 存储值
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`                                            // 过期时间，为空表示永不过期
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PluginKV) TableName() string {
+	return "plugin_kv"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// PluginState 插件启用状态模型
+// 保存通过管理接口启用或禁用插件的结果，实例启动和注册插件时按此恢复，多个实例共享
+type PluginState struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PluginName string    `gorm:"size:100;uniqueIndex;not null" json:"plugin_name"` // 插件名称
+	Enabled    bool      `gorm:"not null;default:true" json:"enabled"`             // 是否启用
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PluginState) TableName() string {
+	return "plugin_states"
+}
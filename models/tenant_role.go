@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TenantRole 用户在租户内的角色
+// 租户角色（如admin）用于校验插件路由和管理接口的角色要求，只能由运维人员在数据库中分配；
+// 用户创建团队获得的团队所有者等团队角色不是租户角色
+type TenantRole struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index:idx_tenant_role,unique" json:"user_id"`
+	TenantID  uint      `gorm:"index:idx_tenant_role,unique" json:"tenant_id"`
+	Role      string    `gorm:"size:50;not null;index:idx_tenant_role,unique" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -49,7 +49,7 @@ func MigrateTables(db *gorm.DB) error {
 	if err := db.AutoMigrate(&Team{}); err != nil {
 		return err
 	}
-	if err := db.AutoMigrate(&Note{}, &LoginHistory{}, &AuditLog{}, &ToolHistory{}); err != nil {
+	if err := db.AutoMigrate(&Note{}, &LoginHistory{}, &AuditLog{}, &ToolHistory{}, &PluginConfig{}, &PluginKV{}, &PluginJob{}, &PluginState{}, &TenantRole{}, &Workflow{}, &WorkflowRun{}, &WorkflowStep{}); err != nil {
 		return err
 	}
 	if err := db.AutoMigrate(&TeamMember{}); err != nil {
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// Workflow 插件工作流模型
+// 保存由多个插件Execute调用组成的有向无环图定义（YAML或JSON格式），按租户隔离
+type Workflow struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"index;uniqueIndex:idx_workflows_tenant_name" json:"tenant_id"`        // 所属租户ID
+	Name        string    `gorm:"size:100;not null;uniqueIndex:idx_workflows_tenant_name" json:"name"` // 工作流名称，租户内唯一
+	Description string    `gorm:"size:500" json:"description"`                                         // 工作流描述
+	Definition  string    `gorm:"type:text;not null" json:"definition"`                                // 工作流定义（YAML或JSON格式）
+	Enabled     bool      `gorm:"not null;default:true" json:"enabled"`                                // 是否允许运行
+	CreatedBy   uint      `json:"created_by"`                                                          // 创建者用户ID
+	UpdatedBy   uint      `json:"updated_by"`                                                          // 最后修改者用户ID
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Workflow) TableName() string {
+	return "workflows"
+}
+
+// WorkflowRun 工作流运行记录模型
+type WorkflowRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	WorkflowID uint       `gorm:"not null;index" json:"workflow_id"` // 工作流ID
+	TenantID   uint       `gorm:"index" json:"tenant_id"`            // 运行所属租户ID
+	UserID     uint       `json:"user_id"`                           // 触发运行的用户ID
+	RequestID  string     `gorm:"size:64" json:"request_id"`         // 触发运行的请求ID
+	Status     string     `gorm:"size:20;not null" json:"status"`    // 运行状态：running、succeeded、failed
+	Input      string     `gorm:"type:text" json:"input"`            // 运行输入（JSON格式）
+	Output     string     `gorm:"type:longtext" json:"output"`       // 运行输出（JSON格式）
+	Error      string     `gorm:"type:text" json:"error"`            // 失败原因
+	StartedAt  time.Time  `json:"started_at"`                        // 开始时间
+	FinishedAt *time.Time `json:"finished_at"`                       // 结束时间
+}
+
+// TableName 指定表名
+func (WorkflowRun) TableName() string {
+	return "workflow_runs"
+}
+
+// WorkflowStep 工作流运行中单个节点的执行记录
+type WorkflowStep struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	RunID      uint       `gorm:"not null;index" json:"run_id"`       // 运行记录ID
+	NodeID     string     `gorm:"size:100;not null" json:"node_id"`   // 节点ID
+	Plugin     string     `gorm:"size:100;not null" json:"plugin"`    // 调用的插件名称
+	Status     string     `gorm:"size:20;not null" json:"status"`     // 节点状态：running、succeeded、failed、skipped、canceled
+	Params     string     `gorm:"type:text" json:"params"`            // 模板渲染后的参数（JSON格式），扇出节点为各项参数的数组
+	Output     string     `gorm:"type:longtext" json:"output"`        // 节点输出（JSON格式）
+	Error      string     `gorm:"type:text" json:"error"`             // 失败或跳过的原因
+	Attempts   int        `gorm:"not null;default:0" json:"attempts"` // 执行次数（含重试），扇出节点为各项执行次数之和
+	StartedAt  *time.Time `json:"started_at"`                         // 开始时间
+	FinishedAt *time.Time `json:"finished_at"`                        // 结束时间
+}
+
+// TableName 指定表名
+func (WorkflowStep) TableName() string {
+	return "workflow_steps"
+}
@@ -133,7 +133,7 @@ var HTTPStatusMap = map[ErrorCode]int{
 	// 插件错误
 	ErrPluginError:      500,
 	ErrPluginNotFound:   404,
-	ErrPluginDisabled:   403,
+	ErrPluginDisabled:   503,
 	ErrPluginDependency: 500,
 	ErrPluginInit:       500,
 	ErrPluginExecution:  500,
@@ -79,6 +79,12 @@ var (
 	PluginErrors            *prometheus.CounterVec
 	PluginMemoryUsage       *prometheus.GaugeVec
 	PluginReloads           *prometheus.CounterVec
+	PluginVersionRequests   *prometheus.CounterVec
+	PluginVersionDuration   *prometheus.HistogramVec
+	PluginJobs              *prometheus.CounterVec
+	PluginJobDuration       *prometheus.HistogramVec
+	PluginBuilds            *prometheus.CounterVec
+	PluginBuildDuration     *prometheus.HistogramVec
 
 	// 系统指标
 	memoryUsage = promauto.NewGauge(
@@ -159,6 +165,57 @@ func init() {
 		},
 		[]string{"plugin_name", "success"},
 	)
+
+	PluginVersionRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_version_requests_total",
+			Help: "Total number of plugin calls per plugin version",
+		},
+		[]string{"plugin_name", "version", "channel", "success"},
+	)
+
+	PluginVersionDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_version_request_duration_seconds",
+			Help:    "Plugin call duration per plugin version in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"plugin_name", "version", "channel"},
+	)
+
+	PluginJobs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_jobs_total",
+			Help: "Total number of plugin job runs by outcome",
+		},
+		[]string{"plugin_name", "status"},
+	)
+
+	PluginJobDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_job_duration_seconds",
+			Help:    "Plugin job run duration in seconds",
+			Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 1800, 3600},
+		},
+		[]string{"plugin_name"},
+	)
+
+	PluginBuilds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_builds_total",
+			Help: "Total number of plugin builds from source by result",
+		},
+		[]string{"plugin_name", "success"},
+	)
+
+	PluginBuildDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_build_duration_seconds",
+			Help:    "Plugin build duration in seconds",
+			Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300},
+		},
+		[]string{"plugin_name"},
+	)
 }
 
 // MetricsManager 指标管理器
@@ -248,6 +305,28 @@ func RecordPluginReload(pluginName string, success bool) {
 	PluginReloads.WithLabelValues(pluginName, successStr).Inc()
 }
 
+// RecordPluginVersionCall 记录插件指定版本的调用情况，用于灰度发布时对比新旧版本
+// channel为"http"（路由请求）或"execute"（ExecutePlugin调用）
+func RecordPluginVersionCall(pluginName, version, channel string, success bool, duration time.Duration) {
+	successStr := strconv.FormatBool(success)
+	PluginVersionRequests.WithLabelValues(pluginName, version, channel, successStr).Inc()
+	PluginVersionDuration.WithLabelValues(pluginName, version, channel).Observe(duration.Seconds())
+}
+
+// RecordPluginJob 记录插件异步任务的一次运行
+// status为本次运行后的任务状态，重试时为"queued"
+func RecordPluginJob(pluginName, status string, duration time.Duration) {
+	PluginJobs.WithLabelValues(pluginName, status).Inc()
+	PluginJobDuration.WithLabelValues(pluginName).Observe(duration.Seconds())
+}
+
+// RecordPluginBuild 记录插件源码的一次编译
+func RecordPluginBuild(pluginName string, success bool, duration time.Duration) {
+	successStr := strconv.FormatBool(success)
+	PluginBuilds.WithLabelValues(pluginName, successStr).Inc()
+	PluginBuildDuration.WithLabelValues(pluginName).Observe(duration.Seconds())
+}
+
 // UpdateSystemMetrics 更新系统指标
 func UpdateSystemMetrics() {
 	// 更新系统运行时间
@@ -0,0 +1,3 @@
+-- Rollback plugin configs table
+
+DROP TABLE IF EXISTS plugin_configs;
@@ -0,0 +1,13 @@
+-- Plugin configs table (MySQL)
+
+-- 插件配置表，保存通过管理接口修改的插件配置
+CREATE TABLE IF NOT EXISTS plugin_configs (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    plugin_name varchar(100) NOT NULL,
+    config text,
+    updated_by bigint unsigned DEFAULT NULL,
+    created_at timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_plugin_name (plugin_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- Rollback plugin key-value storage table
+
+DROP TABLE IF EXISTS plugin_kv;
@@ -0,0 +1,16 @@
+-- Plugin key-value storage table (MySQL)
+
+-- 插件键值存储表，按插件名称和租户隔离
+CREATE TABLE IF NOT EXISTS plugin_kv (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    plugin_name varchar(100) NOT NULL,
+    tenant_id bigint unsigned NOT NULL DEFAULT 0,
+    storage_key varchar(255) NOT NULL,
+    value longblob,
+    expires_at timestamp NULL DEFAULT NULL,
+    created_at timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_plugin_kv_key (plugin_name, tenant_id, storage_key),
+    KEY idx_plugin_kv_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
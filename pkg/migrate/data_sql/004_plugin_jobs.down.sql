@@ -0,0 +1,3 @@
+-- Rollback plugin jobs table
+
+DROP TABLE IF EXISTS plugin_jobs;
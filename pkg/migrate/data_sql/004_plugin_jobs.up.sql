@@ -0,0 +1,30 @@
+-- Plugin jobs table (MySQL)
+
+-- 插件异步任务表，保存排队、执行中和保留期内已结束的任务
+CREATE TABLE IF NOT EXISTS plugin_jobs (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    plugin_name varchar(100) NOT NULL,
+    params text,
+    status varchar(20) NOT NULL,
+    progress bigint NOT NULL DEFAULT 0,
+    progress_message varchar(255) DEFAULT NULL,
+    result longtext,
+    error text,
+    attempts bigint NOT NULL DEFAULT 0,
+    max_attempts bigint NOT NULL DEFAULT 1,
+    cancel_requested tinyint(1) NOT NULL DEFAULT 0,
+    user_id bigint unsigned DEFAULT NULL,
+    tenant_id bigint unsigned DEFAULT NULL,
+    request_id varchar(64) DEFAULT NULL,
+    next_run_at timestamp NULL DEFAULT NULL,
+    started_at timestamp NULL DEFAULT NULL,
+    finished_at timestamp NULL DEFAULT NULL,
+    expires_at timestamp NULL DEFAULT NULL,
+    created_at timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY idx_plugin_jobs_plugin_name (plugin_name),
+    KEY idx_plugin_jobs_tenant_id (tenant_id),
+    KEY idx_plugin_jobs_claim (status, next_run_at),
+    KEY idx_plugin_jobs_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
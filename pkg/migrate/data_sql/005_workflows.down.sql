@@ -0,0 +1,5 @@
+-- Rollback workflow tables
+
+DROP TABLE IF EXISTS workflow_steps;
+DROP TABLE IF EXISTS workflow_runs;
+DROP TABLE IF EXISTS workflows;
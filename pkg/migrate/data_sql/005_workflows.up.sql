@@ -0,0 +1,53 @@
+-- Workflow tables (MySQL)
+
+-- 插件工作流定义表
+CREATE TABLE IF NOT EXISTS workflows (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    tenant_id bigint unsigned DEFAULT NULL,
+    name varchar(100) NOT NULL,
+    description varchar(500) DEFAULT NULL,
+    definition text NOT NULL,
+    enabled tinyint(1) NOT NULL DEFAULT 1,
+    created_by bigint unsigned DEFAULT NULL,
+    updated_by bigint unsigned DEFAULT NULL,
+    created_at timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_workflows_tenant_name (tenant_id, name),
+    KEY idx_workflows_tenant_id (tenant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 工作流运行记录表
+CREATE TABLE IF NOT EXISTS workflow_runs (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    workflow_id bigint unsigned NOT NULL,
+    tenant_id bigint unsigned DEFAULT NULL,
+    user_id bigint unsigned DEFAULT NULL,
+    request_id varchar(64) DEFAULT NULL,
+    status varchar(20) NOT NULL,
+    input text,
+    output longtext,
+    error text,
+    started_at timestamp NULL DEFAULT NULL,
+    finished_at timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY idx_workflow_runs_workflow_id (workflow_id),
+    KEY idx_workflow_runs_tenant_id (tenant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 工作流节点执行记录表
+CREATE TABLE IF NOT EXISTS workflow_steps (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    run_id bigint unsigned NOT NULL,
+    node_id varchar(100) NOT NULL,
+    plugin varchar(100) NOT NULL,
+    status varchar(20) NOT NULL,
+    params text,
+    output longtext,
+    error text,
+    attempts bigint NOT NULL DEFAULT 0,
+    started_at timestamp NULL DEFAULT NULL,
+    finished_at timestamp NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY idx_workflow_steps_run_id (run_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- Rollback plugin states table
+
+DROP TABLE IF EXISTS plugin_states;
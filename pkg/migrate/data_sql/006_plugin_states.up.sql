@@ -0,0 +1,12 @@
+-- Plugin states table (MySQL)
+
+-- 插件启用状态表，保存通过管理接口启用或禁用插件的结果，重启和多实例间保持一致
+CREATE TABLE IF NOT EXISTS plugin_states (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    plugin_name varchar(100) NOT NULL,
+    enabled tinyint(1) NOT NULL DEFAULT 1,
+    created_at timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_plugin_states_plugin_name (plugin_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- Rollback tenant roles table
+
+DROP TABLE IF EXISTS tenant_roles;
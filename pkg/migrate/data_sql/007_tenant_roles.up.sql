@@ -0,0 +1,12 @@
+-- Tenant roles table (MySQL)
+
+-- 租户角色表，记录用户在租户内的角色（如admin），由运维人员分配；团队角色见team_members
+CREATE TABLE IF NOT EXISTS tenant_roles (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    user_id bigint unsigned NOT NULL,
+    tenant_id bigint unsigned NOT NULL,
+    role varchar(50) NOT NULL,
+    created_at timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_tenant_role (user_id, tenant_id, role)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
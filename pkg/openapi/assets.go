@@ -0,0 +1,16 @@
+package openapi
+
+import _ "embed"
+
+// DocsScriptPath 文档页面脚本的访问路径
+const DocsScriptPath = "/docs/docs.js"
+
+// docsScript 渲染文档页面的脚本，内嵌在服务中，不从第三方CDN加载
+//
+//go:embed assets/docs.js
+var docsScript []byte
+
+// DocsScript 返回文档页面使用的脚本
+func DocsScript() []byte {
+	return docsScript
+}
@@ -0,0 +1,205 @@
+// Weave API文档页面：读取OpenAPI 3文档并渲染接口列表
+// 脚本内嵌在服务中，不依赖第三方库；文档内容全部通过textContent写入，不解释为HTML
+(function () {
+  "use strict";
+
+  var root = document.getElementById("docs");
+  var specURL = root.getAttribute("data-spec-url");
+
+  // el 创建元素，text为字符串时作为文本内容，children中的字符串同样作为文本节点
+  function el(tag, className, children) {
+    var node = document.createElement(tag);
+    if (className) {
+      node.className = className;
+    }
+    (children || []).forEach(function (child) {
+      if (child === null || child === undefined) {
+        return;
+      }
+      node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+    });
+    return node;
+  }
+
+  // anchor 将标签名称转换为页面内锚点
+  function anchor(prefix, name) {
+    return prefix + "-" + encodeURIComponent(name).replace(/%/g, "_");
+  }
+
+  // resolve 解析#/components/schemas/下的$ref
+  function resolve(spec, schema) {
+    if (schema && schema.$ref) {
+      var name = schema.$ref.replace("#/components/schemas/", "");
+      var target = ((spec.components || {}).schemas || {})[name];
+      return { name: name, schema: target || {} };
+    }
+    return { name: "", schema: schema || {} };
+  }
+
+  // typeName 返回模式的类型说明，如string、array<Note>
+  function typeName(spec, schema) {
+    var resolved = resolve(spec, schema);
+    var s = resolved.schema;
+    if (resolved.name) {
+      return resolved.name;
+    }
+    if (s.type === "array") {
+      return "array<" + typeName(spec, s.items) + ">";
+    }
+    var name = s.type || "object";
+    if (s.format) {
+      name += " (" + s.format + ")";
+    }
+    return name;
+  }
+
+  // renderSchema 将模式渲染为字段列表，seen用于避免循环引用
+  function renderSchema(spec, schema, seen) {
+    var resolved = resolve(spec, schema);
+    var s = resolved.schema;
+    if (resolved.name) {
+      if (seen[resolved.name]) {
+        return el("span", "type", [resolved.name]);
+      }
+      seen = Object.assign({}, seen);
+      seen[resolved.name] = true;
+    }
+    if (s.type === "array") {
+      return el("div", "schema", [el("span", "type", ["array<" + typeName(spec, s.items) + ">"]), renderSchema(spec, s.items, seen)]);
+    }
+    var properties = s.properties || {};
+    var names = Object.keys(properties);
+    if (names.length === 0) {
+      return el("span", "type", [typeName(spec, schema)]);
+    }
+    var required = s.required || [];
+    var list = el("ul", "fields");
+    names.forEach(function (name) {
+      var property = properties[name];
+      var item = el("li", "", [
+        el("code", "", [name]),
+        " ",
+        el("span", "type", [typeName(spec, property)]),
+        required.indexOf(name) >= 0 ? el("span", "required", [" 必填"]) : null,
+        resolve(spec, property).schema.description ? el("span", "desc", [" " + resolve(spec, property).schema.description]) : null,
+      ]);
+      var nested = resolve(spec, property.type === "array" ? property.items : property).schema;
+      if (nested.properties && Object.keys(nested.properties).length > 0) {
+        item.appendChild(renderSchema(spec, property.type === "array" ? property.items : property, seen));
+      }
+      list.appendChild(item);
+    });
+    return list;
+  }
+
+  // renderContent 渲染请求体或响应体的内容
+  function renderContent(spec, content) {
+    var media = (content || {})["application/json"];
+    if (!media || !media.schema) {
+      return null;
+    }
+    return renderSchema(spec, media.schema, {});
+  }
+
+  // renderOperation 渲染单个接口
+  function renderOperation(spec, path, method, op) {
+    var section = el("section", "operation", [
+      el("h3", "", [el("span", "method method-" + method, [method.toUpperCase()]), " ", el("code", "", [path])]),
+    ]);
+    if (op.summary) {
+      section.appendChild(el("p", "summary", [op.summary]));
+    }
+    if (op.description) {
+      section.appendChild(el("p", "desc", [op.description]));
+    }
+    if (op.security && op.security.length > 0) {
+      section.appendChild(el("p", "auth", ["需要认证"]));
+    }
+
+    if (op.parameters && op.parameters.length > 0) {
+      var table = el("table", "", [el("tr", "", [el("th", "", ["参数"]), el("th", "", ["位置"]), el("th", "", ["类型"]), el("th", "", ["说明"])])]);
+      op.parameters.forEach(function (param) {
+        table.appendChild(el("tr", "", [
+          el("td", "", [el("code", "", [param.name]), param.required ? el("span", "required", [" 必填"]) : null]),
+          el("td", "", [param.in]),
+          el("td", "", [typeName(spec, param.schema)]),
+          el("td", "", [param.description || ""]),
+        ]));
+      });
+      section.appendChild(el("h4", "", ["参数"]));
+      section.appendChild(table);
+    }
+
+    if (op.requestBody) {
+      section.appendChild(el("h4", "", ["请求体"]));
+      section.appendChild(renderContent(spec, op.requestBody.content) || el("p", "desc", ["无结构说明"]));
+    }
+
+    var statuses = Object.keys(op.responses || {}).sort();
+    if (statuses.length > 0) {
+      section.appendChild(el("h4", "", ["响应"]));
+      statuses.forEach(function (status) {
+        var response = op.responses[status];
+        var block = el("div", "response", [el("code", "status", [status]), " ", response.description || ""]);
+        var body = renderContent(spec, response.content);
+        if (body) {
+          block.appendChild(body);
+        }
+        section.appendChild(block);
+      });
+    }
+    return section;
+  }
+
+  // render 按标签分组渲染全部接口，没有标签的接口归入"其他"
+  function render(spec) {
+    var groups = {};
+    Object.keys(spec.paths || {}).sort().forEach(function (path) {
+      var item = spec.paths[path];
+      ["get", "post", "put", "patch", "delete"].forEach(function (method) {
+        var op = item[method];
+        if (!op) {
+          return;
+        }
+        var tag = (op.tags && op.tags[0]) || "其他";
+        (groups[tag] = groups[tag] || []).push({ path: path, method: method, op: op });
+      });
+    });
+
+    var info = spec.info || {};
+    var nav = el("nav", "", [el("h2", "", [info.title || "API"]), el("p", "version", [info.version ? "版本 " + info.version : ""])]);
+    var main = el("main", "", [el("h1", "", [info.title || "API"])]);
+    if (info.description) {
+      main.appendChild(el("p", "desc", [info.description]));
+    }
+
+    Object.keys(groups).sort().forEach(function (tag) {
+      var link = el("a", "", [tag]);
+      link.href = "#" + anchor("tag", tag);
+      nav.appendChild(link);
+
+      var heading = el("h2", "", [tag]);
+      heading.id = anchor("tag", tag);
+      main.appendChild(heading);
+      groups[tag].forEach(function (entry) {
+        main.appendChild(renderOperation(spec, entry.path, entry.method, entry.op));
+      });
+    });
+
+    root.textContent = "";
+    root.appendChild(nav);
+    root.appendChild(main);
+  }
+
+  fetch(specURL, { credentials: "same-origin" })
+    .then(function (response) {
+      if (!response.ok) {
+        throw new Error("HTTP " + response.status);
+      }
+      return response.json();
+    })
+    .then(render)
+    .catch(function (err) {
+      root.textContent = "加载API文档失败: " + err.message;
+    });
+})();
@@ -0,0 +1,335 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version 生成的文档遵循的OpenAPI版本
+const Version = "3.0.3"
+
+// BearerAuth 安全方案名称，对应Authorization: Bearer {token}
+const BearerAuth = "BearerAuth"
+
+// Document OpenAPI文档
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info 文档基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem 单个路径的全部操作，键为小写的HTTP方法
+type PathItem map[string]*Operation
+
+// Operation 单个接口
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter 接口参数
+type Parameter struct {
+	Name        string                 `json:"name"`
+	In          string                 `json:"in"` // path或query
+	Required    bool                   `json:"required,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType 请求体或响应的内容类型
+type MediaType struct {
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// Components 可复用的Schema和安全方案
+type Components struct {
+	Schemas         map[string]interface{} `json:"schemas,omitempty"`
+	SecuritySchemes map[string]interface{} `json:"securitySchemes,omitempty"`
+}
+
+// Builder OpenAPI文档构建器，非并发安全
+type Builder struct {
+	doc *Document
+}
+
+// NewBuilder 创建文档构建器
+func NewBuilder(info Info) *Builder {
+	return &Builder{doc: &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]interface{}),
+			SecuritySchemes: map[string]interface{}{
+				BearerAuth: map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}}
+}
+
+// Document 返回构建完成的文档
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+// HasOperation 判断文档中是否已有指定接口
+func (b *Builder) HasOperation(method, path string) bool {
+	item, exists := b.doc.Paths[ConvertPath(path)]
+	return exists && item[strings.ToLower(method)] != nil
+}
+
+// AddOperation 添加接口，path可以使用Gin的路径参数写法（:id、*path）
+// 路径中未在op.Parameters声明的参数会自动补充；同一接口重复添加时后者覆盖前者
+func (b *Builder) AddOperation(method, path string, op *Operation) {
+	openAPIPath := ConvertPath(path)
+	declared := make(map[string]bool)
+	for _, param := range op.Parameters {
+		if param.In == "path" {
+			declared[param.Name] = true
+		}
+	}
+	for _, name := range PathParams(path) {
+		if !declared[name] {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: map[string]interface{}{"type": "string"}})
+		}
+	}
+	if op.Responses == nil {
+		op.Responses = make(map[string]*Response)
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: "成功"}
+	}
+
+	item, exists := b.doc.Paths[openAPIPath]
+	if !exists {
+		item = make(PathItem)
+		b.doc.Paths[openAPIPath] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// AddGinRoute 根据Gin路由信息添加接口，Gin路由不携带描述信息，只生成路径、参数和操作ID
+func (b *Builder) AddGinRoute(route gin.RouteInfo, secured bool) {
+	op := &Operation{Tags: []string{routeTag(route.Path)}}
+	// 匿名函数的名称不唯一，不作为操作ID
+	if name := HandlerName(route.Handler); !strings.Contains(name, ".func") {
+		op.OperationID = name
+	}
+	if secured {
+		op.Security = []map[string][]string{{BearerAuth: {}}}
+	}
+	b.AddOperation(route.Method, route.Path, op)
+}
+
+// JSONBody 返回内容类型为application/json的内容描述
+func (b *Builder) JSONBody(v interface{}) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: b.SchemaFor(v)}}
+}
+
+// timeType time.Time的反射类型
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor 通过反射生成v的类型对应的JSON Schema
+// 具名结构体放入components.schemas并以$ref引用；字段名取json标签，
+// binding标签包含required的字段为必填，description标签作为字段说明
+func (b *Builder) SchemaFor(v interface{}) map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	if t, ok := v.(reflect.Type); ok {
+		return b.schemaOf(t)
+	}
+	return b.schemaOf(reflect.TypeOf(v))
+}
+
+// schemaOf 生成类型对应的Schema
+func (b *Builder) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := componentName(t)
+		if _, exists := b.doc.Components.Schemas[name]; !exists {
+			// 先占位，避免自引用的结构体无限递归
+			b.doc.Components.Schemas[name] = map[string]interface{}{"type": "object"}
+			b.doc.Components.Schemas[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		// interface{}等无法确定类型的字段允许任意值
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema 生成结构体的对象Schema，匿名嵌入且没有json标签的结构体字段会被展开
+func (b *Builder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	b.collectFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields 收集结构体的导出字段
+func (b *Builder) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			b.collectFields(fieldType, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := b.schemaOf(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			if _, isRef := schema["$ref"]; isRef {
+				// $ref不能与其他关键字并列
+				schema = map[string]interface{}{"allOf": []interface{}{schema}, "description": description}
+			} else {
+				schema["description"] = description
+			}
+		}
+		properties[name] = schema
+
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			if rule == "required" {
+				*required = append(*required, name)
+				break
+			}
+		}
+	}
+}
+
+// invalidComponentChars 组件名称中不允许的字符
+var invalidComponentChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// componentName 具名类型在components.schemas中的名称，格式为"包名.类型名"
+func componentName(t reflect.Type) string {
+	return invalidComponentChars.ReplaceAllString(t.String(), "_")
+}
+
+// ConvertPath 将Gin路径参数写法转换为OpenAPI写法，如/users/:id转换为/users/{id}
+func ConvertPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// PathParams 返回Gin路径中的参数名称
+func PathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// routeTag 按路径生成接口分组，取/api/v1之后的第一段路径
+func routeTag(path string) string {
+	trimmed := strings.TrimPrefix(path, "/api/v1")
+	for _, segment := range strings.Split(trimmed, "/") {
+		if segment != "" && !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			return segment
+		}
+	}
+	return "default"
+}
+
+// HandlerName 将Gin处理函数名称转换为操作ID
+// 如"weave/controllers.(*PluginController).GetAllPlugins-fm"转换为"PluginController.GetAllPlugins"
+func HandlerName(handler string) string {
+	name := handler
+	if index := strings.LastIndex(name, "/"); index >= 0 {
+		name = name[index+1:]
+	}
+	if _, rest, found := strings.Cut(name, "."); found {
+		name = rest
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.NewReplacer("(*", "", "(", "", ")", "").Replace(name)
+	return name
+}
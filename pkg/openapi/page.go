@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	"fmt"
+	"html"
+)
+
+// DocsPage 返回渲染OpenAPI文档的HTML页面，页面脚本加载specURL指向的文档并渲染接口列表
+// 脚本内嵌在服务中，由DocsScriptPath提供，见DocsScript
+func DocsPage(title, specURL string) string {
+	return fmt.Sprintf(docsPageTemplate, html.EscapeString(title), html.EscapeString(specURL), html.EscapeString(DocsScriptPath))
+}
+
+// docsPageTemplate 文档页面模板
+const docsPageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>%s</title>
+  <style>
+    body { margin: 0; font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #222; }
+    #docs { display: flex; min-height: 100vh; }
+    nav { width: 240px; flex-shrink: 0; padding: 16px; background: #f6f7f9; border-right: 1px solid #e3e5e8; position: sticky; top: 0; height: 100vh; overflow-y: auto; box-sizing: border-box; }
+    nav a { display: block; padding: 4px 0; color: #333; text-decoration: none; }
+    nav a:hover { color: #0b63ce; }
+    main { flex: 1; padding: 16px 32px; max-width: 1000px; }
+    .operation { border: 1px solid #e3e5e8; border-radius: 6px; padding: 8px 16px; margin: 12px 0; }
+    .method { display: inline-block; min-width: 60px; padding: 2px 6px; border-radius: 4px; color: #fff; font-size: 12px; text-align: center; }
+    .method-get { background: #2f8132; } .method-post { background: #186faf; } .method-put { background: #95507c; }
+    .method-patch { background: #bf581d; } .method-delete { background: #cc3333; }
+    .desc, .version, .auth { color: #555; }
+    .type { color: #7a3e9d; font-size: 13px; }
+    .required { color: #cc3333; font-size: 12px; }
+    .status { font-weight: bold; }
+    .response { margin: 6px 0; }
+    table { border-collapse: collapse; width: 100%%; }
+    th, td { border-bottom: 1px solid #eee; padding: 4px 8px; text-align: left; vertical-align: top; }
+    ul.fields { margin: 4px 0; padding-left: 20px; }
+  </style>
+</head>
+<body>
+  <div id="docs" data-spec-url="%s">正在加载API文档...</div>
+  <script src="%s"></script>
+</body>
+</html>
+`
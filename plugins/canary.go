@@ -0,0 +1,60 @@
+package plugins
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"weave/config"
+	"weave/pkg"
+	"weave/plugins/core"
+	"weave/plugins/loader"
+)
+
+// canaryLoader 加载灰度版本编译产物的加载器，与稳定版本的加载记录分开，首次部署灰度版本时创建
+var (
+	canaryLoader     *loader.PluginLoader
+	canaryLoaderOnce sync.Once
+)
+
+// DeployCanary 按插件目录中的清单加载插件的新版本，并部署为与稳定版本并存的灰度版本
+// manifestPath为新版本plugin.json相对插件目录的路径，编译产物按清单的entry_point在同一目录下查找，
+// 与其他插件一样需要通过签名校验；清单中的插件名称必须为name
+func DeployCanary(name, manifestPath string, policy core.CanaryPolicy) error {
+	path, err := resolvePluginDirPath(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	canaryLoaderOnce.Do(func() {
+		canaryLoader = loader.NewPluginLoader(pkg.GetLogger())
+	})
+	plugin, manifest, err := canaryLoader.LoadPluginFromManifest(path)
+	if err != nil {
+		return pkg.NewPluginError(fmt.Sprintf("加载插件 '%s' 的灰度版本失败: %v", name, err), err)
+	}
+	if manifest.Name != name {
+		_ = canaryLoader.UnloadPlugin(manifest.Name)
+		return pkg.NewBadRequestError(fmt.Sprintf("清单中的插件名称 '%s' 与 '%s' 不一致", manifest.Name, name), nil)
+	}
+
+	if err := PluginManager.DeployCanaryWithManifest(plugin, manifest, policy); err != nil {
+		_ = canaryLoader.UnloadPlugin(name)
+		return err
+	}
+	return nil
+}
+
+// resolvePluginDirPath 将相对插件目录的路径转换为实际路径，拒绝绝对路径和指向插件目录之外的路径
+func resolvePluginDirPath(relative string) (string, error) {
+	pluginsDir := config.Config.Plugins.Dir
+	if pluginsDir == "" {
+		pluginsDir = "./plugins"
+	}
+
+	cleaned := filepath.Clean(relative)
+	if relative == "" || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", pkg.NewBadRequestError(fmt.Sprintf("清单路径 '%s' 必须是插件目录内的相对路径", relative), nil)
+	}
+	return filepath.Join(pluginsDir, cleaned), nil
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"weave/config"
+	"weave/models"
+	"weave/pkg"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PermissionWildcard 表示全部权限
+const PermissionWildcard = "*"
+
+// AdminRole 核心插件管理接口要求的租户角色
+const AdminRole = "admin"
+
+// TeamRolePrefix 团队角色的前缀，用户在团队中的成员角色解析为"team:<角色>"，如团队所有者为"team:owner"
+// 任何用户都可以创建团队并成为所有者，因此团队角色不会被当作同名的租户角色
+const TeamRolePrefix = "team:"
+
+// rolesContextKey 插件路由处理过程中缓存用户角色的上下文键，处理函数可通过c.GetStringSlice读取
+const rolesContextKey = "roles"
+
+// RoleResolver 解析用户在租户内拥有的角色，用于校验插件路由声明的Roles和Permissions
+type RoleResolver interface {
+	ResolveRoles(ctx context.Context, userID, tenantID uint) ([]string, error)
+}
+
+// DBRoleResolver 基于数据库的角色解析器
+// 角色包括用户的租户角色（tenant_roles表）和用户在租户内各团队中的成员角色（team_members表，带TeamRolePrefix前缀）。
+// DB为空时使用pkg.DB
+type DBRoleResolver struct {
+	DB *gorm.DB
+}
+
+// ResolveRoles 查询用户在租户内的租户角色和团队角色（去重）
+func (r *DBRoleResolver) ResolveRoles(ctx context.Context, userID, tenantID uint) ([]string, error) {
+	db := r.DB
+	if db == nil {
+		db = pkg.DB
+	}
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化，无法获取用户角色")
+	}
+
+	var roles []string
+	err := db.WithContext(storageContext(ctx)).
+		Model(&models.TenantRole{}).
+		Where("user_id = ? AND tenant_id = ?", userID, tenantID).
+		Distinct().
+		Pluck("role", &roles).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户 %d 的角色失败: %w", userID, err)
+	}
+
+	var teamRoles []string
+	err = db.WithContext(storageContext(ctx)).
+		Model(&models.TeamMember{}).
+		Where("user_id = ? AND tenant_id = ?", userID, tenantID).
+		Distinct().
+		Pluck("role", &teamRoles).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询用户 %d 的团队角色失败: %w", userID, err)
+	}
+	for _, role := range teamRoles {
+		roles = append(roles, TeamRolePrefix+role)
+	}
+	return roles, nil
+}
+
+// SetRoleResolver 设置插件路由授权使用的角色解析器，为空时恢复使用数据库解析器
+func (pm *PluginManager) SetRoleResolver(resolver RoleResolver) {
+	pm.authzMu.Lock()
+	defer pm.authzMu.Unlock()
+	pm.roleResolver = resolver
+}
+
+// currentRoleResolver 返回当前角色解析器
+func (pm *PluginManager) currentRoleResolver() RoleResolver {
+	pm.authzMu.RLock()
+	defer pm.authzMu.RUnlock()
+	if pm.roleResolver == nil {
+		return &DBRoleResolver{}
+	}
+	return pm.roleResolver
+}
+
+// requiresAuthorization 判断路由是否声明了角色或权限要求
+func (r Route) requiresAuthorization() bool {
+	return len(r.Roles) > 0 || len(r.Permissions) > 0
+}
+
+// RolePermissions 返回角色拥有的权限（去重），角色与权限的对应关系来自plugins.rolePermissions配置
+func RolePermissions(roles []string) []string {
+	seen := make(map[string]bool)
+	var permissions []string
+	for _, role := range roles {
+		for _, permission := range config.Config.Plugins.RolePermissions[strings.ToLower(role)] {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// HasPermission 判断已授予的权限是否包含所需权限
+// *匹配全部权限，以:*结尾的权限匹配该前缀下的全部权限，如notes:*匹配notes:write
+func HasPermission(granted []string, required string) bool {
+	for _, permission := range granted {
+		if permission == PermissionWildcard || permission == required {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(permission, PermissionWildcard); ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyRole 判断是否拥有所需角色之一，角色名称不区分大小写
+func hasAnyRole(roles, required []string) bool {
+	for _, want := range required {
+		for _, role := range roles {
+			if strings.EqualFold(role, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkRouteAccess 校验角色是否满足路由的要求
+// Roles满足其一即可，Permissions需要全部拥有；两者都声明时需同时满足
+func checkRouteAccess(route Route, roles []string) *pkg.AppError {
+	if len(route.Roles) > 0 && !hasAnyRole(roles, route.Roles) {
+		return pkg.NewAuthInsufficientRoleError(fmt.Sprintf("需要以下角色之一: %s", strings.Join(route.Roles, ", ")), nil)
+	}
+
+	granted := RolePermissions(roles)
+	var missing []string
+	for _, required := range route.Permissions {
+		if !HasPermission(granted, required) {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		return pkg.NewAuthInsufficientRoleError(fmt.Sprintf("缺少权限: %s", strings.Join(missing, ", ")), nil)
+	}
+	return nil
+}
+
+// RequireRoles 返回要求用户拥有指定角色之一的中间件，用于保护核心管理接口，需位于认证中间件之后
+// 角色由与插件路由相同的角色解析器获取，团队角色不满足同名的租户角色要求
+func (pm *PluginManager) RequireRoles(roles ...string) gin.HandlerFunc {
+	return pm.authorizeRoute(Route{Roles: roles})
+}
+
+// authorizeRoute 返回校验路由角色和权限要求的中间件，需位于认证中间件之后
+// 同一请求内解析出的角色缓存在上下文中
+func (pm *PluginManager) authorizeRoute(route Route) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, cached := c.Get(rolesContextKey); !cached {
+			roles, err := pm.currentRoleResolver().ResolveRoles(c.Request.Context(), c.GetUint("user_id"), c.GetUint("tenant_id"))
+			if err != nil {
+				abortWithAppError(c, pkg.NewDatabaseError("获取用户角色失败", err))
+				return
+			}
+			c.Set(rolesContextKey, roles)
+		}
+
+		if appErr := checkRouteAccess(route, c.GetStringSlice(rolesContextKey)); appErr != nil {
+			abortWithAppError(c, appErr)
+			return
+		}
+		c.Next()
+	}
+}
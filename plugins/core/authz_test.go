@@ -0,0 +1,221 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"weave/config"
+	"weave/models"
+	"weave/pkg"
+	"weave/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// staticRoleResolver 按用户ID返回固定角色的测试解析器
+type staticRoleResolver struct {
+	roles map[uint][]string
+	calls int
+}
+
+func (r *staticRoleResolver) ResolveRoles(ctx context.Context, userID, tenantID uint) ([]string, error) {
+	r.calls++
+	return r.roles[userID], nil
+}
+
+func TestRouteAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Config.JWT.Secret = "authz-test-secret"
+	config.Config.JWT.AccessTokenExpiry = 5
+	previous := config.Config.Plugins.RolePermissions
+	config.Config.Plugins.RolePermissions = map[string][]string{
+		"owner":  {"*"},
+		"admin":  {"notes:*"},
+		"member": {"notes:read"},
+	}
+	defer func() { config.Config.Plugins.RolePermissions = previous }()
+
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	resolver := &staticRoleResolver{roles: map[uint][]string{1: {"owner"}, 2: {"admin"}, 3: {"member"}}}
+	pm.SetRoleResolver(resolver)
+	router := gin.New()
+	pm.SetRouter(router)
+
+	plugin := newTestPlugin("P", false)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	plugin.routes = []Route{
+		{Path: "/read", Method: "GET", Handler: ok, Permissions: []string{"notes:read"}},
+		{Path: "/write", Method: "POST", Handler: ok, Permissions: []string{"notes:write"}},
+		{Path: "/admin", Method: "GET", Handler: ok, Roles: []string{"owner", "Admin"}},
+		{Path: "/both", Method: "GET", Handler: ok, Roles: []string{"admin"}, Permissions: []string{"billing:read"}},
+	}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		userID uint
+		status int
+	}{
+		{"GET", "/plugins/P/read", 0, http.StatusUnauthorized},
+		{"GET", "/plugins/P/read", 3, http.StatusOK},
+		{"POST", "/plugins/P/write", 3, http.StatusForbidden},
+		{"POST", "/plugins/P/write", 2, http.StatusOK},
+		{"GET", "/plugins/P/admin", 2, http.StatusOK},
+		{"GET", "/plugins/P/admin", 3, http.StatusForbidden},
+		{"GET", "/plugins/P/both", 1, http.StatusForbidden},
+		{"GET", "/plugins/P/both", 2, http.StatusForbidden},
+		{"GET", "/plugins/P/read", 4, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.userID != 0 {
+			token, err := utils.GenerateToken(tc.userID, 9)
+			if err != nil {
+				t.Fatalf("generate token: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s %s as user %d: expected status %d, got %d: %s", tc.method, tc.path, tc.userID, tc.status, w.Code, w.Body.String())
+		}
+		if tc.status == http.StatusForbidden {
+			var appErr pkg.AppError
+			if err := json.Unmarshal(w.Body.Bytes(), &appErr); err != nil || appErr.Code != pkg.ErrAuthInsufficientRole {
+				t.Fatalf("expected AUTH_INSUFFICIENT_ROLE error, got %s", w.Body.String())
+			}
+		}
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	cases := []struct {
+		granted  []string
+		required string
+		expected bool
+	}{
+		{[]string{"*"}, "notes:write", true},
+		{[]string{"notes:*"}, "notes:write", true},
+		{[]string{"notes:*"}, "notebooks:write", false},
+		{[]string{"notes:read"}, "notes:write", false},
+		{[]string{"notes:read", "notes:write"}, "notes:write", true},
+		{nil, "notes:read", false},
+	}
+	for _, tc := range cases {
+		if got := HasPermission(tc.granted, tc.required); got != tc.expected {
+			t.Fatalf("HasPermission(%v, %q) = %v, expected %v", tc.granted, tc.required, got, tc.expected)
+		}
+	}
+}
+
+func TestTeamOwnerIsNotTenantRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Config.JWT.Secret = "authz-test-secret"
+	config.Config.JWT.AccessTokenExpiry = 5
+	previous := config.Config.Plugins.RolePermissions
+	config.Config.Plugins.RolePermissions = map[string][]string{"admin": {"*"}}
+	defer func() { config.Config.Plugins.RolePermissions = previous }()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "authz.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.TenantRole{}, &models.Team{}, &models.TeamMember{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// 普通用户1创建团队，与团队创建接口一样成为团队所有者，并可以将其他成员设为团队管理员
+	team := models.Team{Name: "mine", OwnerID: 1, TenantID: 9}
+	if err := db.Create(&team).Error; err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	db.Create(&models.TeamMember{TeamID: team.ID, UserID: 1, Role: "owner", TenantID: 9})
+	db.Create(&models.TeamMember{TeamID: team.ID, UserID: 3, Role: "admin", TenantID: 9})
+	// 用户2由运维分配租户管理员角色
+	db.Create(&models.TenantRole{UserID: 2, TenantID: 9, Role: "admin"})
+
+	resolver := &DBRoleResolver{DB: db}
+	roles, err := resolver.ResolveRoles(context.Background(), 1, 9)
+	if err != nil || len(roles) != 1 || roles[0] != "team:owner" {
+		t.Fatalf("expected team owner resolved as team:owner, got %v, %v", roles, err)
+	}
+
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetRoleResolver(resolver)
+	router := gin.New()
+	pm.SetRouter(router)
+
+	plugin := newTestPlugin("P", false)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	plugin.routes = []Route{
+		{Path: "/admin", Method: "GET", Handler: ok, Roles: []string{"owner", "admin"}},
+		{Path: "/manage", Method: "POST", Handler: ok, Permissions: []string{"notes:manage"}},
+		{Path: "/team", Method: "GET", Handler: ok, Roles: []string{"team:owner"}},
+	}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		userID uint
+		status int
+	}{
+		{"GET", "/plugins/P/admin", 1, http.StatusForbidden},
+		{"POST", "/plugins/P/manage", 1, http.StatusForbidden},
+		{"GET", "/plugins/P/admin", 3, http.StatusForbidden},
+		{"POST", "/plugins/P/manage", 3, http.StatusForbidden},
+		{"GET", "/plugins/P/team", 1, http.StatusOK},
+		{"GET", "/plugins/P/admin", 2, http.StatusOK},
+		{"POST", "/plugins/P/manage", 2, http.StatusOK},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		token, err := utils.GenerateToken(tc.userID, 9)
+		if err != nil {
+			t.Fatalf("generate token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s %s as user %d: expected status %d, got %d: %s", tc.method, tc.path, tc.userID, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetRoleResolver(&staticRoleResolver{roles: map[uint][]string{1: {"team:owner", "team:admin"}, 2: {AdminRole}}})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		userID, _ := strconv.Atoi(c.GetHeader("X-User"))
+		c.Set("user_id", uint(userID))
+		c.Set("tenant_id", uint(9))
+	})
+	router.POST("/install", pm.RequireRoles(AdminRole), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	for userID, status := range map[string]int{"1": http.StatusForbidden, "2": http.StatusOK, "3": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/install", nil)
+		req.Header.Set("X-User", userID)
+		router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Fatalf("user %s: expected status %d, got %d: %s", userID, status, w.Code, w.Body.String())
+		}
+	}
+}
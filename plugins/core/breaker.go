@@ -0,0 +1,339 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen 插件熔断器处于打开状态，调用被短路
+var ErrCircuitOpen = errors.New("插件熔断器已打开")
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 关闭：正常放行
+	BreakerOpen     BreakerState = "open"      // 打开：短路所有调用
+	BreakerHalfOpen BreakerState = "half_open" // 半开：放行少量试探调用
+)
+
+// BreakerConfig 插件熔断器配置
+type BreakerConfig struct {
+	FailureThreshold     int           // 连续失败多少次后打开熔断器，<=0表示不熔断
+	OpenTimeout          time.Duration // 熔断器打开后多久进入半开状态
+	HalfOpenMaxCalls     int           // 半开状态下同时放行的试探调用数
+	AutoDisableThreshold int           // 连续失败多少次后自动禁用插件，<=0表示不自动禁用
+}
+
+// DefaultBreakerConfig 默认熔断器配置
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+	HalfOpenMaxCalls: 1,
+}
+
+// BreakerConfigProvider 插件声明自身熔断器配置的接口（可选）
+type BreakerConfigProvider interface {
+	BreakerConfig() BreakerConfig
+}
+
+// BreakerStatus 熔断器状态快照
+type BreakerStatus struct {
+	State                BreakerState `json:"state"`
+	ConsecutiveFailures  int          `json:"consecutive_failures"`
+	TotalFailures        int          `json:"total_failures"`
+	FailureThreshold     int          `json:"failure_threshold"`
+	AutoDisableThreshold int          `json:"auto_disable_threshold"`
+	AutoDisabled         bool         `json:"auto_disabled"`
+	LastError            string       `json:"last_error,omitempty"`
+	LastFailureAt        *time.Time   `json:"last_failure_at,omitempty"`
+	OpenedAt             *time.Time   `json:"opened_at,omitempty"`
+}
+
+// circuitBreaker 单个插件版本的熔断器
+type circuitBreaker struct {
+	mu            sync.Mutex
+	config        BreakerConfig
+	fixed         bool // 配置来自管理器单独设置或插件声明，不随默认配置变化
+	state         BreakerState
+	consecutive   int
+	total         int
+	halfOpenCalls int // 进行中的试探调用数，调用实际结束时才减少，不随状态转换清零
+	openedAt      time.Time
+	lastError     string
+	lastFailureAt time.Time
+	autoDisabled  bool
+	now           func() time.Time
+}
+
+// versionBreakers 插件各版本的熔断器（按版本号）
+type versionBreakers map[string]*circuitBreaker
+
+// newCircuitBreaker 创建处于关闭状态的熔断器
+func newCircuitBreaker(config BreakerConfig, fixed bool) *circuitBreaker {
+	return &circuitBreaker{config: config, fixed: fixed, state: BreakerClosed, now: time.Now}
+}
+
+// allow 判断是否放行本次调用，probe表示放行的是半开状态的试探调用
+// 放行后调用方必须在调用实际结束时调用finish(probe)归还试探名额，调用结果另由success或failure记录
+func (b *circuitBreaker) allow() (probe bool, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config.FailureThreshold <= 0 {
+		return false, true
+	}
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return false, false
+		}
+		b.state = BreakerHalfOpen
+		fallthrough
+	case BreakerHalfOpen:
+		maxCalls := b.config.HalfOpenMaxCalls
+		if maxCalls <= 0 {
+			maxCalls = 1
+		}
+		// 超时后仍在后台运行的试探调用继续占用名额
+		if b.halfOpenCalls >= maxCalls {
+			return false, false
+		}
+		b.halfOpenCalls++
+		return true, true
+	}
+	return false, true
+}
+
+// success 记录一次成功调用，半开状态下关闭熔断器
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 熔断器打开前已放行的调用，其结果不改变打开状态
+	if b.state == BreakerOpen {
+		return
+	}
+	b.consecutive = 0
+	b.state = BreakerClosed
+}
+
+// failure 记录一次失败调用，返回熔断器是否因此打开以及是否需要自动禁用插件
+func (b *circuitBreaker) failure(err error) (opened bool, disable bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutive++
+	b.total++
+	b.lastFailureAt = b.now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+
+	if b.config.FailureThreshold > 0 && b.state != BreakerOpen &&
+		(b.state == BreakerHalfOpen || b.consecutive >= b.config.FailureThreshold) {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		opened = true
+	}
+	if b.config.AutoDisableThreshold > 0 && b.consecutive >= b.config.AutoDisableThreshold && !b.autoDisabled {
+		b.autoDisabled = true
+		disable = true
+	}
+	return opened, disable
+}
+
+// finish 在调用实际结束后归还试探调用名额，probe为allow的返回值
+func (b *circuitBreaker) finish(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.halfOpenCalls > 0 {
+		b.halfOpenCalls--
+	}
+}
+
+// status 返回熔断器状态快照，打开状态超时后显示为半开
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		State:                b.state,
+		ConsecutiveFailures:  b.consecutive,
+		TotalFailures:        b.total,
+		FailureThreshold:     b.config.FailureThreshold,
+		AutoDisableThreshold: b.config.AutoDisableThreshold,
+		AutoDisabled:         b.autoDisabled,
+		LastError:            b.lastError,
+	}
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		status.State = BreakerHalfOpen
+	}
+	if !b.lastFailureAt.IsZero() {
+		lastFailureAt := b.lastFailureAt
+		status.LastFailureAt = &lastFailureAt
+	}
+	if b.state == BreakerOpen {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// SetDefaultBreakerConfig 设置默认熔断器配置，对未单独配置的插件立即生效
+func (pm *PluginManager) SetDefaultBreakerConfig(config BreakerConfig) {
+	pm.breakersMu.Lock()
+	defer pm.breakersMu.Unlock()
+
+	pm.breakerDefaults = &config
+	for _, versions := range pm.breakers {
+		for _, breaker := range versions {
+			breaker.mu.Lock()
+			if !breaker.fixed {
+				breaker.config = config
+			}
+			breaker.mu.Unlock()
+		}
+	}
+}
+
+// SetBreakerConfig 为指定插件设置熔断器配置，优先级高于插件声明和默认配置
+func (pm *PluginManager) SetBreakerConfig(name string, config BreakerConfig) {
+	pm.breakersMu.Lock()
+	defer pm.breakersMu.Unlock()
+
+	if pm.breakerConfigs == nil {
+		pm.breakerConfigs = make(map[string]BreakerConfig)
+	}
+	pm.breakerConfigs[name] = config
+	for _, breaker := range pm.breakers[name] {
+		breaker.mu.Lock()
+		breaker.config = config
+		breaker.fixed = true
+		breaker.mu.Unlock()
+	}
+}
+
+// breakerFor 获取插件实例所属版本的熔断器，不存在时按"管理器单独配置 > 插件声明 > 默认配置"创建
+// 灰度发布期间稳定版本和灰度版本分别统计失败，互不影响
+func (pm *PluginManager) breakerFor(name string, plugin Plugin) *circuitBreaker {
+	pm.breakersMu.Lock()
+	defer pm.breakersMu.Unlock()
+
+	version := plugin.Version()
+	if breaker, exists := pm.breakers[name][version]; exists {
+		return breaker
+	}
+
+	var breaker *circuitBreaker
+	if config, ok := pm.breakerConfigs[name]; ok {
+		breaker = newCircuitBreaker(config, true)
+	} else if provider, ok := plugin.(BreakerConfigProvider); ok {
+		breaker = newCircuitBreaker(provider.BreakerConfig(), true)
+	} else if pm.breakerDefaults != nil {
+		breaker = newCircuitBreaker(*pm.breakerDefaults, false)
+	} else {
+		breaker = newCircuitBreaker(DefaultBreakerConfig, false)
+	}
+
+	if pm.breakers == nil {
+		pm.breakers = make(map[string]versionBreakers)
+	}
+	if pm.breakers[name] == nil {
+		pm.breakers[name] = make(versionBreakers)
+	}
+	pm.breakers[name][version] = breaker
+	return breaker
+}
+
+// removeBreaker 移除插件所有版本的熔断器，插件重新启用或注销时调用
+func (pm *PluginManager) removeBreaker(name string) {
+	pm.breakersMu.Lock()
+	defer pm.breakersMu.Unlock()
+	delete(pm.breakers, name)
+}
+
+// removeVersionBreaker 移除插件指定版本的熔断器，灰度版本提升或回滚时调用
+func (pm *PluginManager) removeVersionBreaker(name, version string) {
+	pm.breakersMu.Lock()
+	defer pm.breakersMu.Unlock()
+	delete(pm.breakers[name], version)
+}
+
+// GetBreakerStatus 获取插件的熔断器状态，插件不存在时返回false
+func (pm *PluginManager) GetBreakerStatus(name string) (BreakerStatus, bool) {
+	pm.mutex.RLock()
+	info, exists := pm.plugins[name]
+	pm.mutex.RUnlock()
+	if !exists {
+		return BreakerStatus{}, false
+	}
+	return pm.breakerFor(name, info.Plugin).status(), true
+}
+
+// ResetBreaker 将插件所有版本的熔断器重置为关闭状态
+func (pm *PluginManager) ResetBreaker(name string) {
+	pm.removeBreaker(name)
+}
+
+// circuitOpenError 熔断器打开时返回的错误
+func circuitOpenError(name string) *pkg.AppError {
+	metrics.RecordPluginError(name, "circuit_open")
+	return pkg.NewServiceUnavailableError(fmt.Sprintf("插件 '%s' 已熔断，请稍后重试", name), ErrCircuitOpen)
+}
+
+// isPluginFailure 判断错误是否计入熔断失败，4xx类业务错误（如参数错误）不计入
+func isPluginFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var appErr *pkg.AppError
+	if errors.As(err, &appErr) && pkg.GetHTTPStatus(appErr) < 500 {
+		return false
+	}
+	return true
+}
+
+// recordCallResult 根据调用结果更新熔断器，达到阈值时自动禁用插件
+// plugin为处理本次调用的插件实例，灰度版本达到阈值时回滚灰度版本，不影响稳定版本。调用方不能持有pm.mutex
+func (pm *PluginManager) recordCallResult(name string, plugin Plugin, breaker *circuitBreaker, err error) {
+	if !isPluginFailure(err) {
+		breaker.success()
+		return
+	}
+
+	opened, disable := breaker.failure(err)
+	if opened {
+		metrics.RecordPluginError(name, "circuit_opened")
+		pkg.Warn("插件熔断器已打开", zap.String("plugin", name), zap.Error(err))
+	}
+	if disable {
+		if canary := pm.canaryPlugin(name); canary != nil && canary.Version() == plugin.Version() {
+			metrics.RecordPluginError(name, "canary_auto_rolled_back")
+			pkg.Error("插件灰度版本连续失败次数过多，自动回滚", zap.String("plugin", name), zap.String("version", plugin.Version()), zap.Error(err))
+			if rollbackErr := pm.RollbackCanary(name); rollbackErr != nil {
+				pkg.Error("自动回滚插件灰度版本失败", zap.String("plugin", name), zap.Error(rollbackErr))
+			}
+			return
+		}
+		metrics.RecordPluginError(name, "auto_disabled")
+		pkg.Error("插件连续失败次数过多，自动禁用", zap.String("plugin", name), zap.Error(err))
+		// 自动禁用只针对本实例，不保存也不广播，重启后插件恢复为保存的状态
+		pm.mutex.Lock()
+		disableErr := pm.disablePluginLocked(name)
+		pm.mutex.Unlock()
+		if disableErr != nil {
+			pkg.Error("自动禁用插件失败", zap.String("plugin", name), zap.Error(disableErr))
+		}
+	}
+}
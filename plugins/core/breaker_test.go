@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"weave/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// panicPlugin Execute和ExecuteContext中发生panic的测试插件
+type panicPlugin struct {
+	testPlugin
+}
+
+func (p *panicPlugin) Execute(params map[string]interface{}) (interface{}, error) {
+	panic("execute boom")
+}
+
+type panicContextPlugin struct {
+	testPlugin
+}
+
+func (p *panicContextPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	panic("context boom")
+}
+
+func TestPluginPanicsAreRecovered(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+
+	if err := pm.Register(&panicPlugin{testPlugin: testPlugin{name: "legacy"}}); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if err := pm.Register(&panicContextPlugin{testPlugin: testPlugin{name: "ctx"}}); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	for _, name := range []string{"legacy", "ctx"} {
+		_, err := pm.ExecutePlugin(name, nil)
+		if !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginExecution}) || !strings.Contains(err.Error(), "panic") {
+			t.Fatalf("expected panic converted to PluginExecutionError for %s, got %v", name, err)
+		}
+	}
+
+	// 生命周期方法中的panic同样被转换为错误
+	initPanic := newTestPlugin("init_panic", false)
+	initPanic.initFunc = func() error { panic("init boom") }
+	if err := pm.Register(initPanic); err == nil || !strings.Contains(err.Error(), "初始化失败") {
+		t.Fatalf("expected init panic to fail registration, got %v", err)
+	}
+	if _, exists := pm.GetPlugin("init_panic"); exists {
+		t.Fatalf("expected plugin with panicking Init not registered")
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetBreakerConfig("flaky", BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	plugin := newTestPlugin("flaky", false)
+	plugin.executeError = errors.New("downstream unavailable")
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := pm.ExecutePlugin("flaky", nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected plugin error on call %d, got %v", i+1, err)
+		}
+	}
+	if _, err := pm.ExecutePlugin("flaky", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected circuit open error, got %v", err)
+	}
+	if plugin.executeCalled != 2 {
+		t.Fatalf("expected open breaker to short-circuit, execute called %d times", plugin.executeCalled)
+	}
+	status, _ := pm.GetBreakerStatus("flaky")
+	if status.State != BreakerOpen || status.ConsecutiveFailures != 2 || status.LastError == "" {
+		t.Fatalf("unexpected breaker status: %+v", status)
+	}
+
+	// 超过熔断时间后进入半开状态，试探调用成功则关闭熔断器
+	breaker := pm.breakerFor("flaky", plugin)
+	breaker.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if status, _ := pm.GetBreakerStatus("flaky"); status.State != BreakerHalfOpen {
+		t.Fatalf("expected half-open after timeout, got %s", status.State)
+	}
+	plugin.executeError = nil
+	if _, err := pm.ExecutePlugin("flaky", nil); err != nil {
+		t.Fatalf("expected probe call to succeed, got %v", err)
+	}
+	if status, _ := pm.GetBreakerStatus("flaky"); status.State != BreakerClosed || status.ConsecutiveFailures != 0 {
+		t.Fatalf("expected breaker closed after successful probe, got %+v", status)
+	}
+
+	// 业务参数错误不计入熔断
+	plugin.executeError = pkg.NewBadRequestError("bad params", nil)
+	for i := 0; i < 3; i++ {
+		_, _ = pm.ExecutePlugin("flaky", nil)
+	}
+	if status, _ := pm.GetBreakerStatus("flaky"); status.State != BreakerClosed {
+		t.Fatalf("expected client errors not to open breaker, got %s", status.State)
+	}
+}
+
+func TestCircuitBreakerProbeHeldUntilLegacyExecuteReturns(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetBreakerConfig("legacy", BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	sp := &slowPlugin{testPlugin: testPlugin{name: "legacy"}, release: make(chan struct{}), timeout: 20 * time.Millisecond}
+	if err := pm.Register(sp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	breaker := pm.breakerFor("legacy", sp)
+
+	// 超时计为失败并打开熔断器
+	if _, err := pm.ExecutePlugin("legacy", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	breaker.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := pm.ExecutePlugin("legacy", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected probe to time out, got %v", err)
+	}
+
+	// 超时的试探调用仍在运行，再次半开时不放行新的试探调用
+	breaker.now = func() time.Time { return time.Now().Add(4 * time.Minute) }
+	if _, err := pm.ExecutePlugin("legacy", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected probe slot held by running Execute, got %v", err)
+	}
+
+	close(sp.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := pm.ExecutePlugin("legacy", nil)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected probe allowed after Execute returned, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status, _ := pm.GetBreakerStatus("legacy"); status.State != BreakerClosed {
+		t.Fatalf("expected breaker closed after successful probe, got %s", status.State)
+	}
+}
+
+func TestCircuitBreakerAutoDisablesPlugin(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetDefaultBreakerConfig(BreakerConfig{AutoDisableThreshold: 3})
+
+	plugin := newTestPlugin("broken", false)
+	plugin.executeError = errors.New("always failing")
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = pm.ExecutePlugin("broken", nil)
+	}
+	if status, _ := pm.GetPluginStatus("broken"); status != "disabled" {
+		t.Fatalf("expected plugin auto-disabled, got %s", status)
+	}
+	if breaker, _ := pm.GetBreakerStatus("broken"); !breaker.AutoDisabled {
+		t.Fatalf("expected breaker to report auto-disable, got %+v", breaker)
+	}
+
+	// 手动启用后熔断器被重置
+	if err := pm.EnablePlugin("broken"); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	if breaker, _ := pm.GetBreakerStatus("broken"); breaker.AutoDisabled || breaker.ConsecutiveFailures != 0 {
+		t.Fatalf("expected breaker reset after enable, got %+v", breaker)
+	}
+}
+
+func TestRouteHandlerPanicAndBreaker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+	pm.SetBreakerConfig("P", BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	plugin := newTestPlugin("P", false)
+	plugin.routes = []Route{{Path: "/boom", Method: "GET", Handler: func(c *gin.Context) { panic("handler boom") }}}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		w := serveRequest(router, http.MethodGet, "/plugins/P/boom")
+		if w.Code != http.StatusInternalServerError || decodeAppError(t, w).Code != pkg.ErrPluginExecution {
+			t.Fatalf("expected 500 PLUGIN_EXECUTION_ERROR, got %d %s", w.Code, w.Body.String())
+		}
+	}
+	w := serveRequest(router, http.MethodGet, "/plugins/P/boom")
+	if w.Code != http.StatusServiceUnavailable || decodeAppError(t, w).Code != pkg.ErrServiceUnavailable {
+		t.Fatalf("expected 503 after breaker opened, got %d %s", w.Code, w.Body.String())
+	}
+}
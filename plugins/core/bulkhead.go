@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"weave/config"
+	"weave/pkg"
+	"weave/pkg/metrics"
+)
+
+// DefaultQueueTimeout 配置了排队但未设置排队超时时使用的默认值
+const DefaultQueueTimeout = 5 * time.Second
+
+var (
+	// ErrBulkheadFull 并发数和排队数均已达到上限
+	ErrBulkheadFull = errors.New("插件并发数已达上限")
+	// ErrBulkheadTimeout 排队等待超时
+	ErrBulkheadTimeout = errors.New("插件排队等待超时")
+)
+
+// ConcurrencyLimits 插件并发限制（舱壁隔离），各项<=0表示不限制
+type ConcurrencyLimits struct {
+	MaxConcurrentExecutions int           // Execute的最大并发数
+	MaxConcurrentRequests   int           // 插件路由的最大并发请求数
+	QueueLength             int           // 达到并发上限后允许排队等待的调用数，为0时直接拒绝
+	QueueTimeout            time.Duration // 排队的最长等待时间，为0时使用DefaultQueueTimeout
+}
+
+// ConcurrencyLimitsProvider 插件声明自身并发限制的接口（可选）
+type ConcurrencyLimitsProvider interface {
+	ConcurrencyLimits() ConcurrencyLimits
+}
+
+// bulkhead 基于信号量的舱壁，超出并发上限的调用排队等待
+type bulkhead struct {
+	slots    chan struct{}
+	maxQueue int
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	queued int
+}
+
+// newBulkhead 创建舱壁，maxConcurrent<=0时返回nil（不限制）
+func newBulkhead(maxConcurrent, queueLength int, timeout time.Duration) *bulkhead {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	if queueLength < 0 {
+		queueLength = 0
+	}
+	if timeout <= 0 {
+		timeout = DefaultQueueTimeout
+	}
+	return &bulkhead{slots: make(chan struct{}, maxConcurrent), maxQueue: queueLength, timeout: timeout}
+}
+
+// acquire 获取执行名额，成功时返回释放函数
+// 队列已满时返回ErrBulkheadFull，排队超时返回ErrBulkheadTimeout，上下文结束时返回上下文错误
+func (b *bulkhead) acquire(ctx context.Context) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	release := func() { <-b.slots }
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	b.mu.Lock()
+	if b.queued >= b.maxQueue {
+		b.mu.Unlock()
+		return nil, ErrBulkheadFull
+	}
+	b.queued++
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.queued--
+		b.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBulkheadTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pluginBulkheads 单个插件版本的舱壁，Execute和HTTP请求分别限制
+type pluginBulkheads struct {
+	execute *bulkhead
+	request *bulkhead
+}
+
+// versionBulkheads 插件各版本的舱壁（按版本号）
+type versionBulkheads map[string]*pluginBulkheads
+
+// SetConcurrencyLimits 为指定插件设置并发限制，优先级高于配置文件和插件声明
+// 已在执行中的调用不受影响，新的限制对后续调用生效
+func (pm *PluginManager) SetConcurrencyLimits(name string, limits ConcurrencyLimits) {
+	pm.bulkheadsMu.Lock()
+	defer pm.bulkheadsMu.Unlock()
+
+	if pm.limitConfigs == nil {
+		pm.limitConfigs = make(map[string]ConcurrencyLimits)
+	}
+	pm.limitConfigs[name] = limits
+	delete(pm.bulkheads, name)
+}
+
+// resolveConcurrencyLimits 按"管理器单独配置 > 配置文件plugins.limits.<插件名> > 插件声明"确定并发限制
+func (pm *PluginManager) resolveConcurrencyLimits(name string, plugin Plugin) ConcurrencyLimits {
+	if limits, ok := pm.limitConfigs[name]; ok {
+		return limits
+	}
+	// Viper会将配置段名称转换为小写
+	if limits, ok := config.Config.Plugins.Limits[strings.ToLower(name)]; ok {
+		return ConcurrencyLimits{
+			MaxConcurrentExecutions: limits.MaxConcurrentExecutions,
+			MaxConcurrentRequests:   limits.MaxConcurrentRequests,
+			QueueLength:             limits.QueueLength,
+			QueueTimeout:            time.Duration(limits.QueueTimeout) * time.Second,
+		}
+	}
+	if provider, ok := plugin.(ConcurrencyLimitsProvider); ok {
+		return provider.ConcurrencyLimits()
+	}
+	return ConcurrencyLimits{}
+}
+
+// bulkheadsFor 获取插件实例所属版本的舱壁，不存在时按当前限制创建
+// 灰度发布期间稳定版本和灰度版本分别限制并发，慢的版本不会占满另一个版本的名额
+func (pm *PluginManager) bulkheadsFor(name string, plugin Plugin) *pluginBulkheads {
+	pm.bulkheadsMu.Lock()
+	defer pm.bulkheadsMu.Unlock()
+
+	version := plugin.Version()
+	if bulkheads, exists := pm.bulkheads[name][version]; exists {
+		return bulkheads
+	}
+	limits := pm.resolveConcurrencyLimits(name, plugin)
+	bulkheads := &pluginBulkheads{
+		execute: newBulkhead(limits.MaxConcurrentExecutions, limits.QueueLength, limits.QueueTimeout),
+		request: newBulkhead(limits.MaxConcurrentRequests, limits.QueueLength, limits.QueueTimeout),
+	}
+	if pm.bulkheads == nil {
+		pm.bulkheads = make(map[string]versionBulkheads)
+	}
+	if pm.bulkheads[name] == nil {
+		pm.bulkheads[name] = make(versionBulkheads)
+	}
+	pm.bulkheads[name][version] = bulkheads
+	return bulkheads
+}
+
+// removeBulkheads 移除插件所有版本的舱壁，插件重新加载或注销时调用
+func (pm *PluginManager) removeBulkheads(name string) {
+	pm.bulkheadsMu.Lock()
+	defer pm.bulkheadsMu.Unlock()
+	delete(pm.bulkheads, name)
+}
+
+// removeVersionBulkheads 移除插件指定版本的舱壁，灰度版本提升或回滚时调用
+func (pm *PluginManager) removeVersionBulkheads(name, version string) {
+	pm.bulkheadsMu.Lock()
+	defer pm.bulkheadsMu.Unlock()
+	delete(pm.bulkheads[name], version)
+}
+
+// bulkheadError 将舱壁拒绝转换为对外错误并记录指标
+// 队列已满返回429，排队超时返回503；kind为execute或request
+func bulkheadError(name, kind string, err error) error {
+	switch {
+	case errors.Is(err, ErrBulkheadFull):
+		metrics.RecordPluginError(name, kind+"_rejected")
+		return pkg.NewTooManyRequests(fmt.Sprintf("插件 '%s' 并发请求过多，请稍后重试", name), err)
+	case errors.Is(err, ErrBulkheadTimeout):
+		metrics.RecordPluginError(name, kind+"_queue_timeout")
+		return pkg.NewServiceUnavailableError(fmt.Sprintf("插件 '%s' 繁忙，排队等待超时", name), err)
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"weave/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockingPlugin 在release关闭前阻塞Execute和路由处理的测试插件，声明自身并发限制
+type blockingPlugin struct {
+	testPlugin
+	limits  ConcurrencyLimits
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingPlugin(name string, limits ConcurrencyLimits) *blockingPlugin {
+	p := &blockingPlugin{
+		testPlugin: testPlugin{name: name},
+		limits:     limits,
+		started:    make(chan struct{}, 8),
+		release:    make(chan struct{}),
+	}
+	p.routes = []Route{{Path: "/slow", Method: "GET", Handler: func(c *gin.Context) {
+		p.started <- struct{}{}
+		<-p.release
+		c.String(http.StatusOK, "done")
+	}}}
+	return p
+}
+
+func (p *blockingPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return "done", nil
+}
+
+func (p *blockingPlugin) ConcurrencyLimits() ConcurrencyLimits { return p.limits }
+
+func TestExecuteBulkheadHeldUntilLegacyExecuteReturns(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	sp := &slowPlugin{testPlugin: testPlugin{name: "legacy"}, release: make(chan struct{}), timeout: 20 * time.Millisecond}
+	if err := pm.Register(sp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	pm.SetConcurrencyLimits("legacy", ConcurrencyLimits{MaxConcurrentExecutions: 1, QueueLength: 1, QueueTimeout: 20 * time.Millisecond})
+
+	// 调用方超时返回后，旧版插件的Execute仍在后台运行，继续占用并发名额
+	if _, err := pm.ExecutePlugin("legacy", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if _, err := pm.ExecutePlugin("legacy", nil); !errors.Is(err, ErrBulkheadTimeout) {
+		t.Fatalf("expected slot held by running Execute, got %v", err)
+	}
+
+	// Execute实际返回后归还名额
+	close(sp.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := pm.ExecutePlugin("legacy", nil)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected slot released after Execute returned, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExecuteBulkhead(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugin := newBlockingPlugin("limited", ConcurrencyLimits{MaxConcurrentExecutions: 1, QueueLength: 1, QueueTimeout: 50 * time.Millisecond})
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pm.ExecutePlugin("limited", nil)
+		done <- err
+	}()
+	<-plugin.started
+
+	// 排队等待超时返回503
+	if _, err := pm.ExecutePlugin("limited", nil); !errors.Is(err, ErrBulkheadTimeout) || !errors.Is(err, &pkg.AppError{Code: pkg.ErrServiceUnavailable}) {
+		t.Fatalf("expected queue timeout, got %v", err)
+	}
+
+	// 队列已满时直接拒绝并返回429
+	queued := make(chan error, 1)
+	pm.SetConcurrencyLimits("limited", ConcurrencyLimits{MaxConcurrentExecutions: 1, QueueLength: 1, QueueTimeout: time.Minute})
+	go func() {
+		_, err := pm.ExecutePlugin("limited", nil)
+		done <- err
+	}()
+	<-plugin.started
+	go func() {
+		_, err := pm.ExecutePlugin("limited", nil)
+		queued <- err
+	}()
+	waitForQueued(t, pm.bulkheadsFor("limited", plugin).execute, 1)
+	_, err := pm.ExecutePlugin("limited", nil)
+	if !errors.Is(err, ErrBulkheadFull) || pkg.GetHTTPStatus(asAppError(t, err)) != http.StatusTooManyRequests {
+		t.Fatalf("expected bulkhead full, got %v", err)
+	}
+
+	// 释放后排队的调用得以执行
+	close(plugin.release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("expected running call to succeed, got %v", err)
+		}
+	}
+	if err := <-queued; err != nil {
+		t.Fatalf("expected queued call to succeed, got %v", err)
+	}
+}
+
+func TestRouteBulkhead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+
+	plugin := newBlockingPlugin("P", ConcurrencyLimits{MaxConcurrentRequests: 1})
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	done := make(chan int, 1)
+	go func() { done <- serveRequest(router, http.MethodGet, "/plugins/P/slow").Code }()
+	<-plugin.started
+
+	w := serveRequest(router, http.MethodGet, "/plugins/P/slow")
+	if w.Code != http.StatusTooManyRequests || decodeAppError(t, w).Code != pkg.ErrTooManyRequests {
+		t.Fatalf("expected 429 when requests exceed limit, got %d %s", w.Code, w.Body.String())
+	}
+
+	close(plugin.release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("expected in-flight request to succeed, got %d", code)
+	}
+	// 拒绝不计入熔断失败
+	if status, _ := pm.GetBreakerStatus("P"); status.ConsecutiveFailures != 0 {
+		t.Fatalf("expected rejection not counted as breaker failure, got %+v", status)
+	}
+}
+
+// waitForQueued 等待舱壁中的排队数达到n
+func waitForQueued(t *testing.T, b *bulkhead, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		queued := b.queued
+		b.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued calls", n)
+}
+
+func asAppError(t *testing.T, err error) *pkg.AppError {
+	t.Helper()
+	var appErr *pkg.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError, got %T %v", err, err)
+	}
+	return appErr
+}
@@ -0,0 +1,406 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+	"weave/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DefaultCanaryHeader 未配置时用于固定插件版本的请求头
+const DefaultCanaryHeader = "X-Plugin-Version"
+
+// 固定版本时除版本号外可以使用的别名
+const (
+	CanaryPinStable = "stable" // 固定使用稳定版本
+	CanaryPinCanary = "canary" // 固定使用灰度版本
+)
+
+// CanaryPolicy 灰度发布的流量分配策略
+// 选择顺序：固定版本（请求头/Cookie/上下文） > 租户白名单 > 流量权重
+type CanaryPolicy struct {
+	Weight  int    `json:"weight"`  // 分配给灰度版本的流量百分比（0-100）
+	Header  string `json:"header"`  // 固定版本的请求头名称，为空时使用X-Plugin-Version
+	Cookie  string `json:"cookie"`  // 固定版本的Cookie名称，为空时不读取Cookie
+	Tenants []uint `json:"tenants"` // 始终使用灰度版本的租户ID
+}
+
+// Validate 校验灰度策略
+func (p CanaryPolicy) Validate() error {
+	if p.Weight < 0 || p.Weight > 100 {
+		return fmt.Errorf("灰度流量权重必须在0到100之间，当前为 %d", p.Weight)
+	}
+	return nil
+}
+
+// headerName 固定版本使用的请求头名称
+func (p CanaryPolicy) headerName() string {
+	if p.Header != "" {
+		return p.Header
+	}
+	return DefaultCanaryHeader
+}
+
+// CanaryStatus 插件灰度发布状态
+type CanaryStatus struct {
+	Plugin        string        `json:"plugin"`
+	StableVersion string        `json:"stable_version"`
+	CanaryVersion string        `json:"canary_version"`
+	Policy        CanaryPolicy  `json:"policy"`
+	StartedAt     time.Time     `json:"started_at"`
+	Breaker       BreakerStatus `json:"breaker"` // 灰度版本的熔断器状态，与稳定版本分别统计
+}
+
+// canaryRelease 与稳定版本并存的灰度版本
+// 发布到pm.canaries后不再修改，更新策略时整体替换，因此可以在释放锁后读取
+type canaryRelease struct {
+	plugin    Plugin
+	policy    CanaryPolicy
+	table     *gin.Engine     // 灰度版本的路由表，路由引擎未设置时为空
+	routes    []Route         // 灰度版本的路由定义
+	specs     []Dependency    // 灰度版本的依赖声明
+	manifest  *PluginManifest // 灰度版本的清单
+	service   *serviceEntry   // 灰度版本在Init中发布的服务，提升时替换稳定版本的服务
+	owner     string          // 灰度版本事件订阅的所有者标识，格式为"<名称>@<版本>"
+	startedAt time.Time
+}
+
+// chooseCanary 判断本次调用是否由灰度版本处理
+// pin为调用方固定的版本（版本号或stable/canary别名），无法识别的值会被忽略
+func (r *canaryRelease) chooseCanary(pin string, tenantID uint, stableVersion string) bool {
+	switch pin {
+	case "":
+	case CanaryPinCanary, r.plugin.Version():
+		return true
+	case CanaryPinStable, stableVersion:
+		return false
+	}
+
+	if tenantID != 0 {
+		for _, tenant := range r.policy.Tenants {
+			if tenant == tenantID {
+				return true
+			}
+		}
+	}
+
+	return r.policy.Weight > 0 && rand.Intn(100) < r.policy.Weight
+}
+
+// httpPin 从请求头或Cookie中读取固定的版本
+func (r *canaryRelease) httpPin(c *gin.Context) string {
+	if pin := strings.TrimSpace(c.GetHeader(r.policy.headerName())); pin != "" {
+		return pin
+	}
+	if r.policy.Cookie != "" {
+		if pin, err := c.Cookie(r.policy.Cookie); err == nil {
+			return strings.TrimSpace(pin)
+		}
+	}
+	return ""
+}
+
+// requestTenantID 获取请求的租户ID
+// 插件路由的认证在插件路由表内进行，分发时上下文中可能还没有租户信息，此时从访问令牌中解析
+func requestTenantID(c *gin.Context) uint {
+	if tenantID := c.GetUint("tenant_id"); tenantID != 0 {
+		return tenantID
+	}
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return 0
+	}
+	_, tokenType, tenantID, err := utils.VerifyToken(parts[1])
+	if err != nil || tokenType != "access" {
+		return 0
+	}
+	return tenantID
+}
+
+// DeployCanary 部署插件的灰度版本，与当前稳定版本并存
+// 插件名称须与已启用的稳定版本相同、版本不同；灰度版本按自身的依赖和清单校验后初始化，
+// 之后按policy分配路由请求和ExecutePlugin调用，可通过PromoteCanary提升为稳定版本或RollbackCanary回滚。
+// 熔断器和舱壁按版本分别统计，灰度版本连续失败达到自动禁用阈值时自动回滚；生效配置仍按插件名称在两个版本间共享。
+// 插件实现ManifestProvider时按其清单校验，见DeployCanaryWithManifest
+func (pm *PluginManager) DeployCanary(plugin Plugin, policy CanaryPolicy) error {
+	return pm.DeployCanaryWithManifest(plugin, nil, policy)
+}
+
+// DeployCanaryWithManifest 按清单校验灰度版本后部署，清单与插件运行时的名称或版本不一致时拒绝部署
+// manifest为空时等同于DeployCanary
+func (pm *PluginManager) DeployCanaryWithManifest(plugin Plugin, manifest *PluginManifest, policy CanaryPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return pkg.NewBadRequestError(err.Error(), nil)
+	}
+	policy.Tenants = append([]uint(nil), policy.Tenants...)
+
+	name := plugin.Name()
+	if manifest == nil {
+		if provider, ok := plugin.(ManifestProvider); ok {
+			manifest = provider.Manifest()
+		}
+	}
+	if manifest != nil {
+		if err := ValidateManifest(manifest, plugin); err != nil {
+			metrics.RecordPluginError(name, "manifest_mismatch")
+			return pkg.NewPluginError(err.Error(), nil)
+		}
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	info, exists := pm.plugins[name]
+	if !exists {
+		return pkg.NewPluginNotFoundError(fmt.Sprintf("插件 '%s' 不存在，无法部署灰度版本", name), nil)
+	}
+	if info.State != StateEnabled {
+		return pkg.NewPluginError(fmt.Sprintf("插件 '%s' 当前状态为 %s，只有已启用的插件可以部署灰度版本", name, info.State), nil)
+	}
+	if current, exists := pm.canaries[name]; exists {
+		return pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' 的灰度版本 %s 已存在，请先提升或回滚", name, current.plugin.Version()), nil)
+	}
+	if plugin.Version() == info.Plugin.Version() {
+		return pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' 的灰度版本与稳定版本相同: %s", name, plugin.Version()), nil)
+	}
+
+	plugin.SetPluginManager(pm)
+
+	// 灰度版本同样需要满足冲突、依赖及其他插件的版本约束
+	specs, err := pm.checkCompatibilityLocked(plugin)
+	if err != nil {
+		return err
+	}
+	if err := pm.applyCanaryConfig(plugin); err != nil {
+		return pkg.NewPluginError(fmt.Sprintf("插件 '%s' 灰度版本配置无效: %v", name, err), err)
+	}
+	pm.injectStorage(plugin)
+
+	release := &canaryRelease{
+		plugin:    plugin,
+		policy:    policy,
+		specs:     specs,
+		manifest:  manifest,
+		owner:     name + "@" + plugin.Version(),
+		startedAt: time.Now(),
+	}
+
+	// 灰度版本在Init中以插件名称发布服务和订阅事件，初始化期间暂时取出稳定版本的服务，
+	// 初始化后将新增的订阅转移给灰度版本，并恢复稳定版本的服务
+	stableService := pm.takeService(name)
+	existing := pm.Events().subscriptionIDs(name)
+	initErr := pm.callInit(name, plugin)
+	pm.Events().reassignOwner(name, release.owner, existing)
+	release.service = pm.takeService(name)
+	pm.putService(name, stableService)
+
+	if initErr != nil {
+		pm.Events().UnsubscribeOwner(release.owner)
+		metrics.RecordPluginError(name, "canary_init_failed")
+		err := fmt.Errorf("插件 '%s' 灰度版本 %s 初始化失败: %w", name, plugin.Version(), initErr)
+		pm.recordFailureLocked(name, "canary", err)
+		return err
+	}
+
+	release.routes = plugin.GetRoutes()
+	if pm.router != nil {
+		table, routes, err := pm.buildRouteTable(plugin)
+		if err != nil {
+			pm.discardRelease(name, release)
+			err = fmt.Errorf("插件 '%s' 灰度版本路由注册失败: %w", name, err)
+			pm.recordFailureLocked(name, "canary", err)
+			return err
+		}
+		release.table = table
+		if len(routes) > 0 {
+			release.routes = routes
+		}
+	}
+
+	if pm.canaries == nil {
+		pm.canaries = make(map[string]*canaryRelease)
+	}
+	pm.canaries[name] = release
+	pm.setStateLocked(name, pm.stateLocked(name), "canary", nil)
+
+	pkg.Info("插件灰度版本已部署",
+		zap.String("plugin", name),
+		zap.String("stable_version", info.Plugin.Version()),
+		zap.String("canary_version", plugin.Version()),
+		zap.Int("weight", policy.Weight))
+	return nil
+}
+
+// SetCanaryPolicy 更新插件灰度版本的流量分配策略
+func (pm *PluginManager) SetCanaryPolicy(name string, policy CanaryPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return pkg.NewBadRequestError(err.Error(), nil)
+	}
+	policy.Tenants = append([]uint(nil), policy.Tenants...)
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	release, exists := pm.canaries[name]
+	if !exists {
+		return pkg.NewNotFound(fmt.Sprintf("插件 '%s' 没有灰度版本", name), nil)
+	}
+	updated := *release
+	updated.policy = policy
+	pm.canaries[name] = &updated
+	return nil
+}
+
+// GetCanary 获取插件的灰度发布状态，没有灰度版本时返回false
+func (pm *PluginManager) GetCanary(name string) (*CanaryStatus, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	release, exists := pm.canaries[name]
+	if !exists {
+		return nil, false
+	}
+	status := &CanaryStatus{
+		Plugin:        name,
+		CanaryVersion: release.plugin.Version(),
+		Policy:        release.policy,
+		StartedAt:     release.startedAt,
+	}
+	if info, exists := pm.plugins[name]; exists {
+		status.StableVersion = info.Plugin.Version()
+	}
+	status.Breaker = pm.breakerFor(name, release.plugin).status()
+	return status, true
+}
+
+// PromoteCanary 将灰度版本提升为稳定版本
+// 先关闭旧版本，关闭失败时保持灰度发布状态不变；之后由灰度版本接管路由、服务和事件订阅
+func (pm *PluginManager) PromoteCanary(name string) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	release, exists := pm.canaries[name]
+	if !exists {
+		return pkg.NewNotFound(fmt.Sprintf("插件 '%s' 没有灰度版本", name), nil)
+	}
+	info := pm.plugins[name]
+	previous := info.Plugin
+
+	if info.State != StateFailed {
+		if err := safeCall(name, "Shutdown", previous.Shutdown); err != nil {
+			metrics.RecordPluginError(name, "shutdown_during_promote_failed")
+			err = fmt.Errorf("插件 '%s' 旧版本 %s 关闭失败: %w", name, previous.Version(), err)
+			pm.recordFailureLocked(name, "promote", err)
+			return err
+		}
+	}
+
+	// 灰度版本接管事件订阅和服务
+	pm.Events().UnsubscribeOwner(name)
+	pm.Events().reassignOwner(release.owner, name, nil)
+	if release.service != nil {
+		release.service.disabled = !info.IsEnabled
+	}
+	pm.putService(name, release.service)
+
+	info.Plugin = release.plugin
+	info.Routes = release.routes
+	info.Dependencies = requiredDependencyNames(release.specs)
+	info.DependencySpecs = release.specs
+	info.Conflicts = release.plugin.GetConflicts()
+	info.Manifest = release.manifest
+	pm.plugins[name] = info
+	delete(pm.canaries, name)
+
+	if release.table != nil {
+		pm.routeTables[name] = release.table
+		info.IsRegistered = true
+		pm.plugins[name] = info
+	} else if pm.router != nil {
+		if err := pm.registerPluginRoutes(name); err != nil {
+			metrics.RecordPluginError(name, "route_registration_during_promote_failed")
+			pkg.Warn("提升灰度版本后注册路由失败", zap.String("plugin", name), zap.Error(err))
+		}
+	}
+	// 灰度版本保留自身的熔断和舱壁状态，只移除旧版本的
+	pm.removeVersionBreaker(name, previous.Version())
+	pm.removeVersionBulkheads(name, previous.Version())
+
+	pm.setStateLocked(name, pm.stateLocked(name), "promote", nil)
+	pkg.Info("插件灰度版本已提升为稳定版本",
+		zap.String("plugin", name),
+		zap.String("previous_version", previous.Version()),
+		zap.String("version", release.plugin.Version()))
+	pm.publishLifecycle(TopicPluginPromoted, release.plugin)
+	return nil
+}
+
+// RollbackCanary 回滚灰度版本，全部流量回到稳定版本
+// 灰度版本关闭失败时只记录错误，灰度版本仍会被移除
+func (pm *PluginManager) RollbackCanary(name string) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	release, exists := pm.canaries[name]
+	if !exists {
+		return pkg.NewNotFound(fmt.Sprintf("插件 '%s' 没有灰度版本", name), nil)
+	}
+	delete(pm.canaries, name)
+	// 状态不变，仅在转换历史中记录回滚及灰度版本的关闭结果
+	pm.setStateLocked(name, pm.stateLocked(name), "rollback", pm.discardRelease(name, release))
+
+	pkg.Info("插件灰度版本已回滚", zap.String("plugin", name), zap.String("version", release.plugin.Version()))
+	pm.publishLifecycle(TopicPluginRolledBack, release.plugin)
+	return nil
+}
+
+// takeCanaryLocked 移除插件的灰度版本（不关闭），调用方需持有pm.mutex
+func (pm *PluginManager) takeCanaryLocked(name string) *canaryRelease {
+	release, exists := pm.canaries[name]
+	if !exists {
+		return nil
+	}
+	delete(pm.canaries, name)
+	return release
+}
+
+// discardRelease 取消灰度版本的事件订阅，移除其熔断器和舱壁并关闭灰度版本
+func (pm *PluginManager) discardRelease(name string, release *canaryRelease) error {
+	pm.Events().UnsubscribeOwner(release.owner)
+	pm.removeVersionBreaker(name, release.plugin.Version())
+	pm.removeVersionBulkheads(name, release.plugin.Version())
+	if err := safeCall(name, "Shutdown", release.plugin.Shutdown); err != nil {
+		metrics.RecordPluginError(name, "canary_shutdown_failed")
+		err = fmt.Errorf("插件 '%s' 灰度版本 %s 关闭失败: %w", name, release.plugin.Version(), err)
+		pkg.Warn("关闭插件灰度版本失败", zap.String("plugin", name), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// canaryPlugin 获取插件的灰度版本实例，没有灰度版本时返回nil
+func (pm *PluginManager) canaryPlugin(name string) Plugin {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	if release, exists := pm.canaries[name]; exists {
+		return release.plugin
+	}
+	return nil
+}
+
+// executeTargetLocked 按灰度策略选择处理ExecutePlugin调用的插件实例，调用方需持有pm.mutex
+func (pm *PluginManager) executeTargetLocked(name string, stable Plugin, pin string, tenantID uint) Plugin {
+	release, exists := pm.canaries[name]
+	if !exists || !release.chooseCanary(pin, tenantID, stable.Version()) {
+		return stable
+	}
+	return release.plugin
+}
@@ -0,0 +1,305 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCanaryTestPlugin 返回版本号的测试插件，Init中发布服务并订阅事件
+func newCanaryTestPlugin(name, version string) *versionedPlugin {
+	p := newVersionedPlugin(name, version)
+	p.routes = []Route{{
+		Path:    "/version",
+		Method:  "GET",
+		Handler: func(c *gin.Context) { c.String(http.StatusOK, version) },
+	}}
+	p.initFunc = func() error {
+		if err := p.pm.Provide(p, version); err != nil {
+			return err
+		}
+		_, err := p.pm.Subscribe(name, "plugin.#", func(Event) {})
+		return err
+	}
+	return p
+}
+
+func serveWithHeader(router *gin.Engine, path, header, value string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCanaryTrafficSplitting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+
+	stable := newCanaryTestPlugin("P", "1.0.0")
+	if err := pm.Register(stable); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	canary := newCanaryTestPlugin("P", "2.0.0")
+	if err := pm.DeployCanary(canary, CanaryPolicy{Weight: 0, Tenants: []uint{7}}); err != nil {
+		t.Fatalf("deploy canary error: %v", err)
+	}
+
+	// 两个版本的服务和订阅互不影响
+	if impl, _ := pm.lookupService("P"); impl != "1.0.0" {
+		t.Fatalf("expected stable service kept during canary, got %v", impl)
+	}
+	if pm.Events().SubscriptionCount("P") != 1 || pm.Events().SubscriptionCount("P@2.0.0") != 1 {
+		t.Fatalf("expected canary subscriptions owned separately")
+	}
+
+	// 权重为0时全部路由到稳定版本，固定版本的请求头优先
+	if w := serveWithHeader(router, "/plugins/P/version", "", ""); w.Body.String() != "1.0.0" || w.Header().Get(DefaultCanaryHeader) != "1.0.0" {
+		t.Fatalf("expected stable version, got %q", w.Body.String())
+	}
+	if w := serveWithHeader(router, "/plugins/P/version", DefaultCanaryHeader, "2.0.0"); w.Body.String() != "2.0.0" {
+		t.Fatalf("expected pinned canary version, got %q", w.Body.String())
+	}
+
+	// 权重为100时全部路由到灰度版本，仍可固定到稳定版本
+	if err := pm.SetCanaryPolicy("P", CanaryPolicy{Weight: 100, Cookie: "plugin_version"}); err != nil {
+		t.Fatalf("set canary policy error: %v", err)
+	}
+	if w := serveWithHeader(router, "/plugins/P/version", "", ""); w.Body.String() != "2.0.0" {
+		t.Fatalf("expected canary version, got %q", w.Body.String())
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/plugins/P/version", nil)
+	req.AddCookie(&http.Cookie{Name: "plugin_version", Value: CanaryPinStable})
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "1.0.0" {
+		t.Fatalf("expected cookie pinned stable version, got %q", w.Body.String())
+	}
+
+	// ExecutePlugin按上下文中的固定版本和租户白名单选择版本
+	if _, err := pm.ExecutePluginContext(WithPluginVersion(context.Background(), CanaryPinStable), "P", nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if stable.executeCalled != 1 || canary.executeCalled != 0 {
+		t.Fatalf("expected pinned stable execution, got stable=%d canary=%d", stable.executeCalled, canary.executeCalled)
+	}
+	if err := pm.SetCanaryPolicy("P", CanaryPolicy{Weight: 0, Tenants: []uint{7}}); err != nil {
+		t.Fatalf("set canary policy error: %v", err)
+	}
+	if _, err := pm.ExecutePluginContext(WithRequestMeta(context.Background(), RequestMeta{TenantID: 7}), "P", nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if _, err := pm.ExecutePlugin("P", nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if stable.executeCalled != 2 || canary.executeCalled != 1 {
+		t.Fatalf("expected allowlisted tenant routed to canary, got stable=%d canary=%d", stable.executeCalled, canary.executeCalled)
+	}
+}
+
+func TestCanaryPromoteAndRollback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+
+	stable := newCanaryTestPlugin("P", "1.0.0")
+	if err := pm.Register(stable); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	canary := newCanaryTestPlugin("P", "2.0.0")
+	if err := pm.DeployCanary(canary, CanaryPolicy{Weight: 0}); err != nil {
+		t.Fatalf("deploy canary error: %v", err)
+	}
+
+	if err := pm.PromoteCanary("P"); err != nil {
+		t.Fatalf("promote error: %v", err)
+	}
+	if stable.shutdownCalled != 1 {
+		t.Fatalf("expected previous version shut down on promote")
+	}
+	if plugin, _ := pm.GetPlugin("P"); plugin.Version() != "2.0.0" {
+		t.Fatalf("expected promoted version, got %s", plugin.Version())
+	}
+	if impl, _ := pm.lookupService("P"); impl != "2.0.0" {
+		t.Fatalf("expected promoted service, got %v", impl)
+	}
+	if pm.Events().SubscriptionCount("P") != 1 || pm.Events().SubscriptionCount("P@2.0.0") != 0 {
+		t.Fatalf("expected promoted version to own subscriptions")
+	}
+	if w := serveWithHeader(router, "/plugins/P/version", "", ""); w.Body.String() != "2.0.0" {
+		t.Fatalf("expected promoted version served, got %q", w.Body.String())
+	}
+	if _, exists := pm.GetCanary("P"); exists {
+		t.Fatalf("expected canary removed after promote")
+	}
+	history, _ := pm.GetPluginHistory("P")
+	if last := history[len(history)-1]; last.Event != "promote" || last.To != StateEnabled {
+		t.Fatalf("expected promote recorded in history, got %+v", last)
+	}
+
+	next := newCanaryTestPlugin("P", "3.0.0")
+	if err := pm.DeployCanary(next, CanaryPolicy{Weight: 100}); err != nil {
+		t.Fatalf("deploy canary error: %v", err)
+	}
+	if err := pm.RollbackCanary("P"); err != nil {
+		t.Fatalf("rollback error: %v", err)
+	}
+	if next.shutdownCalled != 1 || pm.Events().SubscriptionCount("P@3.0.0") != 0 {
+		t.Fatalf("expected rolled back canary shut down and unsubscribed")
+	}
+	if w := serveWithHeader(router, "/plugins/P/version", "", ""); w.Body.String() != "2.0.0" {
+		t.Fatalf("expected stable version after rollback, got %q", w.Body.String())
+	}
+	if err := pm.RollbackCanary("P"); err == nil {
+		t.Fatalf("expected rollback without canary to fail")
+	}
+}
+
+func TestDeployCanaryRejects(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.DeployCanary(newCanaryTestPlugin("P", "2.0.0"), CanaryPolicy{}); err == nil {
+		t.Fatalf("expected canary without stable version rejected")
+	}
+	if err := pm.Register(newCanaryTestPlugin("P", "1.0.0")); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		plugin  Plugin
+		policy  CanaryPolicy
+		errPart string
+	}{
+		{"same version", newCanaryTestPlugin("P", "1.0.0"), CanaryPolicy{}, "相同"},
+		{"bad weight", newCanaryTestPlugin("P", "2.0.0"), CanaryPolicy{Weight: 101}, "权重"},
+		{"missing dependency", newVersionedPlugin("P", "2.0.0", "missing"), CanaryPolicy{}, "依赖"},
+	}
+	for _, tc := range cases {
+		if err := pm.DeployCanary(tc.plugin, tc.policy); err == nil || !strings.Contains(err.Error(), tc.errPart) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.errPart, err)
+		}
+	}
+
+	// 初始化失败时不影响稳定版本的服务
+	failing := newVersionedPlugin("P", "2.0.0")
+	failing.initFunc = func() error {
+		_ = failing.pm.Provide(failing, "broken")
+		return errors.New("init failed")
+	}
+	if err := pm.DeployCanary(failing, CanaryPolicy{}); err == nil {
+		t.Fatalf("expected canary init failure")
+	}
+	if impl, _ := pm.lookupService("P"); impl != "1.0.0" {
+		t.Fatalf("expected stable service restored, got %v", impl)
+	}
+
+	if err := pm.DeployCanary(newCanaryTestPlugin("P", "2.0.0"), CanaryPolicy{}); err != nil {
+		t.Fatalf("deploy canary error: %v", err)
+	}
+	if err := pm.DeployCanary(newCanaryTestPlugin("P", "3.0.0"), CanaryPolicy{}); err == nil || !strings.Contains(err.Error(), "已存在") {
+		t.Fatalf("expected duplicate canary rejected, got %v", err)
+	}
+	// 灰度发布期间不能禁用或重新加载
+	if err := pm.DisablePlugin("P"); err == nil {
+		t.Fatalf("expected disable rejected during canary")
+	}
+	if err := pm.ReloadPlugin("P"); err == nil {
+		t.Fatalf("expected reload rejected during canary")
+	}
+	// 注销时一并移除灰度版本
+	if err := pm.Unregister("P"); err != nil {
+		t.Fatalf("unregister error: %v", err)
+	}
+	if _, exists := pm.GetCanary("P"); exists || pm.Events().SubscriptionCount("P@2.0.0") != 0 {
+		t.Fatalf("expected canary removed on unregister")
+	}
+}
+
+func TestCanaryBreakerIsolatedByVersion(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetBreakerConfig("P", BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	stable := newVersionedPlugin("P", "1.0.0")
+	if err := pm.Register(stable); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	canary := newVersionedPlugin("P", "2.0.0")
+	canary.executeError = errors.New("canary broken")
+	if err := pm.DeployCanary(canary, CanaryPolicy{}); err != nil {
+		t.Fatalf("deploy canary error: %v", err)
+	}
+
+	pinned := WithPluginVersion(context.Background(), CanaryPinCanary)
+	for i := 0; i < 2; i++ {
+		if _, err := pm.ExecutePluginContext(pinned, "P", nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected canary error on call %d, got %v", i+1, err)
+		}
+	}
+	if _, err := pm.ExecutePluginContext(pinned, "P", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected canary breaker open, got %v", err)
+	}
+
+	// 灰度版本熔断不影响稳定版本
+	if _, err := pm.ExecutePlugin("P", nil); err != nil {
+		t.Fatalf("expected stable version unaffected, got %v", err)
+	}
+	if status, _ := pm.GetBreakerStatus("P"); status.State != BreakerClosed {
+		t.Fatalf("expected stable breaker closed, got %s", status.State)
+	}
+	if status, _ := pm.GetCanary("P"); status.Breaker.State != BreakerOpen {
+		t.Fatalf("expected canary breaker open in canary status, got %s", status.Breaker.State)
+	}
+
+	// 提升后灰度版本保留自身的熔断状态，旧版本的状态被移除
+	if err := pm.PromoteCanary("P"); err != nil {
+		t.Fatalf("promote error: %v", err)
+	}
+	if status, _ := pm.GetBreakerStatus("P"); status.State != BreakerOpen {
+		t.Fatalf("expected promoted version to keep its breaker, got %s", status.State)
+	}
+	if _, exists := pm.breakers["P"]["1.0.0"]; exists {
+		t.Fatalf("expected previous version breaker removed")
+	}
+}
+
+func TestFailingCanaryRolledBackInsteadOfDisablingPlugin(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetBreakerConfig("P", BreakerConfig{FailureThreshold: 10, AutoDisableThreshold: 2, OpenTimeout: time.Minute})
+
+	if err := pm.Register(newVersionedPlugin("P", "1.0.0")); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	canary := newVersionedPlugin("P", "2.0.0")
+	canary.executeError = errors.New("canary broken")
+	if err := pm.DeployCanary(canary, CanaryPolicy{Weight: 100}); err != nil {
+		t.Fatalf("deploy canary error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _ = pm.ExecutePlugin("P", nil)
+	}
+	if _, exists := pm.GetCanary("P"); exists {
+		t.Fatalf("expected failing canary rolled back")
+	}
+	if canary.shutdownCalled != 1 {
+		t.Fatalf("expected rolled back canary shut down, got %d", canary.shutdownCalled)
+	}
+	if info, _ := pm.GetPluginInfo("P"); !info.IsEnabled {
+		t.Fatalf("expected stable version to stay enabled")
+	}
+	if _, err := pm.ExecutePlugin("P", nil); err != nil {
+		t.Fatalf("expected stable version to serve calls, got %v", err)
+	}
+}
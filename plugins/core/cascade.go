@@ -0,0 +1,252 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"weave/pkg"
+
+	"go.uber.org/zap"
+)
+
+// 级联操作的动作
+const (
+	CascadeEnable  = "enable"
+	CascadeDisable = "disable"
+	CascadeReload  = "reload" // 批量重新加载，见ReloadPlugins
+)
+
+// CascadeStep 级联启用或禁用中的一步，按执行顺序排列
+type CascadeStep struct {
+	Plugin string `json:"plugin"`
+	Action string `json:"action"` // enable、disable 或 reload
+	Reason string `json:"reason"` // 该插件受影响的原因
+}
+
+// PlanEnable 计算启用插件时受影响的插件及执行顺序，不修改任何状态
+// cascade为true时未启用的必需依赖（包括间接依赖）先于插件启用；为false时依赖未启用返回错误。
+// 插件已启用时返回空列表
+func (pm *PluginManager) PlanEnable(name string, cascade bool) ([]CascadeStep, error) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.planEnableLocked(name, cascade)
+}
+
+// PlanDisable 计算禁用插件时受影响的插件及执行顺序，不修改任何状态
+// cascade为true时已启用的依赖方（包括间接依赖方）先于插件禁用；为false时存在依赖方返回错误。
+// 插件已禁用时返回空列表
+func (pm *PluginManager) PlanDisable(name string, cascade bool) ([]CascadeStep, error) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.planDisableLocked(name, cascade)
+}
+
+// EnablePluginCascade 启用插件及其未启用的依赖，依赖先于依赖方启用
+// 任一插件启用失败时，已启用的插件按相反顺序禁用，返回错误；成功时返回执行的步骤，设置了状态同步时保存并广播各插件的状态
+func (pm *PluginManager) EnablePluginCascade(name string) ([]CascadeStep, error) {
+	return pm.changeStates(func() ([]CascadeStep, error) {
+		pm.mutex.Lock()
+		defer pm.mutex.Unlock()
+
+		steps, err := pm.planEnableLocked(name, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := pm.applyCascadeLocked(steps); err != nil {
+			return nil, err
+		}
+		return steps, nil
+	})
+}
+
+// DisablePluginCascade 禁用插件及依赖它的已启用插件，依赖方先于被依赖的插件禁用
+// 任一插件禁用失败时，已禁用的插件按相反顺序重新启用，返回错误；成功时返回执行的步骤，设置了状态同步时保存并广播各插件的状态
+func (pm *PluginManager) DisablePluginCascade(name string) ([]CascadeStep, error) {
+	return pm.changeStates(func() ([]CascadeStep, error) {
+		pm.mutex.Lock()
+		defer pm.mutex.Unlock()
+
+		steps, err := pm.planDisableLocked(name, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := pm.applyCascadeLocked(steps); err != nil {
+			return nil, err
+		}
+		return steps, nil
+	})
+}
+
+// planEnableLocked 按依赖的后序遍历生成启用顺序，调用方需持有pm.mutex
+func (pm *PluginManager) planEnableLocked(name string, cascade bool) ([]CascadeStep, error) {
+	info, exists := pm.plugins[name]
+	if !exists {
+		return nil, fmt.Errorf("插件 '%s' 不存在", name)
+	}
+	if info.IsEnabled {
+		return []CascadeStep{}, nil
+	}
+
+	if !cascade {
+		for _, depName := range info.Dependencies {
+			depInfo, exists := pm.plugins[depName]
+			if !exists || !depInfo.IsEnabled {
+				return nil, fmt.Errorf("依赖的插件 '%s' 未启用", depName)
+			}
+		}
+		if err := pm.checkTransitionLocked(name, StateEnabled); err != nil {
+			return nil, err
+		}
+		return []CascadeStep{{Plugin: name, Action: CascadeEnable, Reason: "请求启用"}}, nil
+	}
+
+	steps := []CascadeStep{}
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(current, reason string) error
+	visit = func(current, reason string) error {
+		if visited[current] {
+			return nil
+		}
+		if visiting[current] {
+			return fmt.Errorf("插件 '%s' 存在循环依赖", current)
+		}
+		currentInfo, exists := pm.plugins[current]
+		if !exists {
+			return fmt.Errorf("依赖的插件 '%s' 未注册", current)
+		}
+		if currentInfo.IsEnabled {
+			visited[current] = true
+			return nil
+		}
+
+		visiting[current] = true
+		for _, depName := range currentInfo.Dependencies {
+			if err := visit(depName, fmt.Sprintf("被插件 '%s' 依赖", current)); err != nil {
+				return err
+			}
+		}
+		visiting[current] = false
+		visited[current] = true
+
+		if err := pm.checkTransitionLocked(current, StateEnabled); err != nil {
+			return err
+		}
+		steps = append(steps, CascadeStep{Plugin: current, Action: CascadeEnable, Reason: reason})
+		return nil
+	}
+
+	if err := visit(name, "请求启用"); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// planDisableLocked 按依赖方的后序遍历生成禁用顺序，调用方需持有pm.mutex
+func (pm *PluginManager) planDisableLocked(name string, cascade bool) ([]CascadeStep, error) {
+	info, exists := pm.plugins[name]
+	if !exists {
+		return nil, fmt.Errorf("插件 '%s' 不存在", name)
+	}
+	if !info.IsEnabled {
+		return []CascadeStep{}, nil
+	}
+
+	// 已启用插件的必需依赖反向索引，可选依赖不阻止禁用
+	dependents := make(map[string][]string)
+	for pluginName, pluginInfo := range pm.plugins {
+		if !pluginInfo.IsEnabled {
+			continue
+		}
+		for _, depName := range pluginInfo.Dependencies {
+			dependents[depName] = append(dependents[depName], pluginName)
+		}
+	}
+	for _, names := range dependents {
+		sort.Strings(names)
+	}
+
+	if !cascade {
+		for _, dependent := range dependents[name] {
+			if dependent != name {
+				return nil, fmt.Errorf("插件 '%s' 被插件 '%s' 依赖，无法禁用", name, dependent)
+			}
+		}
+		if err := pm.checkCanaryLocked(name); err != nil {
+			return nil, err
+		}
+		return []CascadeStep{{Plugin: name, Action: CascadeDisable, Reason: "请求禁用"}}, nil
+	}
+
+	steps := []CascadeStep{}
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(current, reason string) error
+	visit = func(current, reason string) error {
+		if visited[current] {
+			return nil
+		}
+		if visiting[current] {
+			return fmt.Errorf("插件 '%s' 存在循环依赖", current)
+		}
+
+		visiting[current] = true
+		for _, dependent := range dependents[current] {
+			if err := visit(dependent, fmt.Sprintf("依赖插件 '%s'", current)); err != nil {
+				return err
+			}
+		}
+		visiting[current] = false
+		visited[current] = true
+
+		if err := pm.checkCanaryLocked(current); err != nil {
+			return err
+		}
+		steps = append(steps, CascadeStep{Plugin: current, Action: CascadeDisable, Reason: reason})
+		return nil
+	}
+
+	if err := visit(name, "请求禁用"); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// checkCanaryLocked 灰度发布期间不能禁用插件
+func (pm *PluginManager) checkCanaryLocked(name string) error {
+	if _, exists := pm.canaries[name]; exists {
+		return pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' 正在灰度发布，请先提升或回滚灰度版本", name), nil)
+	}
+	return nil
+}
+
+// applyCascadeLocked 依次执行级联步骤，失败时按相反顺序撤销已执行的步骤
+func (pm *PluginManager) applyCascadeLocked(steps []CascadeStep) error {
+	for i, step := range steps {
+		err := pm.applyCascadeStepLocked(step.Plugin, step.Action)
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			undo := CascadeDisable
+			if steps[j].Action == CascadeDisable {
+				undo = CascadeEnable
+			}
+			if undoErr := pm.applyCascadeStepLocked(steps[j].Plugin, undo); undoErr != nil {
+				pkg.Warn("级联操作回滚失败", zap.String("plugin", steps[j].Plugin), zap.String("action", undo), zap.Error(undoErr))
+			}
+		}
+		return fmt.Errorf("级联操作在插件 '%s' 处失败，已回滚之前的 %d 个插件: %w", step.Plugin, i, err)
+	}
+	return nil
+}
+
+// applyCascadeStepLocked 启用或禁用单个插件
+func (pm *PluginManager) applyCascadeStepLocked(name, action string) error {
+	if action == CascadeEnable {
+		return pm.enablePluginLocked(name)
+	}
+	return pm.disablePluginLocked(name)
+}
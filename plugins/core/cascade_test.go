@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stepsOf 将级联步骤整理为"action:plugin"形式便于断言
+func stepsOf(steps []CascadeStep) string {
+	parts := make([]string, 0, len(steps))
+	for _, step := range steps {
+		parts = append(parts, step.Action+":"+step.Plugin)
+	}
+	return strings.Join(parts, ",")
+}
+
+// newCascadeManager 注册依赖链 C -> B -> A 以及 D -> A
+func newCascadeManager(t *testing.T) (*PluginManager, map[string]*testPlugin) {
+	t.Helper()
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugins := map[string]*testPlugin{
+		"A": newTestPlugin("A", false),
+		"B": newTestPlugin("B", false),
+		"C": newTestPlugin("C", false),
+		"D": newTestPlugin("D", false),
+	}
+	plugins["B"].deps = []string{"A"}
+	plugins["C"].deps = []string{"B"}
+	plugins["D"].deps = []string{"A"}
+	for _, name := range []string{"A", "B", "C", "D"} {
+		if err := pm.Register(plugins[name]); err != nil {
+			t.Fatalf("register %s error: %v", name, err)
+		}
+	}
+	return pm, plugins
+}
+
+func TestCascadeDisableAndEnable(t *testing.T) {
+	pm, _ := newCascadeManager(t)
+
+	if err := pm.DisablePlugin("A"); err == nil {
+		t.Fatalf("expected disable without cascade to fail")
+	}
+	if _, err := pm.PlanDisable("A", false); err == nil {
+		t.Fatalf("expected plan without cascade to fail")
+	}
+
+	// 预览不修改任何状态
+	plan, err := pm.PlanDisable("A", true)
+	if err != nil {
+		t.Fatalf("plan disable error: %v", err)
+	}
+	if got := stepsOf(plan); got != "disable:C,disable:B,disable:D,disable:A" {
+		t.Fatalf("unexpected disable plan: %s", got)
+	}
+	if status, _ := pm.GetPluginStatus("C"); status != "enabled" {
+		t.Fatalf("dry run should not disable plugins, C is %s", status)
+	}
+
+	steps, err := pm.DisablePluginCascade("A")
+	if err != nil {
+		t.Fatalf("cascade disable error: %v", err)
+	}
+	if stepsOf(steps) != stepsOf(plan) {
+		t.Fatalf("executed steps %s differ from plan %s", stepsOf(steps), stepsOf(plan))
+	}
+	for _, name := range []string{"A", "B", "C", "D"} {
+		if status, _ := pm.GetPluginStatus(name); status != "disabled" {
+			t.Fatalf("expected %s disabled, got %s", name, status)
+		}
+	}
+
+	if err := pm.EnablePlugin("C"); err == nil {
+		t.Fatalf("expected enable without cascade to fail")
+	}
+	steps, err = pm.EnablePluginCascade("C")
+	if err != nil {
+		t.Fatalf("cascade enable error: %v", err)
+	}
+	if got := stepsOf(steps); got != "enable:A,enable:B,enable:C" {
+		t.Fatalf("unexpected enable steps: %s", got)
+	}
+	if status, _ := pm.GetPluginStatus("D"); status != "disabled" {
+		t.Fatalf("expected unrelated dependent D to stay disabled, got %s", status)
+	}
+
+	// 已启用的插件不受影响
+	if plan, err := pm.PlanEnable("C", true); err != nil || len(plan) != 0 {
+		t.Fatalf("expected empty plan for enabled plugin, got %v, %v", plan, err)
+	}
+}
+
+func TestCascadeRollback(t *testing.T) {
+	pm, plugins := newCascadeManager(t)
+	if _, err := pm.DisablePluginCascade("A"); err != nil {
+		t.Fatalf("cascade disable error: %v", err)
+	}
+
+	plugins["B"].enableError = errors.New("enable failed")
+	if _, err := pm.EnablePluginCascade("C"); err == nil || !strings.Contains(err.Error(), "enable failed") {
+		t.Fatalf("expected cascade enable to fail at B, got %v", err)
+	}
+	for _, name := range []string{"A", "B", "C"} {
+		if status, _ := pm.GetPluginStatus(name); status != "disabled" {
+			t.Fatalf("expected %s disabled after rollback, got %s", name, status)
+		}
+	}
+	if plugins["A"].enableCalled != 1 || plugins["A"].disableCalled != 2 {
+		t.Fatalf("expected A enabled then rolled back, enable=%d disable=%d", plugins["A"].enableCalled, plugins["A"].disableCalled)
+	}
+
+	// 禁用失败时重新启用已禁用的依赖方
+	plugins["B"].enableError = nil
+	if _, err := pm.EnablePluginCascade("C"); err != nil {
+		t.Fatalf("cascade enable error: %v", err)
+	}
+	plugins["A"].disableError = errors.New("disable failed")
+	if _, err := pm.DisablePluginCascade("A"); err == nil {
+		t.Fatalf("expected cascade disable to fail at A")
+	}
+	for _, name := range []string{"A", "B", "C"} {
+		if status, _ := pm.GetPluginStatus(name); status != "enabled" {
+			t.Fatalf("expected %s enabled after rollback, got %s", name, status)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weave/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultExecuteTimeout 插件执行的默认超时时间
+// 当插件未声明超时且未通过SetExecuteTimeout单独配置时使用
+const DefaultExecuteTimeout = 30 * time.Second
+
+// ContextExecutor 支持上下文的执行接口（可选）
+// 实现该接口的插件会优先通过ExecuteContext被调用，从而可以感知取消信号和截止时间
+type ContextExecutor interface {
+	ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error)
+}
+
+// ExecuteTimeoutProvider 插件声明自身默认执行超时的接口（可选）
+type ExecuteTimeoutProvider interface {
+	ExecuteTimeout() time.Duration
+}
+
+// RequestMeta 调用方请求元数据，随上下文传递给插件
+type RequestMeta struct {
+	UserID    uint   // 调用方用户ID
+	TenantID  uint   // 调用方租户ID
+	RequestID string // 请求ID，用于链路追踪
+}
+
+// requestMetaKey 上下文中存放请求元数据的键
+type requestMetaKey struct{}
+
+// WithRequestMeta 将请求元数据写入上下文
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext 从上下文中读取请求元数据
+func RequestMetaFromContext(ctx context.Context) (RequestMeta, bool) {
+	if ctx == nil {
+		return RequestMeta{}, false
+	}
+	meta, ok := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta, ok
+}
+
+// pluginVersionKey 上下文中存放固定插件版本的键
+type pluginVersionKey struct{}
+
+// WithPluginVersion 固定本次调用使用的插件版本（版本号，或"stable"/"canary"），仅在插件灰度发布期间生效
+func WithPluginVersion(ctx context.Context, version string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, pluginVersionKey{}, version)
+}
+
+// PluginVersionFromContext 从上下文中读取固定的插件版本
+func PluginVersionFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	version, _ := ctx.Value(pluginVersionKey{}).(string)
+	return version
+}
+
+// ContextFromGin 基于Gin请求上下文构建插件执行上下文
+// 继承HTTP请求的取消信号与截止时间（例如TimeoutMiddleware设置的超时），
+// 并携带认证中间件写入的user_id/tenant_id以及请求ID
+func ContextFromGin(c *gin.Context) context.Context {
+	meta := RequestMeta{
+		UserID:    c.GetUint("user_id"),
+		TenantID:  c.GetUint("tenant_id"),
+		RequestID: c.GetString("X-Request-ID"),
+	}
+	if meta.RequestID == "" {
+		meta.RequestID = c.GetHeader("X-Request-ID")
+	}
+	return WithRequestMeta(c.Request.Context(), meta)
+}
+
+// SetExecuteTimeout 为指定插件设置执行超时时间
+// timeout小于等于0时移除单独配置，恢复使用插件声明值或默认值
+func (pm *PluginManager) SetExecuteTimeout(name string, timeout time.Duration) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if timeout <= 0 {
+		delete(pm.executeTimeouts, name)
+		return
+	}
+	if pm.executeTimeouts == nil {
+		pm.executeTimeouts = make(map[string]time.Duration)
+	}
+	pm.executeTimeouts[name] = timeout
+}
+
+// executeTimeoutLocked 获取插件的执行超时时间，调用方需持有锁
+// 优先级：管理器单独配置 > 插件声明 > DefaultExecuteTimeout
+func (pm *PluginManager) executeTimeoutLocked(name string, plugin Plugin) time.Duration {
+	if timeout, ok := pm.executeTimeouts[name]; ok {
+		return timeout
+	}
+	if provider, ok := plugin.(ExecuteTimeoutProvider); ok {
+		if timeout := provider.ExecuteTimeout(); timeout > 0 {
+			return timeout
+		}
+	}
+	return DefaultExecuteTimeout
+}
+
+// ExecutePluginContext 在给定上下文中执行插件功能
+// 插件实现ContextExecutor时直接传入上下文；否则在独立goroutine中调用Execute，
+// 上下文取消或超时后立即返回（旧版插件的Execute无法被中断，会在后台继续运行直至结束，
+// 结束前继续占用舱壁和熔断器试探名额，关闭时同样等待其结束）。
+// 插件灰度发布期间按WithPluginVersion、请求元数据中的租户和流量权重选择版本
+func (pm *PluginManager) ExecutePluginContext(ctx context.Context, name string, params map[string]interface{}) (interface{}, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	meta, _ := RequestMetaFromContext(ctx)
+
+	pm.mutex.RLock()
+	info, exists := pm.plugins[name]
+	var target Plugin
+	var timeout time.Duration
+	if exists {
+		target = pm.executeTargetLocked(name, info.Plugin, PluginVersionFromContext(ctx), meta.TenantID)
+		timeout = pm.executeTimeoutLocked(name, target)
+	}
+	pm.mutex.RUnlock()
+
+	// 异步任务使用任务队列的执行超时
+	if override, ok := ctx.Value(executeTimeoutKey{}).(time.Duration); ok && override > 0 {
+		timeout = override
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("插件 '%s' 不存在", name)
+	}
+
+	// 检查插件是否启用
+	if !info.IsEnabled {
+		return nil, fmt.Errorf("插件 '%s' 已被禁用", name)
+	}
+
+	// 关闭期间拒绝新调用，并登记进行中的调用以便关闭时等待其结束
+	if !pm.requests.begin() {
+		return nil, shuttingDownError(name)
+	}
+
+	// 舱壁隔离：达到并发上限时排队等待，队列已满或排队超时则拒绝
+	release, err := pm.bulkheadsFor(name, target).execute.acquire(ctx)
+	if err != nil {
+		pm.requests.end()
+		metrics.RecordPluginMethodCall(name, "Execute", false)
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return nil, pm.contextError(name, ctxErr)
+		}
+		return nil, bulkheadError(name, "execute", err)
+	}
+
+	// 熔断器打开时直接短路
+	breaker := pm.breakerFor(name, target)
+	probe, ok := breaker.allow()
+	if !ok {
+		release()
+		pm.requests.end()
+		return nil, circuitOpenError(name)
+	}
+
+	// 强制插件默认截止时间；若调用方上下文的截止时间更早则以调用方为准
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	success := true
+
+	result, running, err := invokeExecute(ctx, name, target, params)
+	// 插件代码实际返回后才归还名额和结束调用登记，调用方放弃等待时在后台等待Execute结束
+	defer func() {
+		finish := func() {
+			breaker.finish(probe)
+			release()
+			pm.requests.end()
+		}
+		if running == nil {
+			finish()
+			return
+		}
+		go func() {
+			<-running
+			finish()
+		}()
+	}()
+	if err != nil {
+		success = false
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			err = pm.contextError(name, ctxErr)
+		} else {
+			metrics.RecordPluginError(name, "execute_failed")
+		}
+	}
+
+	// 调用方主动取消不计入熔断统计，执行超时计为失败
+	if !errors.Is(err, context.Canceled) {
+		pm.recordCallResult(name, target, breaker, err)
+	}
+
+	// 记录插件执行时间和结果
+	duration := time.Since(startTime)
+	metrics.RecordPluginExecution(name, success, duration)
+	metrics.RecordPluginMethodCall(name, "Execute", success)
+	metrics.RecordPluginVersionCall(name, target.Version(), "execute", success, duration)
+
+	return result, err
+}
+
+// invokeExecute 根据插件能力选择执行方式，插件panic时返回插件执行错误
+// 旧版插件的Execute在上下文结束前没有返回时，running在Execute实际返回后关闭；其余情况running为nil
+func invokeExecute(ctx context.Context, name string, plugin Plugin, params map[string]interface{}) (result interface{}, running <-chan struct{}, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if executor, ok := plugin.(ContextExecutor); ok {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				result, err = nil, pluginPanicError(name, "ExecuteContext", recovered)
+			}
+		}()
+		result, err = executor.ExecuteContext(ctx, params)
+		return result, nil, err
+	}
+
+	type executeResult struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan executeResult, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		// 后台goroutine中的panic无法被调用方恢复，必须在此处理
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- executeResult{err: pluginPanicError(name, "Execute", recovered)}
+			}
+		}()
+		value, err := plugin.Execute(params)
+		done <- executeResult{value: value, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, nil, res.err
+	case <-ctx.Done():
+		return nil, exited, ctx.Err()
+	}
+}
+
+// contextError 将上下文错误转换为插件执行错误并记录指标
+func (pm *PluginManager) contextError(name string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		metrics.RecordPluginError(name, "execute_timeout")
+		return fmt.Errorf("插件 '%s' 执行超时: %w", name, err)
+	}
+	metrics.RecordPluginError(name, "execute_canceled")
+	return fmt.Errorf("插件 '%s' 执行已取消: %w", name, err)
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextPlugin 实现ContextExecutor的测试插件
+type contextPlugin struct {
+	testPlugin
+	gotMeta RequestMeta
+	block   bool
+}
+
+func (p *contextPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	p.gotMeta, _ = RequestMetaFromContext(ctx)
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return "ctx-ok", nil
+}
+
+// slowPlugin 旧版接口插件，Execute会阻塞直到被释放
+type slowPlugin struct {
+	testPlugin
+	release chan struct{}
+	timeout time.Duration
+}
+
+func (p *slowPlugin) Execute(params map[string]interface{}) (interface{}, error) {
+	<-p.release
+	return "late", nil
+}
+
+func (p *slowPlugin) ExecuteTimeout() time.Duration { return p.timeout }
+
+func TestExecutePluginContextPrefersContextExecutor(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	cp := &contextPlugin{testPlugin: testPlugin{name: "C"}}
+	if err := pm.Register(cp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	ctx := WithRequestMeta(context.Background(), RequestMeta{UserID: 7, TenantID: 3, RequestID: "req-1"})
+	res, err := pm.ExecutePluginContext(ctx, "C", nil)
+	if err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if res != "ctx-ok" {
+		t.Fatalf("expected ExecuteContext result, got %v", res)
+	}
+	if cp.executeCalled != 0 {
+		t.Fatalf("expected legacy Execute not called, got %d", cp.executeCalled)
+	}
+	if cp.gotMeta.UserID != 7 || cp.gotMeta.TenantID != 3 || cp.gotMeta.RequestID != "req-1" {
+		t.Fatalf("unexpected request meta: %+v", cp.gotMeta)
+	}
+}
+
+func TestExecutePluginContextCanceled(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	cp := &contextPlugin{testPlugin: testPlugin{name: "C"}, block: true}
+	if err := pm.Register(cp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	_, err := pm.ExecutePluginContext(ctx, "C", nil)
+	if err == nil || !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "已取消") {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+}
+
+func TestExecutePluginContextLegacyPluginDeadline(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	sp := &slowPlugin{testPlugin: testPlugin{name: "S"}, release: make(chan struct{}), timeout: 20 * time.Millisecond}
+	defer close(sp.release)
+	if err := pm.Register(sp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	start := time.Now()
+	_, err := pm.ExecutePlugin("S", nil)
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "超时") {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("expected execution to return near plugin deadline, took %v", time.Since(start))
+	}
+}
+
+func TestSetExecuteTimeoutOverridesPluginDefault(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	sp := &slowPlugin{testPlugin: testPlugin{name: "S"}, release: make(chan struct{}), timeout: time.Hour}
+	defer close(sp.release)
+	if err := pm.Register(sp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	pm.SetExecuteTimeout("S", 10*time.Millisecond)
+	if _, err := pm.ExecutePlugin("S", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error from manager timeout, got %v", err)
+	}
+
+	pm.SetExecuteTimeout("S", 0)
+	pm.mutex.RLock()
+	timeout := pm.executeTimeoutLocked("S", sp)
+	pm.mutex.RUnlock()
+	if timeout != time.Hour {
+		t.Fatalf("expected plugin declared timeout after reset, got %v", timeout)
+	}
+}
+
+func TestContextFromGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("X-Request-ID", "hdr-id")
+	c.Set("user_id", uint(5))
+	c.Set("tenant_id", uint(9))
+
+	meta, ok := RequestMetaFromContext(ContextFromGin(c))
+	if !ok {
+		t.Fatalf("expected request meta in context")
+	}
+	if meta.UserID != 5 || meta.TenantID != 9 || meta.RequestID != "hdr-id" {
+		t.Fatalf("unexpected request meta: %+v", meta)
+	}
+
+	c.Set("X-Request-ID", "key-id")
+	meta, _ = RequestMetaFromContext(ContextFromGin(c))
+	if meta.RequestID != "key-id" {
+		t.Fatalf("expected request id from gin key, got %q", meta.RequestID)
+	}
+}
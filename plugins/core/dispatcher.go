@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"weave/middleware"
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pluginRoutePrefix 插件路由统一前缀
+const pluginRoutePrefix = "/plugins"
+
+// outerGinContextKey 请求上下文中存放外层Gin上下文的键
+type outerGinContextKey struct{}
+
+// mountDispatcher 在路由引擎上挂载插件请求分发器，调用方需持有写锁
+// Gin不支持动态删除路由，因此主路由只注册一条通配路由，
+// 每个插件的路由保存在独立的路由表（gin.Engine）中，可随时整体替换或删除
+func (pm *PluginManager) mountDispatcher(router *gin.Engine) {
+	if router == nil || pm.dispatcherRouter == router {
+		return
+	}
+	router.Any(pluginRoutePrefix+"/:name/*path", pm.dispatchPluginRequest)
+	pm.dispatcherRouter = router
+}
+
+// dispatchPluginRequest 将请求分发到对应插件的路由表
+func (pm *PluginManager) dispatchPluginRequest(c *gin.Context) {
+	name := c.Param("name")
+
+	pm.mutex.RLock()
+	info, exists := pm.plugins[name]
+	table := pm.routeTables[name]
+	canary := pm.canaries[name]
+	pm.mutex.RUnlock()
+
+	if !exists {
+		abortWithAppError(c, pkg.NewPluginNotFoundError(fmt.Sprintf("插件 '%s' 不存在", name), nil))
+		return
+	}
+	if !info.IsEnabled {
+		abortWithAppError(c, pkg.NewPluginDisabledError(fmt.Sprintf("插件 '%s' 已被禁用", name), nil))
+		return
+	}
+	if table == nil {
+		abortWithAppError(c, pkg.NewNotFound(fmt.Sprintf("插件 '%s' 未注册路由", name), nil))
+		return
+	}
+
+	if !pm.requests.begin() {
+		abortWithAppError(c, shuttingDownError(name))
+		return
+	}
+	defer pm.requests.end()
+
+	// 灰度发布期间按策略选择版本，并通过响应头告知调用方实际处理请求的版本
+	version := info.Plugin.Version()
+	if canary != nil && canary.table != nil {
+		if canary.chooseCanary(canary.httpPin(c), requestTenantID(c), version) {
+			table, version = canary.table, canary.plugin.Version()
+		}
+		c.Header(canary.policy.headerName(), version)
+	}
+
+	// 通过请求上下文传递外层Gin上下文，便于插件路由表继承请求ID等上下文数据
+	startTime := time.Now()
+	req := c.Request.WithContext(context.WithValue(c.Request.Context(), outerGinContextKey{}, c))
+	table.ServeHTTP(c.Writer, req)
+	c.Abort()
+
+	metrics.RecordPluginVersionCall(name, version, "http", c.Writer.Status() < http.StatusInternalServerError, time.Since(startTime))
+}
+
+// buildRouteTable 根据插件路由定义构建新的路由表
+// 构建失败时不影响当前正在使用的路由表
+func (pm *PluginManager) buildRouteTable(plugin Plugin) (*gin.Engine, []Route, error) {
+	table := gin.New()
+	table.Use(inheritOuterContext, pm.guardPluginRequest(plugin))
+	table.NoRoute(func(c *gin.Context) {
+		abortWithAppError(c, pkg.NewNotFound("", nil))
+	})
+
+	// 创建插件路由组
+	pluginGroup := table.Group(fmt.Sprintf("%s/%s", pluginRoutePrefix, plugin.Name()))
+
+	// 添加插件默认中间件
+	if defaultMiddlewares := plugin.GetDefaultMiddlewares(); len(defaultMiddlewares) > 0 {
+		pluginGroup.Use(defaultMiddlewares...)
+	}
+
+	// 获取插件路由
+	routes := plugin.GetRoutes()
+
+	// 如果没有通过GetRoutes提供路由，则回退到旧版的RegisterRoutes方法
+	// 旧版插件注册的路由需位于 /plugins/<插件名> 前缀下才能被分发器访问
+	if len(routes) == 0 {
+		plugin.RegisterRoutes(table)
+		return table, routes, nil
+	}
+
+	// 注册每个路由
+	for _, route := range routes {
+		// 创建路由处理函数链
+		handlers := make([]gin.HandlerFunc, 0, len(route.Middlewares)+3)
+
+		// 如果需要认证，则在处理链前添加认证中间件；声明了角色或权限要求的路由在认证后校验授权
+		if route.AuthRequired || route.requiresAuthorization() {
+			handlers = append(handlers, middleware.AuthMiddleware())
+		}
+		if route.requiresAuthorization() {
+			handlers = append(handlers, pm.authorizeRoute(route))
+		}
+		handlers = append(handlers, route.Middlewares...)
+		handlers = append(handlers, route.Handler)
+
+		// 根据HTTP方法注册路由
+		switch route.Method {
+		case "GET":
+			pluginGroup.GET(route.Path, handlers...)
+		case "POST":
+			pluginGroup.POST(route.Path, handlers...)
+		case "PUT":
+			pluginGroup.PUT(route.Path, handlers...)
+		case "DELETE":
+			pluginGroup.DELETE(route.Path, handlers...)
+		case "PATCH":
+			pluginGroup.PATCH(route.Path, handlers...)
+		case "OPTIONS":
+			pluginGroup.OPTIONS(route.Path, handlers...)
+		default:
+			return nil, nil, fmt.Errorf("不支持的HTTP方法: %s", route.Method)
+		}
+	}
+
+	return table, routes, nil
+}
+
+// inheritOuterContext 插件路由表的首个中间件
+// 复制外层上下文中的键值（如X-Request-ID），并将插件处理过程中产生的错误回传给外层，
+// 使ErrorHandler等全局中间件对插件路由同样生效
+func inheritOuterContext(c *gin.Context) {
+	outer, ok := c.Request.Context().Value(outerGinContextKey{}).(*gin.Context)
+	if !ok {
+		c.Next()
+		return
+	}
+
+	for k, v := range outer.Keys {
+		c.Set(k, v)
+	}
+
+	c.Next()
+
+	outer.Errors = append(outer.Errors, c.Errors...)
+}
+
+// abortWithAppError 以统一错误格式终止请求
+func abortWithAppError(c *gin.Context, appErr *pkg.AppError) {
+	appErr.WithRequestID(c.GetString("X-Request-ID")).WithPath(c.Request.URL.Path)
+	c.AbortWithStatusJSON(pkg.GetHTTPStatus(appErr), appErr)
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"weave/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveRequest(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeAppError(t *testing.T, w *httptest.ResponseRecorder) pkg.AppError {
+	t.Helper()
+	var appErr pkg.AppError
+	if err := json.Unmarshal(w.Body.Bytes(), &appErr); err != nil {
+		t.Fatalf("failed to decode error body %q: %v", w.Body.String(), err)
+	}
+	return appErr
+}
+
+func TestDispatcherServesDisablesAndUnregisters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+	// 重复设置同一路由引擎不应重复挂载分发器
+	pm.SetRouter(router)
+
+	if err := pm.Register(newTestPlugin("P", true)); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	if w := serveRequest(router, http.MethodGet, "/plugins/P/ping"); w.Code != http.StatusOK || w.Body.String() != "pong" {
+		t.Fatalf("expected 200 pong, got %d %q", w.Code, w.Body.String())
+	}
+
+	w := serveRequest(router, http.MethodGet, "/plugins/P/missing")
+	if w.Code != http.StatusNotFound || decodeAppError(t, w).Code != pkg.ErrNotFound {
+		t.Fatalf("expected 404 NOT_FOUND for unknown plugin route, got %d %s", w.Code, w.Body.String())
+	}
+
+	if err := pm.DisablePlugin("P"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	w = serveRequest(router, http.MethodGet, "/plugins/P/ping")
+	if w.Code != http.StatusServiceUnavailable || decodeAppError(t, w).Code != pkg.ErrPluginDisabled {
+		t.Fatalf("expected 503 PLUGIN_DISABLED, got %d %s", w.Code, w.Body.String())
+	}
+
+	if err := pm.EnablePlugin("P"); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	if w := serveRequest(router, http.MethodGet, "/plugins/P/ping"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after re-enable, got %d", w.Code)
+	}
+
+	if err := pm.Unregister("P"); err != nil {
+		t.Fatalf("unregister error: %v", err)
+	}
+	w = serveRequest(router, http.MethodGet, "/plugins/P/ping")
+	if w.Code != http.StatusNotFound || decodeAppError(t, w).Code != pkg.ErrPluginNotFound {
+		t.Fatalf("expected 404 PLUGIN_NOT_FOUND, got %d %s", w.Code, w.Body.String())
+	}
+
+	// 注销后可以重新注册同名插件而不会触发Gin路由冲突
+	if err := pm.Register(newTestPlugin("P", true)); err != nil {
+		t.Fatalf("re-register error: %v", err)
+	}
+	if w := serveRequest(router, http.MethodGet, "/plugins/P/ping"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after re-register, got %d", w.Code)
+	}
+}
+
+func TestDispatcherReloadSwapsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+
+	tp := newTestPlugin("R", true)
+	if err := pm.Register(tp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	tp.routes = []Route{
+		{Path: "/ping", Method: "GET", Handler: func(c *gin.Context) { c.String(200, "pong-v2") }},
+		{Path: "/new", Method: "POST", Handler: func(c *gin.Context) { c.String(201, "created") }},
+	}
+	if err := pm.ReloadPlugin("R"); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+
+	if w := serveRequest(router, http.MethodGet, "/plugins/R/ping"); w.Body.String() != "pong-v2" {
+		t.Fatalf("expected reloaded handler, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveRequest(router, http.MethodPost, "/plugins/R/new"); w.Code != http.StatusCreated {
+		t.Fatalf("expected new route after reload, got %d", w.Code)
+	}
+	if info, _ := pm.GetPluginInfo("R"); len(info.Routes) != 2 {
+		t.Fatalf("expected route metadata updated after reload, got %d routes", len(info.Routes))
+	}
+}
+
+func TestDispatcherInheritsOuterContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	var outerErrors int
+	router.Use(func(c *gin.Context) {
+		c.Set("X-Request-ID", "req-42")
+		c.Next()
+		outerErrors = len(c.Errors)
+	})
+	pm.SetRouter(router)
+
+	tp := newTestPlugin("C", false)
+	tp.routes = []Route{
+		{
+			Path:   "/id",
+			Method: "GET",
+			Handler: func(c *gin.Context) {
+				_ = c.Error(pkg.NewBadRequest("bad", nil))
+				c.String(200, c.GetString("X-Request-ID"))
+			},
+		},
+	}
+	if err := pm.Register(tp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	w := serveRequest(router, http.MethodGet, "/plugins/C/id")
+	if w.Body.String() != "req-42" {
+		t.Fatalf("expected request id inherited from outer context, got %q", w.Body.String())
+	}
+	if outerErrors != 1 {
+		t.Fatalf("expected plugin errors propagated to outer context, got %d", outerErrors)
+	}
+}
+
+func TestRegisterRouteFailureKeepsExistingTable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	router := gin.New()
+	pm.SetRouter(router)
+
+	tp := newTestPlugin("K", true)
+	if err := pm.Register(tp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	tp.routes = []Route{{Path: "/bad", Method: "INVALID", Handler: func(c *gin.Context) {}}}
+	if err := pm.ReloadPlugin("K"); err == nil {
+		t.Fatalf("expected reload to fail with invalid route")
+	}
+	if w := serveRequest(router, http.MethodGet, "/plugins/K/ping"); w.Code != http.StatusOK {
+		t.Fatalf("expected previous route table still served, got %d", w.Code)
+	}
+}
@@ -0,0 +1,357 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// DefaultEventQueueSize 每个订阅者的默认事件队列长度
+const DefaultEventQueueSize = 100
+
+// 核心事件主题
+const (
+	TopicPluginRegistered    = "plugin.registered"     // 插件注册完成
+	TopicPluginUnregistered  = "plugin.unregistered"   // 插件已注销
+	TopicPluginEnabled       = "plugin.enabled"        // 插件已启用
+	TopicPluginDisabled      = "plugin.disabled"       // 插件已禁用
+	TopicPluginReloaded      = "plugin.reloaded"       // 插件已重载
+	TopicPluginConfigChanged = "plugin.config_changed" // 插件配置已更新
+	TopicPluginPromoted      = "plugin.promoted"       // 插件灰度版本已提升为稳定版本
+	TopicPluginRolledBack    = "plugin.rolled_back"    // 插件灰度版本已回滚
+	TopicUserRegistered      = "user.registered"       // 新用户注册
+	TopicTeamMemberAdded     = "team.member_added"     // 团队新增成员
+)
+
+// EventSourceCore 核心系统发布事件时使用的来源标识
+const EventSourceCore = "core"
+
+// Event 事件结构
+type Event struct {
+	Topic     string      // 事件主题，以"."分隔层级，如"plugin.enabled"
+	Payload   interface{} // 事件数据
+	Source    string      // 事件来源（插件名称或"core"）
+	Timestamp time.Time   // 事件发布时间
+}
+
+// PluginLifecycleEvent 插件生命周期事件数据
+type PluginLifecycleEvent struct {
+	Name    string `json:"name"`    // 插件名称
+	Version string `json:"version"` // 插件版本
+}
+
+// PluginConfigChangedEvent 插件配置更新事件数据（不包含配置内容，避免泄露敏感信息）
+type PluginConfigChangedEvent struct {
+	Name      string `json:"name"`       // 插件名称
+	UpdatedBy uint   `json:"updated_by"` // 修改配置的用户ID
+}
+
+// UserRegisteredEvent 用户注册事件数据
+// 事件会投递给所有订阅的插件，因此只包含用户ID和租户ID，不包含用户名、邮箱等个人信息
+type UserRegisteredEvent struct {
+	UserID   uint `json:"user_id"`
+	TenantID uint `json:"tenant_id"`
+}
+
+// TeamMemberAddedEvent 团队新增成员事件数据
+type TeamMemberAddedEvent struct {
+	TeamID   uint   `json:"team_id"`
+	UserID   uint   `json:"user_id"`
+	Role     string `json:"role"`
+	TenantID uint   `json:"tenant_id"`
+	AddedBy  uint   `json:"added_by"`
+}
+
+// EventHandler 事件处理函数
+type EventHandler func(event Event)
+
+// subscription 订阅者，每个订阅者拥有独立的有界队列和投递协程
+type subscription struct {
+	id      uint64
+	owner   string // 所属插件，受EventBus.mu保护，可能被reassignOwner修改
+	plugin  string // 订阅时的插件名称，投递协程记录指标和日志时使用
+	pattern []string
+	handler EventHandler
+	queue   chan Event
+	done    chan struct{}
+}
+
+// Subscription 订阅句柄，用于取消订阅
+type Subscription struct {
+	id  uint64
+	bus *EventBus
+}
+
+// Unsubscribe 取消订阅
+func (s *Subscription) Unsubscribe() {
+	if s == nil || s.bus == nil {
+		return
+	}
+	s.bus.unsubscribe(s.id)
+}
+
+// EventBus 进程内事件总线
+// 发布操作不会阻塞：订阅者队列已满时丢弃该事件并记录指标
+type EventBus struct {
+	mu        sync.RWMutex
+	subs      map[uint64]*subscription
+	nextID    uint64
+	queueSize int
+}
+
+// NewEventBus 创建事件总线，queueSize小于等于0时使用默认队列长度
+func NewEventBus(queueSize int) *EventBus {
+	if queueSize <= 0 {
+		queueSize = DefaultEventQueueSize
+	}
+	return &EventBus{
+		subs:      make(map[uint64]*subscription),
+		queueSize: queueSize,
+	}
+}
+
+// Subscribe 订阅主题
+// pattern支持通配符："*"匹配单个层级，"#"匹配零个或多个层级，例如"plugin.*"、"user.#"、"#"。
+// owner为订阅者所属插件名称，插件被禁用或注销时其全部订阅会被自动移除
+func (b *EventBus) Subscribe(owner, pattern string, handler EventHandler) (*Subscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("事件处理函数不能为空")
+	}
+	segments, err := parseTopicPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	sub := &subscription{
+		id:      b.nextID,
+		owner:   owner,
+		plugin:  owner,
+		pattern: segments,
+		handler: handler,
+		queue:   make(chan Event, b.queueSize),
+		done:    make(chan struct{}),
+	}
+	b.subs[sub.id] = sub
+	b.mu.Unlock()
+
+	go sub.run()
+
+	return &Subscription{id: sub.id, bus: b}, nil
+}
+
+// Publish 发布事件，异步投递给所有匹配的订阅者
+func (b *EventBus) Publish(source, topic string, payload interface{}) {
+	event := Event{
+		Topic:     topic,
+		Payload:   payload,
+		Source:    source,
+		Timestamp: time.Now(),
+	}
+	topicSegments := strings.Split(topic, ".")
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		if !matchTopic(sub.pattern, topicSegments) {
+			continue
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			metrics.RecordPluginError(sub.owner, "event_queue_full")
+			pkg.Warn("事件队列已满，丢弃事件",
+				zap.String("owner", sub.owner),
+				zap.String("topic", topic))
+		}
+	}
+}
+
+// UnsubscribeOwner 移除指定插件的全部订阅
+func (b *EventBus) UnsubscribeOwner(owner string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, sub := range b.subs {
+		if sub.owner == owner {
+			close(sub.done)
+			delete(b.subs, id)
+		}
+	}
+}
+
+// SubscriptionCount 返回指定插件的订阅数量，owner为空时返回全部订阅数量
+func (b *EventBus) SubscriptionCount(owner string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if owner == "" {
+		return len(b.subs)
+	}
+	count := 0
+	for _, sub := range b.subs {
+		if sub.owner == owner {
+			count++
+		}
+	}
+	return count
+}
+
+// subscriptionIDs 返回指定插件当前全部订阅的ID
+func (b *EventBus) subscriptionIDs(owner string) map[uint64]bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ids := make(map[uint64]bool)
+	for id, sub := range b.subs {
+		if sub.owner == owner {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// reassignOwner 将指定插件的订阅（exclude中的除外）转移给新的所有者
+// 用于区分同名插件不同版本的订阅，例如灰度版本在Init中以插件名称订阅的事件
+func (b *EventBus) reassignOwner(from, to string, exclude map[uint64]bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, sub := range b.subs {
+		if sub.owner == from && !exclude[id] {
+			sub.owner = to
+		}
+	}
+}
+
+// unsubscribe 取消单个订阅
+func (b *EventBus) unsubscribe(id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sub, exists := b.subs[id]; exists {
+		close(sub.done)
+		delete(b.subs, id)
+	}
+}
+
+// run 投递协程，订阅取消后队列中尚未处理的事件会被丢弃
+func (s *subscription) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.queue:
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.deliver(event)
+		}
+	}
+}
+
+// deliver 调用事件处理函数，处理函数中的panic不会影响其他订阅者
+func (s *subscription) deliver(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics.RecordPluginError(s.plugin, "event_handler_panic")
+			pkg.Error("事件处理函数发生panic",
+				zap.String("owner", s.plugin),
+				zap.String("topic", event.Topic),
+				zap.Any("panic", r))
+		}
+	}()
+	s.handler(event)
+}
+
+// parseTopicPattern 解析订阅主题模式
+func parseTopicPattern(pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("订阅主题不能为空")
+	}
+	segments := strings.Split(pattern, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("无效的订阅主题: '%s'", pattern)
+		}
+		if segment != "*" && segment != "#" && strings.ContainsAny(segment, "*#") {
+			return nil, fmt.Errorf("无效的订阅主题: '%s'，通配符必须独占一个层级", pattern)
+		}
+	}
+	return segments, nil
+}
+
+// matchTopic 判断主题是否匹配订阅模式
+func matchTopic(pattern, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		// "#"可匹配零个或多个层级
+		for i := 0; i <= len(topic); i++ {
+			if matchTopic(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(topic) > 0 && matchTopic(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && matchTopic(pattern[1:], topic[1:])
+	}
+}
+
+// SubscribeTyped 订阅事件并将数据转换为指定类型
+// 事件数据类型不匹配时（包括指针与值类型不一致）该事件会被忽略
+func SubscribeTyped[T any](bus *EventBus, owner, pattern string, handler func(event Event, payload T)) (*Subscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("事件处理函数不能为空")
+	}
+	return bus.Subscribe(owner, pattern, func(event Event) {
+		if payload, ok := event.Payload.(T); ok {
+			handler(event, payload)
+		}
+	})
+}
+
+// Events 获取插件管理器的事件总线
+func (pm *PluginManager) Events() *EventBus {
+	pm.eventBusOnce.Do(func() {
+		if pm.eventBus == nil {
+			pm.eventBus = NewEventBus(DefaultEventQueueSize)
+		}
+	})
+	return pm.eventBus
+}
+
+// Publish 以核心系统身份发布事件
+func (pm *PluginManager) Publish(topic string, payload interface{}) {
+	pm.Events().Publish(EventSourceCore, topic, payload)
+}
+
+// PublishFrom 以指定插件身份发布事件
+func (pm *PluginManager) PublishFrom(source, topic string, payload interface{}) {
+	pm.Events().Publish(source, topic, payload)
+}
+
+// Subscribe 为插件订阅事件，插件被禁用、重载或注销时订阅会被自动移除
+// 因此插件应在Init和OnEnable中（重新）订阅所需事件
+func (pm *PluginManager) Subscribe(owner, pattern string, handler EventHandler) (*Subscription, error) {
+	return pm.Events().Subscribe(owner, pattern, handler)
+}
+
+// publishLifecycle 发布插件生命周期事件
+func (pm *PluginManager) publishLifecycle(topic string, plugin Plugin) {
+	pm.Publish(topic, PluginLifecycleEvent{Name: plugin.Name(), Version: plugin.Version()})
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestMatchTopicWildcards(t *testing.T) {
+	cases := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"plugin.enabled", "plugin.enabled", true},
+		{"plugin.enabled", "plugin.disabled", false},
+		{"plugin.*", "plugin.enabled", true},
+		{"plugin.*", "plugin", false},
+		{"plugin.*", "plugin.a.b", false},
+		{"*.enabled", "plugin.enabled", true},
+		{"plugin.#", "plugin", true},
+		{"plugin.#", "plugin.a.b", true},
+		{"#", "user.registered", true},
+		{"#.added", "team.member.added", true},
+		{"user.#", "team.member_added", false},
+	}
+	for _, tc := range cases {
+		pattern, err := parseTopicPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.pattern, err)
+		}
+		if got := matchTopic(pattern, strings.Split(tc.topic, ".")); got != tc.want {
+			t.Fatalf("match(%q, %q) = %v, want %v", tc.pattern, tc.topic, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"", "a..b", "plugin.en*"} {
+		if _, err := parseTopicPattern(bad); err == nil {
+			t.Fatalf("expected error for pattern %q", bad)
+		}
+	}
+}
+
+func TestEventBusPublishSubscribe(t *testing.T) {
+	bus := NewEventBus(0)
+	received := make(chan Event, 4)
+	if _, err := bus.Subscribe("p", "orders.*", func(e Event) { received <- e }); err != nil {
+		t.Fatalf("subscribe error: %v", err)
+	}
+
+	bus.Publish("shop", "orders.created", 42)
+	bus.Publish("shop", "users.created", 1)
+
+	e := waitEvent(t, received)
+	if e.Topic != "orders.created" || e.Source != "shop" || e.Payload != 42 || e.Timestamp.IsZero() {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	select {
+	case e := <-received:
+		t.Fatalf("unexpected event for non-matching topic: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEventBusBoundedQueueDropsWhenFull(t *testing.T) {
+	bus := NewEventBus(1)
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var handled int
+	if _, err := bus.Subscribe("slow", "#", func(e Event) {
+		<-block
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	}); err != nil {
+		t.Fatalf("subscribe error: %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		bus.Publish("core", "tick", i)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("publish should not block on slow subscriber")
+	}
+	close(block)
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	// 一个事件正在处理，一个事件在队列中，其余被丢弃
+	if handled == 0 || handled > 2 {
+		t.Fatalf("expected bounded delivery (1-2 events), got %d", handled)
+	}
+}
+
+func TestEventBusHandlerPanicDoesNotStopDelivery(t *testing.T) {
+	bus := NewEventBus(0)
+	received := make(chan Event, 2)
+	if _, err := bus.Subscribe("p", "#", func(e Event) {
+		if e.Payload == "boom" {
+			panic("boom")
+		}
+		received <- e
+	}); err != nil {
+		t.Fatalf("subscribe error: %v", err)
+	}
+	bus.Publish("core", "a", "boom")
+	bus.Publish("core", "a", "ok")
+	if e := waitEvent(t, received); e.Payload != "ok" {
+		t.Fatalf("expected delivery to continue after panic, got %+v", e)
+	}
+}
+
+func TestSubscribeTyped(t *testing.T) {
+	bus := NewEventBus(0)
+	received := make(chan UserRegisteredEvent, 2)
+	if _, err := SubscribeTyped(bus, "p", TopicUserRegistered, func(e Event, payload UserRegisteredEvent) {
+		received <- payload
+	}); err != nil {
+		t.Fatalf("subscribe error: %v", err)
+	}
+
+	bus.Publish(EventSourceCore, TopicUserRegistered, "not a user")
+	bus.Publish(EventSourceCore, TopicUserRegistered, UserRegisteredEvent{UserID: 3, TenantID: 7})
+
+	select {
+	case payload := <-received:
+		if payload.UserID != 3 || payload.TenantID != 7 {
+			t.Fatalf("unexpected payload: %+v", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for typed event")
+	}
+}
+
+func TestSubscriptionsRemovedOnDisableAndUnregister(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.Register(newTestPlugin("P", false)); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	sub, err := pm.Subscribe("P", "user.*", func(Event) {})
+	if err != nil {
+		t.Fatalf("subscribe error: %v", err)
+	}
+	if pm.Events().SubscriptionCount("P") != 1 {
+		t.Fatalf("expected 1 subscription")
+	}
+	sub.Unsubscribe()
+	if pm.Events().SubscriptionCount("P") != 0 {
+		t.Fatalf("expected subscription removed by Unsubscribe")
+	}
+
+	_, _ = pm.Subscribe("P", "user.*", func(Event) {})
+	if err := pm.DisablePlugin("P"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	if pm.Events().SubscriptionCount("P") != 0 {
+		t.Fatalf("expected subscriptions removed on disable")
+	}
+
+	if err := pm.EnablePlugin("P"); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	_, _ = pm.Subscribe("P", "user.*", func(Event) {})
+	if err := pm.Unregister("P"); err != nil {
+		t.Fatalf("unregister error: %v", err)
+	}
+	if pm.Events().SubscriptionCount("P") != 0 {
+		t.Fatalf("expected subscriptions removed on unregister")
+	}
+}
+
+func TestLifecycleEventsPublished(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	received := make(chan Event, 10)
+	if _, err := pm.Subscribe("observer", "plugin.*", func(e Event) { received <- e }); err != nil {
+		t.Fatalf("subscribe error: %v", err)
+	}
+
+	if err := pm.Register(newTestPlugin("L", false)); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	_ = pm.DisablePlugin("L")
+	_ = pm.EnablePlugin("L")
+	_ = pm.ReloadPlugin("L")
+	_ = pm.Unregister("L")
+
+	want := []string{TopicPluginRegistered, TopicPluginDisabled, TopicPluginEnabled, TopicPluginReloaded, TopicPluginUnregistered}
+	for _, topic := range want {
+		e := waitEvent(t, received)
+		payload, ok := e.Payload.(PluginLifecycleEvent)
+		if e.Topic != topic || e.Source != EventSourceCore || !ok || payload.Name != "L" {
+			t.Fatalf("expected %s for L, got %+v", topic, e)
+		}
+	}
+}
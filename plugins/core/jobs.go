@@ -0,0 +1,590 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// JobStatus 插件异步任务状态
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"    // 排队中，包括等待重试的任务
+	JobRunning   JobStatus = "running"   // 执行中
+	JobSucceeded JobStatus = "succeeded" // 执行成功
+	JobFailed    JobStatus = "failed"    // 重试次数用尽后仍失败
+	JobCanceled  JobStatus = "canceled"  // 已取消
+)
+
+// Terminal 判断任务是否已结束
+func (s JobStatus) Terminal() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// ErrJobNotFound 任务不存在或已过期被清理
+var ErrJobNotFound = errors.New("任务不存在")
+
+// 任务执行上下文的取消原因
+var (
+	errJobCanceled     = errors.New("任务已取消")
+	errJobQueueStopped = errors.New("任务队列已停止")
+)
+
+// maxRetryBackoff 重试等待时间的上限
+const maxRetryBackoff = 10 * time.Minute
+
+// Job 插件异步任务
+type Job struct {
+	ID              uint                   `json:"id"`
+	Plugin          string                 `json:"plugin"`
+	Params          map[string]interface{} `json:"params"`
+	Status          JobStatus              `json:"status"`
+	Progress        int                    `json:"progress"`
+	Message         string                 `json:"message,omitempty"` // 插件上报的进度说明
+	Result          json.RawMessage        `json:"result,omitempty"`  // Execute返回值的JSON
+	Error           string                 `json:"error,omitempty"`   // 最近一次失败的错误信息
+	Attempts        int                    `json:"attempts"`
+	MaxAttempts     int                    `json:"max_attempts"`
+	CancelRequested bool                   `json:"cancel_requested"`
+	UserID          uint                   `json:"user_id"`
+	TenantID        uint                   `json:"tenant_id"`
+	RequestID       string                 `json:"request_id,omitempty"`
+	NextRunAt       time.Time              `json:"next_run_at"`
+	StartedAt       *time.Time             `json:"started_at,omitempty"`
+	FinishedAt      *time.Time             `json:"finished_at,omitempty"`
+	ExpiresAt       *time.Time             `json:"expires_at,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+// JobFilter 任务查询条件，零值字段不参与过滤
+type JobFilter struct {
+	Plugin   string
+	TenantID uint
+	Status   JobStatus
+	Limit    int
+}
+
+// JobStore 插件异步任务的持久化存储
+// 多个实例共享同一个存储时，ClaimJob必须保证同一任务只被一个实例领取
+type JobStore interface {
+	// CreateJob 保存新任务并填充ID
+	CreateJob(ctx context.Context, job *Job) error
+	// GetJob 读取任务，不存在时返回ErrJobNotFound
+	GetJob(ctx context.Context, id uint) (*Job, error)
+	// ListJobs 按创建时间倒序列出任务
+	ListJobs(ctx context.Context, filter JobFilter) ([]*Job, error)
+	// ClaimJob 领取一个到期的排队任务，将其标记为执行中并增加执行次数；没有任务时返回nil
+	ClaimJob(ctx context.Context, now time.Time) (*Job, error)
+	// UpdateJobProgress 更新执行中任务的进度，返回任务是否已被请求取消
+	UpdateJobProgress(ctx context.Context, id uint, progress int, message string) (bool, error)
+	// FinishJob 保存一次执行的结果（结束或重新排队），仅当任务仍处于执行中时保存，返回是否保存
+	FinishJob(ctx context.Context, job *Job) (bool, error)
+	// CancelJob 取消任务：排队中的任务直接标记为已取消（结果保留到expiresAt），执行中的任务标记为已请求取消
+	CancelJob(ctx context.Context, id uint, now, expiresAt time.Time) (*Job, error)
+	// RequeueStaleJobs 将startedBefore之前开始、仍处于执行中的任务重新排队（实例异常退出后遗留的任务）
+	RequeueStaleJobs(ctx context.Context, startedBefore time.Time) (int64, error)
+	// DeleteExpiredJobs 删除保留期已过的任务
+	DeleteExpiredJobs(ctx context.Context, now time.Time) (int64, error)
+}
+
+// JobQueueConfig 任务队列配置
+type JobQueueConfig struct {
+	Workers      int           // 工作协程数
+	PollInterval time.Duration // 没有新任务时轮询存储的间隔
+	MaxAttempts  int           // 任务默认最大执行次数（含首次执行）
+	RetryBackoff time.Duration // 首次重试的等待时间，之后每次翻倍，最长10分钟
+	Timeout      time.Duration // 单次执行的超时时间，覆盖插件的执行超时
+	Retention    time.Duration // 已结束任务的保留时间
+}
+
+// DefaultJobQueueConfig 默认任务队列配置
+func DefaultJobQueueConfig() JobQueueConfig {
+	return JobQueueConfig{
+		Workers:      4,
+		PollInterval: 2 * time.Second,
+		MaxAttempts:  3,
+		RetryBackoff: 5 * time.Second,
+		Timeout:      30 * time.Minute,
+		Retention:    24 * time.Hour,
+	}
+}
+
+// JobOptions 提交任务时的可选项
+type JobOptions struct {
+	MaxAttempts int // 最大执行次数，0表示使用队列默认值
+}
+
+// ProgressFunc 插件上报任务进度的回调，progress取值0-100
+type ProgressFunc func(progress int, message string)
+
+// progressKey 上下文中存放进度回调的键
+type progressKey struct{}
+
+// executeTimeoutKey 上下文中存放执行超时覆盖值的键，仅任务队列使用
+type executeTimeoutKey struct{}
+
+// WithProgress 将进度回调写入上下文
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress 上报当前任务的进度，上下文中没有进度回调（如同步调用）时不做任何处理
+// 实现ContextExecutor的插件可以在ExecuteContext中调用
+func ReportProgress(ctx context.Context, progress int, message string) {
+	if ctx == nil {
+		return
+	}
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(progress, message)
+	}
+}
+
+// JobQueue 插件异步任务队列
+// 任务保存在JobStore中，由工作协程领取后通过ExecutePluginContext执行，失败后按指数退避重试
+type JobQueue struct {
+	pm    *PluginManager
+	store JobStore
+	cfg   JobQueueConfig
+
+	wake chan struct{} // 提交任务时唤醒空闲的工作协程
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[uint]context.CancelCauseFunc // 本实例执行中的任务
+	started bool
+	stopped bool
+}
+
+// NewJobQueue 创建任务队列，cfg中未设置的项使用默认值
+func NewJobQueue(pm *PluginManager, store JobStore, cfg JobQueueConfig) *JobQueue {
+	defaults := DefaultJobQueueConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaults.Workers
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.RetryBackoff < 0 {
+		cfg.RetryBackoff = defaults.RetryBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaults.Retention
+	}
+	return &JobQueue{
+		pm:      pm,
+		store:   store,
+		cfg:     cfg,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		running: make(map[uint]context.CancelCauseFunc),
+	}
+}
+
+// SetJobQueue 设置插件管理器使用的任务队列
+func (pm *PluginManager) SetJobQueue(queue *JobQueue) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.jobs = queue
+}
+
+// Jobs 返回插件管理器的任务队列，未设置时返回nil
+func (pm *PluginManager) Jobs() *JobQueue {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.jobs
+}
+
+// Start 启动工作协程和过期任务清理，重复调用无效
+// 启动时将超过执行超时仍处于执行中的任务重新排队
+func (q *JobQueue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started || q.stopped {
+		return
+	}
+	q.started = true
+
+	q.requeueStale()
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.wg.Add(1)
+	go q.janitor()
+
+	pkg.Info("插件任务队列已启动", zap.Int("workers", q.cfg.Workers))
+}
+
+// Stop 停止领取新任务并等待执行中的任务结束
+// ctx结束时取消仍在执行的任务，这些任务会重新排队，不计入执行次数
+func (q *JobQueue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.started || q.stopped {
+		q.stopped = true
+		q.mu.Unlock()
+		return nil
+	}
+	q.stopped = true
+	close(q.stop)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		for _, cancel := range q.running {
+			cancel(errJobQueueStopped)
+		}
+		q.mu.Unlock()
+		return fmt.Errorf("等待插件任务结束超时: %w", ctx.Err())
+	}
+}
+
+// Enqueue 提交插件的异步执行任务，提交者信息从上下文的RequestMeta中读取
+func (q *JobQueue) Enqueue(ctx context.Context, name string, params map[string]interface{}, opts JobOptions) (*Job, error) {
+	info, exists := q.pm.GetPluginInfo(name)
+	if !exists {
+		return nil, pkg.NewPluginNotFoundError(fmt.Sprintf("插件 '%s' 不存在", name), nil)
+	}
+	if !info.IsEnabled {
+		return nil, pkg.NewPluginDisabledError(fmt.Sprintf("插件 '%s' 已被禁用", name), nil)
+	}
+	if opts.MaxAttempts < 0 {
+		return nil, pkg.NewBadRequestError("最大执行次数不能为负数", nil)
+	}
+
+	q.mu.Lock()
+	stopped := q.stopped
+	q.mu.Unlock()
+	if stopped {
+		return nil, pkg.NewServiceUnavailableError("任务队列已停止，暂不接受新任务", nil)
+	}
+
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	if _, err := json.Marshal(params); err != nil {
+		return nil, pkg.NewBadRequestError(fmt.Sprintf("任务参数无法序列化: %v", err), err)
+	}
+
+	meta, _ := RequestMetaFromContext(ctx)
+	now := time.Now()
+	job := &Job{
+		Plugin:      name,
+		Params:      params,
+		Status:      JobQueued,
+		MaxAttempts: opts.MaxAttempts,
+		UserID:      meta.UserID,
+		TenantID:    meta.TenantID,
+		RequestID:   meta.RequestID,
+		NextRunAt:   now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = q.cfg.MaxAttempts
+	}
+	if err := q.store.CreateJob(storageContext(ctx), job); err != nil {
+		return nil, pkg.NewDatabaseError("保存插件任务失败", err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	pkg.Info("插件任务已提交", zap.String("plugin", name), zap.Uint("job_id", job.ID), zap.Uint("tenant_id", job.TenantID))
+	return job, nil
+}
+
+// Get 获取插件的任务，只能获取上下文中租户提交的任务
+func (q *JobQueue) Get(ctx context.Context, name string, id uint) (*Job, error) {
+	job, err := q.store.GetJob(storageContext(ctx), id)
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, pkg.NewNotFound(fmt.Sprintf("插件 '%s' 的任务 %d 不存在", name, id), nil)
+	}
+	if err != nil {
+		return nil, pkg.NewDatabaseError("读取插件任务失败", err)
+	}
+	meta, _ := RequestMetaFromContext(ctx)
+	if job.Plugin != name || job.TenantID != meta.TenantID {
+		return nil, pkg.NewNotFound(fmt.Sprintf("插件 '%s' 的任务 %d 不存在", name, id), nil)
+	}
+	return job, nil
+}
+
+// List 列出插件在上下文中租户下的任务，status为空时不按状态过滤
+func (q *JobQueue) List(ctx context.Context, name string, status JobStatus, limit int) ([]*Job, error) {
+	meta, _ := RequestMetaFromContext(ctx)
+	jobs, err := q.store.ListJobs(storageContext(ctx), JobFilter{Plugin: name, TenantID: meta.TenantID, Status: status, Limit: limit})
+	if err != nil {
+		return nil, pkg.NewDatabaseError("查询插件任务失败", err)
+	}
+	return jobs, nil
+}
+
+// Cancel 取消任务
+// 排队中的任务立即取消；执行中的任务取消其执行上下文，在其他实例上执行的任务在下次上报进度时取消
+func (q *JobQueue) Cancel(ctx context.Context, name string, id uint) (*Job, error) {
+	job, err := q.Get(ctx, name, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.Terminal() {
+		return nil, pkg.NewBadRequestError(fmt.Sprintf("任务 %d 已结束，当前状态为 %s", id, job.Status), nil)
+	}
+
+	now := time.Now()
+	job, err = q.store.CancelJob(storageContext(ctx), id, now, now.Add(q.cfg.Retention))
+	if err != nil {
+		return nil, pkg.NewDatabaseError("取消插件任务失败", err)
+	}
+
+	q.mu.Lock()
+	if cancel, ok := q.running[id]; ok {
+		cancel(errJobCanceled)
+	}
+	q.mu.Unlock()
+
+	pkg.Info("插件任务已请求取消", zap.String("plugin", name), zap.Uint("job_id", id), zap.String("status", string(job.Status)))
+	return job, nil
+}
+
+// worker 循环领取并执行任务
+func (q *JobQueue) worker() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.store.ClaimJob(context.Background(), time.Now())
+		if err != nil {
+			pkg.Warn("领取插件任务失败", zap.Error(err))
+		}
+		if job != nil {
+			q.run(job)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run 执行一次任务并保存结果
+func (q *JobQueue) run(job *Job) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	q.mu.Lock()
+	q.running[job.ID] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+	}()
+
+	ctx = WithRequestMeta(ctx, RequestMeta{UserID: job.UserID, TenantID: job.TenantID, RequestID: job.RequestID})
+	ctx = context.WithValue(ctx, executeTimeoutKey{}, q.cfg.Timeout)
+	ctx = WithProgress(ctx, func(progress int, message string) {
+		q.reportProgress(job, progress, message, cancel)
+	})
+
+	startTime := time.Now()
+	result, err := q.pm.ExecutePluginContext(ctx, job.Plugin, job.Params)
+	q.finish(job, result, err, context.Cause(ctx), time.Since(startTime))
+}
+
+// reportProgress 保存插件上报的进度，任务在其他实例上被请求取消时取消执行上下文
+func (q *JobQueue) reportProgress(job *Job, progress int, message string, cancel context.CancelCauseFunc) {
+	if progress < 0 {
+		progress = 0
+	} else if progress > 100 {
+		progress = 100
+	}
+	if len(message) > 255 {
+		message = message[:255]
+	}
+
+	// 插件可能在其他协程中上报进度，结束任务时读取最后一次上报的进度
+	q.mu.Lock()
+	job.Progress, job.Message = progress, message
+	q.mu.Unlock()
+
+	cancelRequested, err := q.store.UpdateJobProgress(context.Background(), job.ID, progress, message)
+	if err != nil {
+		pkg.Warn("保存插件任务进度失败", zap.String("plugin", job.Plugin), zap.Uint("job_id", job.ID), zap.Error(err))
+		return
+	}
+	if cancelRequested {
+		cancel(errJobCanceled)
+	}
+}
+
+// finish 根据执行结果结束任务、安排重试或重新排队
+func (q *JobQueue) finish(job *Job, result interface{}, err error, cause error, duration time.Duration) {
+	now := time.Now()
+	q.mu.Lock()
+	job.UpdatedAt = now
+	q.mu.Unlock()
+
+	if err == nil {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			err = fmt.Errorf("任务结果无法序列化: %w", marshalErr)
+		} else {
+			job.Result = data
+		}
+	}
+
+	interrupted := errors.Is(cause, errJobQueueStopped)
+	switch {
+	case interrupted:
+		// 实例关闭导致的中断不计入执行次数
+		job.Status = JobQueued
+		job.Attempts--
+		job.NextRunAt = now
+		job.Progress, job.Message = 0, ""
+	case errors.Is(cause, errJobCanceled):
+		job.Status = JobCanceled
+		job.Error = errJobCanceled.Error()
+	case err == nil:
+		job.Status = JobSucceeded
+		job.Progress = 100
+		job.Error = ""
+	case job.Attempts < job.MaxAttempts && !q.cancelRequested(job.ID):
+		job.Status = JobQueued
+		job.Error = err.Error()
+		job.NextRunAt = now.Add(q.retryBackoff(job.Attempts))
+		job.Progress, job.Message = 0, ""
+	default:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		if q.cancelRequested(job.ID) {
+			job.Status = JobCanceled
+		}
+	}
+	if job.Status != JobSucceeded {
+		job.Result = nil
+	}
+	if job.Status.Terminal() {
+		expiresAt := now.Add(q.cfg.Retention)
+		job.FinishedAt = &now
+		job.ExpiresAt = &expiresAt
+	}
+
+	saved, saveErr := q.store.FinishJob(context.Background(), job)
+	if saveErr != nil {
+		pkg.Error("保存插件任务结果失败", zap.String("plugin", job.Plugin), zap.Uint("job_id", job.ID), zap.Error(saveErr))
+		return
+	}
+	if !saved {
+		pkg.Warn("插件任务已不在执行中，丢弃本次执行结果", zap.String("plugin", job.Plugin), zap.Uint("job_id", job.ID))
+		return
+	}
+
+	metrics.RecordPluginJob(job.Plugin, string(job.Status), duration)
+	fields := []zap.Field{
+		zap.String("plugin", job.Plugin),
+		zap.Uint("job_id", job.ID),
+		zap.String("status", string(job.Status)),
+		zap.Int("attempts", job.Attempts),
+		zap.Duration("duration", duration),
+	}
+	if interrupted {
+		pkg.Info("插件任务因任务队列停止而中断，已重新排队", fields...)
+		return
+	}
+	if job.Status == JobFailed || job.Status == JobQueued {
+		pkg.Warn("插件任务执行失败", append(fields, zap.String("error", job.Error))...)
+		return
+	}
+	pkg.Info("插件任务执行结束", fields...)
+}
+
+// cancelRequested 判断任务是否已被请求取消（可能由其他实例发起）
+func (q *JobQueue) cancelRequested(id uint) bool {
+	job, err := q.store.GetJob(context.Background(), id)
+	return err == nil && job.CancelRequested
+}
+
+// retryBackoff 第attempt次执行失败后的重试等待时间
+func (q *JobQueue) retryBackoff(attempt int) time.Duration {
+	backoff := q.cfg.RetryBackoff
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// janitor 定期清理保留期已过的任务，并重新排队异常中断的任务
+func (q *JobQueue) janitor() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			q.requeueStale()
+			if deleted, err := q.store.DeleteExpiredJobs(context.Background(), time.Now()); err != nil {
+				pkg.Warn("清理过期插件任务失败", zap.Error(err))
+			} else if deleted > 0 {
+				pkg.Info("已清理过期插件任务", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+// requeueStale 重新排队超过执行超时仍未结束的任务，这些任务所在的实例已异常退出
+func (q *JobQueue) requeueStale() {
+	startedBefore := time.Now().Add(-q.cfg.Timeout - time.Minute)
+	if requeued, err := q.store.RequeueStaleJobs(context.Background(), startedBefore); err != nil {
+		pkg.Warn("重新排队中断的插件任务失败", zap.Error(err))
+	} else if requeued > 0 {
+		pkg.Warn("已重新排队中断的插件任务", zap.Int64("count", requeued))
+	}
+}
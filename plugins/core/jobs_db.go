@@ -0,0 +1,324 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"weave/models"
+	"weave/pkg"
+
+	"gorm.io/gorm"
+)
+
+// claimAttempts 领取任务时与其他实例竞争失败后的重试次数
+const claimAttempts = 3
+
+// DBJobStore 基于数据库的任务存储（plugin_jobs表）
+// DB为空时使用pkg.DB；领取任务通过带状态条件的UPDATE实现，多个实例可以共享同一张表
+type DBJobStore struct {
+	DB *gorm.DB
+}
+
+// NewDBJobStore 创建数据库任务存储
+func NewDBJobStore(db *gorm.DB) *DBJobStore {
+	return &DBJobStore{DB: db}
+}
+
+// db 返回绑定上下文的数据库连接
+func (s *DBJobStore) db(ctx context.Context) (*gorm.DB, error) {
+	db := s.DB
+	if db == nil {
+		db = pkg.DB
+	}
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化，无法使用插件任务队列")
+	}
+	return db.WithContext(storageContext(ctx)), nil
+}
+
+// CreateJob 保存新任务
+func (s *DBJobStore) CreateJob(ctx context.Context, job *Job) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	record, err := jobToRecord(job)
+	if err != nil {
+		return err
+	}
+	if err := db.Create(record).Error; err != nil {
+		return fmt.Errorf("保存插件 '%s' 的任务失败: %w", job.Plugin, err)
+	}
+	job.ID = record.ID
+	return nil
+}
+
+// GetJob 读取任务
+func (s *DBJobStore) GetJob(ctx context.Context, id uint) (*Job, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var record models.PluginJob
+	err = db.First(&record, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取插件任务 %d 失败: %w", id, err)
+	}
+	return recordToJob(&record)
+}
+
+// ListJobs 按创建时间倒序列出任务
+func (s *DBJobStore) ListJobs(ctx context.Context, filter JobFilter) ([]*Job, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.PluginJob{}).Order("id DESC")
+	if filter.Plugin != "" {
+		query = query.Where("plugin_name = ?", filter.Plugin)
+	}
+	if filter.TenantID != 0 {
+		query = query.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", string(filter.Status))
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var records []models.PluginJob
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询插件任务失败: %w", err)
+	}
+	jobs := make([]*Job, 0, len(records))
+	for i := range records {
+		job, err := recordToJob(&records[i])
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ClaimJob 领取最早到期的排队任务
+func (s *DBJobStore) ClaimJob(ctx context.Context, now time.Time) (*Job, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < claimAttempts; i++ {
+		var record models.PluginJob
+		err := db.Where("status = ? AND next_run_at <= ?", string(JobQueued), now).
+			Order("next_run_at, id").
+			First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("查询待执行的插件任务失败: %w", err)
+		}
+
+		// 以状态为条件更新，其他实例已领取时影响行数为0
+		result := db.Model(&models.PluginJob{}).
+			Where("id = ? AND status = ?", record.ID, string(JobQueued)).
+			Updates(map[string]interface{}{
+				"status":     string(JobRunning),
+				"attempts":   gorm.Expr("attempts + 1"),
+				"started_at": now,
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("领取插件任务 %d 失败: %w", record.ID, result.Error)
+		}
+		if result.RowsAffected == 1 {
+			record.Status = string(JobRunning)
+			record.Attempts++
+			record.StartedAt = &now
+			return recordToJob(&record)
+		}
+	}
+	return nil, nil
+}
+
+// UpdateJobProgress 更新执行中任务的进度
+func (s *DBJobStore) UpdateJobProgress(ctx context.Context, id uint, progress int, message string) (bool, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return false, err
+	}
+	err = db.Model(&models.PluginJob{}).
+		Where("id = ? AND status = ?", id, string(JobRunning)).
+		Updates(map[string]interface{}{"progress": progress, "progress_message": message}).Error
+	if err != nil {
+		return false, fmt.Errorf("更新插件任务 %d 的进度失败: %w", id, err)
+	}
+
+	var record models.PluginJob
+	if err := db.Select("cancel_requested").First(&record, id).Error; err != nil {
+		return false, fmt.Errorf("读取插件任务 %d 失败: %w", id, err)
+	}
+	return record.CancelRequested, nil
+}
+
+// FinishJob 保存一次执行的结果
+func (s *DBJobStore) FinishJob(ctx context.Context, job *Job) (bool, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return false, err
+	}
+	result := db.Model(&models.PluginJob{}).
+		Where("id = ? AND status = ?", job.ID, string(JobRunning)).
+		Updates(map[string]interface{}{
+			"status":           string(job.Status),
+			"progress":         job.Progress,
+			"progress_message": job.Message,
+			"result":           string(job.Result),
+			"error":            job.Error,
+			"attempts":         job.Attempts,
+			"cancel_requested": false,
+			"next_run_at":      job.NextRunAt,
+			"finished_at":      job.FinishedAt,
+			"expires_at":       job.ExpiresAt,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("保存插件任务 %d 的结果失败: %w", job.ID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CancelJob 取消任务
+func (s *DBJobStore) CancelJob(ctx context.Context, id uint, now, expiresAt time.Time) (*Job, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PluginJob{}).
+			Where("id = ? AND status = ?", id, string(JobQueued)).
+			Updates(map[string]interface{}{
+				"status":      string(JobCanceled),
+				"error":       errJobCanceled.Error(),
+				"finished_at": now,
+				"expires_at":  expiresAt,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PluginJob{}).
+			Where("id = ? AND status = ?", id, string(JobRunning)).
+			Update("cancel_requested", true).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("取消插件任务 %d 失败: %w", id, err)
+	}
+	return s.GetJob(ctx, id)
+}
+
+// RequeueStaleJobs 重新排队异常中断的任务
+// 已请求取消的任务标记为已取消，执行次数已用尽的任务标记为失败
+func (s *DBJobStore) RequeueStaleJobs(ctx context.Context, startedBefore time.Time) (int64, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	stale := db.Model(&models.PluginJob{}).Where("status = ? AND started_at < ?", string(JobRunning), startedBefore)
+
+	if err := stale.Session(&gorm.Session{}).Where("cancel_requested = ?", true).
+		Updates(map[string]interface{}{"status": string(JobCanceled), "error": errJobCanceled.Error(), "finished_at": now}).Error; err != nil {
+		return 0, fmt.Errorf("取消中断的插件任务失败: %w", err)
+	}
+	if err := stale.Session(&gorm.Session{}).Where("attempts >= max_attempts").
+		Updates(map[string]interface{}{"status": string(JobFailed), "error": "任务执行中断且执行次数已用尽", "finished_at": now}).Error; err != nil {
+		return 0, fmt.Errorf("结束中断的插件任务失败: %w", err)
+	}
+	result := stale.Session(&gorm.Session{}).
+		Updates(map[string]interface{}{"status": string(JobQueued), "next_run_at": now})
+	if result.Error != nil {
+		return 0, fmt.Errorf("重新排队中断的插件任务失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DeleteExpiredJobs 删除保留期已过的任务
+func (s *DBJobStore) DeleteExpiredJobs(ctx context.Context, now time.Time) (int64, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Delete(&models.PluginJob{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理过期插件任务失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// jobToRecord 将任务转换为数据库记录
+func jobToRecord(job *Job) (*models.PluginJob, error) {
+	params, err := json.Marshal(job.Params)
+	if err != nil {
+		return nil, fmt.Errorf("序列化插件 '%s' 的任务参数失败: %w", job.Plugin, err)
+	}
+	return &models.PluginJob{
+		ID:              job.ID,
+		PluginName:      job.Plugin,
+		Params:          string(params),
+		Status:          string(job.Status),
+		Progress:        job.Progress,
+		ProgressMessage: job.Message,
+		Result:          string(job.Result),
+		Error:           job.Error,
+		Attempts:        job.Attempts,
+		MaxAttempts:     job.MaxAttempts,
+		CancelRequested: job.CancelRequested,
+		UserID:          job.UserID,
+		TenantID:        job.TenantID,
+		RequestID:       job.RequestID,
+		NextRunAt:       job.NextRunAt,
+		StartedAt:       job.StartedAt,
+		FinishedAt:      job.FinishedAt,
+		ExpiresAt:       job.ExpiresAt,
+		CreatedAt:       job.CreatedAt,
+		UpdatedAt:       job.UpdatedAt,
+	}, nil
+}
+
+// recordToJob 将数据库记录转换为任务
+func recordToJob(record *models.PluginJob) (*Job, error) {
+	job := &Job{
+		ID:              record.ID,
+		Plugin:          record.PluginName,
+		Status:          JobStatus(record.Status),
+		Progress:        record.Progress,
+		Message:         record.ProgressMessage,
+		Error:           record.Error,
+		Attempts:        record.Attempts,
+		MaxAttempts:     record.MaxAttempts,
+		CancelRequested: record.CancelRequested,
+		UserID:          record.UserID,
+		TenantID:        record.TenantID,
+		RequestID:       record.RequestID,
+		NextRunAt:       record.NextRunAt,
+		StartedAt:       record.StartedAt,
+		FinishedAt:      record.FinishedAt,
+		ExpiresAt:       record.ExpiresAt,
+		CreatedAt:       record.CreatedAt,
+		UpdatedAt:       record.UpdatedAt,
+	}
+	if record.Params != "" {
+		if err := json.Unmarshal([]byte(record.Params), &job.Params); err != nil {
+			return nil, fmt.Errorf("解析插件任务 %d 的参数失败: %w", record.ID, err)
+		}
+	}
+	if record.Result != "" {
+		job.Result = json.RawMessage(record.Result)
+	}
+	return job, nil
+}
@@ -0,0 +1,333 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryJobStore 基于内存的任务存储，用于测试
+type memoryJobStore struct {
+	mu     sync.Mutex
+	nextID uint
+	jobs   map[uint]*Job
+}
+
+func newMemoryJobStore() *memoryJobStore {
+	return &memoryJobStore{jobs: make(map[uint]*Job)}
+}
+
+func (s *memoryJobStore) copyOf(job *Job) *Job {
+	copied := *job
+	return &copied
+}
+
+func (s *memoryJobStore) CreateJob(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	job.ID = s.nextID
+	s.jobs[job.ID] = s.copyOf(job)
+	return nil
+}
+
+func (s *memoryJobStore) GetJob(ctx context.Context, id uint) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	return s.copyOf(job), nil
+}
+
+func (s *memoryJobStore) ListJobs(ctx context.Context, filter JobFilter) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*Job
+	for _, job := range s.jobs {
+		if (filter.Plugin == "" || job.Plugin == filter.Plugin) && (filter.TenantID == 0 || job.TenantID == filter.TenantID) && (filter.Status == "" || job.Status == filter.Status) {
+			jobs = append(jobs, s.copyOf(job))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+func (s *memoryJobStore) ClaimJob(ctx context.Context, now time.Time) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next *Job
+	for _, job := range s.jobs {
+		if job.Status == JobQueued && !job.NextRunAt.After(now) && (next == nil || job.ID < next.ID) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Status = JobRunning
+	next.Attempts++
+	next.StartedAt = &now
+	return s.copyOf(next), nil
+}
+
+func (s *memoryJobStore) UpdateJobProgress(ctx context.Context, id uint, progress int, message string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[id]
+	if job.Status == JobRunning {
+		job.Progress, job.Message = progress, message
+	}
+	return job.CancelRequested, nil
+}
+
+func (s *memoryJobStore) FinishJob(ctx context.Context, job *Job) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs[job.ID].Status != JobRunning {
+		return false, nil
+	}
+	saved := s.copyOf(job)
+	saved.CancelRequested = false
+	s.jobs[job.ID] = saved
+	return true, nil
+}
+
+func (s *memoryJobStore) CancelJob(ctx context.Context, id uint, now, expiresAt time.Time) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[id]
+	switch job.Status {
+	case JobQueued:
+		job.Status = JobCanceled
+		job.FinishedAt, job.ExpiresAt = &now, &expiresAt
+	case JobRunning:
+		job.CancelRequested = true
+	}
+	return s.copyOf(job), nil
+}
+
+func (s *memoryJobStore) RequeueStaleJobs(ctx context.Context, startedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *memoryJobStore) DeleteExpiredJobs(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for id, job := range s.jobs {
+		if job.ExpiresAt != nil && !job.ExpiresAt.After(now) {
+			delete(s.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// jobPlugin 通过run函数执行任务的测试插件
+type jobPlugin struct {
+	testPlugin
+	calls int32
+	run   func(ctx context.Context, call int32) (interface{}, error)
+}
+
+func (p *jobPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return p.run(ctx, atomic.AddInt32(&p.calls, 1))
+}
+
+// newJobTestQueue 注册插件并创建使用内存存储的任务队列
+func newJobTestQueue(t *testing.T, plugin *jobPlugin) (*JobQueue, *memoryJobStore) {
+	t.Helper()
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	store := newMemoryJobStore()
+	queue := NewJobQueue(pm, store, JobQueueConfig{Workers: 2, PollInterval: 10 * time.Millisecond, RetryBackoff: time.Millisecond, Timeout: time.Minute})
+	pm.SetJobQueue(queue)
+	return queue, store
+}
+
+// waitForJob 等待任务进入指定状态
+func waitForJob(t *testing.T, store *memoryJobStore, id uint, status JobStatus) *Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, _ := store.GetJob(context.Background(), id); job.Status == status {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job, _ := store.GetJob(context.Background(), id)
+	t.Fatalf("expected job %d to become %s, got %+v", id, status, job)
+	return nil
+}
+
+func TestJobQueueRunsJobWithProgress(t *testing.T) {
+	plugin := &jobPlugin{testPlugin: testPlugin{name: "P"}}
+	plugin.run = func(ctx context.Context, call int32) (interface{}, error) {
+		if meta, _ := RequestMetaFromContext(ctx); meta.TenantID != 3 {
+			return nil, errors.New("missing request meta")
+		}
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) < 30*time.Second {
+			return nil, errors.New("expected job timeout to override default execute timeout")
+		}
+		ReportProgress(ctx, 50, "half way")
+		return map[string]interface{}{"count": 2}, nil
+	}
+	queue, store := newJobTestQueue(t, plugin)
+	queue.Start()
+	defer queue.Stop(context.Background())
+
+	ctx := WithRequestMeta(context.Background(), RequestMeta{UserID: 1, TenantID: 3})
+	job, err := queue.Enqueue(ctx, "P", map[string]interface{}{"n": 1}, JobOptions{})
+	if err != nil {
+		t.Fatalf("enqueue error: %v", err)
+	}
+	if job.ID == 0 || job.Status != JobQueued || job.MaxAttempts != 3 {
+		t.Fatalf("unexpected enqueued job: %+v", job)
+	}
+
+	done := waitForJob(t, store, job.ID, JobSucceeded)
+	if string(done.Result) != `{"count":2}` || done.Progress != 100 || done.Message != "half way" || done.Attempts != 1 {
+		t.Fatalf("unexpected finished job: %+v", done)
+	}
+	if done.FinishedAt == nil || done.ExpiresAt == nil || !done.ExpiresAt.After(*done.FinishedAt) {
+		t.Fatalf("expected result retention set, got %+v", done)
+	}
+
+	// 其他租户看不到该任务
+	if _, err := queue.Get(WithRequestMeta(context.Background(), RequestMeta{TenantID: 4}), "P", job.ID); err == nil {
+		t.Fatalf("expected job hidden from other tenants")
+	}
+	if got, err := queue.Get(ctx, "P", job.ID); err != nil || got.Status != JobSucceeded {
+		t.Fatalf("expected job visible to its tenant, got %+v, %v", got, err)
+	}
+	if jobs, _ := queue.List(ctx, "P", JobSucceeded, 10); len(jobs) != 1 {
+		t.Fatalf("expected one succeeded job listed, got %d", len(jobs))
+	}
+}
+
+func TestJobQueueRetriesWithBackoff(t *testing.T) {
+	plugin := &jobPlugin{testPlugin: testPlugin{name: "P"}}
+	plugin.run = func(ctx context.Context, call int32) (interface{}, error) {
+		if call < 3 {
+			return nil, errors.New("temporary failure")
+		}
+		return "ok", nil
+	}
+	queue, store := newJobTestQueue(t, plugin)
+	queue.Start()
+	defer queue.Stop(context.Background())
+
+	job, err := queue.Enqueue(context.Background(), "P", nil, JobOptions{})
+	if err != nil {
+		t.Fatalf("enqueue error: %v", err)
+	}
+	if done := waitForJob(t, store, job.ID, JobSucceeded); done.Attempts != 3 || done.Error != "" {
+		t.Fatalf("expected success on third attempt, got %+v", done)
+	}
+
+	plugin.run = func(ctx context.Context, call int32) (interface{}, error) {
+		return nil, errors.New("permanent failure")
+	}
+	failing, err := queue.Enqueue(context.Background(), "P", nil, JobOptions{MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("enqueue error: %v", err)
+	}
+	if done := waitForJob(t, store, failing.ID, JobFailed); done.Attempts != 2 || done.Error == "" || done.Result != nil {
+		t.Fatalf("expected failure after max attempts, got %+v", done)
+	}
+
+	if backoff := queue.retryBackoff(3); backoff != 4*time.Millisecond {
+		t.Fatalf("expected exponential backoff, got %v", backoff)
+	}
+	slow := NewJobQueue(queue.pm, store, JobQueueConfig{RetryBackoff: time.Minute})
+	if backoff := slow.retryBackoff(10); backoff != maxRetryBackoff {
+		t.Fatalf("expected backoff capped at %v, got %v", maxRetryBackoff, backoff)
+	}
+}
+
+func TestJobQueueCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	plugin := &jobPlugin{testPlugin: testPlugin{name: "P"}}
+	plugin.run = func(ctx context.Context, call int32) (interface{}, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	queue, store := newJobTestQueue(t, plugin)
+
+	// 队列未启动时任务保持排队，可以直接取消
+	queued, _ := queue.Enqueue(context.Background(), "P", nil, JobOptions{})
+	if job, err := queue.Cancel(context.Background(), "P", queued.ID); err != nil || job.Status != JobCanceled {
+		t.Fatalf("expected queued job canceled, got %+v, %v", job, err)
+	}
+	if _, err := queue.Cancel(context.Background(), "P", queued.ID); err == nil {
+		t.Fatalf("expected canceling finished job rejected")
+	}
+
+	queue.Start()
+	defer queue.Stop(context.Background())
+	running, _ := queue.Enqueue(context.Background(), "P", nil, JobOptions{})
+	<-started
+	if _, err := queue.Cancel(context.Background(), "P", running.ID); err != nil {
+		t.Fatalf("cancel error: %v", err)
+	}
+	if done := waitForJob(t, store, running.ID, JobCanceled); done.Attempts != 1 {
+		t.Fatalf("expected canceled job not retried, got %+v", done)
+	}
+}
+
+func TestJobQueueStopRequeuesInterruptedJobs(t *testing.T) {
+	started := make(chan struct{}, 1)
+	plugin := &jobPlugin{testPlugin: testPlugin{name: "P"}}
+	plugin.run = func(ctx context.Context, call int32) (interface{}, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	queue, store := newJobTestQueue(t, plugin)
+	queue.Start()
+
+	job, _ := queue.Enqueue(context.Background(), "P", nil, JobOptions{})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := queue.Stop(ctx); err == nil {
+		t.Fatalf("expected stop to report interrupted jobs")
+	}
+	if requeued := waitForJob(t, store, job.ID, JobQueued); requeued.Attempts != 0 {
+		t.Fatalf("expected interrupted attempt not counted, got %+v", requeued)
+	}
+	if _, err := queue.Enqueue(context.Background(), "P", nil, JobOptions{}); err == nil {
+		t.Fatalf("expected enqueue rejected after stop")
+	}
+}
+
+func TestJobQueueEnqueueRejects(t *testing.T) {
+	plugin := &jobPlugin{testPlugin: testPlugin{name: "P"}}
+	queue, _ := newJobTestQueue(t, plugin)
+
+	if _, err := queue.Enqueue(context.Background(), "missing", nil, JobOptions{}); err == nil {
+		t.Fatalf("expected unknown plugin rejected")
+	}
+	if _, err := queue.Enqueue(context.Background(), "P", map[string]interface{}{"bad": make(chan int)}, JobOptions{}); err == nil {
+		t.Fatalf("expected unserializable params rejected")
+	}
+	if err := queue.pm.DisablePlugin("P"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	if _, err := queue.Enqueue(context.Background(), "P", nil, JobOptions{}); err == nil {
+		t.Fatalf("expected disabled plugin rejected")
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// PluginState 插件生命周期状态
+type PluginState string
+
+const (
+	StateRegistered   PluginState = "registered"   // 已通过冲突和依赖检查，尚未初始化
+	StateInitializing PluginState = "initializing" // 正在加载配置并执行Init
+	StateReady        PluginState = "ready"        // 初始化完成，尚未启用
+	StateEnabled      PluginState = "enabled"      // 已启用，正常对外提供服务
+	StateDisabling    PluginState = "disabling"    // 正在执行OnDisable
+	StateDisabled     PluginState = "disabled"     // 已禁用，路由返回503
+	StateFailed       PluginState = "failed"       // 初始化或路由注册失败，可重新加载或注销
+	StateUnloaded     PluginState = "unloaded"     // 已注销
+)
+
+// maxStateHistory 每个插件保留的状态转换记录数
+const maxStateHistory = 50
+
+// stateTransitions 允许的状态转换，空状态表示插件从未注册
+var stateTransitions = map[PluginState][]PluginState{
+	"":                {StateRegistered},
+	StateRegistered:   {StateInitializing},
+	StateInitializing: {StateReady, StateFailed},
+	StateReady:        {StateEnabled, StateDisabled, StateFailed},
+	StateEnabled:      {StateDisabling, StateInitializing, StateUnloaded},
+	StateDisabling:    {StateDisabled, StateEnabled},
+	StateDisabled:     {StateEnabled, StateInitializing, StateUnloaded},
+	StateFailed:       {StateRegistered, StateInitializing, StateUnloaded},
+	StateUnloaded:     {StateRegistered},
+}
+
+// StateTransition 一次状态转换记录，失败的操作回滚后From与To相同，Error记录失败原因
+type StateTransition struct {
+	From  PluginState `json:"from"`
+	To    PluginState `json:"to"`
+	Event string      `json:"event"`
+	Error string      `json:"error,omitempty"`
+	At    time.Time   `json:"at"`
+}
+
+// pluginLifecycle 插件的当前状态与转换历史，插件注销或注册失败后仍然保留
+type pluginLifecycle struct {
+	state         PluginState
+	history       []StateTransition
+	resumeEnabled bool // 重新加载失败前插件是否启用，从failed状态恢复时使用
+}
+
+// canTransition 判断状态转换是否合法
+func canTransition(from, to PluginState) bool {
+	for _, allowed := range stateTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// stateLocked 获取插件的当前状态，调用方需持有pm.mutex
+func (pm *PluginManager) stateLocked(name string) PluginState {
+	if lifecycle, exists := pm.lifecycles[name]; exists {
+		return lifecycle.state
+	}
+	return ""
+}
+
+// checkTransitionLocked 校验插件能否转换到目标状态，调用方需持有pm.mutex
+func (pm *PluginManager) checkTransitionLocked(name string, to PluginState) error {
+	from := pm.stateLocked(name)
+	if !canTransition(from, to) {
+		return fmt.Errorf("插件 '%s' 当前状态为 %s，无法转换为 %s", name, stateLabel(from), to)
+	}
+	return nil
+}
+
+// setStateLocked 记录状态转换并同步PluginInfo.State，cause不为空时记录失败原因
+// 调用方需持有pm.mutex，并已通过checkTransitionLocked或流程保证转换合法
+func (pm *PluginManager) setStateLocked(name string, to PluginState, event string, cause error) {
+	if pm.lifecycles == nil {
+		pm.lifecycles = make(map[string]*pluginLifecycle)
+	}
+	lifecycle, exists := pm.lifecycles[name]
+	if !exists {
+		lifecycle = &pluginLifecycle{}
+		pm.lifecycles[name] = lifecycle
+	}
+
+	transition := StateTransition{From: lifecycle.state, To: to, Event: event, At: time.Now()}
+	if cause != nil {
+		transition.Error = cause.Error()
+	}
+	lifecycle.state = to
+	lifecycle.history = append(lifecycle.history, transition)
+	if len(lifecycle.history) > maxStateHistory {
+		lifecycle.history = append([]StateTransition(nil), lifecycle.history[len(lifecycle.history)-maxStateHistory:]...)
+	}
+
+	if info, exists := pm.plugins[name]; exists {
+		info.State = to
+		pm.plugins[name] = info
+	}
+}
+
+// recordFailureLocked 记录未改变状态的失败操作（操作已回滚），调用方需持有pm.mutex
+func (pm *PluginManager) recordFailureLocked(name, event string, cause error) {
+	pm.setStateLocked(name, pm.stateLocked(name), event, cause)
+}
+
+// GetPluginState 获取插件的生命周期状态，包括已注销和注册失败的插件
+func (pm *PluginManager) GetPluginState(name string) (PluginState, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	lifecycle, exists := pm.lifecycles[name]
+	if !exists {
+		return "", false
+	}
+	return lifecycle.state, true
+}
+
+// GetPluginHistory 获取插件的状态转换历史（按时间先后），最多保留最近50条
+func (pm *PluginManager) GetPluginHistory(name string) ([]StateTransition, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	lifecycle, exists := pm.lifecycles[name]
+	if !exists {
+		return nil, false
+	}
+	return append([]StateTransition(nil), lifecycle.history...), true
+}
+
+// stateLabel 状态的显示名称，空状态显示为未注册
+func stateLabel(state PluginState) string {
+	if state == "" {
+		return "not_registered"
+	}
+	return string(state)
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// transitionsOf 将状态转换历史整理为"from->to"形式便于断言
+func transitionsOf(history []StateTransition) []string {
+	steps := make([]string, 0, len(history))
+	for _, transition := range history {
+		steps = append(steps, string(transition.From)+"->"+string(transition.To))
+	}
+	return steps
+}
+
+func TestLifecycleTransitions(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugin := newTestPlugin("L", false)
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if err := pm.DisablePlugin("L"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	if err := pm.EnablePlugin("L"); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	if err := pm.Unregister("L"); err != nil {
+		t.Fatalf("unregister error: %v", err)
+	}
+
+	history, ok := pm.GetPluginHistory("L")
+	if !ok {
+		t.Fatalf("expected history retained after unregister")
+	}
+	expected := []string{
+		"->registered", "registered->initializing", "initializing->ready", "ready->enabled",
+		"enabled->disabling", "disabling->disabled", "disabled->enabled", "enabled->unloaded",
+	}
+	if got := transitionsOf(history); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected transitions:\n got %v\nwant %v", got, expected)
+	}
+	if state, _ := pm.GetPluginState("L"); state != StateUnloaded {
+		t.Fatalf("expected unloaded state, got %s", state)
+	}
+
+	// 注销后可以重新注册
+	if err := pm.Register(newTestPlugin("L", false)); err != nil {
+		t.Fatalf("re-register error: %v", err)
+	}
+	if info, _ := pm.GetPluginInfo("L"); info.State != StateEnabled {
+		t.Fatalf("expected enabled after re-register, got %s", info.State)
+	}
+}
+
+func TestLifecycleRollbackOnCallbackFailure(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugin := newTestPlugin("R", false)
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	// OnDisable失败回滚为启用状态
+	plugin.disableError = errors.New("disable failed")
+	if err := pm.DisablePlugin("R"); err == nil {
+		t.Fatalf("expected disable error")
+	}
+	if status, _ := pm.GetPluginStatus("R"); status != "enabled" {
+		t.Fatalf("expected plugin still enabled, got %s", status)
+	}
+	history, _ := pm.GetPluginHistory("R")
+	last := history[len(history)-1]
+	if last.From != StateDisabling || last.To != StateEnabled || !strings.Contains(last.Error, "disable failed") {
+		t.Fatalf("expected rollback recorded with error, got %+v", last)
+	}
+
+	// OnEnable失败时保持禁用状态
+	plugin.disableError = nil
+	if err := pm.DisablePlugin("R"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	plugin.enableError = errors.New("enable failed")
+	if err := pm.EnablePlugin("R"); err == nil {
+		t.Fatalf("expected enable error")
+	}
+	if state, _ := pm.GetPluginState("R"); state != StateDisabled {
+		t.Fatalf("expected disabled after failed enable, got %s", state)
+	}
+}
+
+func TestLifecycleFailedStates(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+
+	// 注册时初始化失败：插件未注册，但保留failed状态和失败原因
+	broken := newTestPlugin("broken", false)
+	broken.initError = errors.New("init failed")
+	if err := pm.Register(broken); err == nil {
+		t.Fatalf("expected register error")
+	}
+	if state, ok := pm.GetPluginState("broken"); !ok || state != StateFailed {
+		t.Fatalf("expected failed state, got %s", state)
+	}
+	history, _ := pm.GetPluginHistory("broken")
+	if last := history[len(history)-1]; !strings.Contains(last.Error, "init failed") {
+		t.Fatalf("expected init error recorded, got %+v", last)
+	}
+	broken.initError = nil
+	if err := pm.Register(broken); err != nil {
+		t.Fatalf("expected retry after failed registration to succeed, got %v", err)
+	}
+
+	// 重新加载时初始化失败：插件保留在管理器中并进入failed状态
+	plugin := newTestPlugin("F", false)
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	plugin.initError = errors.New("reinit failed")
+	if err := pm.ReloadPlugin("F"); err == nil {
+		t.Fatalf("expected reload error")
+	}
+	if status, ok := pm.GetPluginStatus("F"); !ok || status != "failed" {
+		t.Fatalf("expected failed plugin kept in manager, got %s", status)
+	}
+	if err := pm.EnablePlugin("F"); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Fatalf("expected enabling failed plugin to be rejected, got %v", err)
+	}
+
+	// failed状态的插件可以重新加载恢复，且无需再次关闭
+	plugin.initError = nil
+	if err := pm.ReloadPlugin("F"); err != nil {
+		t.Fatalf("expected reload to recover plugin, got %v", err)
+	}
+	if plugin.shutdownCalled != 1 {
+		t.Fatalf("expected shutdown called once, got %d", plugin.shutdownCalled)
+	}
+	if state, _ := pm.GetPluginState("F"); state != StateEnabled {
+		t.Fatalf("expected enabled after recovery, got %s", state)
+	}
+}
+
+func TestLifecycleReloadShutdownFailureKeepsState(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugin := newTestPlugin("S", false)
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	plugin.shutdownError = errors.New("shutdown failed")
+	if err := pm.ReloadPlugin("S"); err == nil {
+		t.Fatalf("expected reload error")
+	}
+	if status, _ := pm.GetPluginStatus("S"); status != "enabled" {
+		t.Fatalf("expected plugin still enabled after failed shutdown, got %s", status)
+	}
+	if _, err := pm.ExecutePlugin("S", nil); err != nil {
+		t.Fatalf("expected plugin still executable, got %v", err)
+	}
+}
+
+func TestLifecycleHistoryIsBounded(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.Register(newTestPlugin("B", false)); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	for i := 0; i < maxStateHistory; i++ {
+		_ = pm.DisablePlugin("B")
+		_ = pm.EnablePlugin("B")
+	}
+	history, _ := pm.GetPluginHistory("B")
+	if len(history) != maxStateHistory {
+		t.Fatalf("expected history bounded to %d, got %d", maxStateHistory, len(history))
+	}
+	if last := history[len(history)-1]; last.To != StateEnabled {
+		t.Fatalf("expected latest transition kept, got %+v", last)
+	}
+}
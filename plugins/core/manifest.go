@@ -0,0 +1,151 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+)
+
+// ManifestFileName 插件目录中的清单文件名
+const ManifestFileName = "plugin.json"
+
+// PluginManifest 插件清单（plugin.json），描述插件的基本信息、依赖和运行要求
+// 每个插件位于独立目录中，清单与编译产物放在同一目录
+type PluginManifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Version           string   `json:"version"`
+	Author            string   `json:"author"`
+	Dependencies      []string `json:"dependencies"`        // 依赖声明，格式与GetDependencies相同
+	Conflicts         []string `json:"conflicts"`           // 冲突的插件名称
+	EntryPoint        string   `json:"entry_point"`         // 编译产物相对清单目录的路径，默认为"<name>.so"
+	BuildTags         []string `json:"build_tags"`          // 编译插件时使用的构建标签
+	RequiredGoVersion string   `json:"required_go_version"` // 要求的Go版本，如"1.22"（即>=1.22）或">=1.22,<2"
+	Permissions       []string `json:"permissions"`         // 插件申请的权限
+}
+
+// ManifestProvider 插件声明自身清单的接口（可选）
+// 实现该接口的插件在注册时会按清单校验
+type ManifestProvider interface {
+	Manifest() *PluginManifest
+}
+
+// LoadPluginManifest 读取并解析插件清单文件，不做内容校验
+func LoadPluginManifest(manifestPath string) (*PluginManifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取插件清单失败: %w", err)
+	}
+
+	manifest := &PluginManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("解析插件清单失败: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// EntryPointOrDefault 返回插件编译产物的文件名，未声明时为"<name>.so"
+func (m *PluginManifest) EntryPointOrDefault() string {
+	if m.EntryPoint != "" {
+		return m.EntryPoint
+	}
+	return m.Name + ".so"
+}
+
+// Validate 校验清单内容：名称、版本号、入口、依赖声明以及Go版本要求
+func (m *PluginManifest) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("插件清单缺少名称")
+	}
+	if _, err := ParseVersion(m.Version); err != nil {
+		return fmt.Errorf("插件 '%s' 清单的%w", m.Name, err)
+	}
+	if entry := m.EntryPointOrDefault(); strings.Contains(entry, "..") {
+		return fmt.Errorf("插件 '%s' 清单的入口 '%s' 不能位于插件目录之外", m.Name, entry)
+	}
+	for _, spec := range m.Dependencies {
+		if _, err := ParseDependency(spec); err != nil {
+			return fmt.Errorf("插件 '%s' 清单%w", m.Name, err)
+		}
+	}
+	for _, permission := range m.Permissions {
+		if strings.TrimSpace(permission) == "" {
+			return fmt.Errorf("插件 '%s' 清单包含空的权限声明", m.Name)
+		}
+	}
+	return m.checkGoVersion(strings.TrimPrefix(runtime.Version(), "go"))
+}
+
+// checkGoVersion 检查当前Go版本是否满足清单要求，无法解析的当前版本（如开发版）不做检查
+func (m *PluginManifest) checkGoVersion(current string) error {
+	required := strings.TrimSpace(m.RequiredGoVersion)
+	if required == "" {
+		return nil
+	}
+	// 未带运算符的版本号表示最低版本
+	if constraintOperator(required) == "" {
+		required = ">=" + required
+	}
+	constraint, err := ParseConstraint(required)
+	if err != nil {
+		return fmt.Errorf("插件 '%s' 清单的Go版本要求无效: %w", m.Name, err)
+	}
+	version, err := ParseVersion(current)
+	if err != nil {
+		return nil
+	}
+	if !constraint.Check(version) {
+		return fmt.Errorf("插件 '%s' 要求Go版本满足 '%s'，但当前版本为 '%s'", m.Name, m.RequiredGoVersion, current)
+	}
+	return nil
+}
+
+// CheckPlugin 校验插件运行时的名称和版本与清单一致
+func (m *PluginManifest) CheckPlugin(plugin Plugin) error {
+	if plugin.Name() != m.Name {
+		return fmt.Errorf("插件名称与清单不一致: 清单为 '%s'，插件为 '%s'", m.Name, plugin.Name())
+	}
+	declared, declaredErr := ParseVersion(m.Version)
+	actual, actualErr := ParseVersion(plugin.Version())
+	if declaredErr != nil || actualErr != nil {
+		if m.Version != plugin.Version() {
+			return fmt.Errorf("插件 '%s' 版本与清单不一致: 清单为 '%s'，插件为 '%s'", m.Name, m.Version, plugin.Version())
+		}
+		return nil
+	}
+	if declared.Compare(actual) != 0 {
+		return fmt.Errorf("插件 '%s' 版本与清单不一致: 清单为 '%s'，插件为 '%s'", m.Name, m.Version, plugin.Version())
+	}
+	return nil
+}
+
+// ValidateManifest 校验清单内容及其与插件运行时信息的一致性
+func ValidateManifest(manifest *PluginManifest, plugin Plugin) error {
+	if err := manifest.Validate(); err != nil {
+		return err
+	}
+	return manifest.CheckPlugin(plugin)
+}
+
+// RegisterWithManifest 按清单校验插件后注册，清单与插件运行时的名称或版本不一致时拒绝注册
+// manifest为空时等同于Register
+func (pm *PluginManager) RegisterWithManifest(plugin Plugin, manifest *PluginManifest) error {
+	if manifest == nil {
+		if provider, ok := plugin.(ManifestProvider); ok {
+			manifest = provider.Manifest()
+		}
+	}
+	if manifest != nil {
+		if err := ValidateManifest(manifest, plugin); err != nil {
+			metrics.RecordPluginError(plugin.Name(), "manifest_mismatch")
+			return pkg.NewPluginError(err.Error(), nil)
+		}
+	}
+	return pm.register(plugin, manifest)
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// manifestPlugin 通过ManifestProvider声明清单的测试插件
+type manifestPlugin struct {
+	testPlugin
+	manifest *PluginManifest
+}
+
+func (p *manifestPlugin) Manifest() *PluginManifest { return p.manifest }
+
+func TestLoadPluginManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ManifestFileName)
+	content := `{"name": "demo", "version": "1.2.0", "dependencies": ["base^1.0"], "permissions": ["db.read"]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	manifest, err := LoadPluginManifest(path)
+	if err != nil {
+		t.Fatalf("load manifest error: %v", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		t.Fatalf("expected valid manifest, got %v", err)
+	}
+	if manifest.EntryPointOrDefault() != "demo.so" || len(manifest.Permissions) != 1 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		manifest PluginManifest
+		errPart  string
+	}{
+		{"missing name", PluginManifest{Version: "1.0.0"}, "缺少名称"},
+		{"bad version", PluginManifest{Name: "p", Version: "abc"}, "版本"},
+		{"entry outside dir", PluginManifest{Name: "p", Version: "1.0.0", EntryPoint: "../p.so"}, "插件目录之外"},
+		{"bad dependency", PluginManifest{Name: "p", Version: "1.0.0", Dependencies: []string{">=1.0"}}, "依赖声明"},
+		{"empty permission", PluginManifest{Name: "p", Version: "1.0.0", Permissions: []string{" "}}, "权限"},
+		{"bad go version", PluginManifest{Name: "p", Version: "1.0.0", RequiredGoVersion: ">=abc"}, "Go版本要求无效"},
+		{"future go version", PluginManifest{Name: "p", Version: "1.0.0", RequiredGoVersion: "99.0"}, "要求Go版本"},
+	}
+	for _, tc := range cases {
+		err := tc.manifest.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.errPart) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.errPart, err)
+		}
+	}
+
+	manifest := PluginManifest{Name: "p", Version: "1.0.0", RequiredGoVersion: ">=1.20,<2"}
+	if err := manifest.checkGoVersion("1.22.3"); err != nil {
+		t.Fatalf("expected go version satisfied, got %v", err)
+	}
+	if err := manifest.checkGoVersion("2.0.0"); err == nil {
+		t.Fatalf("expected go version rejected")
+	}
+	if err := manifest.checkGoVersion("devel"); err != nil {
+		t.Fatalf("expected unparsable current version skipped, got %v", err)
+	}
+}
+
+func TestRegisterWithManifestRejectsMismatch(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+
+	// 名称不一致
+	err := pm.RegisterWithManifest(newTestPlugin("A", false), &PluginManifest{Name: "B", Version: "1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "名称与清单不一致") {
+		t.Fatalf("expected name mismatch error, got %v", err)
+	}
+	// 版本不一致
+	plugin := newTestPlugin("A", false)
+	err = pm.RegisterWithManifest(plugin, &PluginManifest{Name: "A", Version: "2.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "版本与清单不一致") {
+		t.Fatalf("expected version mismatch error, got %v", err)
+	}
+	if plugin.initCalled != 0 {
+		t.Fatalf("expected plugin not initialized on mismatch")
+	}
+	if _, exists := pm.GetPlugin("A"); exists {
+		t.Fatalf("expected plugin not registered on mismatch")
+	}
+
+	// 一致时注册成功并保存清单
+	manifest := &PluginManifest{Name: "A", Version: "1.0", Permissions: []string{"db.read"}}
+	if err := pm.RegisterWithManifest(plugin, manifest); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if info, _ := pm.GetPluginInfo("A"); info.Manifest != manifest {
+		t.Fatalf("expected manifest kept in plugin info")
+	}
+}
+
+func TestRegisterUsesManifestProvider(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugin := &manifestPlugin{
+		testPlugin: testPlugin{name: "M"},
+		manifest:   &PluginManifest{Name: "M", Version: "0.9.0"},
+	}
+	if err := pm.Register(plugin); err == nil {
+		t.Fatalf("expected register to reject mismatched declared manifest")
+	}
+
+	plugin.manifest.Version = "1.0.0"
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if err := pm.ReloadPlugin("M"); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if info, _ := pm.GetPluginInfo("M"); info.Manifest == nil {
+		t.Fatalf("expected manifest kept after reload")
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"weave/pkg/openapi"
+)
+
+// DescribeRoutes 将已启用插件的路由写入OpenAPI文档，路径为/plugins/<插件名><路由路径>
+// 路由的Description作为摘要，Tags为空时按插件名分组；Params中出现在路径里的参数作为路径参数，
+// 其余作为查询参数；RequestBody和ResponseBody通过反射生成Schema。
+// 仍使用旧版RegisterRoutes的插件只能从路由表中得到路径和方法
+func (pm *PluginManager) DescribeRoutes(b *openapi.Builder) {
+	type pluginRoutes struct {
+		plugin Plugin
+		routes []Route
+		legacy []string // 旧版插件路由表中的"方法 路径"
+	}
+
+	pm.mutex.RLock()
+	described := make(map[string]pluginRoutes)
+	for name, info := range pm.plugins {
+		if !info.IsEnabled {
+			continue
+		}
+		entry := pluginRoutes{plugin: info.Plugin, routes: info.Routes}
+		if len(info.Routes) == 0 {
+			if table := pm.routeTables[name]; table != nil {
+				for _, route := range table.Routes() {
+					entry.legacy = append(entry.legacy, route.Method+" "+route.Path)
+				}
+			}
+		}
+		described[name] = entry
+	}
+	pm.mutex.RUnlock()
+
+	names := make([]string, 0, len(described))
+	for name := range described {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry := described[name]
+		prefix := fmt.Sprintf("%s/%s", pluginRoutePrefix, name)
+		for _, route := range entry.routes {
+			b.AddOperation(route.Method, prefix+route.Path, describeRoute(b, entry.plugin, route))
+		}
+		for _, legacy := range entry.legacy {
+			method, path, _ := strings.Cut(legacy, " ")
+			b.AddOperation(method, path, &openapi.Operation{Tags: []string{name}, Description: entry.plugin.Description()})
+		}
+	}
+}
+
+// operationPathReplacer 将路由路径转换为操作ID的一部分，如/notes/:id转换为notes.id
+var operationPathReplacer = strings.NewReplacer("/", ".", ":", "", "*", "")
+
+// describeRoute 生成插件路由对应的OpenAPI操作
+func describeRoute(b *openapi.Builder, plugin Plugin, route Route) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: strings.TrimSuffix(fmt.Sprintf("%s.%s.%s", plugin.Name(), strings.ToLower(route.Method), operationPathReplacer.Replace(strings.Trim(route.Path, "/"))), "."),
+		Summary:     route.Description,
+		Tags:        route.Tags,
+	}
+	if len(op.Tags) == 0 {
+		op.Tags = []string{plugin.Name()}
+	}
+
+	inPath := make(map[string]bool)
+	for _, name := range openapi.PathParams(route.Path) {
+		inPath[name] = true
+	}
+	paramNames := make([]string, 0, len(route.Params))
+	for name := range route.Params {
+		paramNames = append(paramNames, name)
+	}
+	sort.Strings(paramNames)
+	for _, name := range paramNames {
+		param := openapi.Parameter{Name: name, In: "query", Description: route.Params[name], Schema: map[string]interface{}{"type": "string"}}
+		if inPath[name] {
+			param.In = "path"
+			param.Required = true
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+
+	if route.RequestBody != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: b.JSONBody(route.RequestBody)}
+	}
+	success := &openapi.Response{Description: "成功"}
+	if route.ResponseBody != nil {
+		success.Content = b.JSONBody(route.ResponseBody)
+	}
+	op.Responses = map[string]*openapi.Response{"200": success}
+	if route.AuthRequired || route.requiresAuthorization() {
+		op.Security = []map[string][]string{{openapi.BearerAuth: {}}}
+		op.Responses["401"] = &openapi.Response{Description: "未认证"}
+	}
+	if route.requiresAuthorization() {
+		var requirements []string
+		if len(route.Roles) > 0 {
+			requirements = append(requirements, "需要以下角色之一: "+strings.Join(route.Roles, ", "))
+		}
+		if len(route.Permissions) > 0 {
+			requirements = append(requirements, "需要权限: "+strings.Join(route.Permissions, ", "))
+		}
+		op.Description = strings.Join(requirements, "；")
+		op.Responses["403"] = &openapi.Response{Description: "角色权限不足"}
+	}
+	return op
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"weave/pkg/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIItem 用于生成Schema的测试类型
+type openAPIItem struct {
+	ID        uint           `json:"id"`
+	Name      string         `json:"name" binding:"required,max=20"`
+	Tags      []string       `json:"tags,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	Parent    *openAPIItem   `json:"parent,omitempty" description:"上级条目"`
+	Secret    string         `json:"-"`
+	Extra     map[string]int `json:"extra"`
+}
+
+func TestDescribeRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+
+	plugin := newTestPlugin("P", false)
+	plugin.routes = []Route{
+		{
+			Path:         "/items/:id",
+			Method:       "GET",
+			Handler:      func(c *gin.Context) {},
+			Description:  "获取条目",
+			Params:       map[string]string{"id": "条目ID", "verbose": "是否返回详情"},
+			ResponseBody: openAPIItem{},
+		},
+		{
+			Path:         "/items",
+			Method:       "POST",
+			Handler:      func(c *gin.Context) {},
+			AuthRequired: true,
+			Tags:         []string{"items"},
+			RequestBody:  &openAPIItem{},
+		},
+	}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if err := pm.Register(newTestPlugin("Q", true)); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if err := pm.DisablePlugin("Q"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+
+	builder := openapi.NewBuilder(openapi.Info{Title: "test", Version: "1.0.0"})
+	pm.DescribeRoutes(builder)
+	doc := builder.Document()
+
+	get := doc.Paths["/plugins/P/items/{id}"]["get"]
+	if get == nil || get.Summary != "获取条目" || get.Tags[0] != "P" || get.OperationID != "P.get.items.id" {
+		t.Fatalf("unexpected get operation: %+v", get)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].In != "path" || !get.Parameters[0].Required || get.Parameters[1].In != "query" {
+		t.Fatalf("expected path and query parameters, got %+v", get.Parameters)
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema["$ref"]; ref != "#/components/schemas/core.openAPIItem" {
+		t.Fatalf("expected response schema reference, got %v", ref)
+	}
+
+	post := doc.Paths["/plugins/P/items"]["post"]
+	if post == nil || post.RequestBody == nil || len(post.Security) != 1 || post.Responses["401"] == nil {
+		t.Fatalf("unexpected post operation: %+v", post)
+	}
+
+	schema := doc.Components.Schemas["core.openAPIItem"].(map[string]interface{})
+	properties := schema["properties"].(map[string]interface{})
+	if _, exists := properties["Secret"]; exists {
+		t.Fatalf("expected json:\"-\" field skipped")
+	}
+	if required := schema["required"].([]string); len(required) != 1 || required[0] != "name" {
+		t.Fatalf("expected name required, got %v", required)
+	}
+	if created := properties["created_at"].(map[string]interface{}); created["format"] != "date-time" {
+		t.Fatalf("expected time formatted as date-time, got %v", created)
+	}
+	if parent := properties["parent"].(map[string]interface{}); parent["description"] != "上级条目" {
+		t.Fatalf("expected self reference wrapped with description, got %v", parent)
+	}
+
+	// 已禁用插件的路由不写入文档
+	if _, exists := doc.Paths["/plugins/Q/ping"]; exists {
+		t.Fatalf("expected disabled plugin routes excluded")
+	}
+}
+
+func TestOpenAPIHelpers(t *testing.T) {
+	if path := openapi.ConvertPath("/teams/:id/members/:memberId/*rest"); path != "/teams/{id}/members/{memberId}/{rest}" {
+		t.Fatalf("unexpected converted path %s", path)
+	}
+	if name := openapi.HandlerName("weave/controllers.(*PluginController).GetAllPlugins-fm"); name != "PluginController.GetAllPlugins" {
+		t.Fatalf("unexpected handler name %s", name)
+	}
+
+	builder := openapi.NewBuilder(openapi.Info{})
+	builder.AddGinRoute(gin.RouteInfo{Method: "DELETE", Path: "/api/v1/users/:id", Handler: "weave/controllers.(*UserController).DeleteUser-fm"}, true)
+	op := builder.Document().Paths["/api/v1/users/{id}"]["delete"]
+	if op == nil || op.OperationID != "UserController.DeleteUser" || op.Tags[0] != "users" || len(op.Parameters) != 1 || len(op.Security) != 1 {
+		t.Fatalf("unexpected gin route operation: %+v", op)
+	}
+}
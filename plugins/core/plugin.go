@@ -1,15 +1,16 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
-	"weave/middleware"
 	"weave/pkg"
 	"weave/pkg/metrics"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // Route 定义路由结构
@@ -20,8 +21,12 @@ type Route struct {
 	Middlewares  []gin.HandlerFunc // 路由特定中间件
 	Description  string            // 路由描述
 	AuthRequired bool              // 是否需要认证
+	Roles        []string          // 允许访问的角色（满足其一即可），声明后自动要求认证
+	Permissions  []string          // 访问所需的全部权限，如notes:write，声明后自动要求认证
 	Tags         []string          // 路由标签
 	Params       map[string]string // 参数说明
+	RequestBody  interface{}       // 请求体类型的示例值（可选），如CreateNoteRequest{}，用于生成OpenAPI文档
+	ResponseBody interface{}       // 成功响应体类型的示例值（可选），用于生成OpenAPI文档
 } // 路由结构定义
 
 // Plugin 插件接口定义
@@ -57,10 +62,14 @@ type Plugin interface {
 type PluginInfo struct {
 	Plugin       Plugin   // 插件实例
 	Routes       []Route  // 插件路由
-	Dependencies []string // 依赖的插件名称列表
+	Dependencies []string // 必需依赖的插件名称列表
 	Conflicts    []string // 冲突的插件名称列表
 	IsRegistered bool     // 路由是否已注册
 	IsEnabled    bool     // 插件是否启用
+
+	DependencySpecs []Dependency    // 完整的依赖声明（包括版本约束和可选依赖）
+	State           PluginState     // 生命周期状态
+	Manifest        *PluginManifest // 插件清单，未提供清单时为空
 }
 
 // PluginWatcher 定义插件监控器接口
@@ -77,6 +86,34 @@ type PluginManager struct {
 	watcher   PluginWatcher         // 插件文件监控器
 	logger    *pkg.Logger           // 日志记录器
 	pluginDir string                // 插件目录路径
+
+	executeTimeouts  map[string]time.Duration          // 插件执行超时配置（按插件名）
+	routeTables      map[string]*gin.Engine            // 插件路由表（按插件名），由分发器统一调度
+	dispatcherRouter *gin.Engine                       // 已挂载插件分发器的路由引擎
+	eventBus         *EventBus                         // 插件间事件总线
+	eventBusOnce     sync.Once                         // 事件总线延迟初始化
+	services         map[string]*serviceEntry          // 插件发布的服务（按提供者插件名）
+	initializing     map[string]Plugin                 // 正在执行Init的插件实例（按插件名），只有它们可以发布服务
+	servicesMu       sync.RWMutex                      // 服务表独立加锁，允许插件在Init中发布和查找服务
+	configs          map[string]map[string]interface{} // 可配置插件当前生效的配置（按插件名）
+	configStore      PluginConfigStore                 // 插件配置持久化存储，为空时使用数据库
+	configMu         sync.Mutex                        // 插件配置独立加锁，串行化配置更新
+	storageBackend   StorageBackend                    // 插件键值存储后端，为空时使用数据库
+	storageMu        sync.RWMutex                      // 保护存储后端
+	breakers         map[string]versionBreakers        // 插件熔断器（按插件名和版本），延迟创建
+	breakerConfigs   map[string]BreakerConfig          // 插件熔断器单独配置（按插件名）
+	breakerDefaults  *BreakerConfig                    // 默认熔断器配置，为空时使用DefaultBreakerConfig
+	lifecycles       map[string]*pluginLifecycle       // 插件生命周期状态与转换历史，注销后仍然保留
+	requests         requestTracker                    // 进行中的插件调用，关闭时用于拒绝新调用并等待
+	bulkheads        map[string]versionBulkheads       // 插件舱壁（按插件名和版本），延迟创建
+	limitConfigs     map[string]ConcurrencyLimits      // 插件并发限制单独配置（按插件名）
+	bulkheadsMu      sync.Mutex                        // 保护舱壁和并发限制配置
+	breakersMu       sync.Mutex                        // 熔断器独立加锁，执行插件时不持有管理器锁
+	canaries         map[string]*canaryRelease         // 与稳定版本并存的灰度版本（按插件名）
+	roleResolver     RoleResolver                      // 插件路由授权使用的角色解析器，为空时使用数据库
+	authzMu          sync.RWMutex                      // 保护角色解析器
+	jobs             *JobQueue                         // 插件异步任务队列，未启用时为空
+	stateSync        *StateSync                        // 插件启用状态的持久化与集群同步，未启用时为空
 }
 
 // SetPluginWatcher 设置插件监控器实例
@@ -97,15 +134,32 @@ var GlobalPluginManager = &PluginManager{
 	pluginDir: "plugins", // 默认插件目录
 }
 
+// NewPluginManager 创建独立的插件管理器，与GlobalPluginManager互不影响
+// 用于测试（见plugintest包）或在同一进程中隔离运行多组插件
+func NewPluginManager() *PluginManager {
+	return &PluginManager{
+		plugins:   make(map[string]PluginInfo),
+		mutex:     &sync.RWMutex{},
+		pluginDir: "plugins",
+	}
+}
+
 // SetRouter 设置路由引擎
 func (pm *PluginManager) SetRouter(router *gin.Engine) {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 	pm.router = router
+	pm.mountDispatcher(router)
 }
 
 // Register 注册插件
+// 插件实现ManifestProvider时按其清单校验，见RegisterWithManifest
 func (pm *PluginManager) Register(plugin Plugin) error {
+	return pm.RegisterWithManifest(plugin, nil)
+}
+
+// register 注册插件，manifest已通过校验或为空
+func (pm *PluginManager) register(plugin Plugin, manifest *PluginManifest) error {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
@@ -117,54 +171,146 @@ func (pm *PluginManager) Register(plugin Plugin) error {
 	// 设置插件管理器引用
 	plugin.SetPluginManager(pm)
 
-	// 检查冲突插件
-	conflicts := plugin.GetConflicts()
-	for _, conflictName := range conflicts {
-		if _, exists := pm.plugins[conflictName]; exists {
-			return fmt.Errorf("插件 '%s' 与已注册的插件 '%s' 冲突", name, conflictName)
-		}
+	// 检查冲突插件、依赖插件及其版本
+	specs, err := pm.checkCompatibilityLocked(plugin)
+	if err != nil {
+		return err
 	}
 
-	// 检查依赖插件
-	dependencies := plugin.GetDependencies()
-	for _, depName := range dependencies {
-		if _, exists := pm.plugins[depName]; !exists {
-			return fmt.Errorf("依赖的插件未注册: %s", depName)
-		}
+	pm.setStateLocked(name, StateRegistered, "register", nil)
+	pm.setStateLocked(name, StateInitializing, "init", nil)
+
+	// 加载并校验插件配置（仅限实现了Configurable接口的插件）
+	if err := pm.applyInitialConfig(plugin); err != nil {
+		err = fmt.Errorf("插件 '%s' 配置无效: %w", name, err)
+		pm.setStateLocked(name, StateFailed, "init", err)
+		return err
 	}
 
+	// 注入插件键值存储（仅限实现了StorageAware接口的插件）
+	pm.injectStorage(plugin)
+
 	// 初始化插件
-	if err := plugin.Init(); err != nil {
-		return fmt.Errorf("插件 '%s' 初始化失败: %w", name, err)
+	if err := pm.callInit(name, plugin); err != nil {
+		pm.Events().UnsubscribeOwner(name)
+		pm.removeService(name)
+		pm.removeConfig(name)
+		err = fmt.Errorf("插件 '%s' 初始化失败: %w", name, err)
+		pm.setStateLocked(name, StateFailed, "init", err)
+		return err
 	}
 
 	// 创建插件信息
 	info := PluginInfo{
-		Plugin:       plugin,
-		Routes:       plugin.GetRoutes(),
-		Dependencies: dependencies,
-		Conflicts:    conflicts,
-		IsRegistered: false,
-		IsEnabled:    true, // 默认为启用状态
+		Plugin:          plugin,
+		Routes:          plugin.GetRoutes(),
+		Dependencies:    requiredDependencyNames(specs),
+		Conflicts:       plugin.GetConflicts(),
+		IsRegistered:    false,
+		IsEnabled:       true, // 默认为启用状态
+		DependencySpecs: specs,
+		Manifest:        manifest,
 	}
 
 	pm.plugins[name] = info
+	pm.setStateLocked(name, StateReady, "init", nil)
 
-	// 如果路由引擎已设置，自动注册路由
+	// 如果路由引擎已设置，自动注册路由；失败时关闭插件并回滚注册
 	if pm.router != nil {
 		if err := pm.registerPluginRoutes(name); err != nil {
-			return fmt.Errorf("插件 '%s' 路由注册失败: %w", name, err)
+			err = fmt.Errorf("插件 '%s' 路由注册失败: %w", name, err)
+			if shutdownErr := safeCall(name, "Shutdown", plugin.Shutdown); shutdownErr != nil {
+				pkg.Warn("回滚注册时关闭插件失败", zap.String("plugin", name), zap.Error(shutdownErr))
+			}
+			delete(pm.routeTables, name)
+			pm.Events().UnsubscribeOwner(name)
+			pm.removeService(name)
+			pm.removeConfig(name)
+			delete(pm.plugins, name)
+			pm.setStateLocked(name, StateFailed, "register", err)
+			return err
 		}
 	}
 
+	// 保存的状态为禁用时（运维人员禁用后重启或重新注册），插件保持禁用，路由返回503
+	if enabled, known := pm.persistedStateLocked(name); known && !enabled {
+		info = pm.plugins[name]
+		info.IsEnabled = false
+		pm.plugins[name] = info
+		pm.Events().UnsubscribeOwner(name)
+		pm.setServiceDisabled(name, true)
+		pm.setStateLocked(name, StateDisabled, "register", nil)
+		pm.publishLifecycle(TopicPluginRegistered, plugin)
+		return nil
+	}
+
+	pm.setStateLocked(name, StateEnabled, "enable", nil)
+	pm.publishLifecycle(TopicPluginRegistered, plugin)
 	return nil
 }
 
+// checkCompatibilityLocked 检查插件与已注册插件的冲突关系、依赖及版本约束，返回解析后的依赖声明
+// 调用方需持有pm.mutex
+func (pm *PluginManager) checkCompatibilityLocked(plugin Plugin) ([]Dependency, error) {
+	name := plugin.Name()
+
+	// 检查冲突插件
+	for _, conflictName := range plugin.GetConflicts() {
+		if _, exists := pm.plugins[conflictName]; exists {
+			return nil, fmt.Errorf("插件 '%s' 与已注册的插件 '%s' 冲突", name, conflictName)
+		}
+	}
+
+	// 检查依赖插件及其版本
+	specs, err := parseDependencies(plugin)
+	if err != nil {
+		return nil, pkg.NewPluginDependencyError(err.Error(), nil)
+	}
+	for _, dep := range specs {
+		depInfo, exists := pm.plugins[dep.Name]
+		if !exists {
+			if dep.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("依赖的插件未注册: %s", dep.Name)
+		}
+		if err := checkDependencyVersion(name, dep, depInfo.Plugin); err != nil {
+			return nil, pkg.NewPluginDependencyError(err.Error(), nil)
+		}
+	}
+
+	// 检查已注册插件（如可选依赖）对当前插件版本的约束
+	for otherName, otherInfo := range pm.plugins {
+		for _, dep := range otherInfo.DependencySpecs {
+			if dep.Name != name {
+				continue
+			}
+			if err := checkDependencyVersion(otherName, dep, plugin); err != nil {
+				return nil, pkg.NewPluginDependencyError(err.Error(), nil)
+			}
+		}
+	}
+
+	return specs, nil
+}
+
 // EnablePlugin 启用插件
+// 依赖的插件未启用时返回错误，需要同时启用依赖时使用EnablePluginCascade
+// 设置了状态同步时，启用后保存状态并广播给其他实例
 func (pm *PluginManager) EnablePlugin(name string) error {
-	pm.mutex.Lock()
-	defer pm.mutex.Unlock()
+	_, err := pm.changeStates(func() ([]CascadeStep, error) {
+		pm.mutex.Lock()
+		defer pm.mutex.Unlock()
+		if err := pm.enablePluginLocked(name); err != nil {
+			return nil, err
+		}
+		return []CascadeStep{{Plugin: name, Action: CascadeEnable, Reason: "请求启用"}}, nil
+	})
+	return err
+}
 
+// enablePluginLocked 启用插件，调用方需持有pm.mutex写锁
+func (pm *PluginManager) enablePluginLocked(name string) error {
 	info, exists := pm.plugins[name]
 	if !exists {
 		return fmt.Errorf("插件 '%s' 不存在", name)
@@ -173,6 +319,9 @@ func (pm *PluginManager) EnablePlugin(name string) error {
 	if info.IsEnabled {
 		return nil // 已经是启用状态
 	}
+	if err := pm.checkTransitionLocked(name, StateEnabled); err != nil {
+		return err
+	}
 
 	// 检查依赖是否可用
 	for _, depName := range info.Dependencies {
@@ -186,38 +335,64 @@ func (pm *PluginManager) EnablePlugin(name string) error {
 	success := true
 
 	// 调用插件的OnEnable方法
-	if err := info.Plugin.OnEnable(); err != nil {
+	if err := safeCall(name, "OnEnable", info.Plugin.OnEnable); err != nil {
 		success = false
 		metrics.RecordPluginError(name, "enable_failed")
-		return fmt.Errorf("插件 '%s' 启用回调失败: %w", name, err)
+		err = fmt.Errorf("插件 '%s' 启用回调失败: %w", name, err)
+		pm.recordFailureLocked(name, "enable", err)
+		return err
 	}
 
-	// 启用插件
-	info.IsEnabled = true
-	pm.plugins[name] = info
-
-	// 如果路由引擎已设置，注册路由
+	// 如果路由引擎已设置，先注册路由；失败时调用OnDisable回滚，插件保持禁用
 	if pm.router != nil && !info.IsRegistered {
 		if err := pm.registerPluginRoutes(name); err != nil {
 			success = false
 			metrics.RecordPluginError(name, "route_registration_failed")
-			return fmt.Errorf("插件 '%s' 路由注册失败: %w", name, err)
+			if disableErr := safeCall(name, "OnDisable", info.Plugin.OnDisable); disableErr != nil {
+				pkg.Warn("回滚启用时调用禁用回调失败", zap.String("plugin", name), zap.Error(disableErr))
+			}
+			err = fmt.Errorf("插件 '%s' 路由注册失败: %w", name, err)
+			pm.recordFailureLocked(name, "enable", err)
+			return err
 		}
+		info = pm.plugins[name]
 	}
 
+	// 启用插件
+	info.IsEnabled = true
+	pm.plugins[name] = info
+	pm.setServiceDisabled(name, false)
+	pm.setStateLocked(name, StateEnabled, "enable", nil)
+
+	// 重新启用后重置熔断器（包括因连续失败被自动禁用的插件）
+	pm.removeBreaker(name)
+
 	// 记录插件执行时间和结果
 	duration := time.Since(startTime)
 	metrics.RecordPluginExecution(name, success, duration)
 	metrics.RecordPluginMethodCall(name, "OnEnable", success)
 
+	pm.publishLifecycle(TopicPluginEnabled, info.Plugin)
 	return nil
 }
 
 // DisablePlugin 禁用插件
+// 有已启用的插件依赖当前插件时返回错误，需要同时禁用依赖方时使用DisablePluginCascade
+// 设置了状态同步时，禁用后保存状态并广播给其他实例
 func (pm *PluginManager) DisablePlugin(name string) error {
-	pm.mutex.Lock()
-	defer pm.mutex.Unlock()
+	_, err := pm.changeStates(func() ([]CascadeStep, error) {
+		pm.mutex.Lock()
+		defer pm.mutex.Unlock()
+		if err := pm.disablePluginLocked(name); err != nil {
+			return nil, err
+		}
+		return []CascadeStep{{Plugin: name, Action: CascadeDisable, Reason: "请求禁用"}}, nil
+	})
+	return err
+}
 
+// disablePluginLocked 禁用插件，调用方需持有pm.mutex写锁
+func (pm *PluginManager) disablePluginLocked(name string) error {
 	info, exists := pm.plugins[name]
 	if !exists {
 		return fmt.Errorf("插件 '%s' 不存在", name)
@@ -226,6 +401,9 @@ func (pm *PluginManager) DisablePlugin(name string) error {
 	if !info.IsEnabled {
 		return nil // 已经是禁用状态
 	}
+	if err := pm.checkCanaryLocked(name); err != nil {
+		return err
+	}
 
 	// 检查是否有其他插件依赖当前插件
 	for pluginName, pluginInfo := range pm.plugins {
@@ -241,29 +419,38 @@ func (pm *PluginManager) DisablePlugin(name string) error {
 	startTime := time.Now()
 	success := true
 
-	// 调用插件的OnDisable方法
-	if err := info.Plugin.OnDisable(); err != nil {
+	// 调用插件的OnDisable方法，失败时回滚为启用状态
+	pm.setStateLocked(name, StateDisabling, "disable", nil)
+	if err := safeCall(name, "OnDisable", info.Plugin.OnDisable); err != nil {
 		success = false
 		metrics.RecordPluginError(name, "disable_failed")
-		return fmt.Errorf("插件 '%s' 禁用回调失败: %w", name, err)
+		err = fmt.Errorf("插件 '%s' 禁用回调失败: %w", name, err)
+		pm.setStateLocked(name, StateEnabled, "disable", err)
+		return err
 	}
 
 	// 禁用插件
 	info.IsEnabled = false
 	pm.plugins[name] = info
+	pm.setStateLocked(name, StateDisabled, "disable", nil)
+
+	// 路由表保留，分发器会检查IsEnabled状态，禁用期间请求返回503
 
-	// 注意：Gin不支持动态删除路由，这里只能标记为禁用
-	// 在ExecutePlugin等方法中会检查IsEnabled状态
+	// 移除插件的事件订阅，并使其发布的服务失效
+	pm.Events().UnsubscribeOwner(name)
+	pm.setServiceDisabled(name, true)
 
 	// 记录插件执行时间和结果
 	duration := time.Since(startTime)
 	metrics.RecordPluginExecution(name, success, duration)
 	metrics.RecordPluginMethodCall(name, "OnDisable", success)
 
+	pm.publishLifecycle(TopicPluginDisabled, info.Plugin)
 	return nil
 }
 
 // ReloadPlugin 重新加载插件
+// 关闭失败时插件保持原状态；重新初始化失败时插件进入failed状态，可再次重新加载或注销
 // 注意：这是一个简化实现，在实际生产环境中可能需要结合插件文件监控等功能
 func (pm *PluginManager) ReloadPlugin(name string) error {
 	pm.mutex.Lock()
@@ -279,62 +466,107 @@ func (pm *PluginManager) ReloadPlugin(name string) error {
 		metrics.RecordPluginReload(name, success)
 		return fmt.Errorf("插件 '%s' 不存在", name)
 	}
+	if _, exists := pm.canaries[name]; exists {
+		metrics.RecordPluginReload(name, false)
+		return pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' 正在灰度发布，请先提升或回滚灰度版本", name), nil)
+	}
+	if err := pm.checkTransitionLocked(name, StateInitializing); err != nil {
+		metrics.RecordPluginReload(name, false)
+		return err
+	}
 
 	plugin := info.Plugin
 	isEnabled := info.IsEnabled
+	previous := info.State
 
-	// 先禁用插件
-	if isEnabled {
-		info.IsEnabled = false
-		pm.plugins[name] = info
+	// 从failed状态恢复时，按失败前的启用状态恢复
+	if previous == StateFailed {
+		isEnabled = pm.lifecycles[name].resumeEnabled
 	}
 
-	// 关闭当前插件
-	if err := plugin.Shutdown(); err != nil {
-		success = false
-		metrics.RecordPluginReload(name, success)
-		metrics.RecordPluginError(name, "shutdown_during_reload_failed")
-		return fmt.Errorf("插件 '%s' 关闭失败: %w", name, err)
+	// 解析依赖声明，失败时不影响正在运行的插件
+	specs, err := parseDependencies(plugin)
+	if err != nil {
+		metrics.RecordPluginReload(name, false)
+		appErr := pkg.NewPluginDependencyError(err.Error(), nil)
+		pm.recordFailureLocked(name, "reload", appErr)
+		return appErr
 	}
 
-	// 从管理器中移除插件
-	delete(pm.plugins, name)
+	// 关闭当前插件（初始化失败的插件无需关闭），失败时插件保持原状态
+	if previous != StateFailed {
+		if err := safeCall(name, "Shutdown", plugin.Shutdown); err != nil {
+			success = false
+			metrics.RecordPluginReload(name, success)
+			metrics.RecordPluginError(name, "shutdown_during_reload_failed")
+			err = fmt.Errorf("插件 '%s' 关闭失败: %w", name, err)
+			pm.recordFailureLocked(name, "reload", err)
+			return err
+		}
+	}
+
+	// 禁用插件并清理旧实例的事件订阅和发布的服务（插件会在Init中重新订阅和发布）
+	info.IsEnabled = false
+	pm.plugins[name] = info
+	pm.Events().UnsubscribeOwner(name)
+	pm.removeService(name)
+	pm.setStateLocked(name, StateInitializing, "reload", nil)
 
 	// 重新初始化插件
-	if err := plugin.Init(); err != nil {
+	if err := pm.callInit(name, plugin); err != nil {
+		info.IsRegistered = false
+		pm.plugins[name] = info
+		delete(pm.routeTables, name)
 		success = false
 		metrics.RecordPluginReload(name, success)
 		metrics.RecordPluginError(name, "init_during_reload_failed")
-		return fmt.Errorf("插件 '%s' 重新初始化失败: %w", name, err)
+		err = fmt.Errorf("插件 '%s' 重新初始化失败: %w", name, err)
+		pm.setStateLocked(name, StateFailed, "reload", err)
+		pm.lifecycles[name].resumeEnabled = isEnabled
+		return err
 	}
 
 	// 重新创建插件信息
 	newInfo := PluginInfo{
-		Plugin:       plugin,
-		Routes:       plugin.GetRoutes(),
-		Dependencies: plugin.GetDependencies(),
-		Conflicts:    plugin.GetConflicts(),
-		IsRegistered: false,
-		IsEnabled:    isEnabled,
+		Plugin:          plugin,
+		Routes:          plugin.GetRoutes(),
+		Dependencies:    requiredDependencyNames(specs),
+		Conflicts:       plugin.GetConflicts(),
+		IsRegistered:    false,
+		IsEnabled:       isEnabled,
+		DependencySpecs: specs,
+		Manifest:        info.Manifest,
 	}
 
 	pm.plugins[name] = newInfo
+	pm.setStateLocked(name, StateReady, "reload", nil)
+	pm.removeBreaker(name)
+	pm.removeBulkheads(name)
 
-	// 如果路由引擎已设置且插件被启用，重新注册路由
+	// 如果路由引擎已设置且插件被启用，重新注册路由；失败时继续使用原路由表
 	if pm.router != nil && isEnabled {
 		if err := pm.registerPluginRoutes(name); err != nil {
 			success = false
 			metrics.RecordPluginReload(name, success)
 			metrics.RecordPluginError(name, "route_registration_during_reload_failed")
-			return fmt.Errorf("插件 '%s' 路由重新注册失败: %w", name, err)
+			err = fmt.Errorf("插件 '%s' 路由重新注册失败: %w", name, err)
+			pm.setStateLocked(name, StateEnabled, "reload", err)
+			return err
 		}
 	}
 
+	if isEnabled {
+		pm.setStateLocked(name, StateEnabled, "reload", nil)
+	} else {
+		pm.setStateLocked(name, StateDisabled, "reload", nil)
+	}
+
 	// 记录插件执行时间和结果
 	duration := time.Since(startTime)
 	metrics.RecordPluginExecution(name, success, duration)
 	metrics.RecordPluginReload(name, success)
 
+	pm.publishLifecycle(TopicPluginReloaded, plugin)
 	return nil
 }
 
@@ -351,6 +583,9 @@ func (pm *PluginManager) GetPluginStatus(name string) (string, bool) {
 	if info.IsEnabled {
 		return "enabled", true
 	}
+	if info.State == StateFailed {
+		return string(StateFailed), true
+	}
 	return "disabled", true
 }
 
@@ -367,6 +602,7 @@ func (pm *PluginManager) GetAllPluginsInfo() []PluginInfo {
 }
 
 // registerPluginRoutes 注册单个插件的路由
+// 构建新的路由表并整体替换旧表，重复注册（如重载后）不会产生路由冲突
 func (pm *PluginManager) registerPluginRoutes(name string) error {
 	info, exists := pm.plugins[name]
 	if !exists {
@@ -377,67 +613,20 @@ func (pm *PluginManager) registerPluginRoutes(name string) error {
 		return fmt.Errorf("路由引擎未初始化")
 	}
 
-	// 如果路由已经注册，先清理
-	if info.IsRegistered {
-		// 注意：Gin不支持动态删除路由，这里只能标记为未注册
-		// 实际生产环境中可能需要重启服务或使用其他路由方案
-		info.IsRegistered = false
-	}
-
-	plugin := info.Plugin
-	pluginName := plugin.Name()
-
-	// 创建插件路由组
-	pluginGroup := pm.router.Group(fmt.Sprintf("/plugins/%s", pluginName))
-
-	// 添加插件默认中间件
-	if defaultMiddlewares := plugin.GetDefaultMiddlewares(); len(defaultMiddlewares) > 0 {
-		pluginGroup.Use(defaultMiddlewares...)
+	table, routes, err := pm.buildRouteTable(info.Plugin)
+	if err != nil {
+		return err
 	}
 
-	// 获取插件路由
-	routes := plugin.GetRoutes()
-
-	// 如果没有通过GetRoutes提供路由，则回退到旧版的RegisterRoutes方法
-	if len(routes) == 0 {
-		plugin.RegisterRoutes(pm.router)
-		info.IsRegistered = true
-		pm.plugins[name] = info
-		return nil
+	if pm.routeTables == nil {
+		pm.routeTables = make(map[string]*gin.Engine)
 	}
+	pm.routeTables[name] = table
 
-	// 注册每个路由
-	for _, route := range routes {
-		// 创建路由处理函数链
-		handlers := append(route.Middlewares, route.Handler)
-
-		// 如果需要认证，则在处理链前添加认证中间件
-		if route.AuthRequired {
-			handlers = append([]gin.HandlerFunc{middleware.AuthMiddleware()}, handlers...)
-		}
-
-		// 根据HTTP方法注册路由
-		switch route.Method {
-		case "GET":
-			pluginGroup.GET(route.Path, handlers...)
-		case "POST":
-			pluginGroup.POST(route.Path, handlers...)
-		case "PUT":
-			pluginGroup.PUT(route.Path, handlers...)
-		case "DELETE":
-			pluginGroup.DELETE(route.Path, handlers...)
-		case "PATCH":
-			pluginGroup.PATCH(route.Path, handlers...)
-		case "OPTIONS":
-			pluginGroup.OPTIONS(route.Path, handlers...)
-		default:
-			return fmt.Errorf("不支持的HTTP方法: %s", route.Method)
-		}
-
-		// 更新路由信息
+	// 更新路由信息
+	if len(routes) > 0 {
 		info.Routes = routes
 	}
-
 	info.IsRegistered = true
 	pm.plugins[name] = info
 	return nil
@@ -453,17 +642,34 @@ func (pm *PluginManager) Unregister(name string) error {
 		return fmt.Errorf("插件 '%s' 不存在", name)
 	}
 
-	// 关闭插件
+	// 关闭插件（初始化失败的插件无需关闭）
 	plugin := info.Plugin
-	if err := plugin.Shutdown(); err != nil {
-		return fmt.Errorf("插件 '%s' 关闭失败: %w", name, err)
+	if info.State != StateFailed {
+		if err := safeCall(name, "Shutdown", plugin.Shutdown); err != nil {
+			err = fmt.Errorf("插件 '%s' 关闭失败: %w", name, err)
+			pm.recordFailureLocked(name, "unregister", err)
+			return err
+		}
 	}
 
-	// 标记路由为未注册
-	info.IsRegistered = false
+	// 同时移除灰度版本，关闭失败不影响注销
+	if release := pm.takeCanaryLocked(name); release != nil {
+		_ = pm.discardRelease(name, release)
+	}
+
+	// 移除插件路由表、事件订阅、发布的服务、生效配置、熔断器和舱壁
+	delete(pm.routeTables, name)
+	pm.Events().UnsubscribeOwner(name)
+	pm.removeService(name)
+	pm.removeConfig(name)
+	pm.removeBreaker(name)
+	pm.removeBulkheads(name)
 
-	// 从管理器中删除插件
+	// 从管理器中删除插件，保留状态转换历史
 	delete(pm.plugins, name)
+	pm.setStateLocked(name, StateUnloaded, "unregister", nil)
+
+	pm.publishLifecycle(TopicPluginUnregistered, plugin)
 	return nil
 }
 
@@ -540,57 +746,37 @@ func (pm *PluginManager) GetAllRoutes() map[string][]Route {
 }
 
 // ExecutePlugin 执行插件功能
+// 使用后台上下文执行，仍受插件默认超时约束；需要传递请求元数据或取消信号时使用ExecutePluginContext
 func (pm *PluginManager) ExecutePlugin(name string, params map[string]interface{}) (interface{}, error) {
-	pm.mutex.RLock()
-	info, exists := pm.plugins[name]
-	pm.mutex.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("插件 '%s' 不存在", name)
-	}
-
-	// 检查插件是否启用
-	if !info.IsEnabled {
-		return nil, fmt.Errorf("插件 '%s' 已被禁用", name)
-	}
-
-	startTime := time.Now()
-	success := true
-
-	// 调用插件的Execute方法
-	result, err := info.Plugin.Execute(params)
-	if err != nil {
-		success = false
-		metrics.RecordPluginError(name, "execute_failed")
-	}
-
-	// 记录插件执行时间和结果
-	duration := time.Since(startTime)
-	metrics.RecordPluginExecution(name, success, duration)
-	metrics.RecordPluginMethodCall(name, "Execute", success)
-
-	return result, err
+	return pm.ExecutePluginContext(context.Background(), name, params)
 }
 
 // RegisterPlugins 批量注册插件，自动处理依赖顺序
+// 同名插件可传入多个版本，解析器会选出满足所有版本约束的一组插件，无法满足时返回PluginDependencyError
 func (pm *PluginManager) RegisterPlugins(plugins []Plugin) error {
-	// 1. 构建依赖图
+	// 1. 解析版本约束，为每个插件名称确定版本
+	resolved, err := pm.resolvePlugins(plugins)
+	if err != nil {
+		return err
+	}
+
+	// 2. 构建依赖图（可选依赖同样参与排序，以便在同一批次中先于使用者注册）
 	dependencyGraph := make(map[string][]string)
 	pluginMap := make(map[string]Plugin)
 
-	for _, plugin := range plugins {
+	for _, plugin := range resolved {
 		name := plugin.Name()
 		pluginMap[name] = plugin
-		dependencyGraph[name] = plugin.GetDependencies()
+		dependencyGraph[name] = DependencyNames(plugin.GetDependencies())
 	}
 
-	// 2. 拓扑排序
+	// 3. 拓扑排序
 	sortedNames, err := topologicalSort(dependencyGraph)
 	if err != nil {
 		return err
 	}
 
-	// 3. 按排序结果注册插件
+	// 4. 按排序结果注册插件
 	for _, name := range sortedNames {
 		if err := pm.Register(pluginMap[name]); err != nil {
 			return err
@@ -675,6 +861,14 @@ func (pm *PluginManager) CheckDependencies() []error {
 			}
 		}
 
+		for _, dep := range info.DependencySpecs {
+			if depInfo, exists := pm.plugins[dep.Name]; exists {
+				if err := checkDependencyVersion(name, dep, depInfo.Plugin); err != nil {
+					errors = append(errors, err)
+				}
+			}
+		}
+
 		for _, conflictName := range info.Conflicts {
 			if _, exists := pm.plugins[conflictName]; exists {
 				errors = append(errors, fmt.Errorf("插件 '%s' 与插件 '%s' 冲突", name, conflictName))
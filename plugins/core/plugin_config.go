@@ -0,0 +1,324 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"weave/config"
+	"weave/models"
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maskedValue 敏感配置项对外展示的掩码，与config.SanitizeConfig保持一致
+const maskedValue = "***"
+
+// Configurable 可配置插件接口（可选）
+// 插件通过ConfigSchema声明JSON Schema，注册时管理器按"Schema默认值 < 配置文件plugins.<插件名> < 数据库"
+// 的优先级合并配置，校验通过后在Init之前调用OnConfigChange；通过管理接口更新配置时也会再次调用
+type Configurable interface {
+	ConfigSchema() map[string]interface{}               // 配置的JSON Schema（type为object）
+	OnConfigChange(config map[string]interface{}) error // 配置生效回调，返回错误时本次配置不生效
+}
+
+// PluginConfigStore 插件配置持久化接口
+type PluginConfigStore interface {
+	Load(name string) (map[string]interface{}, bool, error)
+	Save(name string, cfg map[string]interface{}, updatedBy uint) error
+}
+
+// dbPluginConfigStore 基于数据库的插件配置存储（plugin_configs表）
+type dbPluginConfigStore struct{}
+
+// Load 读取插件配置，数据库未初始化或没有记录时返回false
+func (dbPluginConfigStore) Load(name string) (map[string]interface{}, bool, error) {
+	if pkg.DB == nil {
+		return nil, false, nil
+	}
+	var record models.PluginConfig
+	if err := pkg.DB.Where("plugin_name = ?", name).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	cfg := make(map[string]interface{})
+	if record.Config != "" {
+		if err := json.Unmarshal([]byte(record.Config), &cfg); err != nil {
+			return nil, false, fmt.Errorf("解析插件 '%s' 的配置失败: %w", name, err)
+		}
+	}
+	return cfg, true, nil
+}
+
+// Save 保存插件配置，已有记录时更新
+func (dbPluginConfigStore) Save(name string, cfg map[string]interface{}, updatedBy uint) error {
+	if pkg.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化插件 '%s' 的配置失败: %w", name, err)
+	}
+
+	var record models.PluginConfig
+	err = pkg.DB.Where("plugin_name = ?", name).First(&record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	record.PluginName = name
+	record.Config = string(data)
+	record.UpdatedBy = updatedBy
+	return pkg.DB.Save(&record).Error
+}
+
+// SetConfigStore 设置插件配置存储，默认使用数据库存储
+func (pm *PluginManager) SetConfigStore(store PluginConfigStore) {
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+	pm.configStore = store
+}
+
+// configStoreLocked 返回当前配置存储，调用方需持有configMu
+func (pm *PluginManager) configStoreLocked() PluginConfigStore {
+	if pm.configStore == nil {
+		return dbPluginConfigStore{}
+	}
+	return pm.configStore
+}
+
+// applyInitialConfig 合并并校验可配置插件的初始配置，在Init之前调用OnConfigChange
+func (pm *PluginManager) applyInitialConfig(plugin Plugin) error {
+	configurable, ok := plugin.(Configurable)
+	if !ok {
+		return nil
+	}
+	name := plugin.Name()
+
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+
+	merged, err := pm.initialConfigLocked(name, configurable)
+	if err != nil {
+		return err
+	}
+
+	if pm.configs == nil {
+		pm.configs = make(map[string]map[string]interface{})
+	}
+	pm.configs[name] = merged
+	return nil
+}
+
+// applyCanaryConfig 为灰度版本应用初始配置，不改变稳定版本的生效配置
+func (pm *PluginManager) applyCanaryConfig(plugin Plugin) error {
+	configurable, ok := plugin.(Configurable)
+	if !ok {
+		return nil
+	}
+
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+
+	_, err := pm.initialConfigLocked(plugin.Name(), configurable)
+	return err
+}
+
+// initialConfigLocked 合并配置文件与持久化配置，按Schema校验后调用OnConfigChange，调用方需持有configMu
+func (pm *PluginManager) initialConfigLocked(name string, configurable Configurable) (map[string]interface{}, error) {
+	schema := configurable.ConfigSchema()
+
+	merged := make(map[string]interface{})
+	// Viper会将配置段名称转换为小写
+	for key, value := range config.Config.Plugins.Settings[strings.ToLower(name)] {
+		merged[key] = value
+	}
+	stored, found, err := pm.configStoreLocked().Load(name)
+	if err != nil {
+		pkg.Warn("加载插件持久化配置失败，使用配置文件中的配置", zap.String("plugin", name), zap.Error(err))
+	} else if found {
+		for key, value := range stored {
+			merged[key] = value
+		}
+	}
+	merged = ApplySchemaDefaults(schema, merged)
+
+	if err := ValidateAgainstSchema(schema, merged); err != nil {
+		return nil, err
+	}
+	if err := configurable.OnConfigChange(copyConfig(merged)); err != nil {
+		return nil, fmt.Errorf("配置变更回调失败: %w", err)
+	}
+	return merged, nil
+}
+
+// removeConfig 移除已注销插件的生效配置（持久化的配置保留，重新注册时生效）
+func (pm *PluginManager) removeConfig(name string) {
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+	delete(pm.configs, name)
+}
+
+// configurablePlugin 获取已注册的可配置插件
+func (pm *PluginManager) configurablePlugin(name string) (Configurable, error) {
+	pm.mutex.RLock()
+	info, exists := pm.plugins[name]
+	pm.mutex.RUnlock()
+
+	if !exists {
+		return nil, pkg.NewPluginNotFoundError(fmt.Sprintf("插件 '%s' 不存在", name), nil)
+	}
+	configurable, ok := info.Plugin.(Configurable)
+	if !ok {
+		return nil, pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' 不支持配置", name), nil)
+	}
+	return configurable, nil
+}
+
+// GetPluginConfig 获取插件当前生效的配置（敏感配置项已隐藏）及其JSON Schema
+func (pm *PluginManager) GetPluginConfig(name string) (map[string]interface{}, map[string]interface{}, error) {
+	configurable, err := pm.configurablePlugin(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema := configurable.ConfigSchema()
+
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+
+	return maskConfig(schema, pm.configs[name]), schema, nil
+}
+
+// UpdatePluginConfig 校验并更新插件配置，返回更新后的配置（敏感配置项已隐藏）
+// 新配置整体替换旧配置；值为"***"的敏感配置项保留原值，便于客户端回传GetPluginConfig的结果。
+// 校验失败返回BadRequest错误；配置在插件回调成功后持久化，持久化失败时回滚插件配置
+func (pm *PluginManager) UpdatePluginConfig(name string, updates map[string]interface{}, updatedBy uint) (map[string]interface{}, error) {
+	configurable, err := pm.configurablePlugin(name)
+	if err != nil {
+		return nil, err
+	}
+	schema := configurable.ConfigSchema()
+	// 在获取configMu之前读取灰度版本，与注册流程保持pm.mutex先于configMu的加锁顺序
+	canary, _ := pm.canaryPlugin(name).(Configurable)
+
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+
+	previous := pm.configs[name]
+	merged := ApplySchemaDefaults(schema, restoreMaskedSecrets(schema, updates, previous))
+	if err := ValidateAgainstSchema(schema, merged); err != nil {
+		return nil, pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' %s", name, err.Error()), err)
+	}
+
+	if err := configurable.OnConfigChange(copyConfig(merged)); err != nil {
+		return nil, pkg.NewPluginError(fmt.Sprintf("插件 '%s' 配置变更回调失败", name), err)
+	}
+
+	if err := pm.configStoreLocked().Save(name, merged, updatedBy); err != nil {
+		if previous != nil {
+			if rollbackErr := configurable.OnConfigChange(copyConfig(previous)); rollbackErr != nil {
+				pkg.Error("回滚插件配置失败", zap.String("plugin", name), zap.Error(rollbackErr))
+			}
+		}
+		return nil, pkg.NewDatabaseError(fmt.Sprintf("保存插件 '%s' 的配置失败", name), err)
+	}
+
+	if pm.configs == nil {
+		pm.configs = make(map[string]map[string]interface{})
+	}
+	pm.configs[name] = merged
+
+	// 灰度版本同步使用新配置，失败时不影响稳定版本
+	if canary != nil {
+		if err := canary.OnConfigChange(copyConfig(merged)); err != nil {
+			metrics.RecordPluginError(name, "canary_config_change_failed")
+			pkg.Warn("灰度版本配置变更回调失败", zap.String("plugin", name), zap.Error(err))
+		}
+	}
+
+	pm.Publish(TopicPluginConfigChanged, PluginConfigChangedEvent{Name: name, UpdatedBy: updatedBy})
+	return maskConfig(schema, merged), nil
+}
+
+// isSecretKey 判断配置项是否需要隐藏
+func isSecretKey(propSchema map[string]interface{}, key string) bool {
+	return isSecretSchema(propSchema) || config.IsSensitiveKey(key)
+}
+
+// propertySchema 获取对象Schema中指定属性的Schema
+func propertySchema(schema map[string]interface{}, key string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	propSchema, _ := properties[key].(map[string]interface{})
+	return propSchema
+}
+
+// maskConfig 返回隐藏了敏感配置项的副本，未设置的敏感项保持为空以便区分
+func maskConfig(schema map[string]interface{}, cfg map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(cfg))
+	for key, value := range cfg {
+		propSchema := propertySchema(schema, key)
+		if nested, ok := value.(map[string]interface{}); ok {
+			masked[key] = maskConfig(propSchema, nested)
+			continue
+		}
+		if isSecretKey(propSchema, key) && value != nil && value != "" {
+			masked[key] = maskedValue
+			continue
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+// restoreMaskedSecrets 将更新中值为掩码的敏感配置项替换为原值
+func restoreMaskedSecrets(schema map[string]interface{}, updates, previous map[string]interface{}) map[string]interface{} {
+	restored := make(map[string]interface{}, len(updates))
+	for key, value := range updates {
+		propSchema := propertySchema(schema, key)
+		if nested, ok := value.(map[string]interface{}); ok {
+			prevNested, _ := previous[key].(map[string]interface{})
+			restored[key] = restoreMaskedSecrets(propSchema, nested, prevNested)
+			continue
+		}
+		if value == maskedValue && isSecretKey(propSchema, key) {
+			if prev, exists := previous[key]; exists {
+				restored[key] = prev
+				continue
+			}
+		}
+		restored[key] = value
+	}
+	return restored
+}
+
+// copyConfig 深拷贝配置，避免插件修改管理器保存的配置
+func copyConfig(cfg map[string]interface{}) map[string]interface{} {
+	if cfg == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(cfg))
+	for key, value := range cfg {
+		copied[key] = copyConfigValue(value)
+	}
+	return copied
+}
+
+// copyConfigValue 深拷贝配置值
+func copyConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyConfig(v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = copyConfigValue(item)
+		}
+		return items
+	}
+	return value
+}
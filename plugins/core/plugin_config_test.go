@@ -0,0 +1,235 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"weave/config"
+	"weave/pkg"
+)
+
+// memoryConfigStore 测试用内存配置存储
+type memoryConfigStore struct {
+	data    map[string]map[string]interface{}
+	saveErr error
+}
+
+func (s *memoryConfigStore) Load(name string) (map[string]interface{}, bool, error) {
+	cfg, ok := s.data[name]
+	return cfg, ok, nil
+}
+
+func (s *memoryConfigStore) Save(name string, cfg map[string]interface{}, updatedBy uint) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	if s.data == nil {
+		s.data = make(map[string]map[string]interface{})
+	}
+	s.data[name] = cfg
+	return nil
+}
+
+// configurableTestPlugin 实现Configurable接口的测试插件
+type configurableTestPlugin struct {
+	testPlugin
+	applied  []map[string]interface{}
+	initSeen map[string]interface{}
+}
+
+func (p *configurableTestPlugin) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"endpoint"},
+		"properties": map[string]interface{}{
+			"endpoint": map[string]interface{}{"type": "string", "pattern": "^https?://"},
+			"retries":  map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 5, "default": 3},
+			"mode":     map[string]interface{}{"type": "string", "enum": []interface{}{"fast", "safe"}, "default": "safe"},
+			"api_key":  map[string]interface{}{"type": "string"},
+			"token":    map[string]interface{}{"type": "string", "writeOnly": true},
+		},
+		"additionalProperties": false,
+	}
+}
+
+func (p *configurableTestPlugin) OnConfigChange(cfg map[string]interface{}) error {
+	p.applied = append(p.applied, cfg)
+	return nil
+}
+
+func (p *configurableTestPlugin) Init() error {
+	p.initCalled++
+	if len(p.applied) > 0 {
+		p.initSeen = p.applied[len(p.applied)-1]
+	}
+	return nil
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	schema := (&configurableTestPlugin{}).ConfigSchema()
+
+	valid := map[string]interface{}{"endpoint": "https://example.com", "retries": 2, "mode": "fast"}
+	if err := ValidateAgainstSchema(schema, valid); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+	// JSON数字解析为float64，整数值仍视为integer
+	if err := ValidateAgainstSchema(schema, map[string]interface{}{"endpoint": "http://x", "retries": float64(1)}); err != nil {
+		t.Fatalf("expected float64 integer to be accepted, got %v", err)
+	}
+
+	cases := map[string]map[string]interface{}{
+		"endpoint": {"retries": 1},
+		"retries":  {"endpoint": "http://x", "retries": 9},
+		"mode":     {"endpoint": "http://x", "mode": "slow"},
+		"格式":       {"endpoint": "ftp://x"},
+		"unknown":  {"endpoint": "http://x", "unknown": true},
+		"integer":  {"endpoint": "http://x", "retries": 1.5},
+	}
+	for want, cfg := range cases {
+		err := ValidateAgainstSchema(schema, cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error mentioning %q for %v, got %v", want, cfg, err)
+		}
+	}
+}
+
+func TestApplySchemaDefaults(t *testing.T) {
+	schema := (&configurableTestPlugin{}).ConfigSchema()
+	cfg := ApplySchemaDefaults(schema, map[string]interface{}{"endpoint": "http://x", "mode": "fast"})
+	if cfg["retries"] != 3 || cfg["mode"] != "fast" {
+		t.Fatalf("unexpected defaults applied: %v", cfg)
+	}
+}
+
+func TestRegisterAppliesMergedConfigBeforeInit(t *testing.T) {
+	config.Config.Plugins.Settings = map[string]map[string]interface{}{
+		"cfg": {"endpoint": "http://yaml", "mode": "fast"},
+	}
+	defer func() { config.Config.Plugins.Settings = make(map[string]map[string]interface{}) }()
+
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetConfigStore(&memoryConfigStore{data: map[string]map[string]interface{}{
+		"cfg": {"endpoint": "https://db"},
+	}})
+
+	plugin := &configurableTestPlugin{testPlugin: testPlugin{name: "cfg"}}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	want := map[string]interface{}{"endpoint": "https://db", "mode": "fast", "retries": 3}
+	for key, value := range want {
+		if plugin.initSeen[key] != value {
+			t.Fatalf("expected %s=%v applied before Init, got %v", key, value, plugin.initSeen)
+		}
+	}
+}
+
+func TestRegisterRejectsInvalidConfig(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetConfigStore(&memoryConfigStore{})
+
+	plugin := &configurableTestPlugin{testPlugin: testPlugin{name: "cfg"}}
+	err := pm.Register(plugin)
+	if err == nil || !strings.Contains(err.Error(), "配置无效") {
+		t.Fatalf("expected missing required config to fail register, got %v", err)
+	}
+	if plugin.initCalled != 0 {
+		t.Fatalf("Init should not be called with invalid config")
+	}
+	if _, exists := pm.GetPlugin("cfg"); exists {
+		t.Fatalf("plugin should not be registered")
+	}
+}
+
+func TestUpdatePluginConfigMasksAndPersists(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	store := &memoryConfigStore{data: map[string]map[string]interface{}{
+		"cfg": {"endpoint": "http://a", "api_key": "k1", "token": "t1"},
+	}}
+	pm.SetConfigStore(store)
+	plugin := &configurableTestPlugin{testPlugin: testPlugin{name: "cfg"}}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	cfg, schema, err := pm.GetPluginConfig("cfg")
+	if err != nil {
+		t.Fatalf("get config error: %v", err)
+	}
+	if cfg["api_key"] != "***" || cfg["token"] != "***" || cfg["endpoint"] != "http://a" || schema == nil {
+		t.Fatalf("expected secrets masked, got %v", cfg)
+	}
+
+	events := make(chan Event, 1)
+	_, _ = pm.Subscribe("observer", TopicPluginConfigChanged, func(e Event) { events <- e })
+
+	// 回传掩码值时保留原密钥
+	cfg["endpoint"] = "https://b"
+	cfg["retries"] = float64(1)
+	updated, err := pm.UpdatePluginConfig("cfg", cfg, 7)
+	if err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+	if updated["api_key"] != "***" {
+		t.Fatalf("expected masked response, got %v", updated)
+	}
+	saved := store.data["cfg"]
+	if saved["api_key"] != "k1" || saved["token"] != "t1" || saved["endpoint"] != "https://b" {
+		t.Fatalf("expected secrets preserved and update persisted, got %v", saved)
+	}
+	last := plugin.applied[len(plugin.applied)-1]
+	if last["endpoint"] != "https://b" || last["api_key"] != "k1" {
+		t.Fatalf("expected plugin to receive new config, got %v", last)
+	}
+	e := waitEvent(t, events)
+	if payload, ok := e.Payload.(PluginConfigChangedEvent); !ok || payload.Name != "cfg" || payload.UpdatedBy != 7 {
+		t.Fatalf("unexpected config changed event: %+v", e)
+	}
+
+	// 校验失败返回BadRequest，配置保持不变
+	_, err = pm.UpdatePluginConfig("cfg", map[string]interface{}{"endpoint": "https://b", "retries": 10}, 7)
+	if !errors.Is(err, &pkg.AppError{Code: pkg.ErrBadRequest}) {
+		t.Fatalf("expected BadRequest for invalid config, got %v", err)
+	}
+	if store.data["cfg"]["retries"] != float64(1) {
+		t.Fatalf("invalid update should not be persisted")
+	}
+}
+
+func TestUpdatePluginConfigRollsBackOnSaveFailure(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	store := &memoryConfigStore{data: map[string]map[string]interface{}{"cfg": {"endpoint": "http://a"}}}
+	pm.SetConfigStore(store)
+	plugin := &configurableTestPlugin{testPlugin: testPlugin{name: "cfg"}}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	store.saveErr = errors.New("db down")
+	if _, err := pm.UpdatePluginConfig("cfg", map[string]interface{}{"endpoint": "http://b"}, 1); err == nil {
+		t.Fatalf("expected save failure")
+	}
+	last := plugin.applied[len(plugin.applied)-1]
+	if last["endpoint"] != "http://a" {
+		t.Fatalf("expected plugin config rolled back, got %v", last)
+	}
+	cfg, _, _ := pm.GetPluginConfig("cfg")
+	if cfg["endpoint"] != "http://a" {
+		t.Fatalf("expected effective config unchanged, got %v", cfg)
+	}
+}
+
+func TestPluginConfigErrors(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if _, _, err := pm.GetPluginConfig("missing"); !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginNotFound}) {
+		t.Fatalf("expected PluginNotFound, got %v", err)
+	}
+	if err := pm.Register(newTestPlugin("plain", false)); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if _, err := pm.UpdatePluginConfig("plain", map[string]interface{}{}, 1); !errors.Is(err, &pkg.AppError{Code: pkg.ErrBadRequest}) {
+		t.Fatalf("expected BadRequest for non-configurable plugin, got %v", err)
+	}
+}
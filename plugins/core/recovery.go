@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// pluginPanicError 记录插件panic并将其转换为插件执行错误
+func pluginPanicError(name, method string, recovered interface{}) *pkg.AppError {
+	metrics.RecordPluginError(name, "panic")
+	pkg.Error("插件发生panic",
+		zap.String("plugin", name),
+		zap.String("method", method),
+		zap.Any("panic", recovered),
+		zap.ByteString("stack", debug.Stack()))
+	return pkg.NewPluginExecutionError(fmt.Sprintf("插件 '%s' 的 %s 发生panic: %v", name, method, recovered), nil)
+}
+
+// safeCall 调用插件方法，将panic转换为错误，避免插件拖垮宿主
+func safeCall(name, method string, fn func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = pluginPanicError(name, method, recovered)
+		}
+	}()
+	return fn()
+}
+
+// guardPluginRequest 插件路由表中的舱壁、熔断与panic恢复中间件
+// 并发请求过多时返回429或503，熔断器打开时直接返回503；处理函数panic或返回5xx时计为失败
+func (pm *PluginManager) guardPluginRequest(plugin Plugin) gin.HandlerFunc {
+	name := plugin.Name()
+	return func(c *gin.Context) {
+		release, err := pm.bulkheadsFor(name, plugin).request.acquire(c.Request.Context())
+		if err != nil {
+			var appErr *pkg.AppError
+			if !errors.As(bulkheadError(name, "request", err), &appErr) {
+				// 客户端已断开连接
+				appErr = pkg.NewServiceUnavailableError(fmt.Sprintf("插件 '%s' 的请求已取消", name), err)
+			}
+			abortWithAppError(c, appErr)
+			return
+		}
+		defer release()
+
+		breaker := pm.breakerFor(name, plugin)
+		probe, ok := breaker.allow()
+		if !ok {
+			abortWithAppError(c, circuitOpenError(name))
+			return
+		}
+		defer breaker.finish(probe)
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				var err error
+				if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+					err = fmt.Errorf("插件 '%s' 返回HTTP %d", name, status)
+				}
+				pm.recordCallResult(name, plugin, breaker, err)
+				return
+			}
+			// 客户端断开连接，不计入熔断统计，交由外层按约定处理
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			appErr := pluginPanicError(name, c.Request.Method+" "+c.FullPath(), recovered)
+			if c.Writer.Written() {
+				c.Abort()
+			} else {
+				abortWithAppError(c, appErr)
+			}
+			pm.recordCallResult(name, plugin, breaker, appErr)
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"weave/pkg"
+
+	"go.uber.org/zap"
+)
+
+// 批量重新加载中各插件的结果
+const (
+	ReloadSucceeded = "reloaded"
+	ReloadFailed    = "failed"
+	ReloadSkipped   = "skipped" // 依赖的插件重新加载失败，未执行
+)
+
+// ReloadStepResult 批量重新加载中一个插件的执行结果
+type ReloadStepResult struct {
+	CascadeStep
+	Status string `json:"status"` // reloaded、failed 或 skipped
+	Error  string `json:"error,omitempty"`
+}
+
+// ReloadBatch 一次批量重新加载的合并结果
+type ReloadBatch struct {
+	Steps      []ReloadStepResult `json:"steps"` // 按执行顺序排列
+	Failed     int                `json:"failed"`
+	Skipped    int                `json:"skipped"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+}
+
+// Err 合并批次中失败和跳过的插件的错误，全部成功时返回nil
+func (b *ReloadBatch) Err() error {
+	var errs []error
+	for _, step := range b.Steps {
+		if step.Status != ReloadSucceeded {
+			errs = append(errs, fmt.Errorf("插件 '%s': %s", step.Plugin, step.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PlanReload 计算批量重新加载的插件及执行顺序，不修改任何状态
+// names中的插件及其依赖方（包括间接依赖方）都会重新加载；dependentsOnly中的插件已由调用方替换为新实例，
+// 只重新加载其依赖方。被依赖的插件先于依赖方重新加载，依赖方据此重新获取依赖插件发布的服务
+func (pm *PluginManager) PlanReload(names, dependentsOnly []string) []CascadeStep {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.planReloadLocked(names, dependentsOnly)
+}
+
+// ReloadPlugins 按PlanReload的顺序依次重新加载插件，返回合并的结果
+// 某个插件重新加载失败时，依赖它的插件不再重新加载，记为skipped；其他插件不受影响
+func (pm *PluginManager) ReloadPlugins(names, dependentsOnly []string) *ReloadBatch {
+	batch := &ReloadBatch{StartedAt: time.Now()}
+	failed := make(map[string]string)
+
+	for _, step := range pm.PlanReload(names, dependentsOnly) {
+		result := ReloadStepResult{CascadeStep: step, Status: ReloadSucceeded}
+		if dep := pm.failedDependency(step.Plugin, failed); dep != "" {
+			result.Status = ReloadSkipped
+			result.Error = fmt.Sprintf("依赖的插件 '%s' 重新加载失败", dep)
+			batch.Skipped++
+		} else if err := pm.ReloadPlugin(step.Plugin); err != nil {
+			result.Status = ReloadFailed
+			result.Error = err.Error()
+			batch.Failed++
+		}
+		if result.Status != ReloadSucceeded {
+			failed[step.Plugin] = result.Error
+		}
+		batch.Steps = append(batch.Steps, result)
+	}
+
+	batch.FinishedAt = time.Now()
+	if len(batch.Steps) > 0 {
+		pkg.Info("批量重新加载插件完成",
+			zap.Int("plugins", len(batch.Steps)),
+			zap.Int("failed", batch.Failed),
+			zap.Int("skipped", batch.Skipped),
+			zap.Duration("duration", batch.FinishedAt.Sub(batch.StartedAt)))
+	}
+	return batch
+}
+
+// planReloadLocked 沿依赖方做后序遍历，逆序即为被依赖的插件在前的执行顺序，调用方需持有pm.mutex
+func (pm *PluginManager) planReloadLocked(names, dependentsOnly []string) []CascadeStep {
+	// 全部已注册插件的必需依赖反向索引，禁用的插件同样需要重新获取依赖
+	dependents := make(map[string][]string)
+	for pluginName, pluginInfo := range pm.plugins {
+		for _, depName := range pluginInfo.Dependencies {
+			dependents[depName] = append(dependents[depName], pluginName)
+		}
+	}
+	for _, list := range dependents {
+		sort.Strings(list)
+	}
+
+	requested := make(map[string]bool)
+	for _, name := range names {
+		requested[name] = true
+	}
+	replaced := make(map[string]bool)
+	for _, name := range dependentsOnly {
+		if !requested[name] {
+			replaced[name] = true
+		}
+	}
+
+	var postOrder []CascadeStep
+	visited := make(map[string]bool)
+
+	var visit func(current, reason string)
+	visit = func(current, reason string) {
+		if visited[current] {
+			return
+		}
+		visited[current] = true
+		if requested[current] {
+			reason = "请求重新加载"
+		}
+		for _, dependent := range dependents[current] {
+			visit(dependent, fmt.Sprintf("依赖插件 '%s'", current))
+		}
+		postOrder = append(postOrder, CascadeStep{Plugin: current, Action: CascadeReload, Reason: reason})
+	}
+
+	// 先遍历请求重新加载的插件，再遍历已替换的插件
+	for _, roots := range [][]string{sortedKeys(requested), sortedKeys(replaced)} {
+		for _, name := range roots {
+			visit(name, "")
+		}
+	}
+
+	steps := make([]CascadeStep, 0, len(postOrder))
+	for i := len(postOrder) - 1; i >= 0; i-- {
+		step := postOrder[i]
+		// 已替换的插件只在它依赖的插件也在本批次中时重新加载
+		if replaced[step.Plugin] {
+			dep := ""
+			for _, depName := range pm.plugins[step.Plugin].Dependencies {
+				if visited[depName] {
+					dep = depName
+					break
+				}
+			}
+			if dep == "" {
+				continue
+			}
+			step.Reason = fmt.Sprintf("依赖插件 '%s'", dep)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// sortedKeys 返回排序后的集合元素
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// failedDependency 返回插件依赖中重新加载失败或被跳过的插件，没有时返回空字符串
+func (pm *PluginManager) failedDependency(name string, failed map[string]string) string {
+	if len(failed) == 0 {
+		return ""
+	}
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	for _, depName := range pm.plugins[name].Dependencies {
+		if _, exists := failed[depName]; exists {
+			return depName
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlanReloadOrdersDependents(t *testing.T) {
+	pm, _ := newCascadeManager(t)
+
+	steps := pm.PlanReload([]string{"C", "A"}, nil)
+	if got := stepsOf(steps); got != "reload:A,reload:D,reload:B,reload:C" {
+		t.Fatalf("unexpected reload plan: %s", got)
+	}
+	if steps[0].Reason != "请求重新加载" || steps[3].Reason != "请求重新加载" || steps[1].Reason != "依赖插件 'A'" {
+		t.Fatalf("unexpected reasons: %+v", steps)
+	}
+
+	// 已替换的插件本身不再重新加载，只重新加载其依赖方
+	if got := stepsOf(pm.PlanReload(nil, []string{"A"})); got != "reload:D,reload:B,reload:C" {
+		t.Fatalf("unexpected plan for replaced plugin: %s", got)
+	}
+	if got := stepsOf(pm.PlanReload(nil, []string{"C"})); got != "" {
+		t.Fatalf("expected nothing to reload for replaced leaf, got %s", got)
+	}
+}
+
+func TestReloadPluginsSkipsDependentsOfFailed(t *testing.T) {
+	pm, plugins := newCascadeManager(t)
+	for _, plugin := range plugins {
+		plugin.initCalled = 0
+	}
+	plugins["B"].initError = errors.New("boom")
+
+	batch := pm.ReloadPlugins([]string{"A"}, nil)
+	if batch.Failed != 1 || batch.Skipped != 1 || batch.Err() == nil {
+		t.Fatalf("unexpected batch result: %+v", batch)
+	}
+	statuses := make(map[string]string)
+	for _, step := range batch.Steps {
+		statuses[step.Plugin] = step.Status
+	}
+	want := map[string]string{"A": ReloadSucceeded, "D": ReloadSucceeded, "B": ReloadFailed, "C": ReloadSkipped}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Fatalf("expected %s %s, got %+v", name, status, batch.Steps)
+		}
+	}
+	if plugins["C"].initCalled != 0 || plugins["D"].initCalled != 1 {
+		t.Fatalf("expected C skipped and D reloaded, got C=%d D=%d", plugins["C"].initCalled, plugins["D"].initCalled)
+	}
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"weave/pkg"
+)
+
+// ResolvedDependency 依赖解析结果
+type ResolvedDependency struct {
+	Name            string `json:"name"`                       // 依赖的插件名称
+	Constraint      string `json:"constraint,omitempty"`       // 版本约束
+	Optional        bool   `json:"optional"`                   // 是否为可选依赖
+	ResolvedVersion string `json:"resolved_version,omitempty"` // 实际绑定的版本，未注册时为空
+	Satisfied       bool   `json:"satisfied"`                  // 是否满足约束（可选依赖缺失视为满足）
+}
+
+// DependencyNode 依赖图中的插件节点
+type DependencyNode struct {
+	Version      string               `json:"version"`      // 插件版本
+	Dependencies []ResolvedDependency `json:"dependencies"` // 依赖列表
+}
+
+// versioned 参与版本解析的插件信息，插件实例和插件清单都可以作为候选
+type versioned interface {
+	Name() string
+	Version() string
+	GetDependencies() []string
+}
+
+// dependencyCandidate 参与解析的候选插件
+type dependencyCandidate struct {
+	plugin  versioned
+	deps    []Dependency
+	version Version
+	valid   bool // 版本号是否可解析
+}
+
+// resolvePlugins 从候选插件中为每个名称选出一个版本，使所有依赖约束同时满足
+// 同名插件可以提供多个版本，优先选择最高版本；当某个版本不满足其他插件的约束时依次降级，
+// 直到所有约束稳定（已注册的插件版本固定不变）
+func (pm *PluginManager) resolvePlugins(plugins []Plugin) ([]Plugin, error) {
+	candidates := make([]versioned, 0, len(plugins))
+	for _, plugin := range plugins {
+		candidates = append(candidates, plugin)
+	}
+	selected, err := resolveCandidates(candidates, pm.registeredSnapshot(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]Plugin, 0, len(selected))
+	for _, candidate := range selected {
+		resolved = append(resolved, candidate.(Plugin))
+	}
+	return resolved, nil
+}
+
+// ResolveManifests 从候选清单中为每个插件名称选出一个版本，规则与注册插件时的版本解析相同：
+// 优先选择最高版本，同时满足候选清单之间以及已注册插件提出的版本约束，已注册插件的版本只做校验。
+// replacing中的已注册插件将被候选版本替换，不再参与校验，也不再对其他插件提出约束。
+// 返回的清单按候选中插件名称首次出现的顺序排列
+func (pm *PluginManager) ResolveManifests(manifests []*PluginManifest, replacing []string) ([]*PluginManifest, error) {
+	candidates := make([]versioned, 0, len(manifests))
+	for _, manifest := range manifests {
+		candidates = append(candidates, manifestCandidate{manifest})
+	}
+	selected, err := resolveCandidates(candidates, pm.registeredSnapshot(replacing))
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]*PluginManifest, 0, len(selected))
+	for _, candidate := range selected {
+		resolved = append(resolved, candidate.(manifestCandidate).manifest)
+	}
+	return resolved, nil
+}
+
+// manifestCandidate 以插件清单作为版本解析的候选
+type manifestCandidate struct {
+	manifest *PluginManifest
+}
+
+func (c manifestCandidate) Name() string              { return c.manifest.Name }
+func (c manifestCandidate) Version() string           { return c.manifest.Version }
+func (c manifestCandidate) GetDependencies() []string { return c.manifest.Dependencies }
+
+// registeredSnapshot 复制已注册插件的信息，excluding中的插件除外
+func (pm *PluginManager) registeredSnapshot(excluding []string) map[string]PluginInfo {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	registered := make(map[string]PluginInfo, len(pm.plugins))
+	for name, info := range pm.plugins {
+		registered[name] = info
+	}
+	for _, name := range excluding {
+		delete(registered, name)
+	}
+	return registered
+}
+
+// resolveCandidates 版本解析的实现，registered为参与校验的已注册插件
+func resolveCandidates(plugins []versioned, registered map[string]PluginInfo) ([]versioned, error) {
+	groups := make(map[string][]dependencyCandidate)
+	var order []string
+	for _, plugin := range plugins {
+		deps, err := parseDependencies(plugin)
+		if err != nil {
+			return nil, pkg.NewPluginDependencyError(err.Error(), nil)
+		}
+		name := plugin.Name()
+		if _, seen := groups[name]; !seen {
+			order = append(order, name)
+		}
+		candidate := dependencyCandidate{plugin: plugin, deps: deps}
+		candidate.version, err = ParseVersion(plugin.Version())
+		candidate.valid = err == nil
+		groups[name] = append(groups[name], candidate)
+	}
+
+	// 每组按版本从高到低排序，无法解析的版本排在最后
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].valid != group[j].valid {
+				return group[i].valid
+			}
+			return group[i].valid && group[i].version.Compare(group[j].version) > 0
+		})
+	}
+
+	selected := make(map[string]int, len(groups))
+	for {
+		constraints := collectConstraints(groups, selected, registered)
+		changed := false
+		for _, name := range order {
+			group := groups[name]
+			idx := selected[name]
+			for idx < len(group) && !satisfiesAll(name, group[idx].plugin, constraints[name]) {
+				idx++
+			}
+			if idx >= len(group) {
+				return nil, pkg.NewPluginDependencyError(unresolvableMessage(name, group, constraints[name]), nil)
+			}
+			if idx != selected[name] {
+				selected[name] = idx
+				changed = true
+			}
+		}
+		if !changed {
+			// 已注册插件的版本无法调整，只能校验
+			for name, reqs := range constraints {
+				if _, inBatch := groups[name]; inBatch {
+					continue
+				}
+				if info, exists := registered[name]; exists {
+					for _, req := range reqs {
+						if err := checkDependencyVersion(req.consumer, req.dep, info.Plugin); err != nil {
+							return nil, pkg.NewPluginDependencyError(err.Error(), nil)
+						}
+					}
+				}
+			}
+			break
+		}
+	}
+
+	resolved := make([]versioned, 0, len(order))
+	for _, name := range order {
+		resolved = append(resolved, groups[name][selected[name]].plugin)
+	}
+	return resolved, nil
+}
+
+// dependencyRequirement 某个插件对依赖提出的约束
+type dependencyRequirement struct {
+	consumer string
+	dep      Dependency
+}
+
+// collectConstraints 汇总当前选择下每个插件名称受到的约束
+func collectConstraints(groups map[string][]dependencyCandidate, selected map[string]int, registered map[string]PluginInfo) map[string][]dependencyRequirement {
+	constraints := make(map[string][]dependencyRequirement)
+	for name, group := range groups {
+		for _, dep := range group[selected[name]].deps {
+			constraints[dep.Name] = append(constraints[dep.Name], dependencyRequirement{consumer: name, dep: dep})
+		}
+	}
+	for name, info := range registered {
+		for _, dep := range info.DependencySpecs {
+			constraints[dep.Name] = append(constraints[dep.Name], dependencyRequirement{consumer: name, dep: dep})
+		}
+	}
+	return constraints
+}
+
+// satisfiesAll 判断插件是否满足全部约束
+func satisfiesAll(name string, plugin versioned, reqs []dependencyRequirement) bool {
+	for _, req := range reqs {
+		if req.consumer == name {
+			continue
+		}
+		if checkDependencyVersion(req.consumer, req.dep, plugin) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// unresolvableMessage 生成依赖无法解析时的错误描述
+func unresolvableMessage(name string, group []dependencyCandidate, reqs []dependencyRequirement) string {
+	versions := make([]string, 0, len(group))
+	for _, candidate := range group {
+		versions = append(versions, candidate.plugin.Version())
+	}
+	wants := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		if !req.dep.Constraint.IsAny() {
+			wants = append(wants, fmt.Sprintf("'%s' 要求 %s", req.consumer, req.dep.Constraint))
+		}
+	}
+	return fmt.Sprintf("无法为插件 '%s' 找到满足约束的版本（%s），可用版本: %s",
+		name, strings.Join(wants, "; "), strings.Join(versions, ", "))
+}
+
+// GetResolvedDependencyGraph 获取包含版本解析结果的依赖图
+func (pm *PluginManager) GetResolvedDependencyGraph() map[string]DependencyNode {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	graph := make(map[string]DependencyNode, len(pm.plugins))
+	for name, info := range pm.plugins {
+		node := DependencyNode{
+			Version:      info.Plugin.Version(),
+			Dependencies: make([]ResolvedDependency, 0, len(info.DependencySpecs)),
+		}
+		for _, dep := range info.DependencySpecs {
+			resolved := ResolvedDependency{
+				Name:       dep.Name,
+				Constraint: dep.Constraint.String(),
+				Optional:   dep.Optional,
+				Satisfied:  dep.Optional,
+			}
+			if depInfo, exists := pm.plugins[dep.Name]; exists {
+				resolved.ResolvedVersion = depInfo.Plugin.Version()
+				resolved.Satisfied = checkDependencyVersion(name, dep, depInfo.Plugin) == nil
+			}
+			node.Dependencies = append(node.Dependencies, resolved)
+		}
+		graph[name] = node
+	}
+	return graph
+}
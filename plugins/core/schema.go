@@ -0,0 +1,308 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 插件配置使用的JSON Schema子集
+// 支持的关键字：type、properties、required、additionalProperties、enum、default、
+// minimum、maximum、minLength、maxLength、pattern、items、writeOnly、format
+
+// ValidateAgainstSchema 按JSON Schema校验配置值，返回所有校验错误合并后的错误
+func ValidateAgainstSchema(schema map[string]interface{}, value interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	var problems []string
+	validateSchemaValue("", schema, value, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("配置校验失败: %s", strings.Join(problems, "; "))
+}
+
+// ApplySchemaDefaults 返回填充了Schema默认值的配置副本，已有的配置项不会被覆盖
+func ApplySchemaDefaults(schema map[string]interface{}, config map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		result[key] = value
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for key, raw := range properties {
+		propSchema, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		current, exists := result[key]
+		if !exists {
+			if def, hasDefault := propSchema["default"]; hasDefault {
+				result[key] = def
+			}
+			continue
+		}
+		// 嵌套对象递归填充默认值
+		if nested, ok := current.(map[string]interface{}); ok && schemaType(propSchema) == "object" {
+			result[key] = ApplySchemaDefaults(propSchema, nested)
+		}
+	}
+	return result
+}
+
+// isSecretSchema 判断Schema中的字段是否为敏感字段（writeOnly或format为password）
+func isSecretSchema(schema map[string]interface{}) bool {
+	if writeOnly, ok := schema["writeOnly"].(bool); ok && writeOnly {
+		return true
+	}
+	format, _ := schema["format"].(string)
+	return format == "password"
+}
+
+// schemaType 返回Schema声明的单一类型，未声明或声明多个类型时返回空字符串
+func schemaType(schema map[string]interface{}) string {
+	t, _ := schema["type"].(string)
+	return t
+}
+
+// validateSchemaValue 递归校验值，将错误追加到problems
+func validateSchemaValue(path string, schema map[string]interface{}, value interface{}, problems *[]string) {
+	field := path
+	if field == "" {
+		field = "配置"
+	}
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, field+" "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("类型应为 %s，实际为 %s", strings.Join(types, "|"), jsonTypeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if valuesEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("取值必须是 %v 之一", enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := toFloat(schema["minLength"]); ok && float64(length) < min {
+			fail("长度不能小于 %v", min)
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(length) > max {
+			fail("长度不能大于 %v", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				fail("的pattern无效: %v", err)
+			} else if !re.MatchString(v) {
+				fail("不匹配格式 %s", pattern)
+			}
+		}
+	case map[string]interface{}:
+		validateObject(path, schema, v, problems)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaValue(fmt.Sprintf("%s[%d]", field, i), items, item, problems)
+			}
+		}
+	default:
+		if number, ok := toFloat(value); ok {
+			if min, ok := toFloat(schema["minimum"]); ok && number < min {
+				fail("不能小于 %v", min)
+			}
+			if max, ok := toFloat(schema["maximum"]); ok && number > max {
+				fail("不能大于 %v", max)
+			}
+		}
+	}
+}
+
+// validateObject 校验对象的必填字段、属性和额外属性
+func validateObject(path string, schema map[string]interface{}, value map[string]interface{}, problems *[]string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for _, raw := range toSlice(schema["required"]) {
+		key, _ := raw.(string)
+		if _, exists := value[key]; !exists {
+			*problems = append(*problems, fmt.Sprintf("缺少必填配置项 %s", joinPath(path, key)))
+		}
+	}
+
+	// 按键名排序，保证错误信息顺序稳定
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propSchema, declared := properties[key].(map[string]interface{})
+		if declared {
+			validateSchemaValue(joinPath(path, key), propSchema, value[key], problems)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*problems = append(*problems, fmt.Sprintf("不允许的配置项 %s", joinPath(path, key)))
+			}
+		case map[string]interface{}:
+			validateSchemaValue(joinPath(path, key), additional, value[key], problems)
+		}
+	}
+}
+
+// schemaTypes 返回Schema声明的类型列表（type可以是字符串或字符串数组）
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	case []string:
+		return t
+	}
+	return nil
+}
+
+// matchesType 判断值是否符合JSON Schema类型
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		number, ok := toFloat(value)
+		return ok && number == math.Trunc(number)
+	}
+	return false
+}
+
+// jsonTypeOf 返回值对应的JSON类型名称，用于错误信息
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toFloat 将数值类型转换为float64（YAML解析得到int，JSON解析得到float64）
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// valuesEqual 比较枚举值，数值按大小比较
+func valuesEqual(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return fmt.Sprintf("%T:%v", a, a) == fmt.Sprintf("%T:%v", b, b)
+}
+
+// toSlice 将required等字段转换为[]interface{}
+func toSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		result := make([]interface{}, len(v))
+		for i, s := range v {
+			result[i] = s
+		}
+		return result
+	}
+	return nil
+}
+
+// joinPath 拼接配置项路径
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
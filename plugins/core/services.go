@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+
+	"weave/pkg"
+)
+
+// ServiceLocator 插件服务查找接口
+// 查找器由ScopeFor创建，只能查找使用方声明过的依赖；*PluginManager不实现该接口
+type ServiceLocator interface {
+	LookupService(name string) (interface{}, error)
+}
+
+// serviceEntry 已注册的插件服务
+type serviceEntry struct {
+	impl     interface{}
+	disabled bool // 提供者已被禁用
+}
+
+// Provide 以提供方插件的名称发布其对外提供的服务
+// provider为提供服务的插件自身，impl通常是一个Go接口的实现（如记事本插件的NoteStore）。
+// 只能在provider自身的Init中调用，插件无法以其他插件的名称发布服务；
+// 插件被重载或注销时服务会被移除，禁用期间查找会失败
+func (pm *PluginManager) Provide(provider Plugin, impl interface{}) error {
+	name := provider.Name()
+	if impl == nil {
+		return fmt.Errorf("插件 '%s' 提供的服务不能为空", name)
+	}
+
+	pm.servicesMu.Lock()
+	defer pm.servicesMu.Unlock()
+
+	if owner, initializing := pm.initializing[name]; !initializing || !samePlugin(owner, provider) {
+		return fmt.Errorf("插件 '%s' 只能在自身的Init中发布服务", name)
+	}
+	if _, exists := pm.services[name]; exists {
+		return fmt.Errorf("插件 '%s' 的服务已存在", name)
+	}
+	if pm.services == nil {
+		pm.services = make(map[string]*serviceEntry)
+	}
+	pm.services[name] = &serviceEntry{impl: impl}
+	return nil
+}
+
+// lookupService 查找插件服务（不检查依赖关系）
+func (pm *PluginManager) lookupService(name string) (interface{}, error) {
+	pm.servicesMu.RLock()
+	defer pm.servicesMu.RUnlock()
+
+	entry, exists := pm.services[name]
+	if !exists {
+		return nil, pkg.NewPluginNotFoundError(fmt.Sprintf("插件 '%s' 未提供服务", name), nil)
+	}
+	if entry.disabled {
+		return nil, pkg.NewPluginDisabledError(fmt.Sprintf("插件 '%s' 已被禁用", name), nil)
+	}
+	return entry.impl, nil
+}
+
+// callInit 调用插件的Init，调用期间该插件实例可以通过Provide以自身名称发布服务
+func (pm *PluginManager) callInit(name string, plugin Plugin) error {
+	pm.servicesMu.Lock()
+	if pm.initializing == nil {
+		pm.initializing = make(map[string]Plugin)
+	}
+	pm.initializing[name] = plugin
+	pm.servicesMu.Unlock()
+
+	defer func() {
+		pm.servicesMu.Lock()
+		delete(pm.initializing, name)
+		pm.servicesMu.Unlock()
+	}()
+	return safeCall(name, "Init", plugin.Init)
+}
+
+// samePlugin 判断两个值是否为同一个插件实例，不可比较的类型视为不同
+func samePlugin(a, b Plugin) bool {
+	typ := reflect.TypeOf(a)
+	return typ != nil && typ == reflect.TypeOf(b) && typ.Comparable() && a == b
+}
+
+// setServiceDisabled 更新服务提供者的启用状态
+func (pm *PluginManager) setServiceDisabled(name string, disabled bool) {
+	pm.servicesMu.Lock()
+	defer pm.servicesMu.Unlock()
+
+	if entry, exists := pm.services[name]; exists {
+		entry.disabled = disabled
+	}
+}
+
+// removeService 移除插件发布的服务
+func (pm *PluginManager) removeService(name string) {
+	pm.servicesMu.Lock()
+	defer pm.servicesMu.Unlock()
+
+	delete(pm.services, name)
+}
+
+// takeService 取出并移除插件发布的服务，未发布时返回nil
+func (pm *PluginManager) takeService(name string) *serviceEntry {
+	pm.servicesMu.Lock()
+	defer pm.servicesMu.Unlock()
+
+	entry := pm.services[name]
+	delete(pm.services, name)
+	return entry
+}
+
+// putService 恢复takeService取出的服务，entry为空时移除服务
+func (pm *PluginManager) putService(name string, entry *serviceEntry) {
+	pm.servicesMu.Lock()
+	defer pm.servicesMu.Unlock()
+
+	if entry == nil {
+		delete(pm.services, name)
+		return
+	}
+	if pm.services == nil {
+		pm.services = make(map[string]*serviceEntry)
+	}
+	pm.services[name] = entry
+}
+
+// scopedLocator 按依赖关系受限的服务查找器
+type scopedLocator struct {
+	pm       *PluginManager
+	consumer string
+	allowed  map[string]bool
+}
+
+// ScopeFor 返回指定插件使用的服务查找器，只能查找该插件声明过的依赖（包括可选依赖）
+// 依赖声明直接从插件实例读取，因此可以在插件的Init中使用
+func (pm *PluginManager) ScopeFor(consumer Plugin) ServiceLocator {
+	allowed := make(map[string]bool)
+	for _, name := range DependencyNames(consumer.GetDependencies()) {
+		allowed[name] = true
+	}
+	return &scopedLocator{pm: pm, consumer: consumer.Name(), allowed: allowed}
+}
+
+// LookupService 查找服务，未声明依赖时返回PluginDependencyError
+func (s *scopedLocator) LookupService(name string) (interface{}, error) {
+	if !s.allowed[name] {
+		return nil, pkg.NewPluginDependencyError(fmt.Sprintf("插件 '%s' 未声明对插件 '%s' 的依赖，无法使用其服务", s.consumer, name), nil)
+	}
+	return s.pm.lookupService(name)
+}
+
+// Lookup 查找插件服务并转换为指定接口类型，locator由ScopeFor创建
+//
+//	store, err := core.Lookup[note.NoteStore](pm.ScopeFor(p), "note")
+func Lookup[T any](locator ServiceLocator, name string) (T, error) {
+	var zero T
+	impl, err := locator.LookupService(name)
+	if err != nil {
+		return zero, err
+	}
+	service, ok := impl.(T)
+	if !ok {
+		return zero, fmt.Errorf("插件 '%s' 提供的服务类型为 %T，与期望的 %v 不匹配", name, impl, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return service, nil
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"weave/pkg"
+)
+
+// greeter 测试用服务接口
+type greeter interface {
+	Greet(name string) string
+}
+
+type greeterImpl struct{ prefix string }
+
+func (g *greeterImpl) Greet(name string) string { return g.prefix + name }
+
+// providerPlugin 在Init中发布服务的测试插件
+type providerPlugin struct {
+	testPlugin
+	service   greeter
+	extra     []interface{} // Init中额外发布的服务
+	extraErrs []error
+}
+
+func (p *providerPlugin) Init() error {
+	p.initCalled++
+	if err := p.pm.Provide(p, p.service); err != nil {
+		return err
+	}
+	for _, impl := range p.extra {
+		p.extraErrs = append(p.extraErrs, p.pm.Provide(p, impl))
+	}
+	return nil
+}
+
+// impostorPlugin 在Init中试图以其他插件的名称发布服务的测试插件
+type impostorPlugin struct {
+	testPlugin
+}
+
+func (p *impostorPlugin) Init() error {
+	return p.pm.Provide(&providerPlugin{testPlugin: testPlugin{name: "greeter"}}, &greeterImpl{})
+}
+
+// consumerPlugin 在Init中查找依赖服务的测试插件
+type consumerPlugin struct {
+	testPlugin
+	got greeter
+}
+
+func (p *consumerPlugin) Init() error {
+	svc, err := Lookup[greeter](p.pm.ScopeFor(p), "greeter")
+	if err != nil {
+		return err
+	}
+	p.got = svc
+	return nil
+}
+
+func TestProvideAndScopedLookup(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	provider := &providerPlugin{testPlugin: testPlugin{name: "greeter"}, service: &greeterImpl{prefix: "hi "}}
+	consumer := &consumerPlugin{testPlugin: testPlugin{name: "app", deps: []string{"greeter>=1.0"}}}
+
+	if err := pm.RegisterPlugins([]Plugin{consumer, provider}); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if consumer.got == nil || consumer.got.Greet("bob") != "hi bob" {
+		t.Fatalf("expected consumer to resolve typed service during Init")
+	}
+
+	// 未声明依赖的插件无法查找服务
+	outsider := newTestPlugin("outsider", false)
+	_, err := Lookup[greeter](pm.ScopeFor(outsider), "greeter")
+	if !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginDependency}) {
+		t.Fatalf("expected PluginDependencyError for undeclared dependency, got %v", err)
+	}
+
+	// 类型不匹配
+	if _, err := Lookup[error](pm.ScopeFor(consumer), "greeter"); err == nil || !strings.Contains(err.Error(), "不匹配") {
+		t.Fatalf("expected type mismatch error, got %v", err)
+	}
+}
+
+func TestServiceInvalidatedWhenProviderDisabled(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	provider := &providerPlugin{testPlugin: testPlugin{name: "greeter"}, service: &greeterImpl{}}
+	if err := pm.Register(provider); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	locator := pm.ScopeFor(&testPlugin{name: "app", deps: []string{"greeter"}})
+
+	if err := pm.DisablePlugin("greeter"); err != nil {
+		t.Fatalf("disable error: %v", err)
+	}
+	if _, err := Lookup[greeter](locator, "greeter"); !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginDisabled}) {
+		t.Fatalf("expected PluginDisabledError while provider disabled, got %v", err)
+	}
+
+	if err := pm.EnablePlugin("greeter"); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	if _, err := Lookup[greeter](locator, "greeter"); err != nil {
+		t.Fatalf("expected lookup to succeed after re-enable, got %v", err)
+	}
+
+	// 重载时旧服务被移除，Init重新发布
+	if err := pm.ReloadPlugin("greeter"); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if provider.initCalled != 2 {
+		t.Fatalf("expected provider re-initialized, got %d", provider.initCalled)
+	}
+	if _, err := Lookup[greeter](locator, "greeter"); err != nil {
+		t.Fatalf("expected service re-provided after reload, got %v", err)
+	}
+
+	if err := pm.Unregister("greeter"); err != nil {
+		t.Fatalf("unregister error: %v", err)
+	}
+	if _, err := Lookup[greeter](locator, "greeter"); !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginNotFound}) {
+		t.Fatalf("expected PluginNotFoundError after unregister, got %v", err)
+	}
+}
+
+func TestProvideOnlyFromOwnInit(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+
+	// Init之外不能发布服务
+	provider := &providerPlugin{testPlugin: testPlugin{name: "svc"}, service: &greeterImpl{}}
+	if err := pm.Provide(provider, &greeterImpl{}); err == nil || !strings.Contains(err.Error(), "Init") {
+		t.Fatalf("expected provide outside Init rejected, got %v", err)
+	}
+
+	// 不能以其他插件的名称发布服务
+	impostor := &impostorPlugin{testPlugin: testPlugin{name: "impostor"}}
+	if err := pm.Register(impostor); err == nil || !strings.Contains(err.Error(), "Init") {
+		t.Fatalf("expected provide under another plugin's name rejected, got %v", err)
+	}
+
+	// 同一插件重复发布和发布空服务
+	provider.extra = []interface{}{&greeterImpl{}, nil}
+	if err := pm.Register(provider); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if len(provider.extraErrs) != 2 || provider.extraErrs[0] == nil || !strings.Contains(provider.extraErrs[0].Error(), "已存在") || provider.extraErrs[1] == nil {
+		t.Fatalf("expected duplicate and nil services rejected, got %v", provider.extraErrs)
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// DefaultShutdownTimeout 单个插件Shutdown的默认超时时间
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultDrainTimeout 关闭时等待进行中的插件调用结束的最长时间
+const DefaultDrainTimeout = 10 * time.Second
+
+// ErrManagerShuttingDown 管理器正在关闭，拒绝新的插件调用
+var ErrManagerShuttingDown = errors.New("插件管理器正在关闭")
+
+// ShutdownTimeoutProvider 插件声明自身关闭超时的接口（可选）
+type ShutdownTimeoutProvider interface {
+	ShutdownTimeout() time.Duration
+}
+
+// ShutdownResult 单个插件的关闭结果
+type ShutdownResult struct {
+	Plugin   string        `json:"plugin"`
+	Duration time.Duration `json:"duration"`
+	TimedOut bool          `json:"timed_out"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// requestTracker 统计进行中的插件调用，关闭时拒绝新调用并等待已有调用结束
+type requestTracker struct {
+	mu       sync.Mutex
+	active   int
+	draining bool
+	idle     chan struct{} // draining期间进行中的调用全部结束时关闭
+}
+
+// begin 开始一次调用，管理器正在关闭时返回false
+func (t *requestTracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.active++
+	return true
+}
+
+// end 结束一次调用
+func (t *requestTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.draining && t.active == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// drain 拒绝新调用并等待进行中的调用结束，返回超时时仍未结束的调用数
+func (t *requestTracker) drain(ctx context.Context) (int, error) {
+	t.mu.Lock()
+	t.draining = true
+	if t.active == 0 {
+		t.mu.Unlock()
+		return 0, nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.active, ctx.Err()
+	}
+}
+
+// shuttingDownError 管理器关闭期间拒绝调用时返回的错误
+func shuttingDownError(name string) *pkg.AppError {
+	return pkg.NewServiceUnavailableError(fmt.Sprintf("服务正在关闭，插件 '%s' 不再接受请求", name), ErrManagerShuttingDown)
+}
+
+// ShutdownAll 关闭全部插件，用于服务退出
+// 先拒绝新的插件调用并等待进行中的调用结束，等待时间不超过DefaultDrainTimeout和ctx剩余时间的一半，
+// 再按依赖关系的逆拓扑顺序（使用者先于被依赖者）逐个调用Shutdown。每个插件按自身的关闭超时单独计时，
+// 不受排空阶段和其他插件耗时的影响，超时的插件不再等待。返回每个插件的关闭结果，存在未能正常关闭的插件时同时返回错误
+func (pm *PluginManager) ShutdownAll(ctx context.Context) ([]ShutdownResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// 1. 等待进行中的插件调用结束，使用独立的截止时间，避免排空阶段耗尽插件关闭的时间
+	drainCtx, cancel := context.WithTimeout(ctx, drainTimeout(ctx))
+	if remaining, err := pm.requests.drain(drainCtx); err != nil {
+		pkg.Warn("等待插件请求结束超时，继续关闭插件", zap.Int("remaining", remaining), zap.Error(err))
+	}
+	cancel()
+	// 插件的关闭超时从pluginShutdownTimeout开始计时，不使用ctx的剩余时间
+	shutdownCtx := context.WithoutCancel(ctx)
+
+	// 2. 计算关闭顺序
+	pm.mutex.RLock()
+	graph := make(map[string][]string, len(pm.plugins))
+	for name, info := range pm.plugins {
+		deps := make([]string, 0, len(info.DependencySpecs))
+		for _, dep := range info.DependencySpecs {
+			if _, exists := pm.plugins[dep.Name]; exists {
+				deps = append(deps, dep.Name)
+			}
+		}
+		graph[name] = deps
+	}
+	pm.mutex.RUnlock()
+
+	order, err := topologicalSort(graph)
+	if err != nil {
+		// 正常注册流程不会产生循环依赖，兜底按名称排序
+		order = make([]string, 0, len(graph))
+		for name := range graph {
+			order = append(order, name)
+		}
+		sort.Strings(order)
+	}
+
+	// 3. 逆序关闭
+	results := make([]ShutdownResult, 0, len(order))
+	var failed []string
+	for i := len(order) - 1; i >= 0; i-- {
+		result := pm.shutdownPlugin(shutdownCtx, order[i])
+		if result == nil {
+			continue
+		}
+		if result.Error != "" {
+			failed = append(failed, result.Plugin)
+		}
+		results = append(results, *result)
+	}
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%d 个插件未能正常关闭: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// shutdownPlugin 关闭单个插件并将其标记为已注销，插件已不存在时返回nil
+// 调用Shutdown期间不持有管理器锁，避免超时的插件阻塞其他操作
+func (pm *PluginManager) shutdownPlugin(ctx context.Context, name string) *ShutdownResult {
+	pm.mutex.Lock()
+	info, exists := pm.plugins[name]
+	if !exists {
+		pm.mutex.Unlock()
+		return nil
+	}
+	// 先禁用插件，路由和事件订阅立即失效
+	info.IsEnabled = false
+	pm.plugins[name] = info
+	pm.Events().UnsubscribeOwner(name)
+	canary := pm.takeCanaryLocked(name)
+	if canary != nil {
+		pm.Events().UnsubscribeOwner(canary.owner)
+	}
+	pm.mutex.Unlock()
+
+	result := &ShutdownResult{Plugin: name}
+	startTime := time.Now()
+
+	var err error
+	// 初始化失败的插件无需关闭
+	if info.State != StateFailed {
+		err = callWithTimeout(ctx, pluginShutdownTimeout(info.Plugin), func() error {
+			return safeCall(name, "Shutdown", info.Plugin.Shutdown)
+		})
+	}
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		result.TimedOut = errors.Is(err, context.DeadlineExceeded)
+		result.Error = err.Error()
+		metrics.RecordPluginError(name, "shutdown_failed")
+		pkg.Error("插件未能正常关闭", zap.String("plugin", name), zap.Duration("duration", result.Duration), zap.Error(err))
+	}
+	metrics.RecordPluginMethodCall(name, "Shutdown", err == nil)
+
+	// 灰度版本与稳定版本一起关闭，失败时只记录日志
+	if canary != nil {
+		if canaryErr := callWithTimeout(ctx, pluginShutdownTimeout(canary.plugin), func() error {
+			return safeCall(name, "Shutdown", canary.plugin.Shutdown)
+		}); canaryErr != nil {
+			metrics.RecordPluginError(name, "canary_shutdown_failed")
+			pkg.Error("插件灰度版本未能正常关闭", zap.String("plugin", name), zap.String("version", canary.plugin.Version()), zap.Error(canaryErr))
+		}
+	}
+
+	pm.mutex.Lock()
+	delete(pm.routeTables, name)
+	pm.removeService(name)
+	pm.removeConfig(name)
+	pm.removeBreaker(name)
+	pm.removeBulkheads(name)
+	delete(pm.plugins, name)
+	pm.setStateLocked(name, StateUnloaded, "shutdown", err)
+	pm.mutex.Unlock()
+
+	pm.publishLifecycle(TopicPluginUnregistered, info.Plugin)
+	return result
+}
+
+// drainTimeout 返回排空阶段的等待时间：DefaultDrainTimeout与ctx剩余时间的一半中较小者
+func drainTimeout(ctx context.Context) time.Duration {
+	timeout := DefaultDrainTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if half := time.Until(deadline) / 2; half < timeout {
+			timeout = half
+		}
+	}
+	return timeout
+}
+
+// pluginShutdownTimeout 获取插件的关闭超时时间
+func pluginShutdownTimeout(plugin Plugin) time.Duration {
+	if provider, ok := plugin.(ShutdownTimeoutProvider); ok {
+		if timeout := provider.ShutdownTimeout(); timeout > 0 {
+			return timeout
+		}
+	}
+	return DefaultShutdownTimeout
+}
+
+// callWithTimeout 在独立goroutine中执行fn，超时或ctx结束时立即返回（fn会在后台继续运行直至结束）
+func callWithTimeout(ctx context.Context, timeout time.Duration, fn func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("关闭超时: %w", ctx.Err())
+	}
+}
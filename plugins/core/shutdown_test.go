@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderedShutdownPlugin 记录Shutdown调用顺序的测试插件
+type orderedShutdownPlugin struct {
+	testPlugin
+	order   *[]string
+	mu      *sync.Mutex
+	block   chan struct{}
+	timeout time.Duration
+}
+
+func (p *orderedShutdownPlugin) Shutdown() error {
+	p.mu.Lock()
+	*p.order = append(*p.order, p.name)
+	p.mu.Unlock()
+	if p.block != nil {
+		<-p.block
+	}
+	return p.shutdownError
+}
+
+func (p *orderedShutdownPlugin) ShutdownTimeout() time.Duration { return p.timeout }
+
+func TestShutdownAllReverseDependencyOrder(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	var order []string
+	mu := &sync.Mutex{}
+	newPlugin := func(name string, deps ...string) *orderedShutdownPlugin {
+		return &orderedShutdownPlugin{testPlugin: testPlugin{name: name, deps: deps}, order: &order, mu: mu}
+	}
+
+	// C依赖B，B依赖A：应按C、B、A的顺序关闭
+	if err := pm.RegisterPlugins([]Plugin{newPlugin("C", "B"), newPlugin("A"), newPlugin("B", "A")}); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	results, err := pm.ShutdownAll(context.Background())
+	if err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+	if strings.Join(order, ",") != "C,B,A" {
+		t.Fatalf("expected reverse dependency order C,B,A, got %v", order)
+	}
+	if len(results) != 3 || len(pm.ListPlugins()) != 0 {
+		t.Fatalf("expected all plugins shut down and removed, got %+v", results)
+	}
+	if state, _ := pm.GetPluginState("A"); state != StateUnloaded {
+		t.Fatalf("expected unloaded state, got %s", state)
+	}
+}
+
+func TestShutdownAllReportsFailures(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	var order []string
+	mu := &sync.Mutex{}
+
+	hung := &orderedShutdownPlugin{testPlugin: testPlugin{name: "hung"}, order: &order, mu: mu, block: make(chan struct{}), timeout: 20 * time.Millisecond}
+	defer close(hung.block)
+	broken := &orderedShutdownPlugin{testPlugin: testPlugin{name: "broken", shutdownError: errors.New("close failed")}, order: &order, mu: mu}
+	healthy := &orderedShutdownPlugin{testPlugin: testPlugin{name: "healthy"}, order: &order, mu: mu}
+	for _, plugin := range []Plugin{hung, broken, healthy} {
+		if err := pm.Register(plugin); err != nil {
+			t.Fatalf("register error: %v", err)
+		}
+	}
+
+	results, err := pm.ShutdownAll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "hung") || !strings.Contains(err.Error(), "broken") || strings.Contains(err.Error(), "healthy") {
+		t.Fatalf("expected failures for hung and broken plugins, got %v", err)
+	}
+	// 超时插件的Shutdown仍在后台运行，读取调用记录需要加锁
+	mu.Lock()
+	shutdownCount := len(order)
+	mu.Unlock()
+	if shutdownCount != 3 {
+		t.Fatalf("expected every plugin shut down despite failures, got %d", shutdownCount)
+	}
+	for _, result := range results {
+		if result.Plugin == "hung" && !result.TimedOut {
+			t.Fatalf("expected hung plugin to time out, got %+v", result)
+		}
+	}
+}
+
+func TestShutdownAllDrainDoesNotConsumePluginTimeouts(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	busy := newBlockingPlugin("busy", ConcurrencyLimits{})
+	defer close(busy.release)
+	var order []string
+	slow := &orderedShutdownPlugin{testPlugin: testPlugin{name: "slow"}, order: &order, mu: &sync.Mutex{}, block: make(chan struct{})}
+	for _, plugin := range []Plugin{busy, slow} {
+		if err := pm.Register(plugin); err != nil {
+			t.Fatalf("register error: %v", err)
+		}
+	}
+
+	// 进行中的调用一直不结束，排空阶段只使用ctx剩余时间的一半
+	go func() { _, _ = pm.ExecutePlugin("busy", nil) }()
+	<-busy.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+	// slow在ctx截止之后才完成关闭，仍在自身的关闭超时之内
+	time.AfterFunc(100*time.Millisecond, func() { close(slow.block) })
+
+	results, err := pm.ShutdownAll(ctx)
+	if err != nil {
+		t.Fatalf("expected plugins shut down within their own timeouts, got %v", err)
+	}
+	for _, result := range results {
+		if result.TimedOut {
+			t.Fatalf("expected no plugin timed out, got %+v", result)
+		}
+	}
+}
+
+func TestShutdownAllDrainsInFlightCalls(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	plugin := newBlockingPlugin("busy", ConcurrencyLimits{})
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pm.ExecutePlugin("busy", nil)
+		done <- err
+	}()
+	<-plugin.started
+
+	shutdown := make(chan struct{})
+	go func() {
+		_, _ = pm.ShutdownAll(context.Background())
+		close(shutdown)
+	}()
+
+	// 进入排空阶段后，新调用被拒绝
+	deadline := time.Now().Add(time.Second)
+	for !isDraining(&pm.requests) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for drain to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := pm.ExecutePlugin("busy", nil); !errors.Is(err, ErrManagerShuttingDown) {
+		t.Fatalf("expected new calls rejected while draining, got %v", err)
+	}
+	select {
+	case <-shutdown:
+		t.Fatalf("expected shutdown to wait for in-flight call")
+	default:
+	}
+	if plugin.shutdownCalled != 0 {
+		t.Fatalf("expected plugin not shut down before in-flight call finished")
+	}
+
+	close(plugin.release)
+	if err := <-done; err != nil {
+		t.Fatalf("expected in-flight call to complete, got %v", err)
+	}
+	<-shutdown
+	if plugin.shutdownCalled != 1 {
+		t.Fatalf("expected plugin shut down once, got %d", plugin.shutdownCalled)
+	}
+}
+
+func TestShutdownAllWaitsForAbandonedLegacyExecute(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	sp := &slowPlugin{testPlugin: testPlugin{name: "legacy"}, release: make(chan struct{}), timeout: 20 * time.Millisecond}
+	if err := pm.Register(sp); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if _, err := pm.ExecutePlugin("legacy", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+
+	// 调用方已超时返回，但Execute仍在运行，关闭时需要等待其结束
+	shutdown := make(chan struct{})
+	go func() {
+		_, _ = pm.ShutdownAll(context.Background())
+		close(shutdown)
+	}()
+	select {
+	case <-shutdown:
+		t.Fatalf("expected shutdown to wait for running Execute")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if sp.shutdownCalled != 0 {
+		t.Fatalf("expected plugin not shut down while Execute is running")
+	}
+
+	close(sp.release)
+	<-shutdown
+	if sp.shutdownCalled != 1 {
+		t.Fatalf("expected plugin shut down once, got %d", sp.shutdownCalled)
+	}
+}
+
+func isDraining(t *requestTracker) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"weave/models"
+	"weave/pkg"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBPluginStateStore 基于数据库的插件启用状态存储（plugin_states表）
+// DB为空时使用pkg.DB
+type DBPluginStateStore struct {
+	DB *gorm.DB
+}
+
+// NewDBPluginStateStore 创建数据库启用状态存储
+func NewDBPluginStateStore(db *gorm.DB) *DBPluginStateStore {
+	return &DBPluginStateStore{DB: db}
+}
+
+// db 返回绑定上下文的数据库连接
+func (s *DBPluginStateStore) db(ctx context.Context) (*gorm.DB, error) {
+	db := s.DB
+	if db == nil {
+		db = pkg.DB
+	}
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化，无法保存插件启用状态")
+	}
+	return db.WithContext(ctx), nil
+}
+
+// LoadStates 读取全部插件的启用状态
+func (s *DBPluginStateStore) LoadStates(ctx context.Context) (map[string]bool, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []models.PluginState
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	states := make(map[string]bool, len(records))
+	for _, record := range records {
+		states[record.PluginName] = record.Enabled
+	}
+	return states, nil
+}
+
+// SaveState 保存插件的启用状态，已存在时覆盖
+func (s *DBPluginStateStore) SaveState(ctx context.Context, name string, enabled bool) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	record := models.PluginState{PluginName: name, Enabled: enabled}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "plugin_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&record).Error
+	if err != nil {
+		return fmt.Errorf("保存插件 '%s' 的启用状态失败: %w", name, err)
+	}
+	return nil
+}
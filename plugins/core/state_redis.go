@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"weave/pkg"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DefaultStateSyncChannel 广播插件启用状态变更的默认Redis频道
+const DefaultStateSyncChannel = "weave:plugin_state"
+
+// RedisStateBroadcaster 基于Redis发布订阅的插件启用状态广播
+// 发布订阅不保证送达，订阅断开期间的变更由StateSync轮询存储补齐
+type RedisStateBroadcaster struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// NewRedisStateBroadcaster 创建Redis状态广播，channel为空时使用DefaultStateSyncChannel
+func NewRedisStateBroadcaster(client redis.UniversalClient, channel string) *RedisStateBroadcaster {
+	if channel == "" {
+		channel = DefaultStateSyncChannel
+	}
+	return &RedisStateBroadcaster{client: client, channel: channel}
+}
+
+// Publish 广播状态变更
+func (b *RedisStateBroadcaster) Publish(ctx context.Context, change PluginStateChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if err := b.client.Publish(ctx, b.channel, payload).Err(); err != nil {
+		return fmt.Errorf("广播插件 '%s' 的启用状态失败: %w", change.Plugin, err)
+	}
+	return nil
+}
+
+// Subscribe 订阅状态变更直至ctx结束
+func (b *RedisStateBroadcaster) Subscribe(ctx context.Context, handler func(PluginStateChange)) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	// 等待订阅确认，连接失败时立即返回
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("订阅插件启用状态失败: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return errors.New("插件启用状态订阅已关闭")
+			}
+			var change PluginStateChange
+			if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
+				pkg.Warn("忽略无法解析的插件启用状态广播", zap.String("payload", msg.Payload), zap.Error(err))
+				continue
+			}
+			handler(change)
+		}
+	}
+}
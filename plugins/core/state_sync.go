@@ -0,0 +1,338 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"weave/pkg"
+
+	"go.uber.org/zap"
+)
+
+// PluginStateChange 插件启用状态变更
+type PluginStateChange struct {
+	Plugin   string `json:"plugin"`
+	Enabled  bool   `json:"enabled"`
+	Instance string `json:"instance,omitempty"` // 发起变更的实例，实例忽略自己发出的广播
+}
+
+// PluginStateStore 插件启用状态的持久化存储，多个实例共享同一个存储
+type PluginStateStore interface {
+	// LoadStates 读取全部插件的启用状态，键为插件名
+	LoadStates(ctx context.Context) (map[string]bool, error)
+	// SaveState 保存插件的启用状态
+	SaveState(ctx context.Context, name string, enabled bool) error
+}
+
+// PluginStateBroadcaster 在实例间广播插件启用状态变更
+type PluginStateBroadcaster interface {
+	// Publish 广播状态变更
+	Publish(ctx context.Context, change PluginStateChange) error
+	// Subscribe 接收广播直至ctx结束，连接断开时返回错误
+	Subscribe(ctx context.Context, handler func(PluginStateChange)) error
+}
+
+// StateSyncConfig 插件启用状态同步配置
+type StateSyncConfig struct {
+	PollInterval time.Duration // 轮询存储的间隔，没有广播或错过广播时依靠轮询收敛
+}
+
+// DefaultStateSyncConfig 默认状态同步配置
+func DefaultStateSyncConfig() StateSyncConfig {
+	return StateSyncConfig{PollInterval: 10 * time.Second}
+}
+
+// stateSyncTimeout 读写状态存储和广播的超时时间
+const stateSyncTimeout = 5 * time.Second
+
+// StateSync 插件启用状态的持久化与集群同步
+// 通过管理接口启用或禁用插件后保存到存储并广播给其他实例；注册插件时按保存的状态启用或禁用，
+// 其他实例的变更通过广播（如Redis发布订阅）即时应用，并定期轮询存储兜底
+type StateSync struct {
+	pm          *PluginManager
+	store       PluginStateStore
+	broadcaster PluginStateBroadcaster // 为空时只通过轮询同步
+	cfg         StateSyncConfig
+	instance    string
+
+	// syncMu 串行化本地变更的保存与远程变更的应用，避免轮询读到的旧状态覆盖刚完成的本地变更
+	// 加锁顺序：syncMu -> pm.mutex -> statesMu
+	syncMu   sync.Mutex
+	statesMu sync.RWMutex
+	states   map[string]bool // 最近一次读取或写入的启用状态
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+// NewStateSync 创建状态同步，broadcaster为空时只轮询存储
+func NewStateSync(pm *PluginManager, store PluginStateStore, broadcaster PluginStateBroadcaster, cfg StateSyncConfig) *StateSync {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultStateSyncConfig().PollInterval
+	}
+	return &StateSync{
+		pm:          pm,
+		store:       store,
+		broadcaster: broadcaster,
+		cfg:         cfg,
+		instance:    newInstanceID(),
+		states:      make(map[string]bool),
+	}
+}
+
+// newInstanceID 生成实例标识，用于忽略自己发出的广播
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// SetStateSync 设置插件管理器使用的状态同步
+// 应在注册插件之前设置，注册时才能按保存的状态启用或禁用插件
+func (pm *PluginManager) SetStateSync(s *StateSync) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.stateSync = s
+}
+
+// StateSync 返回插件管理器的状态同步，未设置时返回nil
+func (pm *PluginManager) StateSync() *StateSync {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.stateSync
+}
+
+// Start 读取保存的启用状态并启动同步，读取失败时返回错误，重复调用无效
+// 已注册的插件立即按读取到的状态启用或禁用
+func (s *StateSync) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+
+	if _, err := s.poll(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.started = true
+
+	s.wg.Add(1)
+	go s.pollLoop(ctx)
+	if s.broadcaster != nil {
+		s.wg.Add(1)
+		go s.subscribeLoop(ctx)
+	}
+
+	pkg.Info("插件启用状态同步已启动",
+		zap.Bool("broadcast", s.broadcaster != nil),
+		zap.Duration("poll_interval", s.cfg.PollInterval))
+	return nil
+}
+
+// Stop 停止轮询和订阅
+func (s *StateSync) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	s.cancel()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// State 返回保存的插件启用状态，known为false表示没有保存过
+func (s *StateSync) State(name string) (enabled bool, known bool) {
+	s.statesMu.RLock()
+	defer s.statesMu.RUnlock()
+	enabled, known = s.states[name]
+	return enabled, known
+}
+
+// pollLoop 定期轮询存储
+func (s *StateSync) pollLoop(ctx context.Context) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.poll(); err != nil {
+				pkg.Warn("轮询插件启用状态失败", zap.Error(err))
+			}
+		}
+	}
+}
+
+// subscribeLoop 订阅其他实例的广播，连接断开后等待一个轮询间隔再重新订阅，期间依靠轮询同步
+func (s *StateSync) subscribeLoop(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		err := s.broadcaster.Subscribe(ctx, s.handleRemote)
+		if ctx.Err() != nil {
+			return
+		}
+		pkg.Warn("插件启用状态订阅已断开，暂时依靠轮询同步", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.cfg.PollInterval):
+		}
+	}
+}
+
+// poll 读取存储中的启用状态，应用与上次读取或写入不同的状态，返回应用的变更
+func (s *StateSync) poll() ([]PluginStateChange, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), stateSyncTimeout)
+	defer cancel()
+	loaded, err := s.store.LoadStates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取插件启用状态失败: %w", err)
+	}
+
+	s.statesMu.Lock()
+	var changes []PluginStateChange
+	for name, enabled := range loaded {
+		if previous, known := s.states[name]; !known || previous != enabled {
+			changes = append(changes, PluginStateChange{Plugin: name, Enabled: enabled})
+		}
+	}
+	s.states = loaded
+	s.statesMu.Unlock()
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Plugin < changes[j].Plugin })
+	s.pm.applyPluginStates(changes)
+	return changes, nil
+}
+
+// handleRemote 应用其他实例广播的状态变更
+func (s *StateSync) handleRemote(change PluginStateChange) {
+	if change.Instance == s.instance || change.Plugin == "" {
+		return
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.statesMu.Lock()
+	s.states[change.Plugin] = change.Enabled
+	s.statesMu.Unlock()
+
+	s.pm.applyPluginStates([]PluginStateChange{change})
+}
+
+// recordLocked 保存本地完成的启用或禁用操作并广播，调用方需持有syncMu
+// 保存失败时操作只在本实例生效并记录错误日志，重启后或其他实例不会看到该变更
+func (s *StateSync) recordLocked(steps []CascadeStep) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateSyncTimeout)
+	defer cancel()
+
+	for _, step := range steps {
+		enabled := step.Action == CascadeEnable
+		if err := s.store.SaveState(ctx, step.Plugin, enabled); err != nil {
+			pkg.Error("保存插件启用状态失败", zap.String("plugin", step.Plugin), zap.Bool("enabled", enabled), zap.Error(err))
+			continue
+		}
+
+		s.statesMu.Lock()
+		s.states[step.Plugin] = enabled
+		s.statesMu.Unlock()
+
+		if s.broadcaster == nil {
+			continue
+		}
+		change := PluginStateChange{Plugin: step.Plugin, Enabled: enabled, Instance: s.instance}
+		if err := s.broadcaster.Publish(ctx, change); err != nil {
+			pkg.Warn("广播插件启用状态失败，其他实例将通过轮询同步", zap.String("plugin", step.Plugin), zap.Error(err))
+		}
+	}
+}
+
+// changeStates 执行启用或禁用操作，成功后保存并广播受影响插件的状态
+// 设置了状态同步时在syncMu内执行，避免与远程变更的应用交错
+func (pm *PluginManager) changeStates(apply func() ([]CascadeStep, error)) ([]CascadeStep, error) {
+	s := pm.StateSync()
+	if s == nil {
+		return apply()
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	steps, err := apply()
+	if err != nil {
+		return nil, err
+	}
+	s.recordLocked(steps)
+	return steps, nil
+}
+
+// persistedStateLocked 返回保存的插件启用状态，调用方需持有pm.mutex
+func (pm *PluginManager) persistedStateLocked(name string) (enabled bool, known bool) {
+	if pm.stateSync == nil {
+		return false, false
+	}
+	return pm.stateSync.State(name)
+}
+
+// applyPluginStates 按保存或广播的状态启用、禁用本实例的插件，不再保存或广播
+// 变更之间可能存在依赖关系（如级联禁用），失败的变更在其他变更应用后重试，直到没有进展
+func (pm *PluginManager) applyPluginStates(changes []PluginStateChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	pending := changes
+	for len(pending) > 0 {
+		var failed []PluginStateChange
+		applied := 0
+		errs := make(map[string]error)
+		for _, change := range pending {
+			info, exists := pm.plugins[change.Plugin]
+			if !exists || info.IsEnabled == change.Enabled {
+				continue
+			}
+			var err error
+			if change.Enabled {
+				err = pm.enablePluginLocked(change.Plugin)
+			} else {
+				err = pm.disablePluginLocked(change.Plugin)
+			}
+			if err != nil {
+				failed = append(failed, change)
+				errs[change.Plugin] = err
+				continue
+			}
+			applied++
+			pkg.Info("已同步插件启用状态", zap.String("plugin", change.Plugin), zap.Bool("enabled", change.Enabled))
+		}
+
+		if len(failed) > 0 && applied == 0 {
+			for _, change := range failed {
+				pkg.Warn("同步插件启用状态失败", zap.String("plugin", change.Plugin), zap.Bool("enabled", change.Enabled), zap.Error(errs[change.Plugin]))
+			}
+			return
+		}
+		pending = failed
+	}
+}
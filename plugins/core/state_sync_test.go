@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStateStore 基于内存的启用状态存储，多个管理器共享时模拟多个实例
+type memoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]bool
+}
+
+func newMemoryStateStore(states map[string]bool) *memoryStateStore {
+	if states == nil {
+		states = make(map[string]bool)
+	}
+	return &memoryStateStore{states: states}
+}
+
+func (s *memoryStateStore) LoadStates(ctx context.Context) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := make(map[string]bool, len(s.states))
+	for name, enabled := range s.states {
+		copied[name] = enabled
+	}
+	return copied, nil
+}
+
+func (s *memoryStateStore) SaveState(ctx context.Context, name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[name] = enabled
+	return nil
+}
+
+func (s *memoryStateStore) get(name string) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enabled, known := s.states[name]
+	return enabled, known
+}
+
+// memoryBroadcaster 进程内的状态广播
+type memoryBroadcaster struct {
+	mu   sync.Mutex
+	subs []chan PluginStateChange
+}
+
+func (b *memoryBroadcaster) Publish(ctx context.Context, change PluginStateChange) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		sub <- change
+	}
+	return nil
+}
+
+func (b *memoryBroadcaster) Subscribe(ctx context.Context, handler func(PluginStateChange)) error {
+	ch := make(chan PluginStateChange, 16)
+	b.mu.Lock()
+	b.subs = append(b.subs, ch)
+	b.mu.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case change := <-ch:
+			handler(change)
+		}
+	}
+}
+
+func (b *memoryBroadcaster) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// newSyncedManager 创建使用共享存储和广播的管理器，并注册 B -> A 的依赖链
+func newSyncedManager(t *testing.T, store PluginStateStore, broadcaster PluginStateBroadcaster, poll time.Duration) (*PluginManager, *StateSync) {
+	t.Helper()
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	s := NewStateSync(pm, store, broadcaster, StateSyncConfig{PollInterval: poll})
+	if err := s.Start(); err != nil {
+		t.Fatalf("start state sync error: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	pm.SetStateSync(s)
+
+	a := newTestPlugin("A", false)
+	b := newTestPlugin("B", false)
+	b.deps = []string{"A"}
+	for _, plugin := range []*testPlugin{a, b} {
+		if err := pm.Register(plugin); err != nil {
+			t.Fatalf("register %s error: %v", plugin.name, err)
+		}
+	}
+	return pm, s
+}
+
+// waitForStatus 等待插件达到期望的启用状态
+func waitForStatus(t *testing.T, pm *PluginManager, name, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, _ := pm.GetPluginStatus(name)
+		if status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be %s, got %s", name, want, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStateSyncRestoresPersistedState(t *testing.T) {
+	store := newMemoryStateStore(map[string]bool{"A": false, "B": false})
+	pm, _ := newSyncedManager(t, store, nil, time.Hour)
+
+	for _, name := range []string{"A", "B"} {
+		if state, _ := pm.GetPluginState(name); state != StateDisabled {
+			t.Fatalf("expected %s registered as disabled, got %s", name, state)
+		}
+	}
+
+	if _, err := pm.EnablePluginCascade("B"); err != nil {
+		t.Fatalf("cascade enable error: %v", err)
+	}
+	for _, name := range []string{"A", "B"} {
+		if enabled, known := store.get(name); !known || !enabled {
+			t.Fatalf("expected %s saved as enabled", name)
+		}
+	}
+}
+
+func TestStateSyncBroadcast(t *testing.T) {
+	store := newMemoryStateStore(nil)
+	broadcaster := &memoryBroadcaster{}
+	pm1, _ := newSyncedManager(t, store, broadcaster, time.Hour)
+	pm2, _ := newSyncedManager(t, store, broadcaster, time.Hour)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for broadcaster.subscribers() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscribers not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := pm1.DisablePluginCascade("A"); err != nil {
+		t.Fatalf("cascade disable error: %v", err)
+	}
+	waitForStatus(t, pm2, "B", "disabled")
+	waitForStatus(t, pm2, "A", "disabled")
+
+	if err := pm2.EnablePlugin("A"); err != nil {
+		t.Fatalf("enable error: %v", err)
+	}
+	waitForStatus(t, pm1, "A", "enabled")
+	if status, _ := pm1.GetPluginStatus("B"); status != "disabled" {
+		t.Fatalf("expected B to stay disabled, got %s", status)
+	}
+}
+
+func TestStateSyncPolling(t *testing.T) {
+	store := newMemoryStateStore(nil)
+	pm1, _ := newSyncedManager(t, store, nil, time.Hour)
+	pm2, s2 := newSyncedManager(t, store, nil, time.Hour)
+
+	if _, err := pm1.DisablePluginCascade("A"); err != nil {
+		t.Fatalf("cascade disable error: %v", err)
+	}
+	// 轮询读到的变更没有顺序，依赖方B需要先于A禁用
+	changes, err := s2.poll()
+	if err != nil {
+		t.Fatalf("poll error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	for _, name := range []string{"A", "B"} {
+		if status, _ := pm2.GetPluginStatus(name); status != "disabled" {
+			t.Fatalf("expected %s disabled after poll, got %s", name, status)
+		}
+	}
+
+	// 再次轮询没有新的变更
+	if changes, _ := s2.poll(); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
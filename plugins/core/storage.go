@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxStorageKeyLength 插件存储键的最大长度（字符）
+const MaxStorageKeyLength = 255
+
+// ErrStorageKeyNotFound 键不存在或已过期
+var ErrStorageKeyNotFound = errors.New("存储键不存在或已过期")
+
+// PluginStorage 插件键值存储接口
+// 数据按插件名称和租户隔离，租户ID从上下文的RequestMeta中读取（未携带时为0，即全局命名空间），
+// 因此处理HTTP请求时应传入core.ContextFromGin(c)
+type PluginStorage interface {
+	// Get 读取键值，键不存在或已过期时返回ErrStorageKeyNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入键值，ttl<=0表示永不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除键，键不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// List 列出指定前缀的全部键值，prefix为空时返回命名空间内的全部键值
+	List(ctx context.Context, prefix string) (map[string][]byte, error)
+	// CompareAndSwap 当前值等于old时原子地写入new并返回true；old为nil表示仅在键不存在时写入
+	CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error)
+}
+
+// StorageAware 需要键值存储的插件实现该接口（可选），管理器在Init之前注入存储句柄
+type StorageAware interface {
+	SetStorage(storage PluginStorage)
+}
+
+// StorageNamespace 存储命名空间
+type StorageNamespace struct {
+	Plugin   string // 插件名称
+	TenantID uint   // 租户ID
+}
+
+// StorageBackend 插件存储后端，由管理器统一配置，插件通过PluginStorage访问
+type StorageBackend interface {
+	Get(ctx context.Context, ns StorageNamespace, key string) ([]byte, error)
+	Set(ctx context.Context, ns StorageNamespace, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, ns StorageNamespace, key string) error
+	List(ctx context.Context, ns StorageNamespace, prefix string) (map[string][]byte, error)
+	CompareAndSwap(ctx context.Context, ns StorageNamespace, key string, old, new []byte, ttl time.Duration) (bool, error)
+}
+
+// SetStorageBackend 设置插件存储后端，默认使用数据库后端
+// 应在注册插件之前调用，已注入的存储句柄会自动使用新的后端
+func (pm *PluginManager) SetStorageBackend(backend StorageBackend) {
+	pm.storageMu.Lock()
+	defer pm.storageMu.Unlock()
+	pm.storageBackend = backend
+}
+
+// currentStorageBackend 返回当前存储后端
+func (pm *PluginManager) currentStorageBackend() StorageBackend {
+	pm.storageMu.RLock()
+	defer pm.storageMu.RUnlock()
+	if pm.storageBackend == nil {
+		return &DBStorageBackend{}
+	}
+	return pm.storageBackend
+}
+
+// StorageFor 返回指定插件的存储句柄
+// 未实现StorageAware接口的插件也可以通过该方法获取自己的存储
+func (pm *PluginManager) StorageFor(name string) PluginStorage {
+	return &pluginStorage{pm: pm, plugin: name}
+}
+
+// injectStorage 为实现了StorageAware接口的插件注入存储句柄
+func (pm *PluginManager) injectStorage(plugin Plugin) {
+	if aware, ok := plugin.(StorageAware); ok {
+		aware.SetStorage(pm.StorageFor(plugin.Name()))
+	}
+}
+
+// pluginStorage 绑定到单个插件的存储句柄
+type pluginStorage struct {
+	pm     *PluginManager
+	plugin string
+}
+
+// namespace 根据上下文中的租户信息确定命名空间
+func (s *pluginStorage) namespace(ctx context.Context) StorageNamespace {
+	ns := StorageNamespace{Plugin: s.plugin}
+	if meta, ok := RequestMetaFromContext(ctx); ok {
+		ns.TenantID = meta.TenantID
+	}
+	return ns
+}
+
+// validateStorageKey 检查存储键是否合法
+func validateStorageKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("存储键不能为空")
+	}
+	if utf8.RuneCountInString(key) > MaxStorageKeyLength {
+		return fmt.Errorf("存储键长度不能超过 %d 个字符", MaxStorageKeyLength)
+	}
+	return nil
+}
+
+// storageContext 保证上下文非空
+func storageContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// Get 读取键值
+func (s *pluginStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validateStorageKey(key); err != nil {
+		return nil, err
+	}
+	ctx = storageContext(ctx)
+	return s.pm.currentStorageBackend().Get(ctx, s.namespace(ctx), key)
+}
+
+// Set 写入键值
+func (s *pluginStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := validateStorageKey(key); err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	ctx = storageContext(ctx)
+	return s.pm.currentStorageBackend().Set(ctx, s.namespace(ctx), key, value, ttl)
+}
+
+// Delete 删除键
+func (s *pluginStorage) Delete(ctx context.Context, key string) error {
+	if err := validateStorageKey(key); err != nil {
+		return err
+	}
+	ctx = storageContext(ctx)
+	return s.pm.currentStorageBackend().Delete(ctx, s.namespace(ctx), key)
+}
+
+// List 按前缀列出键值
+func (s *pluginStorage) List(ctx context.Context, prefix string) (map[string][]byte, error) {
+	ctx = storageContext(ctx)
+	return s.pm.currentStorageBackend().List(ctx, s.namespace(ctx), prefix)
+}
+
+// CompareAndSwap 比较并交换
+func (s *pluginStorage) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	if err := validateStorageKey(key); err != nil {
+		return false, err
+	}
+	if new == nil {
+		new = []byte{}
+	}
+	ctx = storageContext(ctx)
+	return s.pm.currentStorageBackend().CompareAndSwap(ctx, s.namespace(ctx), key, old, new, ttl)
+}
+
+// storageExpiry 根据TTL计算过期时间，ttl<=0时返回nil
+func storageExpiry(ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(ttl)
+	return &expiresAt
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"weave/models"
+	"weave/pkg"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStorageBackend 基于数据库的插件存储后端（plugin_kv表）
+// DB为空时使用pkg.DB；过期的键在读取时被忽略，并在写入同一个键时被覆盖
+type DBStorageBackend struct {
+	DB *gorm.DB
+}
+
+// NewDBStorageBackend 创建数据库存储后端
+func NewDBStorageBackend(db *gorm.DB) *DBStorageBackend {
+	return &DBStorageBackend{DB: db}
+}
+
+// db 返回绑定上下文的数据库连接
+func (b *DBStorageBackend) db(ctx context.Context) (*gorm.DB, error) {
+	db := b.DB
+	if db == nil {
+		db = pkg.DB
+	}
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化，无法使用插件存储")
+	}
+	return db.WithContext(ctx), nil
+}
+
+// scope 限定命名空间和键的查询条件
+func (b *DBStorageBackend) scope(db *gorm.DB, ns StorageNamespace, key string) *gorm.DB {
+	return db.Model(&models.PluginKV{}).
+		Where("plugin_name = ? AND tenant_id = ? AND storage_key = ?", ns.Plugin, ns.TenantID, key)
+}
+
+// Get 读取未过期的键值
+func (b *DBStorageBackend) Get(ctx context.Context, ns StorageNamespace, key string) ([]byte, error) {
+	db, err := b.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var record models.PluginKV
+	err = b.scope(db, ns, key).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStorageKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return record.Value, nil
+}
+
+// Set 写入键值，已存在时覆盖
+func (b *DBStorageBackend) Set(ctx context.Context, ns StorageNamespace, key string, value []byte, ttl time.Duration) error {
+	db, err := b.db(ctx)
+	if err != nil {
+		return err
+	}
+	record := models.PluginKV{
+		PluginName: ns.Plugin,
+		TenantID:   ns.TenantID,
+		StorageKey: key,
+		Value:      value,
+		ExpiresAt:  storageExpiry(ttl),
+	}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "plugin_name"}, {Name: "tenant_id"}, {Name: "storage_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at", "updated_at"}),
+	}).Create(&record).Error
+	if err != nil {
+		return fmt.Errorf("写入插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return nil
+}
+
+// Delete 删除键
+func (b *DBStorageBackend) Delete(ctx context.Context, ns StorageNamespace, key string) error {
+	db, err := b.db(ctx)
+	if err != nil {
+		return err
+	}
+	if err := b.scope(db, ns, key).Delete(&models.PluginKV{}).Error; err != nil {
+		return fmt.Errorf("删除插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return nil
+}
+
+// List 按前缀列出未过期的键值
+func (b *DBStorageBackend) List(ctx context.Context, ns StorageNamespace, prefix string) (map[string][]byte, error) {
+	db, err := b.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.PluginKV{}).
+		Where("plugin_name = ? AND tenant_id = ?", ns.Plugin, ns.TenantID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+	if prefix != "" {
+		query = query.Where("storage_key LIKE ? ESCAPE '!'", escapeLikePrefix(prefix)+"%")
+	}
+
+	var records []models.PluginKV
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("列出插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+
+	items := make(map[string][]byte, len(records))
+	for _, record := range records {
+		// 数据库排序规则可能不区分大小写，再次按前缀精确过滤
+		if strings.HasPrefix(record.StorageKey, prefix) {
+			items[record.StorageKey] = record.Value
+		}
+	}
+	return items, nil
+}
+
+// CompareAndSwap 通过条件更新实现原子的比较并交换
+func (b *DBStorageBackend) CompareAndSwap(ctx context.Context, ns StorageNamespace, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	db, err := b.db(ctx)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+
+	if old == nil {
+		// 仅在键不存在时写入：先清理同名的过期记录，再依赖唯一索引插入
+		if err := b.scope(db, ns, key).Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+			Delete(&models.PluginKV{}).Error; err != nil {
+			return false, fmt.Errorf("写入插件 '%s' 的存储失败: %w", ns.Plugin, err)
+		}
+		record := models.PluginKV{
+			PluginName: ns.Plugin,
+			TenantID:   ns.TenantID,
+			StorageKey: key,
+			Value:      new,
+			ExpiresAt:  storageExpiry(ttl),
+		}
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return false, fmt.Errorf("写入插件 '%s' 的存储失败: %w", ns.Plugin, result.Error)
+		}
+		return result.RowsAffected == 1, nil
+	}
+
+	// 同时更新updated_at，保证新旧值相同时影响行数仍为1
+	result := b.scope(db, ns, key).
+		Where("value = ?", old).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Updates(map[string]interface{}{
+			"value":      new,
+			"expires_at": storageExpiry(ttl),
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("写入插件 '%s' 的存储失败: %w", ns.Plugin, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// escapeLikePrefix 转义LIKE模式中的特殊字符，使用!作为转义符
+func escapeLikePrefix(prefix string) string {
+	replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return replacer.Replace(prefix)
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisStoragePrefix Redis存储后端的默认键前缀
+const DefaultRedisStoragePrefix = "weave:plugin_kv"
+
+// redisScanBatch 按前缀列出键时每次SCAN的数量
+const redisScanBatch = 100
+
+// compareAndSwapScript 原子比较并交换脚本
+// ARGV[1]为"1"时表示仅在键不存在时写入；ARGV[2]为期望的旧值；ARGV[3]为新值；ARGV[4]为过期时间（毫秒，0表示永不过期）
+var compareAndSwapScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if ARGV[1] == '1' then
+	if current then
+		return 0
+	end
+elseif (not current) or current ~= ARGV[2] then
+	return 0
+end
+local ttl = tonumber(ARGV[4])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// RedisStorageBackend 基于Redis的插件存储后端
+// 键格式为 <prefix>:<插件名>:<租户ID>:<键>，TTL由Redis原生过期机制处理
+type RedisStorageBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStorageBackend 创建Redis存储后端，prefix为空时使用DefaultRedisStoragePrefix
+func NewRedisStorageBackend(client redis.UniversalClient, prefix string) *RedisStorageBackend {
+	if prefix == "" {
+		prefix = DefaultRedisStoragePrefix
+	}
+	return &RedisStorageBackend{client: client, prefix: prefix}
+}
+
+// namespaceKey 返回命名空间在Redis中的键前缀
+func (b *RedisStorageBackend) namespaceKey(ns StorageNamespace) string {
+	return fmt.Sprintf("%s:%s:%d:", b.prefix, ns.Plugin, ns.TenantID)
+}
+
+// Get 读取键值
+func (b *RedisStorageBackend) Get(ctx context.Context, ns StorageNamespace, key string) ([]byte, error) {
+	value, err := b.client.Get(ctx, b.namespaceKey(ns)+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrStorageKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return value, nil
+}
+
+// Set 写入键值
+func (b *RedisStorageBackend) Set(ctx context.Context, ns StorageNamespace, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	if err := b.client.Set(ctx, b.namespaceKey(ns)+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("写入插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return nil
+}
+
+// Delete 删除键
+func (b *RedisStorageBackend) Delete(ctx context.Context, ns StorageNamespace, key string) error {
+	if err := b.client.Del(ctx, b.namespaceKey(ns)+key).Err(); err != nil {
+		return fmt.Errorf("删除插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return nil
+}
+
+// List 通过SCAN按前缀列出键值
+func (b *RedisStorageBackend) List(ctx context.Context, ns StorageNamespace, prefix string) (map[string][]byte, error) {
+	base := b.namespaceKey(ns)
+	pattern := escapeRedisPattern(base+prefix) + "*"
+
+	items := make(map[string][]byte)
+	var cursor uint64
+	for {
+		keys, next, err := b.client.Scan(ctx, cursor, pattern, redisScanBatch).Result()
+		if err != nil {
+			return nil, fmt.Errorf("列出插件 '%s' 的存储失败: %w", ns.Plugin, err)
+		}
+		if len(keys) > 0 {
+			values, err := b.client.MGet(ctx, keys...).Result()
+			if err != nil {
+				return nil, fmt.Errorf("列出插件 '%s' 的存储失败: %w", ns.Plugin, err)
+			}
+			for i, value := range values {
+				// 键可能在SCAN和MGET之间过期
+				if s, ok := value.(string); ok {
+					items[strings.TrimPrefix(keys[i], base)] = []byte(s)
+				}
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return items, nil
+}
+
+// CompareAndSwap 通过Lua脚本实现原子的比较并交换
+func (b *RedisStorageBackend) CompareAndSwap(ctx context.Context, ns StorageNamespace, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	absent := "0"
+	if old == nil {
+		absent = "1"
+	}
+	ttlMillis := int64(0)
+	if ttl > 0 {
+		ttlMillis = ttl.Milliseconds()
+		if ttlMillis == 0 {
+			ttlMillis = 1
+		}
+	}
+	swapped, err := compareAndSwapScript.Run(ctx, b.client, []string{b.namespaceKey(ns) + key}, absent, old, new, ttlMillis).Int()
+	if err != nil {
+		return false, fmt.Errorf("写入插件 '%s' 的存储失败: %w", ns.Plugin, err)
+	}
+	return swapped == 1, nil
+}
+
+// escapeRedisPattern 转义SCAN MATCH模式中的通配符
+func escapeRedisPattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStorageBackend 测试用内存存储后端
+type memoryStorageBackend struct {
+	mu    sync.Mutex
+	items map[StorageNamespace]map[string][]byte
+}
+
+func newMemoryStorageBackend() *memoryStorageBackend {
+	return &memoryStorageBackend{items: make(map[StorageNamespace]map[string][]byte)}
+}
+
+func (b *memoryStorageBackend) bucket(ns StorageNamespace) map[string][]byte {
+	if b.items[ns] == nil {
+		b.items[ns] = make(map[string][]byte)
+	}
+	return b.items[ns]
+}
+
+func (b *memoryStorageBackend) Get(ctx context.Context, ns StorageNamespace, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	value, ok := b.bucket(ns)[key]
+	if !ok {
+		return nil, ErrStorageKeyNotFound
+	}
+	return value, nil
+}
+
+func (b *memoryStorageBackend) Set(ctx context.Context, ns StorageNamespace, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket(ns)[key] = value
+	return nil
+}
+
+func (b *memoryStorageBackend) Delete(ctx context.Context, ns StorageNamespace, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.bucket(ns), key)
+	return nil
+}
+
+func (b *memoryStorageBackend) List(ctx context.Context, ns StorageNamespace, prefix string) (map[string][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	items := make(map[string][]byte)
+	for key, value := range b.bucket(ns) {
+		if strings.HasPrefix(key, prefix) {
+			items[key] = value
+		}
+	}
+	return items, nil
+}
+
+func (b *memoryStorageBackend) CompareAndSwap(ctx context.Context, ns StorageNamespace, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current, exists := b.bucket(ns)[key]
+	if (old == nil && exists) || (old != nil && (!exists || !bytes.Equal(current, old))) {
+		return false, nil
+	}
+	b.bucket(ns)[key] = new
+	return true, nil
+}
+
+// storagePlugin 实现StorageAware接口的测试插件，在Init中写入数据
+type storagePlugin struct {
+	testPlugin
+	storage PluginStorage
+}
+
+func (p *storagePlugin) SetStorage(storage PluginStorage) { p.storage = storage }
+
+func (p *storagePlugin) Init() error {
+	p.initCalled++
+	return p.storage.Set(context.Background(), "initialized", []byte("yes"), 0)
+}
+
+func TestStorageInjectedBeforeInit(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetStorageBackend(newMemoryStorageBackend())
+
+	plugin := &storagePlugin{testPlugin: testPlugin{name: "kv"}}
+	if err := pm.Register(plugin); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	value, err := pm.StorageFor("kv").Get(context.Background(), "initialized")
+	if err != nil || string(value) != "yes" {
+		t.Fatalf("expected value written during Init, got %q, %v", value, err)
+	}
+}
+
+func TestStorageNamespacedByPluginAndTenant(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetStorageBackend(newMemoryStorageBackend())
+
+	tenant1 := WithRequestMeta(context.Background(), RequestMeta{TenantID: 1})
+	tenant2 := WithRequestMeta(context.Background(), RequestMeta{TenantID: 2})
+	a, b := pm.StorageFor("a"), pm.StorageFor("b")
+
+	if err := a.Set(tenant1, "counter", []byte("1"), 0); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	if _, err := a.Get(tenant2, "counter"); !errors.Is(err, ErrStorageKeyNotFound) {
+		t.Fatalf("expected other tenant isolated, got %v", err)
+	}
+	if _, err := b.Get(tenant1, "counter"); !errors.Is(err, ErrStorageKeyNotFound) {
+		t.Fatalf("expected other plugin isolated, got %v", err)
+	}
+
+	_ = a.Set(tenant1, "user:1", []byte("x"), 0)
+	_ = a.Set(tenant1, "user:2", []byte("y"), 0)
+	items, err := a.List(tenant1, "user:")
+	if err != nil || len(items) != 2 || string(items["user:2"]) != "y" {
+		t.Fatalf("unexpected list result: %v, %v", items, err)
+	}
+
+	// 比较并交换
+	if ok, _ := a.CompareAndSwap(tenant1, "counter", []byte("0"), []byte("2"), 0); ok {
+		t.Fatalf("expected CAS with stale value to fail")
+	}
+	if ok, _ := a.CompareAndSwap(tenant1, "counter", []byte("1"), []byte("2"), 0); !ok {
+		t.Fatalf("expected CAS with current value to succeed")
+	}
+	if ok, _ := a.CompareAndSwap(tenant1, "lock", nil, []byte("owner"), time.Minute); !ok {
+		t.Fatalf("expected create-if-absent CAS to succeed")
+	}
+	if ok, _ := a.CompareAndSwap(tenant1, "lock", nil, []byte("other"), time.Minute); ok {
+		t.Fatalf("expected create-if-absent CAS to fail when key exists")
+	}
+
+	if err := a.Delete(tenant1, "counter"); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+	if _, err := a.Get(tenant1, "counter"); !errors.Is(err, ErrStorageKeyNotFound) {
+		t.Fatalf("expected key deleted, got %v", err)
+	}
+}
+
+func TestStorageKeyValidation(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	pm.SetStorageBackend(newMemoryStorageBackend())
+	storage := pm.StorageFor("a")
+
+	if err := storage.Set(context.Background(), "", []byte("x"), 0); err == nil {
+		t.Fatalf("expected empty key to be rejected")
+	}
+	if _, err := storage.Get(context.Background(), strings.Repeat("k", MaxStorageKeyLength+1)); err == nil {
+		t.Fatalf("expected long key to be rejected")
+	}
+}
+
+func TestDBStorageBackendWithoutDatabase(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if _, err := pm.StorageFor("a").Get(context.Background(), "k"); err == nil || !strings.Contains(err.Error(), "数据库未初始化") {
+		t.Fatalf("expected database not initialized error, got %v", err)
+	}
+}
+
+func TestStoragePatternEscaping(t *testing.T) {
+	if got := escapeLikePrefix("50%_off!"); got != "50!%!_off!!" {
+		t.Fatalf("unexpected LIKE escape: %q", got)
+	}
+	if got := escapeRedisPattern("a*b?[c]"); got != `a\*b\?\[c\]` {
+		t.Fatalf("unexpected redis pattern escape: %q", got)
+	}
+}
@@ -0,0 +1,385 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version 语义化版本号（major.minor.patch[-prerelease]）
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseVersion 解析版本号
+// 支持可选的"v"前缀以及省略的次版本号/修订号（如"1"、"1.2"），构建元数据（+xxx）会被忽略
+func ParseVersion(s string) (Version, error) {
+	v, _, err := parsePartialVersion(s)
+	return v, err
+}
+
+// parsePartialVersion 解析版本号并返回实际给出的版本段数
+func parsePartialVersion(s string) (Version, int, error) {
+	raw := strings.TrimSpace(s)
+	str := strings.TrimPrefix(strings.TrimPrefix(raw, "v"), "V")
+	if idx := strings.Index(str, "+"); idx >= 0 {
+		str = str[:idx]
+	}
+
+	var v Version
+	if idx := strings.Index(str, "-"); idx >= 0 {
+		v.Prerelease = str[idx+1:]
+		str = str[:idx]
+		if v.Prerelease == "" {
+			return Version{}, 0, fmt.Errorf("无效的版本号: '%s'", raw)
+		}
+	}
+
+	parts := strings.Split(str, ".")
+	if str == "" || len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("无效的版本号: '%s'", raw)
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("无效的版本号: '%s'", raw)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, len(parts), nil
+}
+
+// Compare 比较两个版本号，v小于、等于、大于other时分别返回-1、0、1
+// 预发布版本低于对应的正式版本，预发布标识之间按语义化版本规范逐段比较（见comparePrerelease）
+func (v Version) Compare(other Version) int {
+	if c := compareInt(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	default:
+		return comparePrerelease(v.Prerelease, other.Prerelease)
+	}
+}
+
+// comparePrerelease 按语义化版本规范比较预发布标识：逐段比较，纯数字的段按数值比较且低于非数字段，
+// 其他段按ASCII顺序比较；前面的段都相同时段数少的版本较低，如1.0.0-beta.2 < 1.0.0-beta.10 < 1.0.0-beta.10.1
+func comparePrerelease(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		leftNum, leftErr := strconv.ParseUint(left[i], 10, 64)
+		rightNum, rightErr := strconv.ParseUint(right[i], 10, 64)
+		switch {
+		case leftErr == nil && rightErr == nil:
+			if leftNum != rightNum {
+				if leftNum < rightNum {
+					return -1
+				}
+				return 1
+			}
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(left), len(right))
+}
+
+// String 返回版本号的字符串形式
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// versionClause 单个版本比较条件
+type versionClause struct {
+	op      string
+	version Version
+}
+
+func (c versionClause) match(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Constraint 版本约束，多个条件之间为"且"关系
+type Constraint struct {
+	raw     string
+	clauses []versionClause
+}
+
+// ParseConstraint 解析版本约束表达式
+// 支持 >=、<=、>、<、=、!=、^（兼容版本）、~（近似版本），条件之间以逗号或空格分隔，
+// 例如 ">=1.2.0,<2"、"^1.4"、"~1.2.3"；省略运算符视为"="，省略的版本段按范围匹配（"=1.2"等价于">=1.2.0,<1.3.0"）
+// 空表达式或"*"匹配任意版本
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" || c.raw == "*" {
+		return c, nil
+	}
+
+	fields := strings.FieldsFunc(c.raw, func(r rune) bool { return r == ',' || r == ' ' })
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		op := constraintOperator(field)
+		verStr := strings.TrimSpace(field[len(op):])
+		// 允许运算符与版本号之间有空格，如">= 1.2.0"
+		if verStr == "" && i+1 < len(fields) {
+			i++
+			verStr = fields[i]
+		}
+
+		v, segments, err := parsePartialVersion(verStr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的版本约束 '%s': %w", c.raw, err)
+		}
+		c.clauses = append(c.clauses, expandClause(op, v, segments)...)
+	}
+	return c, nil
+}
+
+// constraintOperator 提取约束条件前缀的运算符
+func constraintOperator(field string) string {
+	for _, op := range []string{">=", "<=", "!=", "==", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(field, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// expandClause 将运算符展开为基础比较条件
+func expandClause(op string, v Version, segments int) []versionClause {
+	switch op {
+	case "^":
+		// ^1.2.3 := >=1.2.3,<2.0.0；^0.2.3 := >=0.2.3,<0.3.0；^0.0.3 := >=0.0.3,<0.0.4
+		upper := Version{Major: v.Major + 1}
+		if v.Major == 0 {
+			switch {
+			case segments == 1:
+				upper = Version{Major: 1}
+			case v.Minor > 0 || segments == 2:
+				upper = Version{Minor: v.Minor + 1}
+			default:
+				upper = Version{Patch: v.Patch + 1}
+			}
+		}
+		return []versionClause{{op: ">=", version: v}, {op: "<", version: upper}}
+	case "~":
+		// ~1.2.3 := >=1.2.3,<1.3.0；~1 := >=1.0.0,<2.0.0
+		upper := Version{Major: v.Major, Minor: v.Minor + 1}
+		if segments == 1 {
+			upper = Version{Major: v.Major + 1}
+		}
+		return []versionClause{{op: ">=", version: v}, {op: "<", version: upper}}
+	case "", "=", "==":
+		if segments < 3 && v.Prerelease == "" {
+			return []versionClause{{op: ">=", version: v}, {op: "<", version: bumpVersion(v, segments)}}
+		}
+		return []versionClause{{op: "=", version: v}}
+	case ">":
+		// >1.2 表示高于1.2.x的所有版本
+		if segments < 3 && v.Prerelease == "" {
+			return []versionClause{{op: ">=", version: bumpVersion(v, segments)}}
+		}
+	case "<=":
+		// <=1.2 包含1.2.x的所有版本
+		if segments < 3 && v.Prerelease == "" {
+			return []versionClause{{op: "<", version: bumpVersion(v, segments)}}
+		}
+	}
+	return []versionClause{{op: op, version: v}}
+}
+
+// bumpVersion 将版本号在最后给出的版本段上加一
+func bumpVersion(v Version, segments int) Version {
+	if segments == 1 {
+		return Version{Major: v.Major + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// Check 判断版本是否满足约束
+func (c *Constraint) Check(v Version) bool {
+	if c == nil {
+		return true
+	}
+	for _, clause := range c.clauses {
+		if !clause.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckString 判断版本字符串是否满足约束
+// 无约束时任意版本（包括无法解析的版本号）均满足
+func (c *Constraint) CheckString(version string) (bool, error) {
+	if c.IsAny() {
+		return true, nil
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+// IsAny 判断约束是否匹配任意版本
+func (c *Constraint) IsAny() bool {
+	return c == nil || len(c.clauses) == 0
+}
+
+// String 返回约束的原始表达式
+func (c *Constraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.raw
+}
+
+// Dependency 插件依赖声明
+type Dependency struct {
+	Name       string      // 依赖的插件名称
+	Constraint *Constraint // 版本约束
+	Optional   bool        // 是否为可选依赖
+}
+
+// ParseDependency 解析依赖声明
+// 格式为"插件名[?][版本约束]"，例如"note"、"note>=1.2.0,<2"、"hello?^1.0"；
+// 名称后（或声明末尾）的"?"表示可选依赖：缺失时不影响注册，存在时仍需满足版本约束
+func ParseDependency(spec string) (Dependency, error) {
+	s := strings.TrimSpace(spec)
+	var dep Dependency
+
+	if strings.HasSuffix(s, "?") {
+		dep.Optional = true
+		s = strings.TrimSpace(strings.TrimSuffix(s, "?"))
+	}
+
+	idx := strings.IndexAny(s, "<>=!^~ ,")
+	if idx < 0 {
+		idx = len(s)
+	}
+	dep.Name = strings.TrimSpace(s[:idx])
+	if strings.HasSuffix(dep.Name, "?") {
+		dep.Optional = true
+		dep.Name = strings.TrimSuffix(dep.Name, "?")
+	}
+	if dep.Name == "" {
+		return Dependency{}, fmt.Errorf("无效的依赖声明: '%s'", spec)
+	}
+
+	constraint, err := ParseConstraint(s[idx:])
+	if err != nil {
+		return Dependency{}, fmt.Errorf("插件依赖 '%s' 声明无效: %w", dep.Name, err)
+	}
+	dep.Constraint = constraint
+	return dep, nil
+}
+
+// String 返回依赖声明的字符串形式
+func (d Dependency) String() string {
+	s := d.Name
+	if d.Optional {
+		s += "?"
+	}
+	return s + d.Constraint.String()
+}
+
+// parseDependencies 解析插件声明的全部依赖
+func parseDependencies(plugin versioned) ([]Dependency, error) {
+	specs := plugin.GetDependencies()
+	deps := make([]Dependency, 0, len(specs))
+	for _, spec := range specs {
+		dep, err := ParseDependency(spec)
+		if err != nil {
+			return nil, fmt.Errorf("插件 '%s' %w", plugin.Name(), err)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// requiredDependencyNames 返回必需依赖的插件名称
+func requiredDependencyNames(deps []Dependency) []string {
+	names := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if !dep.Optional {
+			names = append(names, dep.Name)
+		}
+	}
+	return names
+}
+
+// DependencyNames 从依赖声明中提取插件名称（包括可选依赖），无法解析的声明会被忽略
+func DependencyNames(specs []string) []string {
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if dep, err := ParseDependency(spec); err == nil {
+			names = append(names, dep.Name)
+		}
+	}
+	return names
+}
+
+// checkDependencyVersion 检查插件版本是否满足依赖约束
+func checkDependencyVersion(consumer string, dep Dependency, provider versioned) error {
+	ok, err := dep.Constraint.CheckString(provider.Version())
+	if err != nil {
+		return fmt.Errorf("插件 '%s' 依赖的插件 '%s' 版本号 '%s' 无法解析: %w", consumer, dep.Name, provider.Version(), err)
+	}
+	if !ok {
+		return fmt.Errorf("插件 '%s' 要求依赖 '%s' 的版本满足 '%s'，但当前版本为 '%s'", consumer, dep.Name, dep.Constraint, provider.Version())
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"weave/pkg"
+)
+
+// versionedPlugin 可指定版本号的测试插件
+type versionedPlugin struct {
+	testPlugin
+	version string
+}
+
+func (p *versionedPlugin) Version() string { return p.version }
+
+func newVersionedPlugin(name, version string, deps ...string) *versionedPlugin {
+	return &versionedPlugin{testPlugin: testPlugin{name: name, deps: deps}, version: version}
+}
+
+func TestParseVersionAndCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1", "1.0.0", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"2.0.0-beta", "2.0.0", -1},
+		{"2.0.0-alpha", "2.0.0-beta", -1},
+		// 预发布标识中的数字段按数值比较，数字段低于非数字段，段数少的较低
+		{"1.0.0-beta.10", "1.0.0-beta.2", 1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.0.0+build.5", "1.0.0", 0},
+	}
+	for _, tc := range cases {
+		a, err := ParseVersion(tc.a)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.a, err)
+		}
+		b, err := ParseVersion(tc.b)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.b, err)
+		}
+		if got := a.Compare(b); got != tc.want {
+			t.Fatalf("compare(%s, %s) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"", "x.y", "1.2.3.4", "1.-1", "1.0.0-"} {
+		if _, err := ParseVersion(bad); err == nil {
+			t.Fatalf("expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.2.0,<2", "1.2.0", true},
+		{">=1.2.0,<2", "1.9.9", true},
+		{">=1.2.0,<2", "2.0.0", false},
+		{">=1.2.0,<2", "1.1.9", false},
+		{">= 1.2.0 <2", "1.5.0", true},
+		{"^1.4", "1.9.0", true},
+		{"^1.4", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"~1", "1.9.0", true},
+		{"1.2", "1.2.7", true},
+		{"=1.2", "1.3.0", false},
+		{"1.2.3", "1.2.4", false},
+		{"!=1.2.3", "1.2.3", false},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"*", "0.0.1", true},
+		{"", "9.9.9", true},
+	}
+	for _, tc := range cases {
+		c, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("parse constraint %q: %v", tc.constraint, err)
+		}
+		got, err := c.CheckString(tc.version)
+		if err != nil {
+			t.Fatalf("check %q against %q: %v", tc.version, tc.constraint, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%q matches %q = %v, want %v", tc.version, tc.constraint, got, tc.want)
+		}
+	}
+
+	if _, err := ParseConstraint(">=abc"); err == nil {
+		t.Fatalf("expected error for invalid constraint")
+	}
+}
+
+func TestParseDependency(t *testing.T) {
+	cases := []struct {
+		spec       string
+		name       string
+		constraint string
+		optional   bool
+	}{
+		{"note", "note", "", false},
+		{"note>=1.2.0,<2", "note", ">=1.2.0,<2", false},
+		{"note >= 1.2", "note", ">= 1.2", false},
+		{"hello?", "hello", "", true},
+		{"hello?^1.0", "hello", "^1.0", true},
+		{"hello^1.0?", "hello", "^1.0", true},
+	}
+	for _, tc := range cases {
+		dep, err := ParseDependency(tc.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.spec, err)
+		}
+		if dep.Name != tc.name || dep.Constraint.String() != tc.constraint || dep.Optional != tc.optional {
+			t.Fatalf("parse %q = {%s %q %v}", tc.spec, dep.Name, dep.Constraint, dep.Optional)
+		}
+	}
+
+	for _, bad := range []string{"", ">=1.0", "note>=x"} {
+		if _, err := ParseDependency(bad); err == nil {
+			t.Fatalf("expected error parsing dependency %q", bad)
+		}
+	}
+}
+
+func TestRegisterRejectsIncompatibleVersion(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.Register(newVersionedPlugin("note", "1.5.0")); err != nil {
+		t.Fatalf("register note error: %v", err)
+	}
+
+	err := pm.Register(newVersionedPlugin("app", "1.0.0", "note>=2.0.0"))
+	if err == nil {
+		t.Fatalf("expected version mismatch to fail registration")
+	}
+	if !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginDependency}) || !strings.Contains(err.Error(), "1.5.0") {
+		t.Fatalf("expected PluginDependencyError mentioning version, got %v", err)
+	}
+	if _, ok := pm.GetPlugin("app"); ok {
+		t.Fatalf("expected incompatible plugin not registered")
+	}
+
+	if err := pm.Register(newVersionedPlugin("app", "1.0.0", "note^1.2")); err != nil {
+		t.Fatalf("expected compatible constraint to register, got %v", err)
+	}
+	info, _ := pm.GetPluginInfo("app")
+	if len(info.Dependencies) != 1 || info.Dependencies[0] != "note" {
+		t.Fatalf("expected dependency names without constraint, got %v", info.Dependencies)
+	}
+}
+
+func TestRegisterOptionalDependency(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.Register(newVersionedPlugin("app", "1.0.0", "cache?>=2")); err != nil {
+		t.Fatalf("expected missing optional dependency to be allowed, got %v", err)
+	}
+	if info, _ := pm.GetPluginInfo("app"); len(info.Dependencies) != 0 {
+		t.Fatalf("expected optional dependency excluded from required list, got %v", info.Dependencies)
+	}
+
+	// 后注册的可选依赖同样需要满足版本约束
+	if err := pm.Register(newVersionedPlugin("cache", "1.0.0")); err == nil {
+		t.Fatalf("expected optional dependency with incompatible version to be rejected")
+	}
+	if err := pm.Register(newVersionedPlugin("cache", "2.1.0")); err != nil {
+		t.Fatalf("register compatible optional dependency error: %v", err)
+	}
+	// 可选依赖不阻止禁用
+	if err := pm.DisablePlugin("cache"); err != nil {
+		t.Fatalf("expected optional dependency to be disableable, got %v", err)
+	}
+}
+
+func TestRegisterPluginsResolvesVersions(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	noteV1 := newVersionedPlugin("note", "1.4.0")
+	noteV2 := newVersionedPlugin("note", "2.0.0")
+	app := newVersionedPlugin("app", "1.0.0", "note>=1.2.0,<2")
+
+	if err := pm.RegisterPlugins([]Plugin{app, noteV2, noteV1}); err != nil {
+		t.Fatalf("expected resolver to pick note 1.x, got %v", err)
+	}
+	note, _ := pm.GetPlugin("note")
+	if note.Version() != "1.4.0" {
+		t.Fatalf("expected note 1.4.0 resolved, got %s", note.Version())
+	}
+
+	graph := pm.GetResolvedDependencyGraph()
+	deps := graph["app"].Dependencies
+	if len(deps) != 1 || deps[0].ResolvedVersion != "1.4.0" || !deps[0].Satisfied || deps[0].Constraint != ">=1.2.0,<2" {
+		t.Fatalf("unexpected resolved graph: %+v", graph["app"])
+	}
+}
+
+func TestRegisterPluginsPrefersHighestCompatibleVersion(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.RegisterPlugins([]Plugin{
+		newVersionedPlugin("lib", "1.0.0"),
+		newVersionedPlugin("lib", "1.3.0"),
+		newVersionedPlugin("app", "1.0.0", "lib^1.0"),
+	}); err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	if lib, _ := pm.GetPlugin("lib"); lib.Version() != "1.3.0" {
+		t.Fatalf("expected highest compatible lib 1.3.0, got %s", lib.Version())
+	}
+}
+
+func TestRegisterPluginsUnresolvable(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	err := pm.RegisterPlugins([]Plugin{
+		newVersionedPlugin("note", "1.0.0"),
+		newVersionedPlugin("a", "1.0.0", "note>=1.2"),
+	})
+	if err == nil || !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginDependency}) {
+		t.Fatalf("expected PluginDependencyError, got %v", err)
+	}
+	if len(pm.ListPlugins()) != 0 {
+		t.Fatalf("expected nothing registered when resolution fails, got %v", pm.ListPlugins())
+	}
+
+	// 已注册插件的版本约束同样参与校验
+	if err := pm.Register(newVersionedPlugin("core", "3.0.0")); err != nil {
+		t.Fatalf("register core error: %v", err)
+	}
+	err = pm.RegisterPlugins([]Plugin{newVersionedPlugin("ext", "1.0.0", "core<3")})
+	if err == nil || !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginDependency}) {
+		t.Fatalf("expected PluginDependencyError against registered plugin, got %v", err)
+	}
+}
+
+func TestResolveManifestsHonorsRegisteredConstraints(t *testing.T) {
+	pm := &PluginManager{plugins: make(map[string]PluginInfo), mutex: &sync.RWMutex{}}
+	if err := pm.Register(newVersionedPlugin("lib", "1.0.0")); err != nil {
+		t.Fatalf("register lib error: %v", err)
+	}
+	if err := pm.Register(newVersionedPlugin("app", "1.0.0", "lib^1.0")); err != nil {
+		t.Fatalf("register app error: %v", err)
+	}
+
+	// 替换已注册的lib时，新版本仍需满足app的约束
+	resolved, err := pm.ResolveManifests([]*PluginManifest{
+		{Name: "lib", Version: "2.0.0"},
+		{Name: "lib", Version: "1.5.0"},
+	}, []string{"lib"})
+	if err != nil {
+		t.Fatalf("resolve error: %v", err)
+	}
+	if len(resolved) != 1 || resolved[0].Version != "1.5.0" {
+		t.Fatalf("expected lib 1.5.0 resolved, got %+v", resolved)
+	}
+
+	_, err = pm.ResolveManifests([]*PluginManifest{{Name: "lib", Version: "2.0.0"}}, []string{"lib"})
+	if !errors.Is(err, &pkg.AppError{Code: pkg.ErrPluginDependency}) {
+		t.Fatalf("expected PluginDependencyError, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// 进程外插件示例：以子进程方式运行，通过stdin/stdout上的JSON-RPC协议与宿主通信
+//
+// 构建: go build -o ./bin/process_echo ./plugins/examples/process_echo
+// 配置: 在config.yaml的plugins.processes中添加 {name: process_echo, path: ./bin/process_echo}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+
+	"weave/plugins/loader"
+)
+
+// echoPlugin 回显插件
+type echoPlugin struct {
+	prefix string
+}
+
+// Info 返回插件元数据和路由
+func (p *echoPlugin) Info() loader.HandshakeResponse {
+	return loader.HandshakeResponse{
+		Name:        "process_echo",
+		Version:     "1.0.0",
+		Description: "进程外插件示例，回显请求内容",
+		Routes: []loader.ProcessRoute{
+			{Method: http.MethodPost, Path: "/echo", Description: "回显请求体", Tags: []string{"example"}},
+		},
+	}
+}
+
+// Init 初始化插件
+func (p *echoPlugin) Init() error {
+	p.prefix = os.Getenv("ECHO_PREFIX")
+	// stdout用于协议通信，日志写入stderr
+	log.Printf("process_echo 初始化完成, prefix=%q", p.prefix)
+	return nil
+}
+
+// Execute 回显参数
+func (p *echoPlugin) Execute(ctx context.Context, req *loader.ExecuteRequest) (interface{}, error) {
+	return map[string]interface{}{
+		"prefix": p.prefix,
+		"params": req.Params,
+	}, nil
+}
+
+// HandleHTTP 回显请求体
+func (p *echoPlugin) HandleHTTP(ctx context.Context, req *loader.HTTPRequest) (*loader.HTTPResponse, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"prefix":     p.prefix,
+		"user_id":    req.UserID,
+		"request_id": req.RequestID,
+		"body":       string(req.Body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &loader.HTTPResponse{
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:    body,
+	}, nil
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	if err := loader.ServeProcessPlugin(&echoPlugin{}); err != nil {
+		log.Fatalf("process_echo 退出: %v", err)
+	}
+}
@@ -34,7 +34,8 @@ func (p *SampleDependentPlugin) Version() string {
 
 // GetDependencies 返回依赖的插件
 func (p *SampleDependentPlugin) GetDependencies() []string {
-	return []string{"sample_optimized", "hello_plugin"} // 依赖 sample_optimized 和 hello_plugin 插件
+	// 依赖 1.x 版本的 sample_optimized 插件和 hello_plugin 插件
+	return []string{"sample_optimized>=1.0.0,<2", "hello_plugin"}
 }
 
 // GetConflicts 返回冲突的插件
@@ -65,7 +66,7 @@ func (p *SampleDependentPlugin) Shutdown() error {
 func (p *SampleDependentPlugin) OnEnable() error {
 	fmt.Printf("SampleDependentPlugin: 插件已启用，正在检查依赖...\n")
 	// 在启用时检查依赖是否可用
-	for _, depName := range p.GetDependencies() {
+	for _, depName := range core.DependencyNames(p.GetDependencies()) {
 		if _, exists := p.pluginManager.GetPlugin(depName); exists {
 			fmt.Printf("依赖插件 '%s' 可用\n", depName)
 		} else {
@@ -144,7 +145,8 @@ func (p *SampleDependentPlugin) handleUseDependency(c *gin.Context) {
 	action := c.DefaultQuery("action", "greet")
 	params := map[string]interface{}{"action": action}
 
-	result, err := p.pluginManager.ExecutePlugin("sample_optimized", params)
+	// 透传请求上下文，客户端断开或请求超时时依赖插件的执行会随之取消
+	result, err := p.pluginManager.ExecutePluginContext(core.ContextFromGin(c), "sample_optimized", params)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -165,7 +167,7 @@ func (p *SampleDependentPlugin) handleGetDependencies(c *gin.Context) {
 
 	// 获取当前插件的依赖状态
 	var dependenciesStatus []map[string]interface{}
-	for _, depName := range p.GetDependencies() {
+	for _, depName := range core.DependencyNames(p.GetDependencies()) {
 		if depPlugin, exists := p.pluginManager.GetPlugin(depName); exists {
 			dependenciesStatus = append(dependenciesStatus, map[string]interface{}{
 				"name":        depName,
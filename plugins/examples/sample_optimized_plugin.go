@@ -3,6 +3,7 @@ package examples
 import (
 	"fmt"
 	"log"
+	"sync"
 
 	"weave/plugins/core"
 
@@ -14,6 +15,8 @@ import (
 
 type SampleOptimizedPlugin struct {
 	pluginManager *core.PluginManager
+	greeting      string       // 问候语，可通过plugins.sample_optimized.greeting配置
+	configMutex   sync.RWMutex // 保护配置项
 }
 
 // NewSampleOptimizedPlugin 创建新的SampleOptimizedPlugin实例
@@ -51,6 +54,44 @@ func (p *SampleOptimizedPlugin) SetPluginManager(manager *core.PluginManager) {
 	p.pluginManager = manager
 }
 
+// ConfigSchema 返回插件配置的JSON Schema
+func (p *SampleOptimizedPlugin) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"greeting": map[string]interface{}{
+				"type":      "string",
+				"default":   "Hello",
+				"minLength": 1,
+				"maxLength": 50,
+			},
+		},
+		"additionalProperties": false,
+	}
+}
+
+// OnConfigChange 应用新的插件配置
+func (p *SampleOptimizedPlugin) OnConfigChange(config map[string]interface{}) error {
+	greeting, _ := config["greeting"].(string)
+
+	p.configMutex.Lock()
+	defer p.configMutex.Unlock()
+	p.greeting = greeting
+	return nil
+}
+
+// greet 使用配置的问候语生成问候消息
+func (p *SampleOptimizedPlugin) greet(name string) string {
+	p.configMutex.RLock()
+	greeting := p.greeting
+	p.configMutex.RUnlock()
+
+	if greeting == "" {
+		greeting = "Hello"
+	}
+	return fmt.Sprintf("%s, %s!", greeting, name)
+}
+
 // Init 初始化插件
 func (p *SampleOptimizedPlugin) Init() error {
 	return nil
@@ -138,7 +179,7 @@ func (p *SampleOptimizedPlugin) Execute(params map[string]interface{}) (interfac
 	case "greet":
 		name, _ := params["name"].(string)
 		return map[string]interface{}{
-				"message": fmt.Sprintf("Hello, %s!", name),
+				"message": p.greet(name),
 			},
 			nil
 	case "echo":
@@ -179,7 +220,7 @@ func (p *SampleOptimizedPlugin) handlePluginInfo(c *gin.Context) {
 func (p *SampleOptimizedPlugin) handleGreet(c *gin.Context) {
 	name := c.DefaultQuery("name", "World")
 	c.JSON(200, gin.H{
-		"message": fmt.Sprintf("Hello, %s!", name),
+		"message": p.greet(name),
 		"plugin":  p.Name(),
 	})
 }
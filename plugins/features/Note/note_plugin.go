@@ -1,6 +1,7 @@
 package features
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -25,6 +26,12 @@ type Note struct {
 	UpdatedTime time.Time `json:"updated_time"`
 }
 
+// noteRequest 创建和更新笔记的请求体
+type noteRequest struct {
+	Title   string `json:"title" binding:"required,min=1,max=100"`
+	Content string `json:"content" binding:"required,min=1"`
+}
+
 // NotePlugin 记事本插件
 type NotePlugin struct {
 	// 使用MySQL数据库存储
@@ -64,6 +71,13 @@ func (p *NotePlugin) SetPluginManager(manager *core.PluginManager) {
 
 // Init 初始化插件
 func (p *NotePlugin) Init() error {
+	// 发布NoteStore服务，供声明了依赖的插件通过core.Lookup使用
+	if p.pluginManager != nil {
+		if err := p.pluginManager.Provide(p, NoteStore(p.store())); err != nil {
+			return err
+		}
+	}
+
 	// 插件初始化
 	pkg.Debug("NotePlugin initialized", zap.String("plugin", p.Name()))
 	return nil
@@ -200,202 +214,105 @@ func (p *NotePlugin) Execute(params map[string]interface{}) (interface{}, error)
 
 // listNotes 获取当前用户的所有笔记
 func (p *NotePlugin) listNotes(userID uint, tenantID uint, page, pageSize int) (interface{}, error) {
-	// 获取读锁
-	p.mutex.RLock()
-	defer p.mutex.RUnlock()
-
-	var notes []models.Note
-	var total int64
-
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 10
-	}
-
-	offset := (page - 1) * pageSize
-
-	db := pkg.DB.Where("user_id = ? AND tenant_id = ?", userID, tenantID)
-
-	if err := db.Model(&models.Note{}).Count(&total).Error; err != nil {
-		pkg.Error("Database error when counting notes", zap.Error(err))
-		return nil, fmt.Errorf("获取笔记列表失败，请稍后重试")
-	}
+	page, pageSize = normalizePaging(page, pageSize)
 
-	if err := db.Offset(offset).Limit(pageSize).Order("created_time DESC").Find(&notes).Error; err != nil {
-		pkg.Error("Database error when fetching notes", zap.Error(err))
-		return nil, fmt.Errorf("获取笔记列表失败，请稍后重试")
+	notes, total, err := p.store().ListNotes(context.Background(), userID, tenantID, page, pageSize)
+	if err != nil {
+		return nil, err
 	}
-
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-
-	return gin.H{
-			"notes":      notes,
-			"total":      total,
-			"page":       page,
-			"pageSize":   pageSize,
-			"totalPages": totalPages,
-		},
-		nil
+	return pagedNotes(notes, total, page, pageSize), nil
 }
 
 // getNote 获取单个笔记
 func (p *NotePlugin) getNote(userID uint, tenantID uint, noteID string) (interface{}, error) {
-	p.mutex.RLock()
-	defer p.mutex.RUnlock()
-
-	// 将string类型的noteID转换为uint类型
-	id, err := strconv.ParseUint(noteID, 10, 32)
+	id, err := parseNoteID(noteID)
 	if err != nil {
-		return nil, fmt.Errorf("无效的笔记ID")
+		return nil, err
 	}
 
-	var note models.Note
-	db := pkg.DB.Where("id = ? AND user_id = ? AND tenant_id = ?", uint(id), userID, tenantID)
-	if err := db.First(&note).Error; err != nil {
-		return nil, fmt.Errorf("笔记不存在或无权访问")
+	note, err := p.store().GetNote(context.Background(), userID, tenantID, id)
+	if err != nil {
+		return nil, err
 	}
-	return note, nil
+	return *note, nil
 }
 
 // createNote 创建新笔记
 func (p *NotePlugin) createNote(userID uint, tenantID uint, title, content string) (interface{}, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	note := models.Note{
-		Title:       title,
-		Content:     content,
-		UserID:      userID,
-		TenantID:    tenantID,
-		CreatedTime: time.Now(),
-		UpdatedTime: time.Now(),
-	}
-
-	if err := pkg.DB.Create(&note).Error; err != nil {
-		pkg.Error("Database error when creating note", zap.Error(err))
-		return nil, fmt.Errorf("创建笔记失败，请稍后重试")
+	note, err := p.store().CreateNote(context.Background(), userID, tenantID, title, content)
+	if err != nil {
+		return nil, err
 	}
-	return note, nil
+	return *note, nil
 }
 
 // updateNote 更新笔记
 func (p *NotePlugin) updateNote(userID uint, tenantID uint, noteID, title, content string) (interface{}, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	// 将string类型的noteID转换为uint类型
-	id, err := strconv.ParseUint(noteID, 10, 32)
+	id, err := parseNoteID(noteID)
 	if err != nil {
-		return nil, fmt.Errorf("无效的笔记ID")
+		return nil, err
 	}
 
-	var note models.Note
-	db := pkg.DB.Where("id = ? AND user_id = ? AND tenant_id = ?", uint(id), userID, tenantID)
-	if err := db.First(&note).Error; err != nil {
-		return nil, fmt.Errorf("笔记不存在或无权访问")
-	}
-
-	note.Title = title
-	note.Content = content
-	note.UpdatedTime = time.Now()
-
-	if err := pkg.DB.Save(&note).Error; err != nil {
-		pkg.Error("Database error when updating note", zap.Error(err))
-		return nil, fmt.Errorf("更新笔记失败，请稍后重试")
+	note, err := p.store().UpdateNote(context.Background(), userID, tenantID, id, title, content)
+	if err != nil {
+		return nil, err
 	}
-	return note, nil
+	return *note, nil
 }
 
 // deleteNoteHandler 删除笔记的处理器
 func (p *NotePlugin) deleteNoteHandler(userID uint, tenantID uint, noteID string) (interface{}, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	// 将string类型的noteID转换为uint类型
-	id, err := strconv.ParseUint(noteID, 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("无效的笔记ID")
-	}
-
-	var note models.Note
-	db := pkg.DB.Where("id = ? AND user_id = ? AND tenant_id = ?", uint(id), userID, tenantID)
-	if err := db.First(&note).Error; err != nil {
-		return nil, fmt.Errorf("笔记不存在或无权访问")
-	}
-
-	if err := pkg.DB.Delete(&note).Error; err != nil {
-		pkg.Error("Database error when deleting note", zap.Error(err))
-		return nil, fmt.Errorf("删除笔记失败，请稍后重试")
+	if err := p.deleteNote(userID, tenantID, noteID); err != nil {
+		return nil, err
 	}
-
 	return gin.H{"message": "删除成功"}, nil
 }
 
 // deleteNote 删除笔记（用户关联）
 func (p *NotePlugin) deleteNote(userID uint, tenantID uint, noteID string) error {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	// 将string类型的noteID转换为uint类型
-	id, err := strconv.ParseUint(noteID, 10, 32)
+	id, err := parseNoteID(noteID)
 	if err != nil {
-		return fmt.Errorf("无效的笔记ID")
-	}
-
-	var note models.Note
-	db := pkg.DB
-	if err := db.Where("id = ? AND user_id = ? AND tenant_id = ?", uint(id), userID, tenantID).First(&note).Error; err != nil {
-		return errors.New("笔记不存在或无权访问")
-	}
-
-	if err := db.Delete(&note).Error; err != nil {
 		return err
 	}
-	return nil
+	return p.store().DeleteNote(context.Background(), userID, tenantID, id)
 }
 
 // searchNotes 搜索当前用户的笔记
 func (p *NotePlugin) searchNotes(userID uint, tenantID uint, keyword string, page, pageSize int) (interface{}, error) {
-	p.mutex.RLock()
-	defer p.mutex.RUnlock()
+	page, pageSize = normalizePaging(page, pageSize)
 
-	var notes []models.Note
-	var total int64
-
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 10
+	notes, total, err := p.store().SearchNotes(context.Background(), userID, tenantID, keyword, page, pageSize)
+	if err != nil {
+		return nil, err
 	}
+	return pagedNotes(notes, total, page, pageSize), nil
+}
 
-	offset := (page - 1) * pageSize
-
-	query := "%" + keyword + "%"
-	db := pkg.DB.Where("user_id = ? AND tenant_id = ? AND (title LIKE ? OR content LIKE ?)", userID, tenantID, query, query)
-
-	if err := db.Model(&models.Note{}).Count(&total).Error; err != nil {
-		pkg.Error("Database error when counting search results", zap.Error(err))
-		return nil, fmt.Errorf("搜索笔记失败，请稍后重试")
-	}
+// store 返回插件的笔记服务实现
+func (p *NotePlugin) store() *noteStore {
+	return &noteStore{p: p}
+}
 
-	if err := db.Offset(offset).Limit(pageSize).Order("created_time DESC").Find(&notes).Error; err != nil {
-		pkg.Error("Database error when searching notes", zap.Error(err))
-		return nil, fmt.Errorf("搜索笔记失败，请稍后重试")
+// parseNoteID 将string类型的noteID转换为uint类型
+func parseNoteID(noteID string) (uint, error) {
+	id, err := strconv.ParseUint(noteID, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("无效的笔记ID")
 	}
+	return uint(id), nil
+}
 
+// pagedNotes 构造分页结果
+func pagedNotes(notes []models.Note, total int64, page, pageSize int) gin.H {
 	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
 
 	return gin.H{
-			"notes":      notes,
-			"total":      total,
-			"page":       page,
-			"pageSize":   pageSize,
-			"totalPages": totalPages,
-		},
-		nil
+		"notes":      notes,
+		"total":      total,
+		"page":       page,
+		"pageSize":   pageSize,
+		"totalPages": totalPages,
+	}
 }
 
 // 下面是核心业务方法的实现
@@ -474,6 +391,7 @@ func (p *NotePlugin) GetRoutes() []core.Route {
 			Description:  "获取单个笔记（用户关联）",
 			AuthRequired: true,
 			Tags:         []string{"notes", "get"},
+			ResponseBody: models.Note{},
 			Params: map[string]string{
 				"id": "笔记ID",
 			},
@@ -485,10 +403,7 @@ func (p *NotePlugin) GetRoutes() []core.Route {
 				userID := c.GetUint("user_id")
 				tenantID := c.GetUint("tenant_id")
 
-				var request struct {
-					Title   string `json:"title" binding:"required,min=1,max=100"`
-					Content string `json:"content" binding:"required,min=1"`
-				}
+				var request noteRequest
 				if err := c.ShouldBindJSON(&request); err != nil {
 					c.JSON(400, gin.H{"error": err.Error()})
 					return
@@ -504,6 +419,8 @@ func (p *NotePlugin) GetRoutes() []core.Route {
 			Description:  "创建新笔记（用户关联）",
 			AuthRequired: true,
 			Tags:         []string{"notes", "create"},
+			RequestBody:  noteRequest{},
+			ResponseBody: models.Note{},
 		},
 		{
 			Path:   "/notes/:id",
@@ -513,10 +430,7 @@ func (p *NotePlugin) GetRoutes() []core.Route {
 				tenantID := c.GetUint("tenant_id")
 				id := c.Param("id")
 
-				var request struct {
-					Title   string `json:"title" binding:"required,min=1,max=100"`
-					Content string `json:"content" binding:"required,min=1"`
-				}
+				var request noteRequest
 				if err := c.ShouldBindJSON(&request); err != nil {
 					c.JSON(400, gin.H{"error": err.Error()})
 					return
@@ -532,6 +446,8 @@ func (p *NotePlugin) GetRoutes() []core.Route {
 			Description:  "更新笔记（用户关联）",
 			AuthRequired: true,
 			Tags:         []string{"notes", "update"},
+			RequestBody:  noteRequest{},
+			ResponseBody: models.Note{},
 		},
 		{
 			Path:   "/notes/:id",
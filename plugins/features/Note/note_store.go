@@ -0,0 +1,169 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weave/models"
+	"weave/pkg"
+
+	"go.uber.org/zap"
+)
+
+// NoteStore 记事本插件对外提供的笔记服务
+// 依赖记事本插件的插件可通过 core.Lookup[features.NoteStore](pm.ScopeFor(p), "note") 获取
+type NoteStore interface {
+	ListNotes(ctx context.Context, userID, tenantID uint, page, pageSize int) ([]models.Note, int64, error)
+	SearchNotes(ctx context.Context, userID, tenantID uint, keyword string, page, pageSize int) ([]models.Note, int64, error)
+	GetNote(ctx context.Context, userID, tenantID, noteID uint) (*models.Note, error)
+	CreateNote(ctx context.Context, userID, tenantID uint, title, content string) (*models.Note, error)
+	UpdateNote(ctx context.Context, userID, tenantID, noteID uint, title, content string) (*models.Note, error)
+	DeleteNote(ctx context.Context, userID, tenantID, noteID uint) error
+}
+
+// errNoteNotFound 笔记不存在或无权访问
+var errNoteNotFound = errors.New("笔记不存在或无权访问")
+
+// noteStore NoteStore的数据库实现，与插件共用读写锁
+type noteStore struct {
+	p *NotePlugin
+}
+
+// normalizePaging 规范化分页参数
+func normalizePaging(page, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+// ListNotes 分页获取用户的笔记
+func (s *noteStore) ListNotes(ctx context.Context, userID, tenantID uint, page, pageSize int) ([]models.Note, int64, error) {
+	s.p.mutex.RLock()
+	defer s.p.mutex.RUnlock()
+
+	page, pageSize = normalizePaging(page, pageSize)
+
+	var notes []models.Note
+	var total int64
+
+	db := pkg.DB.WithContext(ctx).Where("user_id = ? AND tenant_id = ?", userID, tenantID)
+
+	if err := db.Model(&models.Note{}).Count(&total).Error; err != nil {
+		pkg.Error("Database error when counting notes", zap.Error(err))
+		return nil, 0, fmt.Errorf("获取笔记列表失败，请稍后重试")
+	}
+
+	if err := db.Offset((page - 1) * pageSize).Limit(pageSize).Order("created_time DESC").Find(&notes).Error; err != nil {
+		pkg.Error("Database error when fetching notes", zap.Error(err))
+		return nil, 0, fmt.Errorf("获取笔记列表失败，请稍后重试")
+	}
+	return notes, total, nil
+}
+
+// SearchNotes 按关键字搜索用户的笔记
+func (s *noteStore) SearchNotes(ctx context.Context, userID, tenantID uint, keyword string, page, pageSize int) ([]models.Note, int64, error) {
+	s.p.mutex.RLock()
+	defer s.p.mutex.RUnlock()
+
+	page, pageSize = normalizePaging(page, pageSize)
+
+	var notes []models.Note
+	var total int64
+
+	query := "%" + keyword + "%"
+	db := pkg.DB.WithContext(ctx).Where("user_id = ? AND tenant_id = ? AND (title LIKE ? OR content LIKE ?)", userID, tenantID, query, query)
+
+	if err := db.Model(&models.Note{}).Count(&total).Error; err != nil {
+		pkg.Error("Database error when counting search results", zap.Error(err))
+		return nil, 0, fmt.Errorf("搜索笔记失败，请稍后重试")
+	}
+
+	if err := db.Offset((page - 1) * pageSize).Limit(pageSize).Order("created_time DESC").Find(&notes).Error; err != nil {
+		pkg.Error("Database error when searching notes", zap.Error(err))
+		return nil, 0, fmt.Errorf("搜索笔记失败，请稍后重试")
+	}
+	return notes, total, nil
+}
+
+// GetNote 获取单个笔记
+func (s *noteStore) GetNote(ctx context.Context, userID, tenantID, noteID uint) (*models.Note, error) {
+	s.p.mutex.RLock()
+	defer s.p.mutex.RUnlock()
+
+	return s.findNote(ctx, userID, tenantID, noteID)
+}
+
+// CreateNote 创建新笔记
+func (s *noteStore) CreateNote(ctx context.Context, userID, tenantID uint, title, content string) (*models.Note, error) {
+	s.p.mutex.Lock()
+	defer s.p.mutex.Unlock()
+
+	note := models.Note{
+		Title:       title,
+		Content:     content,
+		UserID:      userID,
+		TenantID:    tenantID,
+		CreatedTime: time.Now(),
+		UpdatedTime: time.Now(),
+	}
+
+	if err := pkg.DB.WithContext(ctx).Create(&note).Error; err != nil {
+		pkg.Error("Database error when creating note", zap.Error(err))
+		return nil, fmt.Errorf("创建笔记失败，请稍后重试")
+	}
+	return &note, nil
+}
+
+// UpdateNote 更新笔记标题和内容
+func (s *noteStore) UpdateNote(ctx context.Context, userID, tenantID, noteID uint, title, content string) (*models.Note, error) {
+	s.p.mutex.Lock()
+	defer s.p.mutex.Unlock()
+
+	note, err := s.findNote(ctx, userID, tenantID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	note.Title = title
+	note.Content = content
+	note.UpdatedTime = time.Now()
+
+	if err := pkg.DB.WithContext(ctx).Save(note).Error; err != nil {
+		pkg.Error("Database error when updating note", zap.Error(err))
+		return nil, fmt.Errorf("更新笔记失败，请稍后重试")
+	}
+	return note, nil
+}
+
+// DeleteNote 删除笔记
+func (s *noteStore) DeleteNote(ctx context.Context, userID, tenantID, noteID uint) error {
+	s.p.mutex.Lock()
+	defer s.p.mutex.Unlock()
+
+	note, err := s.findNote(ctx, userID, tenantID, noteID)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.DB.WithContext(ctx).Delete(note).Error; err != nil {
+		pkg.Error("Database error when deleting note", zap.Error(err))
+		return fmt.Errorf("删除笔记失败，请稍后重试")
+	}
+	return nil
+}
+
+// findNote 查询属于用户的笔记，调用方需持有锁
+func (s *noteStore) findNote(ctx context.Context, userID, tenantID, noteID uint) (*models.Note, error) {
+	var note models.Note
+	db := pkg.DB.WithContext(ctx).Where("id = ? AND user_id = ? AND tenant_id = ?", noteID, userID, tenantID)
+	if err := db.First(&note).Error; err != nil {
+		return nil, errNoteNotFound
+	}
+	return &note, nil
+}
@@ -1,18 +1,32 @@
 package plugins
 
 import (
+	"context"
 	"fmt"
+	"time"
 	"weave/config"
 	"weave/pkg"
 	"weave/plugins/core"
+	"weave/plugins/loader"
+	"weave/plugins/registry"
 	"weave/plugins/watcher"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
 // 全局插件管理器实例
 var PluginManager = core.GlobalPluginManager
 
+// ProcessLoader 全局进程外插件加载器，在LoadProcessPlugins中创建
+var ProcessLoader *loader.ProcessLoader
+
+// PluginBuilder 插件监控器使用的源码编译器，未启用监控器或plugins.build时为nil
+var PluginBuilder *loader.PluginBuilder
+
+// PluginRegistry 从插件仓库安装插件的安装器，在LoadInstalledPlugins中创建
+var PluginRegistry *registry.Installer
+
 // pluginManagerAdapter 适配器，将core.PluginManager适配到watcher.PluginManager接口
 type pluginManagerAdapter struct {
 	manager *core.PluginManager
@@ -44,6 +58,24 @@ func (adapter *pluginManagerAdapter) Register(plugin watcher.Plugin) error {
 	return fmt.Errorf("plugin does not implement core.Plugin interface")
 }
 
+// RegisterWithManifest 实现watcher.ManifestRegistrar接口
+func (adapter *pluginManagerAdapter) RegisterWithManifest(plugin watcher.Plugin, manifest *core.PluginManifest) error {
+	if corePlugin, ok := plugin.(core.Plugin); ok {
+		return adapter.manager.RegisterWithManifest(corePlugin, manifest)
+	}
+	return fmt.Errorf("plugin does not implement core.Plugin interface")
+}
+
+// ListPlugins 实现watcher.PluginLister接口
+func (adapter *pluginManagerAdapter) ListPlugins() []string {
+	return adapter.manager.ListPlugins()
+}
+
+// ReloadPlugins 实现watcher.BatchReloader接口
+func (adapter *pluginManagerAdapter) ReloadPlugins(names, dependentsOnly []string) *core.ReloadBatch {
+	return adapter.manager.ReloadPlugins(names, dependentsOnly)
+}
+
 // InitPluginSystem 初始化插件系统
 // 包括创建和设置PluginWatcher实例
 func InitPluginSystem() error {
@@ -67,6 +99,10 @@ func InitPluginSystem() error {
 
 		// 设置插件监控器
 		PluginManager.SetPluginWatcher(pw)
+		PluginBuilder = pw.Builder()
+		if PluginBuilder != nil {
+			pkg.Info("插件源码编译已启用", zap.String("outputDir", config.Config.Plugins.Build.OutputDir))
+		}
 
 		// 启动插件监控器
 		if err := pw.Start(); err != nil {
@@ -80,3 +116,162 @@ func InitPluginSystem() error {
 
 	return nil
 }
+
+// SetupPluginCircuitBreaker 根据配置设置插件熔断器的默认配置
+func SetupPluginCircuitBreaker() {
+	breaker := config.Config.Plugins.CircuitBreaker
+	PluginManager.SetDefaultBreakerConfig(core.BreakerConfig{
+		FailureThreshold:     breaker.FailureThreshold,
+		OpenTimeout:          time.Duration(breaker.OpenTimeout) * time.Second,
+		HalfOpenMaxCalls:     breaker.HalfOpenMaxCalls,
+		AutoDisableThreshold: breaker.AutoDisableThreshold,
+	})
+}
+
+// SetupPluginStorage 根据配置设置插件键值存储后端
+// 需要在注册插件之前调用；Redis不可用时回退到数据库存储
+func SetupPluginStorage() error {
+	storageConfig := config.Config.Plugins.Storage
+	if storageConfig.Backend != "redis" {
+		PluginManager.SetStorageBackend(core.NewDBStorageBackend(nil))
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     storageConfig.Redis.Addr,
+		Password: storageConfig.Redis.Password,
+		DB:       storageConfig.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		PluginManager.SetStorageBackend(core.NewDBStorageBackend(nil))
+		return fmt.Errorf("连接插件存储Redis失败，已回退到数据库存储: %w", err)
+	}
+
+	PluginManager.SetStorageBackend(core.NewRedisStorageBackend(client, storageConfig.Redis.Prefix))
+	pkg.Info("插件存储使用Redis后端", zap.String("addr", storageConfig.Redis.Addr))
+	return nil
+}
+
+// SetupPluginStateSync 读取保存的插件启用状态并启动集群同步（需在注册插件之前）
+// 配置了Redis时通过发布订阅即时广播，连接失败时返回错误并回退到只轮询数据库
+func SetupPluginStateSync() error {
+	stateSync := config.Config.Plugins.StateSync
+	if !stateSync.Enabled {
+		pkg.Info("插件启用状态同步未启用")
+		return nil
+	}
+
+	var broadcaster core.PluginStateBroadcaster
+	var redisErr error
+	if stateSync.Redis {
+		redisConfig := config.Config.Plugins.Storage.Redis
+		client := redis.NewClient(&redis.Options{
+			Addr:     redisConfig.Addr,
+			Password: redisConfig.Password,
+			DB:       redisConfig.DB,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := client.Ping(ctx).Err()
+		cancel()
+		if err != nil {
+			_ = client.Close()
+			redisErr = fmt.Errorf("连接插件状态同步Redis失败，已回退到轮询数据库: %w", err)
+		} else {
+			broadcaster = core.NewRedisStateBroadcaster(client, stateSync.Channel)
+		}
+	}
+
+	syncer := core.NewStateSync(PluginManager, core.NewDBPluginStateStore(nil), broadcaster, core.StateSyncConfig{
+		PollInterval: time.Duration(stateSync.PollInterval) * time.Second,
+	})
+	if err := syncer.Start(); err != nil {
+		return fmt.Errorf("启动插件启用状态同步失败，插件启用状态不会保存: %w", err)
+	}
+	PluginManager.SetStateSync(syncer)
+	return redisErr
+}
+
+// StopPluginStateSync 停止插件启用状态同步
+func StopPluginStateSync() {
+	if syncer := PluginManager.StateSync(); syncer != nil {
+		syncer.Stop()
+	}
+}
+
+// LoadProcessPlugins 启动配置中的进程外插件并注册到插件管理器
+// 单个插件加载或注册失败不影响其他插件，失败的插件进程会被结束
+func LoadProcessPlugins() {
+	if ProcessLoader == nil {
+		ProcessLoader = loader.NewProcessLoader(pkg.GetLogger())
+	}
+	for _, process := range config.Config.Plugins.Processes {
+		plugin, err := ProcessLoader.LoadPlugin(loader.ProcessConfig{
+			Path: process.Path,
+			Args: process.Args,
+			Env:  process.Env,
+			Dir:  process.Dir,
+		}, process.Name)
+		if err != nil {
+			pkg.Error("加载进程外插件失败", zap.String("path", process.Path), zap.Error(err))
+			continue
+		}
+
+		if err := PluginManager.Register(plugin); err != nil {
+			pkg.Error("注册进程外插件失败", zap.String("plugin", plugin.Name()), zap.Error(err))
+			_ = ProcessLoader.UnloadPlugin(plugin.Name())
+			continue
+		}
+		pkg.Info("进程外插件注册成功", zap.String("plugin", plugin.Name()), zap.Int("pid", plugin.PID()))
+	}
+}
+
+// LoadInstalledPlugins 创建插件安装器，并按依赖顺序注册通过插件仓库安装的插件
+// 需要在注册内置插件之后调用，单个插件加载失败不影响其他插件
+func LoadInstalledPlugins() {
+	PluginRegistry = registry.NewInstaller(registry.ConfigFromConfig(), PluginManager, pkg.GetLogger())
+	PluginRegistry.LoadInstalled()
+	if PluginRegistry.Registry() == nil {
+		pkg.Info("插件仓库未配置，只加载已安装的插件")
+	}
+}
+
+// UnloadProcessPlugins 结束全部进程外插件进程，在宿主退出前调用
+func UnloadProcessPlugins() {
+	if ProcessLoader != nil {
+		ProcessLoader.UnloadAll()
+	}
+}
+
+// StartPluginJobs 根据配置创建并启动插件异步任务队列，plugins.jobs.workers为0时不启动
+// 需要在数据库初始化之后、插件注册完成后调用
+func StartPluginJobs() {
+	jobs := config.Config.Plugins.Jobs
+	if jobs.Workers <= 0 {
+		pkg.Info("插件任务队列未启用")
+		return
+	}
+
+	queue := core.NewJobQueue(PluginManager, core.NewDBJobStore(nil), core.JobQueueConfig{
+		Workers:      jobs.Workers,
+		PollInterval: time.Duration(jobs.PollInterval) * time.Second,
+		MaxAttempts:  jobs.MaxAttempts,
+		RetryBackoff: time.Duration(jobs.RetryBackoff) * time.Second,
+		Timeout:      time.Duration(jobs.Timeout) * time.Second,
+		Retention:    time.Duration(jobs.Retention) * time.Hour,
+	})
+	PluginManager.SetJobQueue(queue)
+	queue.Start()
+}
+
+// StopPluginJobs 停止插件任务队列，等待执行中的任务结束，超时后中断的任务会重新排队
+func StopPluginJobs(ctx context.Context) error {
+	if queue := PluginManager.Jobs(); queue != nil {
+		return queue.Stop(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"weave/config"
+	"weave/pkg"
+	"weave/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+// 插件编译状态
+const (
+	BuildRunning   = "running"
+	BuildSucceeded = "succeeded"
+	BuildFailed    = "failed"
+)
+
+// maxBuildOutput 编译状态中保留的编译器输出长度上限（字节），超出部分截断
+const maxBuildOutput = 64 * 1024
+
+// BuildConfig 从源码编译插件的配置
+type BuildConfig struct {
+	Toolchain string        // go命令，默认为"go"
+	Flags     []string      // 附加的go build参数，如-trimpath
+	OutputDir string        // 编译产物目录，产物位于<OutputDir>/<插件名>/<构建版本>/<插件名>.so
+	Timeout   time.Duration // 单次编译的超时时间
+	Keep      int           // 每个插件保留的构建版本数，0表示不清理
+}
+
+// BuildConfigFromConfig 根据plugins.build配置创建编译配置
+func BuildConfigFromConfig() BuildConfig {
+	build := config.Config.Plugins.Build
+	return BuildConfig{
+		Toolchain: build.Toolchain,
+		Flags:     build.Flags,
+		OutputDir: build.OutputDir,
+		Timeout:   time.Duration(build.Timeout) * time.Second,
+		Keep:      build.Keep,
+	}
+}
+
+// BuildSource 待编译的插件源码
+type BuildSource struct {
+	Name    string   // 插件名称，产物命名为<Name>.so
+	Dir     string   // 源码目录，编译在该目录下执行
+	Files   []string // 参与编译的文件（相对Dir），为空时使用Dir下除测试文件外的全部.go文件
+	Tags    []string // 构建标签，通常取自插件清单的build_tags
+	Version string   // 插件版本，通常取自插件清单，用于构建版本的命名
+}
+
+// BuildResult 插件的一次编译结果
+type BuildResult struct {
+	Plugin     string    `json:"plugin"`
+	Version    string    `json:"version"` // 构建版本，即产物所在的目录名
+	Status     string    `json:"status"`  // running、succeeded 或 failed
+	Command    []string  `json:"command"`
+	Artifact   string    `json:"artifact,omitempty"`
+	Output     string    `json:"output,omitempty"` // 编译器输出，失败时包含编译错误
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   float64   `json:"duration_seconds"`
+	Loaded     bool      `json:"loaded"`               // 产物是否已成功加载
+	LoadError  string    `json:"load_error,omitempty"` // 加载或注册产物失败的原因，如未通过签名校验
+}
+
+// PluginBuilder 使用go build -buildmode=plugin将插件源码编译到带版本的产物目录，并记录各插件最近一次的编译状态
+// 每次编译输出到新的目录，避免覆盖正在使用的产物，也避免plugin.Open按路径复用已打开的旧版本
+type PluginBuilder struct {
+	cfg    BuildConfig
+	logger *pkg.Logger
+
+	mu       sync.RWMutex
+	results  map[string]*BuildResult // 插件名 -> 最近一次编译结果
+	building map[string]*sync.Mutex  // 插件名 -> 编译锁，同一插件的编译串行执行
+}
+
+// NewPluginBuilder 创建插件编译器
+func NewPluginBuilder(cfg BuildConfig, logger *pkg.Logger) *PluginBuilder {
+	if cfg.Toolchain == "" {
+		cfg.Toolchain = "go"
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = filepath.Join("plugins", ".build")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	return &PluginBuilder{
+		cfg:      cfg,
+		logger:   logger,
+		results:  make(map[string]*BuildResult),
+		building: make(map[string]*sync.Mutex),
+	}
+}
+
+// Build 编译插件源码，成功时返回的结果中Artifact为产物路径
+// 编译失败时编译器输出记录在结果的Output中，同时返回错误；失败的构建版本目录会被删除
+func (b *PluginBuilder) Build(source BuildSource) (*BuildResult, error) {
+	if source.Name == "" {
+		return nil, fmt.Errorf("编译插件缺少插件名称")
+	}
+
+	lock := b.buildLock(source.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	startedAt := time.Now()
+	result := &BuildResult{
+		Plugin:    source.Name,
+		Version:   buildVersion(source.Version, startedAt),
+		Status:    BuildRunning,
+		StartedAt: startedAt,
+	}
+	b.store(result)
+
+	err := b.run(source, result)
+	result.FinishedAt = time.Now()
+	result.Duration = result.FinishedAt.Sub(startedAt).Seconds()
+	metrics.RecordPluginBuild(source.Name, err == nil, result.FinishedAt.Sub(startedAt))
+
+	if err != nil {
+		result.Status = BuildFailed
+		result.Error = err.Error()
+		result.Artifact = ""
+		b.store(result)
+		metrics.RecordPluginError(source.Name, "build_failed")
+		b.logger.Error("编译插件失败",
+			zap.String("plugin", source.Name),
+			zap.String("version", result.Version),
+			zap.Error(err),
+			zap.String("output", result.Output))
+		copied := *result
+		return &copied, err
+	}
+
+	result.Status = BuildSucceeded
+	b.store(result)
+	b.prune(source.Name, result.Version)
+	b.logger.Info("插件编译成功",
+		zap.String("plugin", source.Name),
+		zap.String("version", result.Version),
+		zap.String("artifact", result.Artifact),
+		zap.Float64("duration_seconds", result.Duration))
+	copied := *result
+	return &copied, nil
+}
+
+// run 执行go build，失败时删除本次的构建版本目录
+func (b *PluginBuilder) run(source BuildSource, result *BuildResult) error {
+	files, err := sourceFiles(source)
+	if err != nil {
+		return err
+	}
+
+	versionDir, err := filepath.Abs(filepath.Join(b.cfg.OutputDir, source.Name, result.Version))
+	if err != nil {
+		return fmt.Errorf("解析编译产物目录失败: %w", err)
+	}
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return fmt.Errorf("创建编译产物目录失败: %w", err)
+	}
+	artifact := filepath.Join(versionDir, source.Name+".so")
+
+	args := []string{"build", "-buildmode=plugin"}
+	if len(source.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(source.Tags, ","))
+	}
+	args = append(args, b.cfg.Flags...)
+	args = append(args, "-o", artifact)
+	args = append(args, files...)
+	result.Command = append([]string{b.cfg.Toolchain}, args...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, b.cfg.Toolchain, args...)
+	cmd.Dir = source.Dir
+	output, runErr := cmd.CombinedOutput()
+	result.Output = truncateOutput(string(output))
+
+	if runErr == nil {
+		if _, statErr := os.Stat(artifact); statErr != nil {
+			runErr = fmt.Errorf("编译完成但未生成产物: %w", statErr)
+		}
+	} else if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		runErr = fmt.Errorf("编译超时（%s）", b.cfg.Timeout)
+	} else {
+		runErr = fmt.Errorf("go build执行失败: %w", runErr)
+	}
+	if runErr != nil {
+		os.RemoveAll(versionDir)
+		return runErr
+	}
+
+	result.Artifact = artifact
+	return nil
+}
+
+// RecordLoad 记录编译产物的加载结果，version与最近一次编译不一致时忽略
+func (b *PluginBuilder) RecordLoad(name, version string, loadErr error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result, exists := b.results[name]
+	if !exists || result.Version != version {
+		return
+	}
+	result.Loaded = loadErr == nil
+	result.LoadError = ""
+	if loadErr != nil {
+		result.LoadError = loadErr.Error()
+	}
+}
+
+// Status 返回插件最近一次的编译结果
+func (b *PluginBuilder) Status(name string) (BuildResult, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	result, exists := b.results[name]
+	if !exists {
+		return BuildResult{}, false
+	}
+	return *result, true
+}
+
+// Statuses 返回全部插件最近一次的编译结果，按插件名称排序
+func (b *PluginBuilder) Statuses() []BuildResult {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	results := make([]BuildResult, 0, len(b.results))
+	for _, result := range b.results {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Plugin < results[j].Plugin })
+	return results
+}
+
+// buildLock 返回插件的编译锁
+func (b *PluginBuilder) buildLock(name string) *sync.Mutex {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lock, exists := b.building[name]
+	if !exists {
+		lock = &sync.Mutex{}
+		b.building[name] = lock
+	}
+	return lock
+}
+
+// store 保存编译结果的副本
+func (b *PluginBuilder) store(result *BuildResult) {
+	copied := *result
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results[result.Plugin] = &copied
+}
+
+// prune 删除插件较早的构建版本，保留最近的Keep个（包括current）
+func (b *PluginBuilder) prune(name, current string) {
+	if b.cfg.Keep <= 0 {
+		return
+	}
+	pluginDir := filepath.Join(b.cfg.OutputDir, name)
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil {
+		return
+	}
+
+	// 构建版本以编译时间开头，按名称排序即为时间顺序
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != current {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Strings(versions)
+	for len(versions) > b.cfg.Keep-1 {
+		if err := os.RemoveAll(filepath.Join(pluginDir, versions[0])); err != nil {
+			b.logger.Warn("清理插件旧构建版本失败", zap.String("plugin", name), zap.String("version", versions[0]), zap.Error(err))
+		}
+		versions = versions[1:]
+	}
+}
+
+// sourceFiles 返回参与编译的文件，未指定时为目录下除测试文件外的全部.go文件
+func sourceFiles(source BuildSource) ([]string, error) {
+	if len(source.Files) > 0 {
+		return source.Files, nil
+	}
+	entries, err := os.ReadDir(source.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取插件源码目录失败: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, name)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("插件源码目录 '%s' 中没有.go文件", source.Dir)
+	}
+	return files, nil
+}
+
+// HasGoSources 判断目录中是否有可编译的.go文件
+func HasGoSources(dir string) bool {
+	files, err := sourceFiles(BuildSource{Dir: dir})
+	return err == nil && len(files) > 0
+}
+
+// buildVersion 生成构建版本：编译时间，插件声明了版本时附加在后面
+func buildVersion(version string, at time.Time) string {
+	stamp := at.UTC().Format("20060102T150405.000000")
+	if version == "" {
+		return stamp
+	}
+	return stamp + "-v" + strings.TrimPrefix(version, "v")
+}
+
+// truncateOutput 截断过长的编译器输出，保留开头（首个编译错误通常在最前面）
+func truncateOutput(output string) string {
+	if len(output) <= maxBuildOutput {
+		return output
+	}
+	return output[:maxBuildOutput] + "\n...(输出过长已截断)"
+}
@@ -0,0 +1,142 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"weave/pkg"
+)
+
+// fakeToolchain 写入模拟go命令的脚本：参数记录到args.txt，
+// 源码包含"syntax error"时输出编译错误并失败，否则在-o指定的路径生成产物
+func fakeToolchain(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake toolchain requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > "` + filepath.Join(dir, "args.txt") + `"
+if grep -q "syntax error" *.go; then
+  echo "./hello.go:3:1: syntax error: non-declaration statement outside function body" >&2
+  exit 1
+fi
+while [ $# -gt 0 ]; do
+  if [ "$1" = "-o" ]; then
+    echo "plugin" > "$2"
+  fi
+  shift
+done
+`
+	path := filepath.Join(dir, "go")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("write fake toolchain: %v", err)
+	}
+	return path
+}
+
+func writeSource(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "hello.go"), []byte(content), 0644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+}
+
+func TestPluginBuilderBuild(t *testing.T) {
+	toolchain := fakeToolchain(t)
+	src := t.TempDir()
+	writeSource(t, src, "package main\n")
+	if err := os.WriteFile(filepath.Join(src, "hello_test.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("write test source: %v", err)
+	}
+	out := t.TempDir()
+
+	builder := NewPluginBuilder(BuildConfig{Toolchain: toolchain, Flags: []string{"-trimpath"}, OutputDir: out}, pkg.GetLogger())
+	result, err := builder.Build(BuildSource{Name: "hello", Dir: src, Tags: []string{"pro", "linux"}, Version: "1.2.0"})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	if result.Status != BuildSucceeded || !strings.HasSuffix(result.Version, "-v1.2.0") {
+		t.Fatalf("unexpected build result: %+v", result)
+	}
+	want := filepath.Join(out, "hello", result.Version, "hello.so")
+	if abs, _ := filepath.Abs(want); result.Artifact != abs {
+		t.Fatalf("expected artifact %s, got %s", abs, result.Artifact)
+	}
+	if _, err := os.Stat(result.Artifact); err != nil {
+		t.Fatalf("artifact missing: %v", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(filepath.Dir(toolchain), "args.txt"))
+	got := strings.TrimSpace(string(args))
+	if !strings.HasPrefix(got, "build -buildmode=plugin -tags pro,linux -trimpath -o ") || !strings.HasSuffix(got, " hello.go") {
+		t.Fatalf("unexpected go build arguments: %s", got)
+	}
+
+	builder.RecordLoad("hello", result.Version, nil)
+	if status, ok := builder.Status("hello"); !ok || !status.Loaded {
+		t.Fatalf("expected build marked as loaded, got %+v", status)
+	}
+}
+
+func TestPluginBuilderCapturesCompilerErrors(t *testing.T) {
+	toolchain := fakeToolchain(t)
+	src := t.TempDir()
+	writeSource(t, src, "package main\nsyntax error\n")
+	out := t.TempDir()
+
+	builder := NewPluginBuilder(BuildConfig{Toolchain: toolchain, OutputDir: out}, pkg.GetLogger())
+	if _, err := builder.Build(BuildSource{Name: "hello", Dir: src}); err == nil {
+		t.Fatalf("expected build to fail")
+	}
+
+	status, ok := builder.Status("hello")
+	if !ok || status.Status != BuildFailed || status.Artifact != "" {
+		t.Fatalf("unexpected build status: %+v", status)
+	}
+	if !strings.Contains(status.Output, "hello.go:3:1: syntax error") {
+		t.Fatalf("expected compiler output captured, got %q", status.Output)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(out, "hello")); len(entries) != 0 {
+		t.Fatalf("expected failed build directory removed, got %d entries", len(entries))
+	}
+	if statuses := builder.Statuses(); len(statuses) != 1 || statuses[0].Plugin != "hello" {
+		t.Fatalf("unexpected build statuses: %+v", statuses)
+	}
+}
+
+func TestPluginBuilderPrunesOldVersions(t *testing.T) {
+	toolchain := fakeToolchain(t)
+	src := t.TempDir()
+	writeSource(t, src, "package main\n")
+	out := t.TempDir()
+
+	builder := NewPluginBuilder(BuildConfig{Toolchain: toolchain, OutputDir: out, Keep: 2}, pkg.GetLogger())
+	var last *BuildResult
+	for i := 0; i < 3; i++ {
+		result, err := builder.Build(BuildSource{Name: "hello", Dir: src})
+		if err != nil {
+			t.Fatalf("build %d error: %v", i, err)
+		}
+		last = result
+		time.Sleep(time.Millisecond)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(out, "hello"))
+	if err != nil {
+		t.Fatalf("read output dir: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Name() != last.Version {
+		t.Fatalf("expected the 2 latest versions kept, got %v", entries)
+	}
+
+	// 旧版本的加载结果不覆盖最近一次编译
+	builder.RecordLoad("hello", entries[0].Name(), nil)
+	if status, _ := builder.Status("hello"); status.Loaded {
+		t.Fatalf("stale load result should be ignored")
+	}
+}
@@ -2,12 +2,18 @@
 package loader
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"plugin"
 	"sync"
 
 	"weave/pkg"
+	"weave/pkg/metrics"
 	"weave/plugins/core"
 
 	"go.uber.org/zap"
@@ -18,17 +24,39 @@ type PluginLoader struct {
 	loadedPlugins map[string]*plugin.Plugin
 	mutex         sync.RWMutex
 	logger        *pkg.Logger
+	trust         *TrustPolicy
+	privateDir    string            // 保存通过校验的插件副本的私有目录（0700），首次加载时创建
+	privateCopies map[string]string // 插件内容的SHA-256摘要 -> 私有副本路径
 }
 
-// NewPluginLoader 创建插件加载器实例
+// NewPluginLoader 创建插件加载器实例，信任策略取自plugins.trust配置
 func NewPluginLoader(logger *pkg.Logger) *PluginLoader {
+	trust, err := TrustPolicyFromConfig()
+	if err != nil {
+		// 公钥配置无效时不信任任何插件
+		logger.Error("插件信任策略配置无效，将拒绝加载所有插件", zap.Error(err))
+		trust = &TrustPolicy{}
+	}
+	if trust.DevMode() {
+		logger.Warn("插件信任策略处于开发模式，加载插件时不校验签名")
+	}
+
 	return &PluginLoader{
 		loadedPlugins: make(map[string]*plugin.Plugin),
 		mutex:         sync.RWMutex{},
 		logger:        logger,
+		trust:         trust,
+		privateCopies: make(map[string]string),
 	}
 }
 
+// SetTrustPolicy 设置插件信任策略
+func (pl *PluginLoader) SetTrustPolicy(trust *TrustPolicy) {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+	pl.trust = trust
+}
+
 // LoadPlugin 动态加载插件
 // 参数:
 // - pluginPath: 插件文件路径(.so文件)
@@ -42,16 +70,14 @@ func (pl *PluginLoader) LoadPlugin(pluginPath string, pluginName string) (core.P
 
 	// 检查插件是否已经加载
 	if _, exists := pl.loadedPlugins[pluginName]; exists {
-		// 先卸载已加载的插件
-		if err := pl.UnloadPlugin(pluginName); err != nil {
-			pl.logger.Warn("卸载已加载的插件失败", zap.String("plugin", pluginName), zap.Error(err))
-		}
+		// 先卸载已加载的插件（已持有锁，不能调用UnloadPlugin）
+		pl.unloadLocked(pluginName)
 	}
 
-	// 加载插件
-	p, err := plugin.Open(pluginPath)
+	// 校验插件摘要和签名后加载，未通过校验的插件不会被打开
+	p, err := pl.openVerifiedLocked(pluginPath, pluginName)
 	if err != nil {
-		return nil, fmt.Errorf("加载插件失败: %w", err)
+		return nil, err
 	}
 
 	// 查找插件入口点
@@ -81,15 +107,160 @@ func (pl *PluginLoader) LoadPlugin(pluginPath string, pluginName string) (core.P
 	return pluginInstance, nil
 }
 
+// openVerifiedLocked 读取插件并按信任策略校验，校验通过后将同一份内容写入私有目录并打开该副本，
+// 避免校验与打开之间插件文件被替换；开发模式下不做校验，直接打开原文件。调用方需持有pl.mutex
+func (pl *PluginLoader) openVerifiedLocked(pluginPath string, pluginName string) (*plugin.Plugin, error) {
+	trust := pl.trust
+	if trust == nil {
+		trust = &TrustPolicy{}
+	}
+	if trust.DevMode() {
+		pl.logger.Warn("开发模式：跳过插件签名校验", zap.String("plugin", pluginName), zap.String("path", pluginPath))
+		p, err := plugin.Open(pluginPath)
+		if err != nil {
+			return nil, fmt.Errorf("加载插件失败: %w", err)
+		}
+		return p, nil
+	}
+
+	data, err := os.ReadFile(pluginPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载插件失败: 读取插件失败: %w", err)
+	}
+	if err := pl.verifyLocked(trust, pluginPath, pluginName, data); err != nil {
+		return nil, err
+	}
+	privatePath, err := pl.privateCopyLocked(pluginPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("加载插件失败: %w", err)
+	}
+
+	p, err := plugin.Open(privatePath)
+	if err != nil {
+		return nil, fmt.Errorf("加载插件失败: %w", err)
+	}
+	return p, nil
+}
+
+// privateCopyLocked 将通过校验的插件内容写入私有目录，并确认副本与校验的内容一致，返回副本路径
+// 相同内容复用同一个副本，使重复加载同一插件时plugin.Open返回已打开的插件。调用方需持有pl.mutex
+func (pl *PluginLoader) privateCopyLocked(pluginPath string, data []byte) (string, error) {
+	digest := sha256.Sum256(data)
+	key := hex.EncodeToString(digest[:])
+
+	privatePath, exists := pl.privateCopies[key]
+	if !exists {
+		if pl.privateDir == "" {
+			// MkdirTemp创建的目录权限为0700，其他用户无法替换其中的文件
+			dir, err := os.MkdirTemp("", "weave-plugins-")
+			if err != nil {
+				return "", fmt.Errorf("创建插件私有目录失败: %w", err)
+			}
+			pl.privateDir = dir
+		}
+		privatePath = filepath.Join(pl.privateDir, key[:16]+"-"+filepath.Base(pluginPath))
+		if err := os.WriteFile(privatePath, data, 0600); err != nil {
+			return "", fmt.Errorf("写入插件私有副本失败: %w", err)
+		}
+		pl.privateCopies[key] = privatePath
+	}
+
+	// 确认即将打开的副本就是通过校验的内容
+	copied, err := os.ReadFile(privatePath)
+	if err != nil {
+		return "", fmt.Errorf("读取插件私有副本失败: %w", err)
+	}
+	if !bytes.Equal(copied, data) {
+		delete(pl.privateCopies, key)
+		return "", fmt.Errorf("%w: 插件私有副本与校验的内容不一致", ErrPluginTampered)
+	}
+	return privatePath, nil
+}
+
+// verifyLocked 按信任策略校验插件内容，拒绝时记录指标和审计日志，调用方需持有pl.mutex
+func (pl *PluginLoader) verifyLocked(trust *TrustPolicy, pluginPath string, pluginName string, data []byte) error {
+	err := trust.VerifyData(pluginPath, data)
+	if err == nil {
+		return nil
+	}
+
+	var kind string
+	switch {
+	case errors.Is(err, ErrPluginUnsigned):
+		kind = "unsigned_binary"
+	case errors.Is(err, ErrPluginTampered):
+		kind = "tampered_binary"
+	case errors.Is(err, ErrPluginUntrusted):
+		kind = "untrusted_binary"
+	default:
+		// 读取插件失败等非信任问题直接返回
+		return fmt.Errorf("加载插件失败: %w", err)
+	}
+
+	metrics.RecordPluginError(pluginName, kind)
+	pl.logger.Error("拒绝加载未通过签名校验的插件",
+		zap.String("plugin", pluginName),
+		zap.String("path", pluginPath),
+		zap.Error(err))
+	if pkg.DB != nil {
+		_ = pkg.AuditLog(pkg.AuditLogOptions{
+			Username:     "system",
+			Action:       "plugin_load_refused",
+			ResourceType: "plugin",
+			ResourceID:   pluginName,
+			NewValue:     map[string]string{"path": pluginPath, "reason": err.Error()},
+		})
+	}
+
+	return fmt.Errorf("插件 '%s' 签名校验失败: %w", pluginName, err)
+}
+
+// LoadPluginFromManifest 按插件清单加载插件
+// 参数:
+// - manifestPath: 插件目录中plugin.json的路径，编译产物按清单的entry_point在同一目录下查找
+// 返回值:
+// - core.Plugin: 加载的插件实例
+// - *core.PluginManifest: 通过校验的插件清单
+// - error: 清单无效或插件运行时的名称、版本与清单不一致时返回错误
+func (pl *PluginLoader) LoadPluginFromManifest(manifestPath string) (core.Plugin, *core.PluginManifest, error) {
+	manifest, err := core.LoadPluginManifest(manifestPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("插件清单无效: %w", err)
+	}
+
+	pluginPath := filepath.Join(filepath.Dir(manifestPath), manifest.EntryPointOrDefault())
+	pluginInstance, err := pl.LoadPlugin(pluginPath, manifest.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 校验插件版本与清单一致，不一致时撤销加载
+	if err := manifest.CheckPlugin(pluginInstance); err != nil {
+		_ = pl.UnloadPlugin(manifest.Name)
+		return nil, nil, err
+	}
+
+	return pluginInstance, manifest, nil
+}
+
 // UnloadPlugin 卸载插件
 func (pl *PluginLoader) UnloadPlugin(pluginName string) error {
 	pl.mutex.Lock()
 	defer pl.mutex.Unlock()
 
+	pl.unloadLocked(pluginName)
+	return nil
+}
+
+// unloadLocked 卸载插件，调用方需持有pl.mutex
+func (pl *PluginLoader) unloadLocked(pluginName string) {
 	// 检查插件是否已加载
 	_, exists := pl.loadedPlugins[pluginName]
 	if !exists {
-		return nil
+		return
 	}
 
 	// Go标准库的plugin包不提供显式关闭插件的机制
@@ -98,8 +269,6 @@ func (pl *PluginLoader) UnloadPlugin(pluginName string) error {
 	// 从映射中删除
 	delete(pl.loadedPlugins, pluginName)
 	pl.logger.Debug("插件卸载成功", zap.String("plugin", pluginName))
-
-	return nil
 }
 
 // GetLoadedPlugin 检查插件是否已加载
@@ -154,6 +154,7 @@ func TestLoadRealPluginOnLinux(t *testing.T) {
 	}
 	logger := pkg.GetLogger()
 	pl := NewPluginLoader(logger)
+	pl.SetTrustPolicy(&TrustPolicy{devMode: true}) // 测试中编译的插件未签名
 
 	tmpDir := t.TempDir()
 	src := filepath.Join(tmpDir, "covplugin.go")
@@ -242,6 +243,7 @@ func TestLoadPluginNameMismatch(t *testing.T) {
 	// 创建一个模拟插件并构建
 	logger := pkg.GetLogger()
 	pl := NewPluginLoader(logger)
+	pl.SetTrustPolicy(&TrustPolicy{devMode: true}) // 测试中编译的插件未签名
 
 	tmpDir := t.TempDir()
 	src := filepath.Join(tmpDir, "namemismatch.go")
@@ -301,6 +303,7 @@ func TestLoadPluginSuccessReload(t *testing.T) {
 
 	logger := pkg.GetLogger()
 	pl := NewPluginLoader(logger)
+	pl.SetTrustPolicy(&TrustPolicy{devMode: true}) // 测试中编译的插件未签名
 
 	tmpDir := t.TempDir()
 	src := filepath.Join(tmpDir, "reloadplugin.go")
@@ -393,6 +396,7 @@ func TestLoadPluginWrongEntryPointType(t *testing.T) {
 	dir := t.TempDir()
 	logger := pkg.GetLogger()
 	loader := NewPluginLoader(logger)
+	loader.SetTrustPolicy(&TrustPolicy{devMode: true}) // 测试中编译的插件未签名
 
 	// 创建一个错误的插件Go文件，入口点是一个变量而不是函数
 	pluginGoPath := filepath.Join(dir, "wrong_entry.go")
@@ -437,6 +441,7 @@ func TestLoadPluginEntryPointNotFound(t *testing.T) {
 	dir := t.TempDir()
 	logger := pkg.GetLogger()
 	loader := NewPluginLoader(logger)
+	loader.SetTrustPolicy(&TrustPolicy{devMode: true}) // 测试中编译的插件未签名
 
 	// 创建一个没有Plugin入口点的插件Go文件
 	pluginGoPath := filepath.Join(dir, "no_entry.go")
@@ -493,6 +498,7 @@ func TestMultipleLoadAndUnload(t *testing.T) {
 	dir := t.TempDir()
 	logger := pkg.GetLogger()
 	loader := NewPluginLoader(logger)
+	loader.SetTrustPolicy(&TrustPolicy{devMode: true}) // 测试中编译的插件未签名
 
 	// 创建一个简单的插件Go文件
 	pluginGoPath := filepath.Join(dir, "test_plugin.go")
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"fmt"
+	"sync"
+
+	"weave/pkg"
+
+	"go.uber.org/zap"
+)
+
+// ProcessLoader 负责启动、卸载和重启进程外插件
+// 与基于Go plugin包的PluginLoader不同，进程外插件可以真正卸载，插件崩溃也不会影响宿主进程
+type ProcessLoader struct {
+	loadedPlugins map[string]*ProcessPlugin
+	mutex         sync.RWMutex
+	logger        *pkg.Logger
+}
+
+// NewProcessLoader 创建进程外插件加载器实例
+func NewProcessLoader(logger *pkg.Logger) *ProcessLoader {
+	if logger == nil {
+		logger = pkg.GetLogger()
+	}
+	return &ProcessLoader{
+		loadedPlugins: make(map[string]*ProcessPlugin),
+		logger:        logger,
+	}
+}
+
+// LoadPlugin 启动插件进程并完成握手
+// 参数:
+// - config: 插件进程的启动配置
+// - pluginName: 期望的插件名称，为空时使用插件握手返回的名称
+// 返回值:
+// - *ProcessPlugin: 实现了core.Plugin接口的插件实例，可直接注册到PluginManager
+// - error: 加载过程中的错误
+func (pl *ProcessLoader) LoadPlugin(config ProcessConfig, pluginName string) (*ProcessPlugin, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("插件可执行文件路径不能为空")
+	}
+
+	plugin := NewProcessPlugin(config, pl.logger)
+	if err := plugin.Start(); err != nil {
+		return nil, fmt.Errorf("加载插件失败: %w", err)
+	}
+	name := plugin.Name()
+	if pluginName != "" && name != pluginName {
+		_ = plugin.Shutdown()
+		return nil, fmt.Errorf("插件名称不匹配: 期望 %s, 实际 %s", pluginName, name)
+	}
+
+	pl.mutex.Lock()
+	previous := pl.loadedPlugins[name]
+	pl.loadedPlugins[name] = plugin
+	pl.mutex.Unlock()
+
+	// 同名插件已加载时关闭旧进程
+	if previous != nil {
+		if err := previous.Shutdown(); err != nil {
+			pl.logger.Warn("卸载已加载的插件失败", zap.String("plugin", name), zap.Error(err))
+		}
+	}
+
+	pl.logger.Debug("插件加载成功",
+		zap.String("plugin", name),
+		zap.String("path", config.Path),
+		zap.Int("pid", plugin.PID()))
+	return plugin, nil
+}
+
+// UnloadPlugin 卸载插件并结束插件进程
+// 已注册到PluginManager的插件应先注销，否则其路由会返回服务不可用
+func (pl *ProcessLoader) UnloadPlugin(pluginName string) error {
+	pl.mutex.Lock()
+	plugin, exists := pl.loadedPlugins[pluginName]
+	delete(pl.loadedPlugins, pluginName)
+	pl.mutex.Unlock()
+
+	if !exists {
+		return nil
+	}
+	if err := plugin.Shutdown(); err != nil {
+		return fmt.Errorf("卸载插件 '%s' 失败: %w", pluginName, err)
+	}
+	pl.logger.Debug("插件卸载成功", zap.String("plugin", pluginName))
+	return nil
+}
+
+// RestartPlugin 重启插件进程
+func (pl *ProcessLoader) RestartPlugin(pluginName string) error {
+	plugin, exists := pl.GetLoadedPlugin(pluginName)
+	if !exists {
+		return fmt.Errorf("插件 '%s' 未加载", pluginName)
+	}
+	return plugin.Restart()
+}
+
+// GetLoadedPlugin 获取已加载的插件
+func (pl *ProcessLoader) GetLoadedPlugin(pluginName string) (*ProcessPlugin, bool) {
+	pl.mutex.RLock()
+	defer pl.mutex.RUnlock()
+
+	plugin, exists := pl.loadedPlugins[pluginName]
+	return plugin, exists
+}
+
+// UnloadAll 卸载全部插件，用于宿主退出前结束所有插件进程
+func (pl *ProcessLoader) UnloadAll() {
+	pl.mutex.Lock()
+	plugins := pl.loadedPlugins
+	pl.loadedPlugins = make(map[string]*ProcessPlugin)
+	pl.mutex.Unlock()
+
+	for name, plugin := range plugins {
+		if err := plugin.Shutdown(); err != nil {
+			pl.logger.Warn("卸载插件失败", zap.String("plugin", name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,569 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"weave/pkg"
+	"weave/pkg/metrics"
+	"weave/plugins/core"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultProcessStartTimeout 启动插件进程并完成握手的默认超时时间
+	DefaultProcessStartTimeout = 10 * time.Second
+	// DefaultProcessStopTimeout 等待插件进程退出的默认超时时间，超时后强制结束进程
+	DefaultProcessStopTimeout = 10 * time.Second
+	// DefaultProcessCallTimeout 生命周期调用的默认超时时间
+	DefaultProcessCallTimeout = 30 * time.Second
+
+	// maxForwardBodySize 转发给插件进程的请求体上限
+	maxForwardBodySize = 10 << 20
+	// maxStderrLineSize 插件进程stderr单行日志的最大长度
+	maxStderrLineSize = 64 << 10
+	// minRestartInterval 崩溃后两次自动重启之间的最小间隔，避免插件反复崩溃时频繁拉起进程
+	minRestartInterval = time.Second
+)
+
+// ProcessConfig 进程外插件的启动配置
+type ProcessConfig struct {
+	Path         string        // 插件可执行文件路径
+	Args         []string      // 启动参数
+	Env          []string      // 额外的环境变量（KEY=VALUE），会追加到宿主进程的环境变量之后
+	Dir          string        // 工作目录，为空时使用宿主进程的工作目录
+	StartTimeout time.Duration // 启动及握手超时，为0时使用DefaultProcessStartTimeout
+	StopTimeout  time.Duration // 关闭超时，为0时使用DefaultProcessStopTimeout
+	CallTimeout  time.Duration // 生命周期调用超时，为0时使用DefaultProcessCallTimeout
+}
+
+// ProcessPlugin 将运行在独立进程中的插件适配为core.Plugin
+// 宿主与插件进程通过stdin/stdout上的JSON-RPC通信：
+//   - Init时启动进程（未运行时），Shutdown时关闭进程，因此重新加载插件会真正重启进程；
+//   - 插件进程崩溃不会影响宿主，处于初始化状态的插件会在下一次调用时自动重启；
+//   - 插件声明的路由由宿主注册，请求通过plugin.handle_http转发给插件进程处理。
+type ProcessPlugin struct {
+	config ProcessConfig
+	logger *pkg.Logger
+
+	mu          sync.Mutex
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	client      *rpcClient
+	exited      chan struct{}
+	info        HandshakeResponse
+	initialized bool
+	restarts    int
+	lastRestart time.Time
+
+	pluginManager *core.PluginManager
+}
+
+// NewProcessPlugin 创建进程外插件适配器，进程在Start或Init时启动
+func NewProcessPlugin(config ProcessConfig, logger *pkg.Logger) *ProcessPlugin {
+	if config.StartTimeout <= 0 {
+		config.StartTimeout = DefaultProcessStartTimeout
+	}
+	if config.StopTimeout <= 0 {
+		config.StopTimeout = DefaultProcessStopTimeout
+	}
+	if config.CallTimeout <= 0 {
+		config.CallTimeout = DefaultProcessCallTimeout
+	}
+	if logger == nil {
+		logger = pkg.GetLogger()
+	}
+	return &ProcessPlugin{config: config, logger: logger}
+}
+
+// Start 启动插件进程并完成握手，进程已运行时直接返回
+func (p *ProcessPlugin) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ensureStartedLocked()
+}
+
+// ensureStartedLocked 确保插件进程正在运行，调用方需持有mu
+func (p *ProcessPlugin) ensureStartedLocked() error {
+	if p.client != nil && !p.client.isClosed() {
+		return nil
+	}
+	p.killLocked()
+	return p.startLocked()
+}
+
+// startLocked 启动插件进程并握手，调用方需持有mu
+func (p *ProcessPlugin) startLocked() error {
+	cmd := exec.Command(p.config.Path, p.config.Args...)
+	cmd.Dir = p.config.Dir
+	cmd.Env = append(os.Environ(), p.config.Env...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("WEAVE_PLUGIN_PROTOCOL=%d", ProcessProtocolVersion))
+	cmd.Stderr = &stderrLogger{logger: p.logger, path: p.config.Path}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建插件进程输入管道失败: %w", err)
+	}
+	// 使用独立的管道读取stdout：进程退出后仍可读完缓冲区中的响应
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		_ = stdin.Close()
+		return fmt.Errorf("创建插件进程输出管道失败: %w", err)
+	}
+	cmd.Stdout = stdoutWriter
+
+	if err := cmd.Start(); err != nil {
+		_ = stdin.Close()
+		_ = stdoutReader.Close()
+		_ = stdoutWriter.Close()
+		return fmt.Errorf("启动插件进程失败: %w", err)
+	}
+	_ = stdoutWriter.Close()
+
+	exited := make(chan struct{})
+	p.cmd = cmd
+	p.stdin = stdin
+	p.exited = exited
+	p.client = newRPCClient(stdoutReader, stdin)
+	go p.wait(cmd, stdoutReader, exited)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.StartTimeout)
+	defer cancel()
+	var info HandshakeResponse
+	request := HandshakeRequest{ProtocolVersion: ProcessProtocolVersion, Host: "weave"}
+	if err := p.client.Call(ctx, MethodHandshake, request, &info); err != nil {
+		p.killLocked()
+		return fmt.Errorf("插件进程握手失败: %w", err)
+	}
+	if info.ProtocolVersion != ProcessProtocolVersion {
+		p.killLocked()
+		return fmt.Errorf("插件进程协议版本不兼容: 期望 %d, 实际 %d", ProcessProtocolVersion, info.ProtocolVersion)
+	}
+	if info.Name == "" {
+		p.killLocked()
+		return fmt.Errorf("插件进程握手失败: 插件名称为空")
+	}
+	if p.info.Name != "" && info.Name != p.info.Name {
+		p.killLocked()
+		return fmt.Errorf("插件名称不匹配: 期望 %s, 实际 %s", p.info.Name, info.Name)
+	}
+	p.info = info
+
+	p.logger.Debug("插件进程已启动",
+		zap.String("plugin", info.Name),
+		zap.String("path", p.config.Path),
+		zap.Int("pid", cmd.Process.Pid))
+	return nil
+}
+
+// wait 等待进程退出，非主动关闭的退出视为崩溃
+func (p *ProcessPlugin) wait(cmd *exec.Cmd, stdout io.Closer, exited chan struct{}) {
+	err := cmd.Wait()
+	_ = stdout.Close()
+	close(exited)
+
+	p.mu.Lock()
+	crashed := p.cmd == cmd
+	if crashed {
+		p.cmd = nil
+		p.stdin = nil
+		p.client.close(err)
+		p.client = nil
+	}
+	name := p.info.Name
+	p.mu.Unlock()
+
+	if crashed {
+		p.logger.Error("插件进程异常退出", zap.String("plugin", name), zap.Error(err))
+		metrics.RecordPluginError(name, "process_crashed")
+	}
+}
+
+// killLocked 强制结束当前进程，调用方需持有mu
+func (p *ProcessPlugin) killLocked() {
+	if p.cmd == nil {
+		return
+	}
+	cmd, exited, client := p.cmd, p.exited, p.client
+	p.cmd, p.stdin, p.client = nil, nil, nil
+	_ = cmd.Process.Kill()
+	<-exited
+	client.close(nil)
+}
+
+// stopLocked 通知插件进程关闭并等待其退出，超时后强制结束，调用方需持有mu
+func (p *ProcessPlugin) stopLocked() error {
+	if p.cmd == nil {
+		return nil
+	}
+	cmd, stdin, exited, client := p.cmd, p.stdin, p.exited, p.client
+	p.cmd, p.stdin, p.client = nil, nil, nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.StopTimeout)
+	defer cancel()
+
+	var err error
+	if callErr := client.Call(ctx, MethodShutdown, nil, nil); callErr != nil &&
+		!isMethodNotFound(callErr) && !errors.Is(callErr, ErrProcessNotRunning) {
+		err = callErr
+	}
+	// 关闭stdin，插件进程读到EOF后退出
+	_ = stdin.Close()
+
+	select {
+	case <-exited:
+	case <-ctx.Done():
+		p.logger.Warn("插件进程未在超时时间内退出，强制结束", zap.String("plugin", p.info.Name))
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+	client.close(nil)
+	return err
+}
+
+// callLocked 在运行中的进程上发起调用，调用方需持有mu
+func (p *ProcessPlugin) callLocked(method string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.CallTimeout)
+	defer cancel()
+	if err := p.client.Call(ctx, method, nil, nil); err != nil && !isMethodNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// runningClient 返回可用的连接；已初始化的插件进程崩溃后在此自动重启
+func (p *ProcessPlugin) runningClient() (*rpcClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil && !p.client.isClosed() {
+		return p.client, nil
+	}
+	if !p.initialized {
+		return nil, ErrProcessNotRunning
+	}
+	if time.Since(p.lastRestart) < minRestartInterval {
+		return nil, fmt.Errorf("%w: 插件 '%s' 重启过于频繁", ErrProcessNotRunning, p.info.Name)
+	}
+	p.lastRestart = time.Now()
+
+	p.killLocked()
+	if err := p.startLocked(); err != nil {
+		return nil, fmt.Errorf("重启插件进程失败: %w", err)
+	}
+	if err := p.callLocked(MethodInit); err != nil {
+		_ = p.stopLocked()
+		return nil, fmt.Errorf("重启插件进程后初始化失败: %w", err)
+	}
+	p.restarts++
+	p.logger.Warn("插件进程已自动重启", zap.String("plugin", p.info.Name), zap.Int("restarts", p.restarts))
+	return p.client, nil
+}
+
+// call 发起调用，上下文没有截止时间时使用CallTimeout
+func (p *ProcessPlugin) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	client, err := p.runningClient()
+	if err != nil {
+		return err
+	}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.CallTimeout)
+		defer cancel()
+	}
+	return client.Call(ctx, method, params, result)
+}
+
+// Restart 重启插件进程，已初始化的插件会在新进程中重新初始化
+func (p *ProcessPlugin) Restart() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.stopLocked(); err != nil {
+		p.logger.Warn("关闭插件进程失败", zap.String("plugin", p.info.Name), zap.Error(err))
+	}
+	if err := p.startLocked(); err != nil {
+		return err
+	}
+	if p.initialized {
+		if err := p.callLocked(MethodInit); err != nil {
+			p.initialized = false
+			_ = p.stopLocked()
+			return fmt.Errorf("插件 '%s' 重新初始化失败: %w", p.info.Name, err)
+		}
+	}
+	p.restarts++
+	return nil
+}
+
+// Running 插件进程是否正在运行
+func (p *ProcessPlugin) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.client != nil && !p.client.isClosed()
+}
+
+// PID 返回插件进程ID，未运行时返回0
+func (p *ProcessPlugin) PID() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// Restarts 返回插件进程的重启次数
+func (p *ProcessPlugin) Restarts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.restarts
+}
+
+// Path 返回插件可执行文件路径
+func (p *ProcessPlugin) Path() string {
+	return p.config.Path
+}
+
+// handshakeInfo 返回握手得到的插件元数据
+func (p *ProcessPlugin) handshakeInfo() HandshakeResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.info
+}
+
+// Name 返回插件名称
+func (p *ProcessPlugin) Name() string {
+	return p.handshakeInfo().Name
+}
+
+// Description 返回插件描述
+func (p *ProcessPlugin) Description() string {
+	return p.handshakeInfo().Description
+}
+
+// Version 返回插件版本
+func (p *ProcessPlugin) Version() string {
+	return p.handshakeInfo().Version
+}
+
+// GetDependencies 返回插件依赖
+func (p *ProcessPlugin) GetDependencies() []string {
+	return p.handshakeInfo().Dependencies
+}
+
+// GetConflicts 返回插件冲突
+func (p *ProcessPlugin) GetConflicts() []string {
+	return p.handshakeInfo().Conflicts
+}
+
+// Init 启动插件进程（未运行时）并初始化插件
+func (p *ProcessPlugin) Init() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.ensureStartedLocked(); err != nil {
+		return err
+	}
+	if err := p.callLocked(MethodInit); err != nil {
+		_ = p.stopLocked()
+		return err
+	}
+	p.initialized = true
+	return nil
+}
+
+// Shutdown 关闭插件并结束插件进程
+func (p *ProcessPlugin) Shutdown() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.initialized = false
+	return p.stopLocked()
+}
+
+// OnEnable 插件启用回调
+func (p *ProcessPlugin) OnEnable() error {
+	if err := p.call(context.Background(), MethodOnEnable, nil, nil); err != nil && !isMethodNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// OnDisable 插件禁用回调
+func (p *ProcessPlugin) OnDisable() error {
+	if err := p.call(context.Background(), MethodOnDisable, nil, nil); err != nil && !isMethodNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// GetRoutes 将插件声明的路由转换为core.Route，处理函数将请求转发给插件进程
+func (p *ProcessPlugin) GetRoutes() []core.Route {
+	info := p.handshakeInfo()
+	routes := make([]core.Route, 0, len(info.Routes))
+	for _, route := range info.Routes {
+		routes = append(routes, core.Route{
+			Path:         route.Path,
+			Method:       strings.ToUpper(route.Method),
+			Handler:      p.forwardHTTP(route.Path),
+			Description:  route.Description,
+			AuthRequired: route.AuthRequired,
+			Tags:         route.Tags,
+			Params:       route.Params,
+		})
+	}
+	return routes
+}
+
+// RegisterRoutes 进程外插件的路由由PluginManager统一注册
+func (p *ProcessPlugin) RegisterRoutes(router *gin.Engine) {}
+
+// GetDefaultMiddlewares 获取插件默认中间件
+func (p *ProcessPlugin) GetDefaultMiddlewares() []gin.HandlerFunc {
+	return nil
+}
+
+// SetPluginManager 设置插件管理器引用
+func (p *ProcessPlugin) SetPluginManager(manager *core.PluginManager) {
+	p.pluginManager = manager
+}
+
+// Execute 执行插件功能
+func (p *ProcessPlugin) Execute(params map[string]interface{}) (interface{}, error) {
+	return p.ExecuteContext(context.Background(), params)
+}
+
+// ExecuteContext 在插件进程中执行插件功能，上下文取消时通知插件进程取消执行
+func (p *ProcessPlugin) ExecuteContext(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	request := ExecuteRequest{Params: params}
+	if meta, ok := core.RequestMetaFromContext(ctx); ok {
+		request.UserID = meta.UserID
+		request.TenantID = meta.TenantID
+		request.RequestID = meta.RequestID
+	}
+	var result interface{}
+	if err := p.call(ctx, MethodExecute, request, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// forwardHTTP 创建将请求转发给插件进程的处理函数
+func (p *ProcessPlugin) forwardHTTP(routePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxForwardBodySize+1))
+		if err != nil {
+			abortWithAppError(c, pkg.NewBadRequestError("读取请求体失败", err))
+			return
+		}
+		if len(body) > maxForwardBodySize {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"code":    "REQUEST_ENTITY_TOO_LARGE",
+				"message": fmt.Sprintf("请求体不能超过 %d 字节", maxForwardBodySize),
+			})
+			return
+		}
+
+		ctx := core.ContextFromGin(c)
+		meta, _ := core.RequestMetaFromContext(ctx)
+		// 用户身份已通过user_id/tenant_id传递，不向插件进程转发凭据
+		headers := c.Request.Header.Clone()
+		headers.Del("Authorization")
+		headers.Del("Cookie")
+		request := HTTPRequest{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Route:     routePath,
+			Params:    make(map[string]string, len(c.Params)),
+			Query:     c.Request.URL.RawQuery,
+			Headers:   headers,
+			Body:      body,
+			UserID:    meta.UserID,
+			TenantID:  meta.TenantID,
+			RequestID: meta.RequestID,
+		}
+		for _, param := range c.Params {
+			request.Params[param.Key] = param.Value
+		}
+
+		var response HTTPResponse
+		if err := p.call(ctx, MethodHandleHTTP, request, &response); err != nil {
+			name := p.Name()
+			if errors.Is(err, ErrProcessNotRunning) {
+				abortWithAppError(c, pkg.NewServiceUnavailableError(fmt.Sprintf("插件 '%s' 的进程不可用", name), err))
+				return
+			}
+			abortWithAppError(c, pkg.NewPluginExecutionError(fmt.Sprintf("插件 '%s' 处理请求失败", name), err))
+			return
+		}
+
+		for key, values := range response.Headers {
+			for _, value := range values {
+				c.Writer.Header().Add(key, value)
+			}
+		}
+		status := response.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		c.Status(status)
+		if len(response.Body) > 0 {
+			_, _ = c.Writer.Write(response.Body)
+		}
+	}
+}
+
+// abortWithAppError 以统一格式返回错误响应
+func abortWithAppError(c *gin.Context, appErr *pkg.AppError) {
+	c.AbortWithStatusJSON(pkg.GetHTTPStatus(appErr), gin.H{
+		"code":    string(appErr.Code),
+		"message": appErr.Message,
+	})
+}
+
+// stderrLogger 将插件进程的stderr按行写入宿主日志
+type stderrLogger struct {
+	logger *pkg.Logger
+	path   string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+// Write 实现io.Writer接口
+func (w *stderrLogger) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, data...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			// 不完整的行等待后续输出，过长时直接输出
+			if len(w.buf) < maxStderrLineSize {
+				break
+			}
+			i = len(w.buf)
+		}
+		line := strings.TrimRight(string(w.buf[:i]), "\r")
+		if i < len(w.buf) {
+			i++
+		}
+		w.buf = w.buf[i:]
+		if line != "" {
+			w.logger.Info("插件进程输出", zap.String("path", w.path), zap.String("line", line))
+		}
+	}
+	return len(data), nil
+}
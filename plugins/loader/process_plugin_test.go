@@ -0,0 +1,226 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"weave/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// echoProcessPlugin 在子进程中运行的测试插件
+type echoProcessPlugin struct{}
+
+func (echoProcessPlugin) Info() HandshakeResponse {
+	return HandshakeResponse{
+		Name:        "echo",
+		Version:     "1.0.0",
+		Description: "process echo plugin",
+		Routes:      []ProcessRoute{{Method: "get", Path: "/echo/:id"}},
+	}
+}
+
+func (echoProcessPlugin) Execute(ctx context.Context, req *ExecuteRequest) (interface{}, error) {
+	switch req.Params["action"] {
+	case "crash":
+		os.Exit(2)
+	case "sleep":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "pid":
+		return os.Getpid(), nil
+	case "fail":
+		return nil, errors.New("boom")
+	}
+	return req.Params, nil
+}
+
+func (echoProcessPlugin) HandleHTTP(ctx context.Context, req *HTTPRequest) (*HTTPResponse, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":       req.Params["id"],
+		"query":    req.Query,
+		"user_id":  req.UserID,
+		"has_auth": len(req.Headers["Authorization"]) > 0,
+		"body":     string(req.Body),
+	})
+	return &HTTPResponse{
+		Status:  http.StatusCreated,
+		Headers: map[string][]string{"Content-Type": {"application/json"}, "X-Plugin": {"echo"}},
+		Body:    body,
+	}, nil
+}
+
+// TestProcessPluginHelper 不是真正的测试，作为子进程运行时提供插件服务
+func TestProcessPluginHelper(t *testing.T) {
+	if os.Getenv("WEAVE_PROCESS_PLUGIN_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	if err := ServeProcessPlugin(echoProcessPlugin{}); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func helperProcessConfig() ProcessConfig {
+	return ProcessConfig{
+		Path: os.Args[0],
+		Args: []string{"-test.run=^TestProcessPluginHelper$"},
+		Env:  []string{"WEAVE_PROCESS_PLUGIN_HELPER=1"},
+	}
+}
+
+func loadEchoPlugin(t *testing.T) *ProcessPlugin {
+	t.Helper()
+	pl := NewProcessLoader(pkg.GetLogger())
+	plugin, err := pl.LoadPlugin(helperProcessConfig(), "echo")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	t.Cleanup(pl.UnloadAll)
+	return plugin
+}
+
+func executePID(t *testing.T, plugin *ProcessPlugin) int {
+	t.Helper()
+	result, err := plugin.Execute(map[string]interface{}{"action": "pid"})
+	if err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	return int(result.(float64))
+}
+
+func TestProcessPluginLifecycle(t *testing.T) {
+	plugin := loadEchoPlugin(t)
+	if plugin.Name() != "echo" || plugin.Version() != "1.0.0" {
+		t.Fatalf("unexpected handshake info: %s %s", plugin.Name(), plugin.Version())
+	}
+	routes := plugin.GetRoutes()
+	if len(routes) != 1 || routes[0].Method != "GET" || routes[0].Path != "/echo/:id" {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+
+	if err := plugin.Init(); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	result, err := plugin.Execute(map[string]interface{}{"msg": "hi"})
+	if err != nil || result.(map[string]interface{})["msg"] != "hi" {
+		t.Fatalf("unexpected execute result: %v, %v", result, err)
+	}
+	if _, err := plugin.Execute(map[string]interface{}{"action": "fail"}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected plugin error, got %v", err)
+	}
+	firstPID := executePID(t, plugin)
+
+	// Shutdown真正结束进程，Init重新启动新进程
+	if err := plugin.Shutdown(); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+	if plugin.Running() {
+		t.Fatalf("expected process stopped after shutdown")
+	}
+	if _, err := plugin.Execute(nil); !errors.Is(err, ErrProcessNotRunning) {
+		t.Fatalf("expected ErrProcessNotRunning, got %v", err)
+	}
+	if err := plugin.Init(); err != nil {
+		t.Fatalf("re-init error: %v", err)
+	}
+	if pid := executePID(t, plugin); pid == firstPID {
+		t.Fatalf("expected a new process after re-init")
+	}
+}
+
+func TestProcessPluginCrashRecovery(t *testing.T) {
+	plugin := loadEchoPlugin(t)
+	if err := plugin.Init(); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+	firstPID := executePID(t, plugin)
+
+	if _, err := plugin.Execute(map[string]interface{}{"action": "crash"}); !errors.Is(err, ErrProcessNotRunning) {
+		t.Fatalf("expected crash to surface as ErrProcessNotRunning, got %v", err)
+	}
+
+	// 下一次调用自动重启进程
+	if pid := executePID(t, plugin); pid == firstPID {
+		t.Fatalf("expected plugin process restarted")
+	}
+	if plugin.Restarts() != 1 {
+		t.Fatalf("expected 1 restart, got %d", plugin.Restarts())
+	}
+}
+
+func TestProcessPluginExecuteCancel(t *testing.T) {
+	plugin := loadEchoPlugin(t)
+	if err := plugin.Init(); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := plugin.ExecuteContext(ctx, map[string]interface{}{"action": "sleep"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if _, err := plugin.Execute(map[string]interface{}{"msg": "after"}); err != nil {
+		t.Fatalf("expected plugin usable after cancel, got %v", err)
+	}
+}
+
+func TestProcessPluginForwardsHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	plugin := loadEchoPlugin(t)
+	if err := plugin.Init(); err != nil {
+		t.Fatalf("init error: %v", err)
+	}
+
+	router := gin.New()
+	route := plugin.GetRoutes()[0]
+	router.Handle(route.Method, route.Path, func(c *gin.Context) {
+		c.Set("user_id", uint(7))
+		c.Next()
+	}, route.Handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/echo/42?q=1", strings.NewReader("payload"))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated || w.Header().Get("X-Plugin") != "echo" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if body["id"] != "42" || body["query"] != "q=1" || body["user_id"] != float64(7) || body["body"] != "payload" {
+		t.Fatalf("unexpected forwarded request: %v", body)
+	}
+	if body["has_auth"] != false {
+		t.Fatalf("expected Authorization header not forwarded")
+	}
+
+	// 进程关闭后返回服务不可用
+	_ = plugin.Shutdown()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/echo/42", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after shutdown, got %d", w.Code)
+	}
+}
+
+func TestProcessLoaderNameMismatch(t *testing.T) {
+	pl := NewProcessLoader(pkg.GetLogger())
+	if _, err := pl.LoadPlugin(helperProcessConfig(), "other"); err == nil || !strings.Contains(err.Error(), "不匹配") {
+		t.Fatalf("expected name mismatch error, got %v", err)
+	}
+	if _, exists := pl.GetLoadedPlugin("echo"); exists {
+		t.Fatalf("expected mismatched plugin not to be kept")
+	}
+}
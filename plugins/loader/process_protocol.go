@@ -0,0 +1,116 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProcessProtocolVersion 进程外插件协议版本，握手时双方必须一致
+const ProcessProtocolVersion = 1
+
+// 进程外插件协议方法名
+// 宿主通过插件进程的stdin发送JSON-RPC 2.0请求，插件通过stdout返回响应，每条消息占一行；
+// 插件的stderr输出会被转发到宿主日志，因此插件不能向stdout写入协议以外的内容
+const (
+	MethodHandshake     = "plugin.handshake"   // 握手，返回插件元数据和路由
+	MethodInit          = "plugin.init"        // 初始化
+	MethodShutdown      = "plugin.shutdown"    // 关闭
+	MethodOnEnable      = "plugin.on_enable"   // 启用回调
+	MethodOnDisable     = "plugin.on_disable"  // 禁用回调
+	MethodExecute       = "plugin.execute"     // 执行插件功能
+	MethodHandleHTTP    = "plugin.handle_http" // 处理转发的HTTP请求
+	MethodCancelRequest = "$/cancel_request"   // 通知：取消进行中的请求
+)
+
+// JSON-RPC 2.0错误码
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	RPCPluginError    = -32000 // 插件业务错误
+)
+
+// RPCError JSON-RPC错误对象
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error 实现error接口
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("插件进程返回错误(%d): %s", e.Code, e.Message)
+}
+
+// rpcMessage JSON-RPC消息，请求、响应和通知共用同一结构
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *uint64         `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// HandshakeRequest 握手请求
+type HandshakeRequest struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Host            string `json:"host"`
+}
+
+// HandshakeResponse 握手响应，描述插件的元数据和路由
+type HandshakeResponse struct {
+	ProtocolVersion int            `json:"protocol_version"`
+	Name            string         `json:"name"`
+	Version         string         `json:"version"`
+	Description     string         `json:"description"`
+	Dependencies    []string       `json:"dependencies,omitempty"`
+	Conflicts       []string       `json:"conflicts,omitempty"`
+	Routes          []ProcessRoute `json:"routes,omitempty"`
+}
+
+// ProcessRoute 进程外插件声明的路由，请求由宿主通过plugin.handle_http转发
+type ProcessRoute struct {
+	Method       string            `json:"method"`
+	Path         string            `json:"path"`
+	Description  string            `json:"description,omitempty"`
+	AuthRequired bool              `json:"auth_required,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`
+}
+
+// ExecuteRequest plugin.execute的参数
+type ExecuteRequest struct {
+	Params    map[string]interface{} `json:"params"`
+	UserID    uint                   `json:"user_id,omitempty"`
+	TenantID  uint                   `json:"tenant_id,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// HTTPRequest plugin.handle_http的参数
+type HTTPRequest struct {
+	Method    string              `json:"method"`
+	Path      string              `json:"path"`  // 实际请求路径
+	Route     string              `json:"route"` // 匹配的路由模板（插件声明的Path）
+	Params    map[string]string   `json:"params,omitempty"`
+	Query     string              `json:"query,omitempty"`
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Body      []byte              `json:"body,omitempty"`
+	UserID    uint                `json:"user_id,omitempty"`
+	TenantID  uint                `json:"tenant_id,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+// HTTPResponse plugin.handle_http的返回值，Status为0时按200处理
+type HTTPResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    []byte              `json:"body,omitempty"`
+}
+
+// cancelRequestParams $/cancel_request通知的参数
+type cancelRequestParams struct {
+	ID uint64 `json:"id"`
+}
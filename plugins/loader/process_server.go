@@ -0,0 +1,207 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ProcessPluginHandler 进程外插件的实现接口
+// 使用Go编写的插件可执行文件实现该接口并调用ServeProcessPlugin即可接入宿主。
+// 以下生命周期方法为可选实现：Init() error、Shutdown() error、OnEnable() error、OnDisable() error
+type ProcessPluginHandler interface {
+	// Info 返回插件元数据和路由，协议版本由SDK填充
+	Info() HandshakeResponse
+	// Execute 执行插件功能，宿主取消请求时ctx会被取消
+	Execute(ctx context.Context, req *ExecuteRequest) (interface{}, error)
+	// HandleHTTP 处理宿主转发的HTTP请求
+	HandleHTTP(ctx context.Context, req *HTTPRequest) (*HTTPResponse, error)
+}
+
+// ServeProcessPlugin 在stdin/stdout上提供进程外插件服务，宿主关闭stdin后返回
+// 插件的日志应写入stderr，stdout专用于协议通信
+func ServeProcessPlugin(handler ProcessPluginHandler) error {
+	return ServeProcessPluginIO(handler, os.Stdin, os.Stdout)
+}
+
+// ServeProcessPluginIO 在指定的读写端上提供进程外插件服务，读到EOF后等待进行中的请求结束并返回
+// 生命周期方法按到达顺序依次处理，plugin.execute和plugin.handle_http并发处理
+func ServeProcessPluginIO(handler ProcessPluginHandler, r io.Reader, w io.Writer) error {
+	s := &processServer{
+		handler:  handler,
+		enc:      json.NewEncoder(w),
+		inflight: make(map[uint64]context.CancelFunc),
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var msg rpcMessage
+		if err := dec.Decode(&msg); err != nil {
+			s.cancelAll()
+			s.wg.Wait()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("读取宿主请求失败: %w", err)
+		}
+
+		if msg.ID == nil {
+			if msg.Method == MethodCancelRequest {
+				var params cancelRequestParams
+				if json.Unmarshal(msg.Params, &params) == nil {
+					s.cancel(params.ID)
+				}
+			}
+			continue
+		}
+
+		switch msg.Method {
+		case MethodExecute, MethodHandleHTTP:
+			ctx := s.track(*msg.ID)
+			s.wg.Add(1)
+			go func(msg rpcMessage) {
+				defer s.wg.Done()
+				s.handle(ctx, &msg)
+			}(msg)
+		default:
+			s.handle(context.Background(), &msg)
+		}
+	}
+}
+
+// processServer 插件进程一侧的JSON-RPC服务
+type processServer struct {
+	handler ProcessPluginHandler
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu       sync.Mutex
+	inflight map[uint64]context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// track 为并发处理的请求创建可取消的上下文
+func (s *processServer) track(id uint64) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.inflight[id] = cancel
+	s.mu.Unlock()
+	return ctx
+}
+
+// cancel 取消指定请求
+func (s *processServer) cancel(id uint64) {
+	s.mu.Lock()
+	cancel := s.inflight[id]
+	delete(s.inflight, id)
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// cancelAll 取消全部进行中的请求
+func (s *processServer) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, cancel := range s.inflight {
+		cancel()
+		delete(s.inflight, id)
+	}
+}
+
+// handle 处理单个请求并写回响应，处理函数panic时返回内部错误，插件进程不会退出
+func (s *processServer) handle(ctx context.Context, msg *rpcMessage) {
+	defer s.cancel(*msg.ID)
+
+	result, rpcErr := func() (result interface{}, rpcErr *RPCError) {
+		defer func() {
+			if r := recover(); r != nil {
+				rpcErr = &RPCError{Code: RPCInternalError, Message: fmt.Sprintf("插件处理 %s 时发生panic: %v", msg.Method, r)}
+			}
+		}()
+		return s.dispatch(ctx, msg)
+	}()
+
+	response := &rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			response.Error = &RPCError{Code: RPCInternalError, Message: fmt.Sprintf("序列化返回值失败: %v", err)}
+		} else {
+			response.Result = data
+		}
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.enc.Encode(response)
+}
+
+// dispatch 根据方法名调用插件实现
+func (s *processServer) dispatch(ctx context.Context, msg *rpcMessage) (interface{}, *RPCError) {
+	switch msg.Method {
+	case MethodHandshake:
+		info := s.handler.Info()
+		info.ProtocolVersion = ProcessProtocolVersion
+		return info, nil
+	case MethodInit:
+		if hook, ok := s.handler.(interface{ Init() error }); ok {
+			return nil, toRPCError(hook.Init())
+		}
+		return nil, nil
+	case MethodShutdown:
+		if hook, ok := s.handler.(interface{ Shutdown() error }); ok {
+			return nil, toRPCError(hook.Shutdown())
+		}
+		return nil, nil
+	case MethodOnEnable:
+		if hook, ok := s.handler.(interface{ OnEnable() error }); ok {
+			return nil, toRPCError(hook.OnEnable())
+		}
+		return nil, nil
+	case MethodOnDisable:
+		if hook, ok := s.handler.(interface{ OnDisable() error }); ok {
+			return nil, toRPCError(hook.OnDisable())
+		}
+		return nil, nil
+	case MethodExecute:
+		var req ExecuteRequest
+		if err := json.Unmarshal(msg.Params, &req); err != nil {
+			return nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+		}
+		result, err := s.handler.Execute(ctx, &req)
+		if err != nil {
+			return nil, toRPCError(err)
+		}
+		return result, nil
+	case MethodHandleHTTP:
+		var req HTTPRequest
+		if err := json.Unmarshal(msg.Params, &req); err != nil {
+			return nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+		}
+		resp, err := s.handler.HandleHTTP(ctx, &req)
+		if err != nil {
+			return nil, toRPCError(err)
+		}
+		return resp, nil
+	}
+	return nil, &RPCError{Code: RPCMethodNotFound, Message: fmt.Sprintf("不支持的方法: %s", msg.Method)}
+}
+
+// toRPCError 将插件返回的错误转换为JSON-RPC错误，插件可直接返回*RPCError指定错误码
+func toRPCError(err error) *RPCError {
+	if err == nil {
+		return nil
+	}
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return &RPCError{Code: RPCPluginError, Message: err.Error()}
+}
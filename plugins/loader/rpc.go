@@ -0,0 +1,168 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrProcessNotRunning 插件进程未运行或连接已断开
+var ErrProcessNotRunning = errors.New("插件进程未运行")
+
+// rpcClient 基于换行分隔JSON的JSON-RPC 2.0客户端
+type rpcClient struct {
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu       sync.Mutex
+	nextID   uint64
+	pending  map[uint64]chan *rpcMessage
+	closed   bool
+	closeErr error
+	done     chan struct{}
+}
+
+// newRPCClient 创建客户端并开始读取响应
+func newRPCClient(r io.Reader, w io.Writer) *rpcClient {
+	c := &rpcClient{
+		enc:     json.NewEncoder(w),
+		pending: make(map[uint64]chan *rpcMessage),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(r)
+	return c
+}
+
+// readLoop 读取响应并分发给等待中的调用，读取失败时关闭连接
+func (c *rpcClient) readLoop(r io.Reader) {
+	dec := json.NewDecoder(r)
+	for {
+		var msg rpcMessage
+		if err := dec.Decode(&msg); err != nil {
+			c.close(err)
+			return
+		}
+		// 插件主动发送的通知暂不处理
+		if msg.ID == nil {
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- &msg
+		}
+	}
+}
+
+// close 关闭连接，所有等待中的调用立即返回
+func (c *rpcClient) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if err == nil || errors.Is(err, io.EOF) {
+		c.closeErr = ErrProcessNotRunning
+	} else {
+		c.closeErr = fmt.Errorf("%w: %v", ErrProcessNotRunning, err)
+	}
+	close(c.done)
+}
+
+// isClosed 连接是否已关闭
+func (c *rpcClient) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// write 发送一条消息
+func (c *rpcClient) write(msg *rpcMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.enc.Encode(msg)
+}
+
+// Call 发起调用并等待响应，result为nil时忽略返回值
+// 上下文取消时向插件发送$/cancel_request通知并立即返回
+func (c *rpcClient) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("序列化 %s 参数失败: %w", method, err)
+	}
+
+	ch := make(chan *rpcMessage, 1)
+	c.mu.Lock()
+	if c.closed {
+		err := c.closeErr
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.write(&rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: rawParams}); err != nil {
+		c.forget(id)
+		return fmt.Errorf("%w: %v", ErrProcessNotRunning, err)
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("解析 %s 返回值失败: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		c.forget(id)
+		c.notify(MethodCancelRequest, cancelRequestParams{ID: id})
+		return ctx.Err()
+	case <-c.done:
+		// 响应可能在连接关闭前已送达
+		select {
+		case msg := <-ch:
+			if msg.Error != nil {
+				return msg.Error
+			}
+			return nil
+		default:
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.closeErr
+	}
+}
+
+// notify 发送通知，失败时忽略
+func (c *rpcClient) notify(method string, params interface{}) {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	_ = c.write(&rpcMessage{JSONRPC: "2.0", Method: method, Params: rawParams})
+}
+
+// forget 移除等待中的调用
+func (c *rpcClient) forget(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// isMethodNotFound 判断错误是否为插件未实现该方法
+func isMethodNotFound(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == RPCMethodNotFound
+}
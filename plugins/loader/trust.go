@@ -0,0 +1,152 @@
+package loader
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"weave/config"
+)
+
+const (
+	// DigestSuffix 插件SHA-256摘要文件的后缀，内容为十六进制摘要（兼容sha256sum输出格式）
+	DigestSuffix = ".sha256"
+	// SignatureSuffix 插件分离签名文件的后缀，内容为base64编码的ed25519签名
+	SignatureSuffix = ".sig"
+)
+
+var (
+	// ErrPluginUnsigned 插件缺少摘要或签名文件
+	ErrPluginUnsigned = errors.New("插件未签名")
+	// ErrPluginTampered 插件内容与摘要不一致
+	ErrPluginTampered = errors.New("插件内容与摘要不一致")
+	// ErrPluginUntrusted 插件签名无效或不是由受信任的公钥签发
+	ErrPluginUntrusted = errors.New("插件签名不受信任")
+)
+
+// TrustPolicy 动态加载插件的信任策略
+// 插件编译产物旁需要有摘要文件（<产物>.sha256）和签名文件（<产物>.sig），
+// 签名为受信任公钥之一对产物SHA-256摘要的ed25519签名
+type TrustPolicy struct {
+	publicKeys []ed25519.PublicKey
+	devMode    bool
+}
+
+// NewTrustPolicy 创建信任策略
+// 参数:
+// - publicKeys: base64编码的ed25519公钥
+// - devMode: 开发模式，跳过签名校验
+func NewTrustPolicy(publicKeys []string, devMode bool) (*TrustPolicy, error) {
+	policy := &TrustPolicy{devMode: devMode}
+	for i, encoded := range publicKeys {
+		key, err := ParsePublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个插件签名公钥无效: %w", i+1, err)
+		}
+		policy.publicKeys = append(policy.publicKeys, key)
+	}
+	return policy, nil
+}
+
+// TrustPolicyFromConfig 根据plugins.trust配置创建信任策略
+func TrustPolicyFromConfig() (*TrustPolicy, error) {
+	trust := config.Config.Plugins.Trust
+	return NewTrustPolicy(trust.PublicKeys, trust.DevMode)
+}
+
+// ParsePublicKey 解析base64编码的ed25519公钥
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("公钥不是有效的base64编码: %w", err)
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("公钥长度应为 %d 字节，实际为 %d 字节", ed25519.PublicKeySize, len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// DevMode 是否为开发模式
+func (tp *TrustPolicy) DevMode() bool {
+	return tp.devMode
+}
+
+// Verify 校验插件编译产物的摘要和签名，开发模式下不做校验
+// 校验失败时返回的错误包装ErrPluginUnsigned、ErrPluginTampered或ErrPluginUntrusted
+func (tp *TrustPolicy) Verify(pluginPath string) error {
+	if tp.devMode {
+		return nil
+	}
+
+	data, err := os.ReadFile(pluginPath)
+	if err != nil {
+		return fmt.Errorf("读取插件失败: %w", err)
+	}
+	return tp.VerifyData(pluginPath, data)
+}
+
+// VerifyData 校验已读取的插件内容，摘要文件和签名文件按pluginPath查找
+// 调用方读取一次插件后校验并使用同一份内容，避免校验与使用之间文件被替换
+func (tp *TrustPolicy) VerifyData(pluginPath string, data []byte) error {
+	if tp.devMode {
+		return nil
+	}
+	digest := sha256.Sum256(data)
+
+	// 校验摘要
+	digestData, err := os.ReadFile(pluginPath + DigestSuffix)
+	if err != nil {
+		return fmt.Errorf("%w: 读取摘要文件失败: %v", ErrPluginUnsigned, err)
+	}
+	fields := strings.Fields(string(digestData))
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: 摘要文件为空", ErrPluginTampered)
+	}
+	expected, err := hex.DecodeString(fields[0])
+	if err != nil || subtle.ConstantTimeCompare(expected, digest[:]) != 1 {
+		return ErrPluginTampered
+	}
+
+	// 校验签名
+	signatureData, err := os.ReadFile(pluginPath + SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("%w: 读取签名文件失败: %v", ErrPluginUnsigned, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signatureData)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: 签名格式无效", ErrPluginUntrusted)
+	}
+	if len(tp.publicKeys) == 0 {
+		return fmt.Errorf("%w: 未配置受信任的公钥", ErrPluginUntrusted)
+	}
+	for _, key := range tp.publicKeys {
+		if ed25519.Verify(key, digest[:], signature) {
+			return nil
+		}
+	}
+	return ErrPluginUntrusted
+}
+
+// SignPlugin 为插件编译产物生成摘要文件和签名文件
+func SignPlugin(pluginPath string, privateKey ed25519.PrivateKey) error {
+	data, err := os.ReadFile(pluginPath)
+	if err != nil {
+		return fmt.Errorf("读取插件失败: %w", err)
+	}
+	digest := sha256.Sum256(data)
+
+	if err := os.WriteFile(pluginPath+DigestSuffix, []byte(hex.EncodeToString(digest[:])+"\n"), 0644); err != nil {
+		return fmt.Errorf("写入摘要文件失败: %w", err)
+	}
+	signature := ed25519.Sign(privateKey, digest[:])
+	if err := os.WriteFile(pluginPath+SignatureSuffix, []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0644); err != nil {
+		return fmt.Errorf("写入签名文件失败: %w", err)
+	}
+	return nil
+}
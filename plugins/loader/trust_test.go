@@ -0,0 +1,161 @@
+package loader
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"weave/pkg"
+)
+
+// newSignedArtifact 写入一个插件产物并用新生成的密钥签名，返回产物路径和公钥
+func newSignedArtifact(t *testing.T) (string, ed25519.PublicKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "signed.so")
+	if err := os.WriteFile(path, []byte("plugin binary"), 0644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+	if err := SignPlugin(path, privateKey); err != nil {
+		t.Fatalf("sign plugin: %v", err)
+	}
+	return path, publicKey
+}
+
+func TestTrustPolicyVerify(t *testing.T) {
+	path, publicKey := newSignedArtifact(t)
+	encoded := base64.StdEncoding.EncodeToString(publicKey)
+
+	policy, err := NewTrustPolicy([]string{encoded}, false)
+	if err != nil {
+		t.Fatalf("new trust policy: %v", err)
+	}
+	if err := policy.Verify(path); err != nil {
+		t.Fatalf("expected signed artifact trusted, got %v", err)
+	}
+
+	// 其他公钥签发的签名不受信任
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	untrusted, _ := NewTrustPolicy([]string{base64.StdEncoding.EncodeToString(otherKey)}, false)
+	if err := untrusted.Verify(path); !errors.Is(err, ErrPluginUntrusted) {
+		t.Fatalf("expected untrusted error, got %v", err)
+	}
+	if err := (&TrustPolicy{}).Verify(path); !errors.Is(err, ErrPluginUntrusted) {
+		t.Fatalf("expected untrusted error without configured keys, got %v", err)
+	}
+
+	// 篡改产物
+	if err := os.WriteFile(path, []byte("tampered binary"), 0644); err != nil {
+		t.Fatalf("tamper artifact: %v", err)
+	}
+	if err := policy.Verify(path); !errors.Is(err, ErrPluginTampered) {
+		t.Fatalf("expected tampered error, got %v", err)
+	}
+
+	// 缺少签名
+	if err := os.Remove(path + SignatureSuffix); err != nil {
+		t.Fatalf("remove signature: %v", err)
+	}
+	if err := os.Remove(path + DigestSuffix); err != nil {
+		t.Fatalf("remove digest: %v", err)
+	}
+	if err := policy.Verify(path); !errors.Is(err, ErrPluginUnsigned) {
+		t.Fatalf("expected unsigned error, got %v", err)
+	}
+
+	// 开发模式不做校验
+	if err := (&TrustPolicy{devMode: true}).Verify(path); err != nil {
+		t.Fatalf("expected dev mode to skip verification, got %v", err)
+	}
+}
+
+func TestNewTrustPolicyRejectsInvalidKey(t *testing.T) {
+	for _, key := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewTrustPolicy([]string{key}, false); err == nil {
+			t.Fatalf("expected invalid key %q rejected", key)
+		}
+	}
+}
+
+func TestLoadPluginRefusesUntrustedArtifact(t *testing.T) {
+	pl := NewPluginLoader(pkg.GetLogger())
+	pl.SetTrustPolicy(&TrustPolicy{})
+
+	path := filepath.Join(t.TempDir(), "unsigned.so")
+	if err := os.WriteFile(path, []byte("plugin binary"), 0644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+	_, err := pl.LoadPlugin(path, "unsigned")
+	if !errors.Is(err, ErrPluginUnsigned) {
+		t.Fatalf("expected unsigned plugin refused, got %v", err)
+	}
+	if pl.GetLoadedPlugin("unsigned") {
+		t.Fatalf("expected refused plugin not loaded")
+	}
+
+	// 签名有效的产物通过校验后才会被打开
+	signed, publicKey := newSignedArtifact(t)
+	pl.SetTrustPolicy(&TrustPolicy{publicKeys: []ed25519.PublicKey{publicKey}})
+	_, err = pl.LoadPlugin(signed, "signed")
+	if err == nil || strings.Contains(err.Error(), "签名校验失败") {
+		t.Fatalf("expected trusted artifact to pass verification and fail opening, got %v", err)
+	}
+}
+
+func TestPrivateCopyIsolatesVerifiedBytes(t *testing.T) {
+	pl := NewPluginLoader(pkg.GetLogger())
+	signed, _ := newSignedArtifact(t)
+	data, err := os.ReadFile(signed)
+	if err != nil {
+		t.Fatalf("read artifact: %v", err)
+	}
+
+	privatePath, err := pl.privateCopyLocked(signed, data)
+	if err != nil {
+		t.Fatalf("private copy: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(pl.privateDir) })
+	if filepath.Dir(privatePath) != pl.privateDir {
+		t.Fatalf("expected copy in private dir %s, got %s", pl.privateDir, privatePath)
+	}
+	info, err := os.Stat(pl.privateDir)
+	if err != nil {
+		t.Fatalf("stat private dir: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Fatalf("expected private dir mode 0700, got %o", perm)
+	}
+
+	// 校验后替换原文件不影响将要打开的副本
+	if err := os.WriteFile(signed, []byte("swapped binary"), 0644); err != nil {
+		t.Fatalf("swap artifact: %v", err)
+	}
+	copied, err := os.ReadFile(privatePath)
+	if err != nil {
+		t.Fatalf("read private copy: %v", err)
+	}
+	if string(copied) != string(data) {
+		t.Fatalf("expected private copy to keep verified bytes, got %q", copied)
+	}
+
+	// 相同内容复用同一个副本
+	again, err := pl.privateCopyLocked(signed, data)
+	if err != nil || again != privatePath {
+		t.Fatalf("expected same private copy, got %s, %v", again, err)
+	}
+
+	// 副本被改动时拒绝打开
+	if err := os.WriteFile(privatePath, []byte("tampered copy"), 0600); err != nil {
+		t.Fatalf("tamper private copy: %v", err)
+	}
+	if _, err := pl.privateCopyLocked(signed, data); !errors.Is(err, ErrPluginTampered) {
+		t.Fatalf("expected tampered error, got %v", err)
+	}
+}
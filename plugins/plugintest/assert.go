@@ -0,0 +1,147 @@
+package plugintest
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"weave/pkg/metrics"
+	"weave/plugins/core"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// 指标是进程级全局的，注册插件时记录插件相关计数器的当前值，断言时只比较增量，
+// 同一进程中的其他测试不会影响断言结果
+var trackedCounters = map[string]trackedCounter{
+	"execution":    {metrics.PluginExecutionCount, []string{"plugin_name", "success"}},
+	"method_calls": {metrics.PluginMethodCalls, []string{"plugin_name", "method", "success"}},
+}
+
+// trackedCounter 计数器及其声明的标签顺序
+type trackedCounter struct {
+	vec    *prometheus.CounterVec
+	labels []string
+}
+
+// CounterValue 返回计数器在给定标签下的当前值
+func CounterValue(vec *prometheus.CounterVec, labels ...string) float64 {
+	counter, err := vec.GetMetricWithLabelValues(labels...)
+	if err != nil {
+		return 0
+	}
+	var m dto.Metric
+	if err := counter.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
+// recordBaselines 记录插件相关计数器的当前值
+func (h *Harness) recordBaselines(name string) {
+	h.baselineMu.Lock()
+	defer h.baselineMu.Unlock()
+
+	for vecName, tracked := range trackedCounters {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			tracked.vec.Collect(ch)
+			close(ch)
+		}()
+		for metric := range ch {
+			var m dto.Metric
+			if err := metric.Write(&m); err != nil {
+				continue
+			}
+			// 导出的标签按名称排序，按声明顺序重新排列后作为基线的键
+			byName := make(map[string]string, len(m.GetLabel()))
+			for _, label := range m.GetLabel() {
+				byName[label.GetName()] = label.GetValue()
+			}
+			if byName["plugin_name"] != name {
+				continue
+			}
+			values := make([]string, 0, len(tracked.labels))
+			for _, label := range tracked.labels {
+				values = append(values, byName[label])
+			}
+			h.baselines[baselineKey(vecName, values)] = m.GetCounter().GetValue()
+		}
+	}
+}
+
+// counterDelta 返回计数器自插件注册以来的增量
+func (h *Harness) counterDelta(vecName string, labels ...string) float64 {
+	h.baselineMu.Lock()
+	defer h.baselineMu.Unlock()
+	return CounterValue(trackedCounters[vecName].vec, labels...) - h.baselines[baselineKey(vecName, labels)]
+}
+
+// baselineKey 基线的键，values按计数器声明的标签顺序排列
+func baselineKey(vecName string, values []string) string {
+	return vecName + "|" + strings.Join(values, "|")
+}
+
+// Executions 返回插件注册以来记录的执行次数（plugin_execution_total，包括启用、禁用和重新加载）
+func (h *Harness) Executions(name string, success bool) int {
+	return int(h.counterDelta("execution", name, strconv.FormatBool(success)))
+}
+
+// MethodCalls 返回插件注册以来指定方法的调用次数（plugin_method_calls_total），如Execute、OnEnable
+func (h *Harness) MethodCalls(name, method string, success bool) int {
+	return int(h.counterDelta("method_calls", name, method, strconv.FormatBool(success)))
+}
+
+// AssertMethodCalls 断言插件注册以来指定方法成功和失败的调用次数
+func (h *Harness) AssertMethodCalls(name, method string, succeeded, failed int) {
+	h.t.Helper()
+	if got := h.MethodCalls(name, method, true); got != succeeded {
+		h.t.Errorf("插件 '%s' 的 %s 成功调用次数为 %d，期望 %d", name, method, got, succeeded)
+	}
+	if got := h.MethodCalls(name, method, false); got != failed {
+		h.t.Errorf("插件 '%s' 的 %s 失败调用次数为 %d，期望 %d", name, method, got, failed)
+	}
+}
+
+// AssertState 断言插件的当前生命周期状态
+func (h *Harness) AssertState(name string, want core.PluginState) {
+	h.t.Helper()
+	state, exists := h.Manager.GetPluginState(name)
+	if !exists {
+		h.t.Errorf("插件 '%s' 没有生命周期记录", name)
+		return
+	}
+	if state != want {
+		h.t.Errorf("插件 '%s' 的状态为 %s，期望 %s", name, state, want)
+	}
+}
+
+// AssertTransitions 断言插件依次经历的状态（各次转换的目标状态），
+// 失败后回滚的转换（目标状态与原状态相同）同样计入
+func (h *Harness) AssertTransitions(name string, want ...core.PluginState) {
+	h.t.Helper()
+	history, _ := h.Manager.GetPluginHistory(name)
+	got := make([]core.PluginState, 0, len(history))
+	for _, transition := range history {
+		got = append(got, transition.To)
+	}
+	if len(got) != len(want) {
+		h.t.Errorf("插件 '%s' 的状态转换为 %v，期望 %v", name, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			h.t.Errorf("插件 '%s' 的状态转换为 %v，期望 %v", name, got, want)
+			return
+		}
+	}
+}
+
+// AssertStatus 断言响应状态码
+func (h *Harness) AssertStatus(w *httptest.ResponseRecorder, want int) {
+	h.t.Helper()
+	if w.Code != want {
+		h.t.Errorf("响应状态码为 %d，期望 %d，响应体: %s", w.Code, want, w.Body.String())
+	}
+}
@@ -0,0 +1,34 @@
+package plugintest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"weave/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// dbCounter 为每个测试环境生成独立的内存数据库名称
+var dbCounter atomic.Int64
+
+// openDB 创建内存SQLite数据库并迁移全部表
+// 使用具名的共享缓存数据库，同一测试环境内的多个连接访问同一个数据库
+func openDB(t testing.TB) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("file:plugintest_%d?mode=memory&cache=shared", dbCounter.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := models.MigrateTables(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
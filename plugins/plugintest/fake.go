@@ -0,0 +1,75 @@
+package plugintest
+
+import (
+	"fmt"
+
+	"weave/plugins/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FakePlugin 替身插件，用于满足被测插件的依赖，或直接作为被测插件测试管理器的行为
+// 零值字段使用默认行为：版本为1.0.0，没有路由，Execute返回nil
+type FakePlugin struct {
+	PluginName    string
+	PluginVersion string
+	Dependencies  []string
+	Conflicts     []string
+	Service       interface{} // 初始化时通过Provide以插件名发布的服务，为空时不发布
+	Routes        []core.Route
+	ExecuteFunc   func(params map[string]interface{}) (interface{}, error)
+	InitErr       error // 不为空时Init返回该错误
+
+	manager *core.PluginManager
+}
+
+// Fake 创建发布service服务的替身插件，被测插件可以通过core.Lookup获取该服务
+func Fake(name string, service interface{}) *FakePlugin {
+	return &FakePlugin{PluginName: name, Service: service}
+}
+
+func (p *FakePlugin) Name() string { return p.PluginName }
+
+func (p *FakePlugin) Description() string { return fmt.Sprintf("%s 替身插件", p.PluginName) }
+
+func (p *FakePlugin) Version() string {
+	if p.PluginVersion == "" {
+		return "1.0.0"
+	}
+	return p.PluginVersion
+}
+
+func (p *FakePlugin) GetDependencies() []string { return p.Dependencies }
+
+func (p *FakePlugin) GetConflicts() []string { return p.Conflicts }
+
+func (p *FakePlugin) Init() error {
+	if p.InitErr != nil {
+		return p.InitErr
+	}
+	if p.Service != nil && p.manager != nil {
+		return p.manager.Provide(p, p.Service)
+	}
+	return nil
+}
+
+func (p *FakePlugin) Shutdown() error { return nil }
+
+func (p *FakePlugin) OnEnable() error { return nil }
+
+func (p *FakePlugin) OnDisable() error { return nil }
+
+func (p *FakePlugin) GetRoutes() []core.Route { return p.Routes }
+
+func (p *FakePlugin) RegisterRoutes(router *gin.Engine) {}
+
+func (p *FakePlugin) Execute(params map[string]interface{}) (interface{}, error) {
+	if p.ExecuteFunc != nil {
+		return p.ExecuteFunc(params)
+	}
+	return nil, nil
+}
+
+func (p *FakePlugin) GetDefaultMiddlewares() []gin.HandlerFunc { return nil }
+
+func (p *FakePlugin) SetPluginManager(manager *core.PluginManager) { p.manager = manager }
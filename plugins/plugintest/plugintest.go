@@ -0,0 +1,272 @@
+// Package plugintest 提供插件单元测试使用的隔离环境
+//
+// Harness创建独立的插件管理器、httptest使用的gin路由引擎和内存SQLite数据库（替换pkg.DB），
+// 请求默认以测试用户的身份发送，插件可以像在服务中一样通过路由、Execute、服务、事件和存储工作：
+//
+//	h := plugintest.New(t, plugintest.WithUser(1, 10))
+//	h.MustRegister(myplugin.New(), plugintest.Fake("note", fakeNoteStore))
+//	w := h.Do("GET", "/plugins/myplugin/items", nil)
+//	h.AssertStatus(w, http.StatusOK)
+//
+// Harness会修改pkg.DB和config.Config.JWT等全局状态（测试结束后恢复），使用Harness的测试不能并行执行
+package plugintest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"weave/config"
+	"weave/pkg"
+	"weave/plugins/core"
+	"weave/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 测试环境的默认值
+const (
+	DefaultUserID   uint = 1
+	DefaultTenantID uint = 1
+	jwtSecret            = "plugintest-secret"
+)
+
+// Harness 插件测试环境
+type Harness struct {
+	// Manager 测试专用的插件管理器
+	Manager *core.PluginManager
+	// Router 已挂载插件路由的gin引擎
+	Router *gin.Engine
+	// DB 内存SQLite数据库，已执行models.MigrateTables，同时设置为pkg.DB
+	DB *gorm.DB
+	// UserID和TenantID 请求和Execute默认使用的用户和租户
+	UserID   uint
+	TenantID uint
+
+	t     testing.TB
+	roles *roleResolver
+
+	baselineMu sync.Mutex
+	baselines  map[string]float64 // 注册插件时的指标值，断言时计算增量
+}
+
+// Option 测试环境选项
+type Option func(*Harness)
+
+// WithUser 设置请求和Execute默认使用的用户和租户
+func WithUser(userID, tenantID uint) Option {
+	return func(h *Harness) {
+		h.UserID = userID
+		h.TenantID = tenantID
+	}
+}
+
+// WithRoles 设置默认用户的角色，用于测试声明了Roles或Permissions的路由
+func WithRoles(roles ...string) Option {
+	return func(h *Harness) {
+		h.roles.set(h.UserID, roles)
+	}
+}
+
+// New 创建测试环境，测试结束时关闭全部插件并恢复全局状态
+func New(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := &Harness{
+		Manager:   core.NewPluginManager(),
+		Router:    gin.New(),
+		UserID:    DefaultUserID,
+		TenantID:  DefaultTenantID,
+		t:         t,
+		roles:     &roleResolver{roles: make(map[uint][]string)},
+		baselines: make(map[string]float64),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	// 请求使用真实的认证中间件，令牌以测试密钥签发
+	previousJWT := config.Config.JWT
+	config.Config.JWT.Secret = jwtSecret
+	config.Config.JWT.AccessTokenExpiry = 60
+
+	previousDB := pkg.DB
+	db, err := openDB(t)
+	if err != nil {
+		t.Fatalf("plugintest: 创建测试数据库失败: %v", err)
+	}
+	h.DB = db
+	pkg.DB = db
+
+	h.Manager.SetRoleResolver(h.roles)
+	h.Manager.SetRouter(h.Router)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := h.Manager.ShutdownAll(ctx); err != nil {
+			t.Errorf("plugintest: 关闭插件失败: %v", err)
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		pkg.DB = previousDB
+		config.Config.JWT = previousJWT
+	})
+	return h
+}
+
+// Register 注册插件，fakes中的替身插件先于插件注册，用于满足插件的依赖
+func (h *Harness) Register(plugin core.Plugin, fakes ...*FakePlugin) error {
+	for _, fake := range fakes {
+		if _, exists := h.Manager.GetPlugin(fake.Name()); exists {
+			continue
+		}
+		if err := h.Manager.Register(fake); err != nil {
+			return err
+		}
+	}
+	h.recordBaselines(plugin.Name())
+	return h.Manager.Register(plugin)
+}
+
+// MustRegister 注册插件，失败时终止测试
+func (h *Harness) MustRegister(plugin core.Plugin, fakes ...*FakePlugin) {
+	h.t.Helper()
+	if err := h.Register(plugin, fakes...); err != nil {
+		h.t.Fatalf("plugintest: 注册插件 '%s' 失败: %v", plugin.Name(), err)
+	}
+}
+
+// SetRoles 设置用户的角色
+func (h *Harness) SetRoles(userID uint, roles ...string) {
+	h.roles.set(userID, roles)
+}
+
+// Context 返回携带默认用户请求元数据的上下文
+func (h *Harness) Context() context.Context {
+	return core.WithRequestMeta(context.Background(), core.RequestMeta{
+		UserID:    h.UserID,
+		TenantID:  h.TenantID,
+		RequestID: "plugintest",
+	})
+}
+
+// Execute 以默认用户的身份调用插件的Execute，经过与服务中相同的超时、熔断和并发限制
+func (h *Harness) Execute(name string, params map[string]interface{}) (interface{}, error) {
+	return h.Manager.ExecutePluginContext(h.Context(), name, params)
+}
+
+// RequestOption 请求选项
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	anonymous bool
+	userID    uint
+	tenantID  uint
+	headers   map[string]string
+}
+
+// Anonymous 发送不带访问令牌的请求
+func Anonymous() RequestOption {
+	return func(o *requestOptions) { o.anonymous = true }
+}
+
+// AsUser 以指定用户和租户的身份发送请求
+func AsUser(userID, tenantID uint) RequestOption {
+	return func(o *requestOptions) {
+		o.userID = userID
+		o.tenantID = tenantID
+	}
+}
+
+// WithHeader 设置请求头
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) { o.headers[key] = value }
+}
+
+// Do 发送请求并返回响应
+// body为nil时不带请求体，为string或[]byte时原样发送，其他值编码为JSON
+func (h *Harness) Do(method, path string, body interface{}, opts ...RequestOption) *httptest.ResponseRecorder {
+	h.t.Helper()
+	options := &requestOptions{userID: h.UserID, tenantID: h.TenantID, headers: make(map[string]string)}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	case []byte:
+		reader = bytes.NewBuffer(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("plugintest: 编码请求体失败: %v", err)
+		}
+		reader = bytes.NewBuffer(data)
+		contentType = "application/json"
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if !options.anonymous {
+		req.Header.Set("Authorization", "Bearer "+h.Token(options.userID, options.tenantID))
+	}
+	for key, value := range options.headers {
+		req.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, req)
+	return w
+}
+
+// Token 签发用户的访问令牌
+func (h *Harness) Token(userID, tenantID uint) string {
+	h.t.Helper()
+	token, err := utils.GenerateToken(userID, tenantID)
+	if err != nil {
+		h.t.Fatalf("plugintest: 签发访问令牌失败: %v", err)
+	}
+	return token
+}
+
+// DecodeJSON 将响应体解码到v，失败时终止测试
+func (h *Harness) DecodeJSON(w *httptest.ResponseRecorder, v interface{}) {
+	h.t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		h.t.Fatalf("plugintest: 解码响应失败: %v，响应体: %s", err, w.Body.String())
+	}
+}
+
+// roleResolver 按用户返回测试设置的角色
+type roleResolver struct {
+	mu    sync.RWMutex
+	roles map[uint][]string
+}
+
+func (r *roleResolver) set(userID uint, roles []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles[userID] = roles
+}
+
+// ResolveRoles 实现core.RoleResolver
+func (r *roleResolver) ResolveRoles(ctx context.Context, userID, tenantID uint) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.roles[userID], nil
+}
@@ -0,0 +1,132 @@
+package plugintest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"weave/models"
+	"weave/pkg"
+	"weave/plugins/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// greeter 被测插件依赖的服务
+type greeter interface {
+	Greet(name string) string
+}
+
+type fakeGreeter struct{}
+
+func (fakeGreeter) Greet(name string) string { return "hello " + name }
+
+// newHelloPlugin 依赖greeter插件服务的被测插件
+func newHelloPlugin() *FakePlugin {
+	plugin := &FakePlugin{PluginName: "hello", Dependencies: []string{"greeter"}}
+	plugin.Routes = []core.Route{
+		{
+			Path:         "/whoami",
+			Method:       "GET",
+			AuthRequired: true,
+			Handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id"), "tenant_id": c.GetUint("tenant_id")})
+			},
+		},
+		{
+			Path:   "/admin",
+			Method: "GET",
+			Roles:  []string{"admin"},
+			Handler: func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			},
+		},
+	}
+	plugin.ExecuteFunc = func(params map[string]interface{}) (interface{}, error) {
+		name, _ := params["name"].(string)
+		if name == "" {
+			return nil, errors.New("缺少name参数")
+		}
+		g, err := core.Lookup[greeter](plugin.manager.ScopeFor(plugin), "greeter")
+		if err != nil {
+			return nil, err
+		}
+		return g.Greet(name), nil
+	}
+	return plugin
+}
+
+func TestHarnessRoutesAndAuth(t *testing.T) {
+	h := New(t, WithUser(7, 3))
+	h.MustRegister(newHelloPlugin(), Fake("greeter", fakeGreeter{}))
+
+	w := h.Do("GET", "/plugins/hello/whoami", nil)
+	h.AssertStatus(w, http.StatusOK)
+	var body struct {
+		UserID   uint `json:"user_id"`
+		TenantID uint `json:"tenant_id"`
+	}
+	h.DecodeJSON(w, &body)
+	if body.UserID != 7 || body.TenantID != 3 {
+		t.Errorf("认证信息为 %+v，期望用户7租户3", body)
+	}
+
+	h.AssertStatus(h.Do("GET", "/plugins/hello/whoami", nil, Anonymous()), http.StatusUnauthorized)
+
+	h.AssertStatus(h.Do("GET", "/plugins/hello/admin", nil), http.StatusForbidden)
+	h.SetRoles(7, "admin")
+	h.AssertStatus(h.Do("GET", "/plugins/hello/admin", nil), http.StatusNoContent)
+}
+
+func TestHarnessExecuteAndMetrics(t *testing.T) {
+	h := New(t)
+	h.MustRegister(newHelloPlugin(), Fake("greeter", fakeGreeter{}))
+
+	result, err := h.Execute("hello", map[string]interface{}{"name": "weave"})
+	if err != nil {
+		t.Fatalf("执行插件失败: %v", err)
+	}
+	if result != "hello weave" {
+		t.Errorf("执行结果为 %v，期望 hello weave", result)
+	}
+	if _, err := h.Execute("hello", nil); err == nil {
+		t.Error("缺少参数时应返回错误")
+	}
+	h.AssertMethodCalls("hello", "Execute", 1, 1)
+}
+
+func TestHarnessLifecycle(t *testing.T) {
+	h := New(t)
+	h.MustRegister(newHelloPlugin(), Fake("greeter", fakeGreeter{}))
+	h.AssertState("hello", core.StateEnabled)
+
+	if err := h.Manager.DisablePlugin("hello"); err != nil {
+		t.Fatalf("禁用插件失败: %v", err)
+	}
+	h.AssertTransitions("hello",
+		core.StateRegistered, core.StateInitializing, core.StateReady, core.StateEnabled,
+		core.StateDisabling, core.StateDisabled)
+	h.AssertMethodCalls("hello", "OnDisable", 1, 0)
+
+	broken := &FakePlugin{PluginName: "broken", InitErr: errors.New("初始化失败")}
+	if err := h.Register(broken); err == nil {
+		t.Fatal("初始化失败的插件不应注册成功")
+	}
+	h.AssertState("broken", core.StateFailed)
+}
+
+func TestHarnessDatabase(t *testing.T) {
+	h := New(t)
+	if pkg.DB != h.DB {
+		t.Fatal("pkg.DB 应指向测试数据库")
+	}
+	note := models.Note{UserID: h.UserID, TenantID: h.TenantID, Title: "plugintest", Content: "内存数据库"}
+	if err := h.DB.Create(&note).Error; err != nil {
+		t.Fatalf("写入测试数据库失败: %v", err)
+	}
+	var count int64
+	h.DB.Model(&models.Note{}).Where("user_id = ?", h.UserID).Count(&count)
+	if count != 1 {
+		t.Errorf("笔记数量为 %d，期望 1", count)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"weave/config"
+	"weave/pkg"
+	"weave/plugins/registry"
+)
+
+const usage = "用法: registry [search|list|install|upgrade|rollback|uninstall] [参数]"
+
+func main() {
+	// 初始化配置
+	if err := config.LoadConfig(); err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 初始化日志
+	if err := pkg.InitLogger(pkg.DefaultOptions()); err != nil {
+		log.Fatalf("初始化日志失败: %v", err)
+	}
+
+	// 解析命令行参数
+	searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	installCmd := flag.NewFlagSet("install", flag.ExitOnError)
+	upgradeCmd := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	rollbackCmd := flag.NewFlagSet("rollback", flag.ExitOnError)
+	uninstallCmd := flag.NewFlagSet("uninstall", flag.ExitOnError)
+
+	installDryRun := installCmd.Bool("dry-run", false, "只打印安装计划，不执行安装")
+	upgradeDryRun := upgradeCmd.Bool("dry-run", false, "只打印升级计划，不执行升级")
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	// 命令行工具不连接运行中的服务，安装的插件在服务下次启动时加载
+	installer := registry.NewInstaller(registry.ConfigFromConfig(), nil, pkg.GetLogger())
+
+	// 处理子命令
+	switch os.Args[1] {
+	case "search":
+		searchCmd.Parse(os.Args[2:])
+		results, err := installer.Search(strings.Join(searchCmd.Args(), " "))
+		if err != nil {
+			log.Fatalf("搜索插件仓库失败: %v", err)
+		}
+		for _, result := range results {
+			installed := ""
+			if result.Installed != "" {
+				installed = fmt.Sprintf(" (已安装 %s)", result.Installed)
+			}
+			fmt.Printf("%s %s%s\n    %s\n", result.Name, result.Latest(), installed, result.Description)
+		}
+
+	case "list":
+		listCmd.Parse(os.Args[2:])
+		installed, err := installer.Installed()
+		if err != nil {
+			log.Fatalf("获取已安装插件列表失败: %v", err)
+		}
+		for _, plugin := range installed {
+			fmt.Printf("%s %s", plugin.Name, plugin.Version)
+			if len(plugin.Previous) > 0 {
+				fmt.Printf(" (可回滚到 %s)", plugin.Previous[len(plugin.Previous)-1])
+			}
+			fmt.Println()
+		}
+
+	case "install":
+		installCmd.Parse(os.Args[2:])
+		name, constraint := requireSpec(installCmd)
+		if *installDryRun {
+			steps, err := installer.PlanInstall(name, constraint)
+			if err != nil {
+				log.Fatalf("生成安装计划失败: %v", err)
+			}
+			printSteps(steps)
+			return
+		}
+		result, err := installer.Install(name, constraint)
+		if err != nil {
+			log.Fatalf("安装插件失败: %v", err)
+		}
+		printSteps(result.Steps)
+		fmt.Println("插件安装成功，重启服务后加载")
+
+	case "upgrade":
+		upgradeCmd.Parse(os.Args[2:])
+		name, constraint := requireSpec(upgradeCmd)
+		if *upgradeDryRun {
+			steps, err := installer.PlanUpgrade(name, constraint)
+			if err != nil {
+				log.Fatalf("生成升级计划失败: %v", err)
+			}
+			printSteps(steps)
+			return
+		}
+		result, err := installer.Upgrade(name, constraint)
+		if err != nil {
+			log.Fatalf("升级插件失败: %v", err)
+		}
+		if len(result.Steps) == 0 {
+			fmt.Println("插件已是最新版本")
+			return
+		}
+		printSteps(result.Steps)
+		fmt.Println("插件升级成功，重启服务后加载")
+
+	case "rollback":
+		rollbackCmd.Parse(os.Args[2:])
+		name, _ := requireSpec(rollbackCmd)
+		result, err := installer.Rollback(name)
+		if err != nil {
+			log.Fatalf("回滚插件失败: %v", err)
+		}
+		printSteps(result.Steps)
+		fmt.Println("插件回滚成功，重启服务后加载")
+
+	case "uninstall":
+		uninstallCmd.Parse(os.Args[2:])
+		name, _ := requireSpec(uninstallCmd)
+		if _, err := installer.Uninstall(name); err != nil {
+			log.Fatalf("卸载插件失败: %v", err)
+		}
+		fmt.Println("插件卸载成功")
+
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+}
+
+// requireSpec 读取子命令的"插件名[@版本约束]"参数
+func requireSpec(cmd *flag.FlagSet) (string, string) {
+	if cmd.NArg() != 1 {
+		fmt.Printf("用法: registry %s [选项] <插件名[@版本约束]>\n", cmd.Name())
+		cmd.PrintDefaults()
+		os.Exit(1)
+	}
+	return registry.ParseSpec(cmd.Arg(0))
+}
+
+// printSteps 打印安装操作的步骤
+func printSteps(steps []registry.Step) {
+	for _, step := range steps {
+		version := step.To
+		if step.From != "" {
+			version = step.From + " -> " + step.To
+		}
+		fmt.Printf("%-9s %s %s (%s)\n", step.Action, step.Plugin, version, step.Reason)
+	}
+}
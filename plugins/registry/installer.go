@@ -0,0 +1,943 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"weave/config"
+	"weave/pkg"
+	"weave/plugins/core"
+	"weave/plugins/loader"
+
+	"go.uber.org/zap"
+)
+
+// 安装操作中各插件的动作
+const (
+	ActionInstall   = "install"
+	ActionUpgrade   = "upgrade"
+	ActionRollback  = "rollback"
+	ActionUninstall = "uninstall"
+)
+
+// stateFileName 安装目录中记录已安装插件的文件名
+const stateFileName = "installed.json"
+
+// Config 插件仓库和安装目录的配置
+type Config struct {
+	URL        string        // 仓库地址：本地目录或HTTP(S)地址，为空时不能搜索、安装和升级插件
+	InstallDir string        // 已安装插件的目录，每个版本位于<InstallDir>/<插件名>/<版本>/
+	Timeout    time.Duration // 从HTTP仓库下载单个文件的超时时间
+	Keep       int           // 每个插件保留的已安装版本数（包括当前版本），大于1时才能回滚
+}
+
+// ConfigFromConfig 根据plugins.registry配置创建插件仓库配置
+func ConfigFromConfig() Config {
+	registry := config.Config.Plugins.Registry
+	return Config{
+		URL:        registry.URL,
+		InstallDir: registry.InstallDir,
+		Timeout:    time.Duration(registry.Timeout) * time.Second,
+		Keep:       registry.Keep,
+	}
+}
+
+// Loader 加载已安装插件的编译产物，由loader.PluginLoader实现
+type Loader interface {
+	LoadPluginFromManifest(manifestPath string) (core.Plugin, *core.PluginManifest, error)
+	UnloadPlugin(pluginName string) error
+}
+
+// InstalledPlugin 通过插件仓库安装的插件
+type InstalledPlugin struct {
+	Name         string    `json:"name"`
+	Version      string    `json:"version"`                // 当前使用的版本
+	Previous     []string  `json:"previous,omitempty"`     // 保留的早先版本，最近的在最后，回滚时使用
+	Dependencies []string  `json:"dependencies,omitempty"` // 当前版本清单中的依赖声明
+	AsDependency bool      `json:"as_dependency"`          // 是否作为其他插件的依赖自动安装
+	InstalledAt  time.Time `json:"installed_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SearchResult 仓库搜索结果
+type SearchResult struct {
+	IndexEntry
+	Installed string `json:"installed,omitempty"` // 已安装或已注册的版本，未安装时为空
+}
+
+// Step 安装操作中的一步，按执行顺序排列
+type Step struct {
+	Plugin string `json:"plugin"`
+	Action string `json:"action"` // install、upgrade、rollback 或 uninstall
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Result 安装操作的结果
+type Result struct {
+	Steps    []Step                  `json:"steps"`
+	Reloaded []core.ReloadStepResult `json:"reloaded,omitempty"` // 升级或回滚后按依赖顺序重新加载的依赖方
+}
+
+// installPlan 安装或升级的执行计划
+type installPlan struct {
+	steps    []Step
+	versions map[string]IndexVersion // 插件名 -> 需要下载的版本
+}
+
+// Installer 从插件仓库安装、升级、回滚和卸载插件，并记录已安装的插件
+// 版本选择使用插件管理器的依赖解析：同时满足新插件之间以及已注册插件提出的版本约束，依赖先于依赖方安装。
+// manager为空时（如命令行工具）只管理安装目录，已安装的插件在服务下次启动时通过LoadInstalled加载
+type Installer struct {
+	registry *Registry // 未配置仓库地址时为空
+	dir      string
+	keep     int
+	manager  *core.PluginManager
+	loader   Loader
+	trust    *loader.TrustPolicy
+	logger   *pkg.Logger
+	mu       sync.Mutex // 串行化安装操作
+}
+
+// NewInstaller 创建插件安装器，信任策略取自plugins.trust配置
+func NewInstaller(cfg Config, manager *core.PluginManager, logger *pkg.Logger) *Installer {
+	trust, err := loader.TrustPolicyFromConfig()
+	if err != nil {
+		// 公钥配置无效时不信任任何插件
+		logger.Error("插件信任策略配置无效，将拒绝安装所有插件", zap.Error(err))
+		trust = &loader.TrustPolicy{}
+	}
+	if cfg.Keep < 1 {
+		cfg.Keep = 1
+	}
+
+	installer := &Installer{
+		dir:     cfg.InstallDir,
+		keep:    cfg.Keep,
+		manager: manager,
+		trust:   trust,
+		logger:  logger,
+	}
+	if cfg.URL != "" {
+		installer.registry = New(cfg.URL, cfg.Timeout)
+	}
+	if manager != nil {
+		installer.loader = loader.NewPluginLoader(logger)
+	}
+	return installer
+}
+
+// SetLoader 设置加载已安装插件的加载器
+func (i *Installer) SetLoader(pluginLoader Loader) {
+	i.loader = pluginLoader
+}
+
+// SetTrustPolicy 设置校验下载的发布包所用的信任策略
+func (i *Installer) SetTrustPolicy(trust *loader.TrustPolicy) {
+	i.trust = trust
+}
+
+// Registry 返回插件仓库，未配置仓库地址时返回nil
+func (i *Installer) Registry() *Registry {
+	return i.registry
+}
+
+// ParseSpec 解析"插件名[@版本约束]"形式的安装参数，如"hello"、"hello@1.2.0"、"hello@^1.2"
+func ParseSpec(spec string) (name, constraint string) {
+	name, constraint, _ = strings.Cut(strings.TrimSpace(spec), "@")
+	return strings.TrimSpace(name), strings.TrimSpace(constraint)
+}
+
+// Installed 返回已安装的插件，按名称排序
+func (i *Installer) Installed() ([]InstalledPlugin, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	state, err := i.loadState()
+	if err != nil {
+		return nil, err
+	}
+	installed := make([]InstalledPlugin, 0, len(state))
+	for _, name := range sortedNames(state) {
+		installed = append(installed, *state[name])
+	}
+	return installed, nil
+}
+
+// Search 搜索仓库中名称或描述包含query的插件，并标注已安装的版本
+func (i *Installer) Search(query string) ([]SearchResult, error) {
+	if err := i.requireRegistry(); err != nil {
+		return nil, err
+	}
+	entries, err := i.registry.Search(query)
+	if err != nil {
+		return nil, pkg.NewServiceUnavailableError(err.Error(), err)
+	}
+
+	i.mu.Lock()
+	state, err := i.loadState()
+	i.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(entries))
+	for _, entry := range entries {
+		result := SearchResult{IndexEntry: entry}
+		if installed, exists := state[entry.Name]; exists {
+			result.Installed = installed.Version
+		} else if i.manager != nil {
+			if plugin, exists := i.manager.GetPlugin(entry.Name); exists {
+				result.Installed = plugin.Version()
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// PlanInstall 计算安装插件及其缺失依赖的步骤，不修改任何状态
+func (i *Installer) PlanInstall(name, constraint string) ([]Step, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	plan, err := i.plan(name, constraint, false)
+	if err != nil {
+		return nil, err
+	}
+	return plan.steps, nil
+}
+
+// Install 安装插件的最高可用版本（constraint为空时）或满足约束的最高版本，缺失的依赖一并安装
+// 全部发布包先下载并通过签名校验，再按依赖顺序加载和注册；任一插件失败时撤销本次已完成的步骤
+func (i *Installer) Install(name, constraint string) (*Result, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	plan, err := i.plan(name, constraint, false)
+	if err != nil {
+		return nil, err
+	}
+	return i.apply(name, plan)
+}
+
+// PlanUpgrade 计算升级插件的步骤，不修改任何状态，已是最新版本时返回空列表
+func (i *Installer) PlanUpgrade(name, constraint string) ([]Step, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	plan, err := i.plan(name, constraint, true)
+	if err != nil {
+		return nil, err
+	}
+	return plan.steps, nil
+}
+
+// Upgrade 将已安装的插件升级到满足约束的最高版本，新版本需要的缺失依赖一并安装
+// 已注册插件对该插件的版本约束同样需要满足；升级后依赖该插件的插件按依赖顺序重新加载
+func (i *Installer) Upgrade(name, constraint string) (*Result, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	plan, err := i.plan(name, constraint, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.steps) == 0 {
+		return &Result{Steps: []Step{}}, nil
+	}
+	return i.apply(name, plan)
+}
+
+// Rollback 将插件切换回上一个保留的版本，回滚前的版本被删除
+func (i *Installer) Rollback(name string) (*Result, error) {
+	if err := requireValidName(name); err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	state, err := i.loadState()
+	if err != nil {
+		return nil, err
+	}
+	installed, exists := state[name]
+	if !exists {
+		return nil, notInstalledError(name)
+	}
+	if len(installed.Previous) == 0 {
+		return nil, pkg.NewBadRequestError(fmt.Sprintf("插件 '%s' 没有可回滚的版本", name), nil)
+	}
+
+	target := installed.Previous[len(installed.Previous)-1]
+	for _, version := range []string{installed.Version, target} {
+		if err := i.within(i.versionDir(name, version)); err != nil {
+			return nil, err
+		}
+	}
+	manifestPath := i.manifestPath(name, target)
+	manifest, err := core.LoadPluginManifest(manifestPath)
+	if err != nil {
+		return nil, pkg.NewPluginError(fmt.Sprintf("读取插件 '%s' %s 的清单失败: %v", name, target, err), err)
+	}
+	// 回滚后的版本同样需要满足依赖方的版本约束
+	if _, err := i.resolver().ResolveManifests(append(i.pinned(state, name), manifest), []string{name}); err != nil {
+		return nil, err
+	}
+
+	if i.manager != nil {
+		if err := i.replace(name, manifestPath); err != nil {
+			return nil, pkg.NewPluginError(err.Error(), err)
+		}
+	}
+
+	from := installed.Version
+	installed.Version = target
+	installed.Previous = installed.Previous[:len(installed.Previous)-1]
+	installed.Dependencies = manifest.Dependencies
+	installed.UpdatedAt = time.Now()
+	if err := i.saveState(state); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(i.versionDir(name, from)); err != nil {
+		i.logger.Warn("删除回滚前的插件版本失败", zap.String("plugin", name), zap.String("version", from), zap.Error(err))
+	}
+
+	result := &Result{Steps: []Step{{Plugin: name, Action: ActionRollback, From: from, To: target, Reason: "请求回滚"}}}
+	if i.manager != nil {
+		result.Reloaded = i.manager.ReloadPlugins(nil, []string{name}).Steps
+	}
+	i.logger.Info("插件已回滚", zap.String("plugin", name), zap.String("from", from), zap.String("to", target))
+	return result, nil
+}
+
+// Uninstall 注销并删除通过插件仓库安装的插件，仍有插件依赖它时拒绝卸载
+func (i *Installer) Uninstall(name string) (*Result, error) {
+	if err := requireValidName(name); err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	state, err := i.loadState()
+	if err != nil {
+		return nil, err
+	}
+	installed, exists := state[name]
+	if !exists {
+		return nil, notInstalledError(name)
+	}
+	if dependents := i.dependents(name, state); len(dependents) > 0 {
+		return nil, pkg.NewConflictError(fmt.Sprintf("插件 '%s' 被以下插件依赖，不能卸载: %s", name, strings.Join(dependents, ", ")), nil)
+	}
+	pluginDir := filepath.Join(i.dir, name)
+	if err := i.within(pluginDir); err != nil {
+		return nil, err
+	}
+
+	if i.registered(name) {
+		if err := i.manager.Unregister(name); err != nil {
+			return nil, pkg.NewPluginError(err.Error(), err)
+		}
+	}
+	if i.loader != nil {
+		_ = i.loader.UnloadPlugin(name)
+	}
+
+	delete(state, name)
+	if err := i.saveState(state); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(pluginDir); err != nil {
+		i.logger.Warn("删除已卸载插件的文件失败", zap.String("plugin", name), zap.Error(err))
+	}
+
+	i.logger.Info("插件已卸载", zap.String("plugin", name), zap.String("version", installed.Version))
+	return &Result{Steps: []Step{{Plugin: name, Action: ActionUninstall, From: installed.Version, Reason: "请求卸载"}}}, nil
+}
+
+// LoadInstalled 按依赖顺序加载并注册已安装的插件，需要在注册内置插件之后调用
+// 单个插件加载失败时记录日志并继续加载其他插件
+func (i *Installer) LoadInstalled() {
+	if i.manager == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	state, err := i.loadState()
+	if err != nil {
+		i.logger.Error("读取已安装的插件失败", zap.Error(err))
+		return
+	}
+	for _, name := range installOrder(state) {
+		installed := state[name]
+		if i.registered(name) {
+			i.logger.Warn("已注册同名插件，跳过加载已安装的插件", zap.String("plugin", name))
+			continue
+		}
+		if err := i.load(name, i.manifestPath(name, installed.Version)); err != nil {
+			i.logger.Error("加载已安装的插件失败", zap.String("plugin", name), zap.String("version", installed.Version), zap.Error(err))
+			continue
+		}
+		i.logger.Info("已安装的插件注册成功", zap.String("plugin", name), zap.String("version", installed.Version))
+	}
+}
+
+// plan 计算安装或升级的步骤
+// 候选版本包括目标插件满足约束的版本，以及仓库中缺失依赖（包括间接依赖）的全部版本；
+// 已注册的插件和其他已安装的插件保持当前版本，由管理器的依赖解析选出一组同时满足全部约束的最高版本
+func (i *Installer) plan(name, constraintExpr string, upgrade bool) (*installPlan, error) {
+	if err := requireValidName(name); err != nil {
+		return nil, err
+	}
+	if err := i.requireRegistry(); err != nil {
+		return nil, err
+	}
+	constraint, err := core.ParseConstraint(constraintExpr)
+	if err != nil {
+		return nil, pkg.NewBadRequestError(fmt.Sprintf("版本约束无效: %v", err), err)
+	}
+	idx, err := i.registry.Index()
+	if err != nil {
+		return nil, pkg.NewServiceUnavailableError(err.Error(), err)
+	}
+	state, err := i.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*IndexEntry, len(idx.Plugins))
+	for k := range idx.Plugins {
+		entries[idx.Plugins[k].Name] = &idx.Plugins[k]
+	}
+	target, exists := entries[name]
+	if !exists {
+		return nil, pkg.NewPluginNotFoundError(fmt.Sprintf("插件仓库中没有插件 '%s'", name), nil)
+	}
+
+	var current string
+	if upgrade {
+		installed, exists := state[name]
+		if !exists {
+			return nil, notInstalledError(name)
+		}
+		current = installed.Version
+	} else if _, exists := state[name]; exists || i.registered(name) {
+		return nil, pkg.NewConflictError(fmt.Sprintf("插件 '%s' 已安装，请使用升级", name), nil)
+	}
+
+	targetVersions := target.Match(constraint)
+	if upgrade {
+		targetVersions = newerThan(targetVersions, current)
+		if len(targetVersions) == 0 {
+			return &installPlan{}, nil
+		}
+	}
+	if len(targetVersions) == 0 {
+		return nil, pkg.NewPluginNotFoundError(fmt.Sprintf("插件仓库中没有满足 '%s' 的插件 '%s' 版本", constraintExpr, name), nil)
+	}
+
+	// 收集候选版本
+	candidates := i.pinned(state, name)
+	pinned := make(map[string]bool, len(candidates))
+	for _, manifest := range candidates {
+		pinned[manifest.Name] = true
+	}
+	versions := make(map[string]map[string]IndexVersion)
+	requiredBy := map[string]string{name: ""}
+	queue := []string{name}
+	add := func(pluginName string, list []IndexVersion) {
+		versions[pluginName] = make(map[string]IndexVersion, len(list))
+		for _, v := range list {
+			candidates = append(candidates, v.manifest(pluginName))
+			versions[pluginName][v.Version] = v
+			for _, depName := range requiredDependencies(v.Dependencies) {
+				if _, seen := requiredBy[depName]; !seen {
+					requiredBy[depName] = pluginName
+					queue = append(queue, depName)
+				}
+			}
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		switch {
+		case current == name:
+			add(current, targetVersions)
+		case pinned[current] || i.registered(current):
+			// 已注册或已安装的插件保持当前版本，由依赖解析校验版本约束
+		default:
+			entry, exists := entries[current]
+			if !exists {
+				if i.manager == nil {
+					// 未连接管理器时无法确认内置插件，假定由服务提供
+					continue
+				}
+				return nil, pkg.NewPluginDependencyError(fmt.Sprintf("插件 '%s' 依赖的插件 '%s' 未注册，插件仓库中也没有该插件", requiredBy[current], current), nil)
+			}
+			add(current, entry.Versions)
+		}
+	}
+
+	var replacing []string
+	if upgrade {
+		replacing = []string{name}
+	}
+	for {
+		resolved, err := i.resolver().ResolveManifests(candidates, replacing)
+		if err != nil {
+			return nil, err
+		}
+		chosen := make(map[string]*core.PluginManifest)
+		for _, manifest := range resolved {
+			if !pinned[manifest.Name] {
+				chosen[manifest.Name] = manifest
+			}
+		}
+
+		// 只保留目标插件实际需要的依赖；有未使用的候选时去掉后重新解析，避免其约束影响版本选择
+		order, reasons := dependencyOrder(name, chosen)
+		if len(order) < len(chosen) {
+			candidates = filterCandidates(candidates, pinned, reasons)
+			continue
+		}
+
+		plan := &installPlan{versions: make(map[string]IndexVersion, len(order))}
+		for _, pluginName := range order {
+			manifest := chosen[pluginName]
+			plan.versions[pluginName] = versions[pluginName][manifest.Version]
+			step := Step{Plugin: pluginName, Action: ActionInstall, To: manifest.Version, Reason: reasons[pluginName]}
+			if pluginName == name && upgrade {
+				step.Action = ActionUpgrade
+				step.From = current
+			}
+			plan.steps = append(plan.steps, step)
+		}
+		return plan, nil
+	}
+}
+
+// apply 下载计划中的全部发布包并按顺序加载，更新安装记录
+func (i *Installer) apply(name string, plan *installPlan) (*Result, error) {
+	// 先下载并校验全部发布包，失败时不影响正在运行的插件
+	manifests := make(map[string]*core.PluginManifest, len(plan.steps))
+	var created []string
+	cleanup := func() {
+		for _, dir := range created {
+			os.RemoveAll(dir)
+		}
+	}
+	for _, step := range plan.steps {
+		manifest, isNew, err := i.fetch(step.Plugin, plan.versions[step.Plugin])
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		if isNew {
+			created = append(created, i.versionDir(step.Plugin, step.To))
+		}
+		manifests[step.Plugin] = manifest
+	}
+
+	// 依赖先于依赖方加载，任一插件失败时按相反顺序撤销已完成的步骤
+	if i.manager != nil {
+		var undo []func()
+		for _, step := range plan.steps {
+			step := step
+			var err error
+			switch step.Action {
+			case ActionInstall:
+				if err = i.load(step.Plugin, i.manifestPath(step.Plugin, step.To)); err == nil {
+					undo = append(undo, func() { i.unload(step.Plugin) })
+				}
+			case ActionUpgrade:
+				if err = i.replace(step.Plugin, i.manifestPath(step.Plugin, step.To)); err == nil {
+					undo = append(undo, func() {
+						if err := i.replace(step.Plugin, i.manifestPath(step.Plugin, step.From)); err != nil {
+							i.logger.Error("恢复插件旧版本失败", zap.String("plugin", step.Plugin), zap.Error(err))
+						}
+					})
+				}
+			}
+			if err != nil {
+				for k := len(undo) - 1; k >= 0; k-- {
+					undo[k]()
+				}
+				cleanup()
+				return nil, pkg.NewPluginError(fmt.Sprintf("%s插件 '%s' %s 失败: %v", actionName(step.Action), step.Plugin, step.To, err), err)
+			}
+		}
+	}
+
+	// 更新安装记录
+	state, err := i.loadState()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var upgraded []string
+	for _, step := range plan.steps {
+		manifest := manifests[step.Plugin]
+		if step.Action == ActionUpgrade {
+			installed := state[step.Plugin]
+			installed.Previous = append(installed.Previous, installed.Version)
+			installed.Version = step.To
+			installed.Dependencies = manifest.Dependencies
+			installed.UpdatedAt = now
+			i.prune(installed)
+			upgraded = append(upgraded, step.Plugin)
+			continue
+		}
+		state[step.Plugin] = &InstalledPlugin{
+			Name:         step.Plugin,
+			Version:      step.To,
+			Dependencies: manifest.Dependencies,
+			AsDependency: step.Plugin != name,
+			InstalledAt:  now,
+			UpdatedAt:    now,
+		}
+	}
+	if err := i.saveState(state); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Steps: plan.steps}
+	if i.manager != nil && len(upgraded) > 0 {
+		result.Reloaded = i.manager.ReloadPlugins(nil, upgraded).Steps
+	}
+	i.logger.Info("插件安装操作完成", zap.String("plugin", name), zap.Any("steps", plan.steps))
+	return result, nil
+}
+
+// fetch 下载插件版本的发布包并校验签名，isNew表示安装目录中原本没有该版本
+func (i *Installer) fetch(name string, version IndexVersion) (manifest *core.PluginManifest, isNew bool, err error) {
+	dest := i.versionDir(name, version.Version)
+	tmp := filepath.Join(i.dir, name, ".download-"+version.Version)
+	for _, path := range []string{dest, tmp} {
+		if err := i.within(path); err != nil {
+			return nil, false, err
+		}
+	}
+	os.RemoveAll(tmp)
+
+	manifest, err = i.registry.Download(name, version, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, false, pkg.NewServiceUnavailableError(err.Error(), err)
+	}
+	if err := i.trust.Verify(filepath.Join(tmp, manifest.EntryPointOrDefault())); err != nil {
+		os.RemoveAll(tmp)
+		return nil, false, pkg.NewPluginError(fmt.Sprintf("插件 '%s' %s 未通过签名校验: %v", name, version.Version, err), err)
+	}
+
+	_, statErr := os.Stat(dest)
+	isNew = os.IsNotExist(statErr)
+	if err := os.RemoveAll(dest); err != nil {
+		os.RemoveAll(tmp)
+		return nil, false, fmt.Errorf("替换插件安装目录失败: %w", err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.RemoveAll(tmp)
+		return nil, false, fmt.Errorf("移动插件发布包失败: %w", err)
+	}
+	return manifest, isNew, nil
+}
+
+// load 加载已安装的插件版本并按清单注册
+func (i *Installer) load(name, manifestPath string) error {
+	plugin, manifest, err := i.loader.LoadPluginFromManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if err := i.manager.RegisterWithManifest(plugin, manifest); err != nil {
+		_ = i.loader.UnloadPlugin(name)
+		return err
+	}
+	return nil
+}
+
+// unload 注销并卸载插件，用于撤销安装
+func (i *Installer) unload(name string) {
+	if err := i.manager.Unregister(name); err != nil {
+		i.logger.Error("撤销安装时注销插件失败", zap.String("plugin", name), zap.Error(err))
+	}
+	_ = i.loader.UnloadPlugin(name)
+}
+
+// replace 以另一个已安装的版本替换已注册的插件，新版本注册失败时重新注册旧实例
+func (i *Installer) replace(name, manifestPath string) error {
+	plugin, manifest, err := i.loader.LoadPluginFromManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	previous, exists := i.manager.GetPluginInfo(name)
+	if exists {
+		if err := i.manager.Unregister(name); err != nil {
+			return fmt.Errorf("注销旧版本插件失败: %w", err)
+		}
+	}
+	if err := i.manager.RegisterWithManifest(plugin, manifest); err != nil {
+		if !exists {
+			return err
+		}
+		if restoreErr := i.manager.RegisterWithManifest(previous.Plugin, previous.Manifest); restoreErr != nil {
+			return fmt.Errorf("注册新版本插件失败: %w，恢复旧版本也失败: %v", err, restoreErr)
+		}
+		return fmt.Errorf("注册新版本插件失败，已恢复旧版本: %w", err)
+	}
+	return nil
+}
+
+// prune 删除超出保留数量的早先版本
+func (i *Installer) prune(installed *InstalledPlugin) {
+	for len(installed.Previous) > i.keep-1 {
+		version := installed.Previous[0]
+		if err := os.RemoveAll(i.versionDir(installed.Name, version)); err != nil {
+			i.logger.Warn("清理插件早先版本失败", zap.String("plugin", installed.Name), zap.String("version", version), zap.Error(err))
+		}
+		installed.Previous = installed.Previous[1:]
+	}
+}
+
+// dependents 返回必需依赖该插件的已注册插件和已安装插件
+func (i *Installer) dependents(name string, state map[string]*InstalledPlugin) []string {
+	found := make(map[string]bool)
+	if i.manager != nil {
+		for _, info := range i.manager.GetAllPluginsInfo() {
+			for _, depName := range info.Dependencies {
+				if depName == name {
+					found[info.Plugin.Name()] = true
+				}
+			}
+		}
+	}
+	for _, installed := range state {
+		for _, depName := range requiredDependencies(installed.Dependencies) {
+			if depName == name {
+				found[installed.Name] = true
+			}
+		}
+	}
+	delete(found, name)
+
+	dependents := make([]string, 0, len(found))
+	for dependent := range found {
+		dependents = append(dependents, dependent)
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// pinned 返回保持当前版本参与依赖解析的已安装插件（未注册的，excluding除外），
+// 已注册的插件由管理器直接参与解析
+func (i *Installer) pinned(state map[string]*InstalledPlugin, excluding string) []*core.PluginManifest {
+	var manifests []*core.PluginManifest
+	for _, name := range sortedNames(state) {
+		if name == excluding || i.registered(name) {
+			continue
+		}
+		installed := state[name]
+		manifests = append(manifests, &core.PluginManifest{Name: name, Version: installed.Version, Dependencies: installed.Dependencies})
+	}
+	return manifests
+}
+
+// resolver 返回用于依赖解析的插件管理器，未连接管理器时使用空的管理器
+func (i *Installer) resolver() *core.PluginManager {
+	if i.manager != nil {
+		return i.manager
+	}
+	return core.NewPluginManager()
+}
+
+// registered 判断插件是否已在管理器中注册
+func (i *Installer) registered(name string) bool {
+	if i.manager == nil {
+		return false
+	}
+	_, exists := i.manager.GetPlugin(name)
+	return exists
+}
+
+// requireRegistry 检查是否配置了插件仓库
+func (i *Installer) requireRegistry() error {
+	if i.registry == nil {
+		return pkg.NewServiceUnavailableError("插件仓库未配置（plugins.registry.url）", nil)
+	}
+	return nil
+}
+
+// versionDir 返回插件版本的安装目录
+func (i *Installer) versionDir(name, version string) string {
+	return filepath.Join(i.dir, name, version)
+}
+
+// within 确认路径清理后位于安装目录之内，避免写入或删除安装目录之外的文件
+func (i *Installer) within(path string) error {
+	rel, err := filepath.Rel(filepath.Clean(i.dir), filepath.Clean(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return pkg.NewBadRequestError(fmt.Sprintf("路径 '%s' 不在插件安装目录之内", path), err)
+	}
+	return nil
+}
+
+// manifestPath 返回插件版本的清单路径
+func (i *Installer) manifestPath(name, version string) string {
+	return filepath.Join(i.versionDir(name, version), core.ManifestFileName)
+}
+
+// loadState 读取安装记录，文件不存在时返回空记录
+func (i *Installer) loadState() (map[string]*InstalledPlugin, error) {
+	state := make(map[string]*InstalledPlugin)
+	data, err := os.ReadFile(filepath.Join(i.dir, stateFileName))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取插件安装记录失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析插件安装记录失败: %w", err)
+	}
+	return state, nil
+}
+
+// saveState 保存安装记录，先写入临时文件再替换，避免写入中断损坏记录
+func (i *Installer) saveState(state map[string]*InstalledPlugin) error {
+	if err := os.MkdirAll(i.dir, 0755); err != nil {
+		return fmt.Errorf("创建插件安装目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化插件安装记录失败: %w", err)
+	}
+	path := filepath.Join(i.dir, stateFileName)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("保存插件安装记录失败: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("保存插件安装记录失败: %w", err)
+	}
+	return nil
+}
+
+// dependencyOrder 从目标插件出发沿必需依赖做后序遍历，返回依赖在前的安装顺序及各插件被安装的原因
+func dependencyOrder(name string, chosen map[string]*core.PluginManifest) ([]string, map[string]string) {
+	var order []string
+	reasons := make(map[string]string)
+
+	var visit func(current, reason string)
+	visit = func(current, reason string) {
+		if _, visited := reasons[current]; visited {
+			return
+		}
+		reasons[current] = reason
+		for _, depName := range requiredDependencies(chosen[current].Dependencies) {
+			if _, exists := chosen[depName]; exists {
+				visit(depName, fmt.Sprintf("插件 '%s' 的依赖", current))
+			}
+		}
+		order = append(order, current)
+	}
+	visit(name, "请求的插件")
+	return order, reasons
+}
+
+// installOrder 返回已安装插件的加载顺序，依赖先于依赖方，其余按名称排序
+func installOrder(state map[string]*InstalledPlugin) []string {
+	var order []string
+	visited := make(map[string]bool, len(state))
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, depName := range requiredDependencies(state[name].Dependencies) {
+			if _, exists := state[depName]; exists {
+				visit(depName)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range sortedNames(state) {
+		visit(name)
+	}
+	return order
+}
+
+// filterCandidates 只保留保持当前版本的插件和needed中插件的候选版本
+func filterCandidates(candidates []*core.PluginManifest, pinned map[string]bool, needed map[string]string) []*core.PluginManifest {
+	filtered := candidates[:0:0]
+	for _, manifest := range candidates {
+		if _, ok := needed[manifest.Name]; ok || pinned[manifest.Name] {
+			filtered = append(filtered, manifest)
+		}
+	}
+	return filtered
+}
+
+// newerThan 返回高于current的版本
+func newerThan(list []IndexVersion, current string) []IndexVersion {
+	currentVersion, err := core.ParseVersion(current)
+	if err != nil {
+		return list
+	}
+	var newer []IndexVersion
+	for _, v := range list {
+		if parsed, err := core.ParseVersion(v.Version); err == nil && parsed.Compare(currentVersion) > 0 {
+			newer = append(newer, v)
+		}
+	}
+	return newer
+}
+
+// requiredDependencies 返回依赖声明中必需依赖的插件名称，可选依赖缺失时不安装
+func requiredDependencies(specs []string) []string {
+	var names []string
+	for _, spec := range specs {
+		dep, err := core.ParseDependency(spec)
+		if err == nil && !dep.Optional {
+			names = append(names, dep.Name)
+		}
+	}
+	return names
+}
+
+// sortedNames 返回排序后的已安装插件名称
+func sortedNames(state map[string]*InstalledPlugin) []string {
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requireValidName 校验请求中的插件名称，名称会作为安装目录名使用
+func requireValidName(name string) error {
+	if err := validateName(name); err != nil {
+		return pkg.NewBadRequestError(err.Error(), err)
+	}
+	return nil
+}
+
+// notInstalledError 插件不是通过插件仓库安装的
+func notInstalledError(name string) error {
+	return pkg.NewPluginNotFoundError(fmt.Sprintf("插件 '%s' 不是通过插件仓库安装的", name), nil)
+}
+
+// actionName 返回动作的中文名称，用于错误信息
+func actionName(action string) string {
+	if action == ActionUpgrade {
+		return "升级"
+	}
+	return "安装"
+}
@@ -0,0 +1,280 @@
+// Package registry 提供基于文件的插件仓库：从本地目录或HTTP地址搜索插件，
+// 安装、升级、回滚和卸载插件发布包
+//
+// 仓库根目录下的index.json列出全部插件及其版本，每个版本的发布包是一个目录，
+// 包含插件清单（plugin.json）、编译产物以及产物的摘要文件和签名文件：
+//
+//	index.json
+//	hello/1.2.0/plugin.json
+//	hello/1.2.0/hello.so
+//	hello/1.2.0/hello.so.sha256
+//	hello/1.2.0/hello.so.sig
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"weave/plugins/core"
+	"weave/plugins/loader"
+)
+
+// IndexFileName 仓库索引的文件名
+const IndexFileName = "index.json"
+
+// maxDownloadSize 从HTTP仓库下载的单个文件的大小上限（字节）
+const maxDownloadSize = 512 << 20
+
+// 插件名称和版本号会作为安装目录名使用，只允许字母、数字和少量标点，不能包含路径分隔符
+var (
+	namePattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	versionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.+-]*$`)
+)
+
+// validateName 校验插件名称是否为只含字母、数字、下划线、点和连字符的标识符
+func validateName(name string) error {
+	if !namePattern.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("插件名称 '%s' 无效，只能包含字母、数字、下划线、点和连字符", name)
+	}
+	return nil
+}
+
+// validateVersionName 校验版本号能否作为目录名使用，ParseVersion不检查构建元数据，需要单独校验
+func validateVersionName(version string) error {
+	if !versionPattern.MatchString(version) || strings.Contains(version, "..") {
+		return fmt.Errorf("版本号 '%s' 无效，只能包含字母、数字、下划线、点、加号和连字符", version)
+	}
+	return nil
+}
+
+// Index 仓库索引（index.json）
+type Index struct {
+	Plugins []IndexEntry `json:"plugins"`
+}
+
+// IndexEntry 仓库中的一个插件
+type IndexEntry struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Author      string         `json:"author,omitempty"`
+	Versions    []IndexVersion `json:"versions"` // 读取索引后按版本从高到低排列
+}
+
+// IndexVersion 插件的一个发布版本
+type IndexVersion struct {
+	Version      string   `json:"version"`
+	Dependencies []string `json:"dependencies,omitempty"` // 依赖声明，与发布包清单中的dependencies一致
+	Path         string   `json:"path,omitempty"`         // 发布包目录相对仓库根目录的路径，默认为"<插件名>/<版本>"
+}
+
+// Latest 返回插件的最高版本，没有版本时返回空字符串
+func (e *IndexEntry) Latest() string {
+	if len(e.Versions) == 0 {
+		return ""
+	}
+	return e.Versions[0].Version
+}
+
+// Find 查找插件的指定版本
+func (e *IndexEntry) Find(version string) (IndexVersion, bool) {
+	for _, v := range e.Versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return IndexVersion{}, false
+}
+
+// Match 返回满足版本约束的版本，按版本从高到低排列
+func (e *IndexEntry) Match(constraint *core.Constraint) []IndexVersion {
+	var matched []IndexVersion
+	for _, v := range e.Versions {
+		if ok, err := constraint.CheckString(v.Version); err == nil && ok {
+			matched = append(matched, v)
+		}
+	}
+	return matched
+}
+
+// manifest 返回索引中该版本的依赖信息，用于版本解析
+func (v IndexVersion) manifest(name string) *core.PluginManifest {
+	return &core.PluginManifest{Name: name, Version: v.Version, Dependencies: v.Dependencies}
+}
+
+// bundlePath 返回发布包目录相对仓库根目录的路径
+func (v IndexVersion) bundlePath(name string) string {
+	if v.Path != "" {
+		return path.Clean(v.Path)
+	}
+	return name + "/" + v.Version
+}
+
+// validate 校验索引内容并将各插件的版本按从高到低排序
+func (idx *Index) validate() error {
+	seen := make(map[string]bool, len(idx.Plugins))
+	for i := range idx.Plugins {
+		entry := &idx.Plugins[i]
+		if strings.TrimSpace(entry.Name) == "" {
+			return fmt.Errorf("仓库索引中第 %d 个插件缺少名称", i+1)
+		}
+		if err := validateName(entry.Name); err != nil {
+			return fmt.Errorf("仓库索引中%w", err)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("仓库索引中插件 '%s' 重复", entry.Name)
+		}
+		seen[entry.Name] = true
+
+		for _, v := range entry.Versions {
+			if _, err := core.ParseVersion(v.Version); err != nil {
+				return fmt.Errorf("仓库索引中插件 '%s' 的%w", entry.Name, err)
+			}
+			if err := validateVersionName(v.Version); err != nil {
+				return fmt.Errorf("仓库索引中插件 '%s' 的%w", entry.Name, err)
+			}
+			for _, spec := range v.Dependencies {
+				if _, err := core.ParseDependency(spec); err != nil {
+					return fmt.Errorf("仓库索引中插件 '%s' %s 的%w", entry.Name, v.Version, err)
+				}
+			}
+			if p := v.bundlePath(entry.Name); path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+				return fmt.Errorf("仓库索引中插件 '%s' %s 的发布包路径 '%s' 不能位于仓库之外", entry.Name, v.Version, v.Path)
+			}
+		}
+		sort.SliceStable(entry.Versions, func(a, b int) bool {
+			va, _ := core.ParseVersion(entry.Versions[a].Version)
+			vb, _ := core.ParseVersion(entry.Versions[b].Version)
+			return va.Compare(vb) > 0
+		})
+	}
+	return nil
+}
+
+// Registry 插件仓库，位于本地目录或HTTP(S)地址
+type Registry struct {
+	url    string
+	client *http.Client
+}
+
+// New 创建插件仓库，url为本地目录或HTTP(S)地址，timeout为从HTTP仓库下载单个文件的超时时间
+func New(url string, timeout time.Duration) *Registry {
+	return &Registry{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// URL 返回仓库地址
+func (r *Registry) URL() string {
+	return r.url
+}
+
+// Index 读取并校验仓库索引
+func (r *Registry) Index() (*Index, error) {
+	data, err := r.read(IndexFileName)
+	if err != nil {
+		return nil, fmt.Errorf("读取插件仓库索引失败: %w", err)
+	}
+	idx := &Index{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("解析插件仓库索引失败: %w", err)
+	}
+	if err := idx.validate(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Search 搜索名称或描述中包含query的插件（忽略大小写），query为空时返回全部插件，结果按名称排序
+func (r *Registry) Search(query string) ([]IndexEntry, error) {
+	idx, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	entries := make([]IndexEntry, 0, len(idx.Plugins))
+	for _, entry := range idx.Plugins {
+		if query == "" || strings.Contains(strings.ToLower(entry.Name), query) || strings.Contains(strings.ToLower(entry.Description), query) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Download 将插件版本的发布包下载到dest目录，返回通过校验的清单
+// 发布包中的清单必须与索引中的名称、版本一致，编译产物的摘要文件和签名文件一并下载
+func (r *Registry) Download(name string, version IndexVersion, dest string) (*core.PluginManifest, error) {
+	bundle := version.bundlePath(name)
+	data, err := r.read(bundle + "/" + core.ManifestFileName)
+	if err != nil {
+		return nil, fmt.Errorf("下载插件 '%s' %s 的清单失败: %w", name, version.Version, err)
+	}
+	manifest := &core.PluginManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("解析插件 '%s' %s 的清单失败: %w", name, version.Version, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("插件 '%s' %s 的清单无效: %w", name, version.Version, err)
+	}
+	if manifest.Name != name || manifest.Version != version.Version {
+		return nil, fmt.Errorf("发布包清单与仓库索引不一致: 索引为 %s %s，清单为 %s %s", name, version.Version, manifest.Name, manifest.Version)
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, fmt.Errorf("创建插件安装目录失败: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, core.ManifestFileName), data, 0644); err != nil {
+		return nil, fmt.Errorf("写入插件清单失败: %w", err)
+	}
+	entry := path.Clean(filepath.ToSlash(manifest.EntryPointOrDefault()))
+	for _, file := range []string{entry, entry + loader.DigestSuffix, entry + loader.SignatureSuffix} {
+		content, err := r.read(bundle + "/" + file)
+		if err != nil {
+			return nil, fmt.Errorf("下载插件 '%s' %s 的 %s 失败: %w", name, version.Version, file, err)
+		}
+		target := filepath.Join(dest, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("创建插件安装目录失败: %w", err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return nil, fmt.Errorf("写入 %s 失败: %w", file, err)
+		}
+	}
+	return manifest, nil
+}
+
+// read 读取仓库中的文件，rel为相对仓库根目录、以/分隔的路径
+func (r *Registry) read(rel string) ([]byte, error) {
+	if !isRemote(r.url) {
+		return os.ReadFile(filepath.Join(r.url, filepath.FromSlash(rel)))
+	}
+
+	resp, err := r.client.Get(strings.TrimSuffix(r.url, "/") + "/" + rel)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s 返回状态码 %d", rel, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("%s 超过 %d 字节的大小上限", rel, maxDownloadSize)
+	}
+	return data, nil
+}
+
+// isRemote 判断仓库地址是否为HTTP(S)地址
+func isRemote(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
@@ -0,0 +1,429 @@
+package registry
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"weave/pkg"
+	"weave/plugins/core"
+	"weave/plugins/loader"
+	"weave/plugins/plugintest"
+)
+
+// testRegistry 测试用的本地插件仓库，发布包用新生成的密钥签名
+type testRegistry struct {
+	t          *testing.T
+	dir        string
+	privateKey ed25519.PrivateKey
+	trust      *loader.TrustPolicy
+	index      Index
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	trust, err := loader.NewTrustPolicy([]string{base64.StdEncoding.EncodeToString(publicKey)}, false)
+	if err != nil {
+		t.Fatalf("new trust policy: %v", err)
+	}
+	return &testRegistry{t: t, dir: t.TempDir(), privateKey: privateKey, trust: trust}
+}
+
+// publish 写入插件版本的发布包并更新索引
+func (r *testRegistry) publish(name, version string, deps ...string) {
+	r.t.Helper()
+	bundle := filepath.Join(r.dir, name, version)
+	if err := os.MkdirAll(bundle, 0755); err != nil {
+		r.t.Fatalf("mkdir: %v", err)
+	}
+	manifest, _ := json.Marshal(core.PluginManifest{Name: name, Version: version, Dependencies: deps})
+	if err := os.WriteFile(filepath.Join(bundle, core.ManifestFileName), manifest, 0644); err != nil {
+		r.t.Fatalf("write manifest: %v", err)
+	}
+	artifact := filepath.Join(bundle, name+".so")
+	if err := os.WriteFile(artifact, []byte(name+" "+version), 0644); err != nil {
+		r.t.Fatalf("write artifact: %v", err)
+	}
+	if err := loader.SignPlugin(artifact, r.privateKey); err != nil {
+		r.t.Fatalf("sign plugin: %v", err)
+	}
+
+	entry := -1
+	for k := range r.index.Plugins {
+		if r.index.Plugins[k].Name == name {
+			entry = k
+		}
+	}
+	if entry < 0 {
+		r.index.Plugins = append(r.index.Plugins, IndexEntry{Name: name, Description: name + " 插件"})
+		entry = len(r.index.Plugins) - 1
+	}
+	r.index.Plugins[entry].Versions = append(r.index.Plugins[entry].Versions, IndexVersion{Version: version, Dependencies: deps})
+	data, _ := json.Marshal(r.index)
+	if err := os.WriteFile(filepath.Join(r.dir, IndexFileName), data, 0644); err != nil {
+		r.t.Fatalf("write index: %v", err)
+	}
+}
+
+// fakeLoader 根据清单返回替身插件，不加载编译产物
+type fakeLoader struct {
+	loaded   []string
+	unloaded []string
+	initErr  map[string]error // 插件版本（name@version） -> 初始化错误
+}
+
+func (l *fakeLoader) LoadPluginFromManifest(manifestPath string) (core.Plugin, *core.PluginManifest, error) {
+	manifest, err := core.LoadPluginManifest(manifestPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	l.loaded = append(l.loaded, manifest.Name+"@"+manifest.Version)
+	return &plugintest.FakePlugin{
+		PluginName:    manifest.Name,
+		PluginVersion: manifest.Version,
+		Dependencies:  manifest.Dependencies,
+		InitErr:       l.initErr[manifest.Name+"@"+manifest.Version],
+	}, manifest, nil
+}
+
+func (l *fakeLoader) UnloadPlugin(pluginName string) error {
+	l.unloaded = append(l.unloaded, pluginName)
+	return nil
+}
+
+func newTestInstaller(t *testing.T, reg *testRegistry, manager *core.PluginManager) (*Installer, *fakeLoader) {
+	t.Helper()
+	installer := NewInstaller(Config{URL: reg.dir, InstallDir: filepath.Join(t.TempDir(), ".installed"), Timeout: time.Second, Keep: 2}, manager, pkg.GetLogger())
+	installer.SetTrustPolicy(reg.trust)
+	fake := &fakeLoader{initErr: make(map[string]error)}
+	installer.SetLoader(fake)
+	return installer, fake
+}
+
+func stepsString(steps []Step) string {
+	parts := make([]string, 0, len(steps))
+	for _, step := range steps {
+		parts = append(parts, step.Action+" "+step.Plugin+" "+step.From+"->"+step.To)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestParseSpec(t *testing.T) {
+	for spec, want := range map[string][2]string{
+		"hello":        {"hello", ""},
+		"hello@1.2.0":  {"hello", "1.2.0"},
+		" hello@^1.2 ": {"hello", "^1.2"},
+	} {
+		name, constraint := ParseSpec(spec)
+		if name != want[0] || constraint != want[1] {
+			t.Errorf("ParseSpec(%q) = %q, %q, want %q, %q", spec, name, constraint, want[0], want[1])
+		}
+	}
+}
+
+func TestRegistry_IndexAndSearch(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("hello", "1.0.0")
+	reg.publish("hello", "1.2.0")
+	reg.publish("greeter", "0.1.0", "hello^1.0")
+
+	idx, err := New(reg.dir, time.Second).Index()
+	if err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	if idx.Plugins[0].Latest() != "1.2.0" {
+		t.Fatalf("expected versions sorted descending, got %+v", idx.Plugins[0].Versions)
+	}
+
+	entries, err := New(reg.dir, time.Second).Search("GREET")
+	if err != nil || len(entries) != 1 || entries[0].Name != "greeter" {
+		t.Fatalf("unexpected search result %+v, %v", entries, err)
+	}
+
+	// 发布包路径不能指向仓库之外
+	reg.index.Plugins[0].Versions[0].Path = "../outside"
+	data, _ := json.Marshal(reg.index)
+	_ = os.WriteFile(filepath.Join(reg.dir, IndexFileName), data, 0644)
+	if _, err := New(reg.dir, time.Second).Index(); err == nil {
+		t.Fatal("expected bundle path outside registry rejected")
+	}
+}
+
+func TestRegistry_HTTP(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("hello", "1.0.0")
+	server := httptest.NewServer(http.FileServer(http.Dir(reg.dir)))
+	defer server.Close()
+
+	remote := New(server.URL, time.Second)
+	idx, err := remote.Index()
+	if err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	dest := t.TempDir()
+	manifest, err := remote.Download("hello", idx.Plugins[0].Versions[0], dest)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if err := reg.trust.Verify(filepath.Join(dest, manifest.EntryPointOrDefault())); err != nil {
+		t.Fatalf("expected downloaded bundle trusted, got %v", err)
+	}
+
+	if _, err := remote.Download("hello", IndexVersion{Version: "9.9.9"}, t.TempDir()); err == nil {
+		t.Fatal("expected missing bundle to fail")
+	}
+}
+
+func TestInstaller_InstallWithDependencies(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("base", "1.0.0")
+	reg.publish("base", "2.0.0")
+	reg.publish("hello", "1.0.0", "base^1.0")
+	reg.publish("unused", "1.0.0")
+
+	manager := core.NewPluginManager()
+	installer, fake := newTestInstaller(t, reg, manager)
+
+	steps, err := installer.PlanInstall("hello", "")
+	if err != nil {
+		t.Fatalf("plan install: %v", err)
+	}
+	// 依赖先安装，并选择满足约束的最高版本
+	if got := stepsString(steps); got != "install base ->1.0.0; install hello ->1.0.0" {
+		t.Fatalf("unexpected plan: %s", got)
+	}
+	if len(fake.loaded) != 0 {
+		t.Fatalf("plan must not load plugins, loaded %v", fake.loaded)
+	}
+
+	if _, err := installer.Install("hello", ""); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	for _, name := range []string{"base", "hello"} {
+		if _, exists := manager.GetPlugin(name); !exists {
+			t.Fatalf("expected %s registered", name)
+		}
+	}
+	installed, _ := installer.Installed()
+	if len(installed) != 2 || !installed[0].AsDependency || installed[1].AsDependency {
+		t.Fatalf("unexpected installed plugins %+v", installed)
+	}
+
+	var appErr *pkg.AppError
+	if _, err := installer.Install("hello", ""); !errors.As(err, &appErr) || appErr.Code != pkg.ErrConflict {
+		t.Fatalf("expected conflict reinstalling, got %v", err)
+	}
+	if _, err := installer.Install("hello", ">=5.0.0"); err == nil {
+		t.Fatal("expected unsatisfiable constraint to fail")
+	}
+}
+
+func TestInstaller_InstallRollsBackOnFailure(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("base", "1.0.0")
+	reg.publish("hello", "1.0.0", "base")
+
+	manager := core.NewPluginManager()
+	installer, fake := newTestInstaller(t, reg, manager)
+	fake.initErr["hello@1.0.0"] = errors.New("init failed")
+
+	if _, err := installer.Install("hello", ""); err == nil {
+		t.Fatal("expected install to fail")
+	}
+	// 已注册的依赖被撤销，安装目录被清理
+	if _, exists := manager.GetPlugin("base"); exists {
+		t.Fatal("expected dependency unregistered after failure")
+	}
+	if installed, _ := installer.Installed(); len(installed) != 0 {
+		t.Fatalf("expected nothing installed, got %+v", installed)
+	}
+	if _, err := os.Stat(installer.versionDir("base", "1.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected downloaded bundle removed, got %v", err)
+	}
+}
+
+func TestInstaller_RejectsUntrustedBundle(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("hello", "1.0.0")
+
+	installer, fake := newTestInstaller(t, reg, core.NewPluginManager())
+	installer.SetTrustPolicy(&loader.TrustPolicy{})
+	if _, err := installer.Install("hello", ""); err == nil {
+		t.Fatal("expected untrusted bundle rejected")
+	}
+	if len(fake.loaded) != 0 {
+		t.Fatalf("untrusted bundle must not be loaded, loaded %v", fake.loaded)
+	}
+}
+
+func TestInstaller_RejectsUnsafeNames(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("hello", "1.0.0")
+
+	// 请求中的插件名称会作为安装目录名使用，不能包含路径分隔符和".."
+	installer, fake := newTestInstaller(t, reg, core.NewPluginManager())
+	var appErr *pkg.AppError
+	for _, name := range []string{"../hello", "hello/../../x", "..", ".", ""} {
+		if _, err := installer.Install(name, ""); !errors.As(err, &appErr) || appErr.Code != pkg.ErrBadRequest {
+			t.Fatalf("expected install of %q rejected as bad request, got %v", name, err)
+		}
+		if _, err := installer.Uninstall(name); !errors.As(err, &appErr) || appErr.Code != pkg.ErrBadRequest {
+			t.Fatalf("expected uninstall of %q rejected as bad request, got %v", name, err)
+		}
+	}
+	if len(fake.loaded) != 0 {
+		t.Fatalf("nothing should be loaded, loaded %v", fake.loaded)
+	}
+
+	// 索引中的插件名称和版本号同样需要校验
+	for _, mutate := range []func(){
+		func() { reg.index.Plugins[0].Name = "../outside" },
+		func() { reg.index.Plugins[0].Versions[0].Version = "1.0.0+x/../../../outside" },
+	} {
+		saved := reg.index.Plugins[0]
+		saved.Versions = append([]IndexVersion(nil), saved.Versions...)
+		mutate()
+		data, _ := json.Marshal(reg.index)
+		_ = os.WriteFile(filepath.Join(reg.dir, IndexFileName), data, 0644)
+		if _, err := New(reg.dir, time.Second).Index(); err == nil {
+			t.Fatalf("expected index %+v rejected", reg.index.Plugins[0])
+		}
+		reg.index.Plugins[0] = saved
+	}
+}
+
+func TestInstaller_UpgradeRollbackUninstall(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("hello", "1.0.0")
+	reg.publish("hello", "1.1.0")
+	reg.publish("hello", "2.0.0")
+
+	manager := core.NewPluginManager()
+	installer, _ := newTestInstaller(t, reg, manager)
+	if _, err := installer.Install("hello", "1.0.0"); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	// 已注册的依赖方限制了可升级的版本
+	if err := manager.Register(&plugintest.FakePlugin{PluginName: "consumer", Dependencies: []string{"hello^1.0"}}); err != nil {
+		t.Fatalf("register consumer: %v", err)
+	}
+
+	result, err := installer.Upgrade("hello", "")
+	if err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if got := stepsString(result.Steps); got != "upgrade hello 1.0.0->1.1.0" {
+		t.Fatalf("unexpected upgrade steps: %s", got)
+	}
+	if plugin, _ := manager.GetPlugin("hello"); plugin.Version() != "1.1.0" {
+		t.Fatalf("expected hello 1.1.0 registered, got %s", plugin.Version())
+	}
+	if len(result.Reloaded) != 1 || result.Reloaded[0].Plugin != "consumer" {
+		t.Fatalf("expected consumer reloaded, got %+v", result.Reloaded)
+	}
+	// 更高的版本不满足依赖方的约束
+	if _, err := installer.PlanUpgrade("hello", ""); err == nil {
+		t.Fatal("expected upgrade beyond consumer constraint rejected")
+	}
+
+	// 被依赖的插件不能卸载
+	var appErr *pkg.AppError
+	if _, err := installer.Uninstall("hello"); !errors.As(err, &appErr) || appErr.Code != pkg.ErrConflict {
+		t.Fatalf("expected uninstall refused while consumer depends on hello, got %v", err)
+	}
+
+	if _, err := installer.Rollback("hello"); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if plugin, _ := manager.GetPlugin("hello"); plugin.Version() != "1.0.0" {
+		t.Fatalf("expected hello 1.0.0 after rollback, got %s", plugin.Version())
+	}
+	if _, err := os.Stat(installer.versionDir("hello", "1.1.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected rolled back version removed, got %v", err)
+	}
+	if _, err := installer.Rollback("hello"); err == nil {
+		t.Fatal("expected no version left to roll back to")
+	}
+
+	if err := manager.Unregister("consumer"); err != nil {
+		t.Fatalf("unregister consumer: %v", err)
+	}
+	if _, err := installer.Uninstall("hello"); err != nil {
+		t.Fatalf("uninstall: %v", err)
+	}
+	if _, exists := manager.GetPlugin("hello"); exists {
+		t.Fatal("expected hello unregistered")
+	}
+	if _, err := os.Stat(filepath.Join(installer.dir, "hello")); !os.IsNotExist(err) {
+		t.Fatalf("expected hello files removed, got %v", err)
+	}
+}
+
+func TestInstaller_KeepsLimitedVersions(t *testing.T) {
+	reg := newTestRegistry(t)
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		reg.publish("hello", version)
+	}
+
+	installer, _ := newTestInstaller(t, reg, core.NewPluginManager())
+	if _, err := installer.Install("hello", "1.0.0"); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	for _, version := range []string{"1.1.0", "1.2.0"} {
+		if _, err := installer.Upgrade("hello", version); err != nil {
+			t.Fatalf("upgrade to %s: %v", version, err)
+		}
+	}
+
+	if steps, err := installer.PlanUpgrade("hello", ""); err != nil || len(steps) != 0 {
+		t.Fatalf("expected already up to date, got %v, %v", steps, err)
+	}
+
+	installed, _ := installer.Installed()
+	if len(installed[0].Previous) != 1 || installed[0].Previous[0] != "1.1.0" {
+		t.Fatalf("expected only 1.1.0 kept for rollback, got %v", installed[0].Previous)
+	}
+	if _, err := os.Stat(installer.versionDir("hello", "1.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected 1.0.0 pruned, got %v", err)
+	}
+}
+
+func TestInstaller_OfflineLoadInstalled(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("base", "1.0.0")
+	reg.publish("hello", "1.0.0", "base", "builtin")
+
+	// 未连接管理器时只下载发布包，未知的依赖假定由服务内置
+	offline, _ := newTestInstaller(t, reg, nil)
+	if _, err := offline.Install("hello", ""); err != nil {
+		t.Fatalf("offline install: %v", err)
+	}
+
+	manager := core.NewPluginManager()
+	if err := manager.Register(&plugintest.FakePlugin{PluginName: "builtin"}); err != nil {
+		t.Fatalf("register builtin: %v", err)
+	}
+	server := NewInstaller(Config{URL: reg.dir, InstallDir: offline.dir, Keep: 2}, manager, pkg.GetLogger())
+	fake := &fakeLoader{}
+	server.SetLoader(fake)
+	server.LoadInstalled()
+
+	if got := strings.Join(fake.loaded, ","); got != "base@1.0.0,hello@1.0.0" {
+		t.Fatalf("expected dependencies loaded first, got %s", got)
+	}
+	if _, exists := manager.GetPlugin("hello"); !exists {
+		t.Fatal("expected hello registered")
+	}
+}
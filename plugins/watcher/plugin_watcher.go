@@ -1,17 +1,20 @@
 package watcher
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"weave/config"
 	"weave/pkg"
 	"weave/pkg/metrics"
+	"weave/plugins/core"
 	"weave/plugins/loader"
 
 	"github.com/fsnotify/fsnotify"
@@ -31,19 +34,115 @@ type PluginManager interface {
 	Register(plugin Plugin) error
 }
 
+// ManifestRegistrar 支持按清单注册插件的管理器（可选）
+// 管理器实现该接口时，通过清单加载的插件会连同清单一起注册
+type ManifestRegistrar interface {
+	RegisterWithManifest(plugin Plugin, manifest *core.PluginManifest) error
+}
+
+// PluginLister 支持列出已注册插件的管理器（可选）
+// 管理器实现该接口时，没有清单的源码目录按目录名匹配已注册的插件（忽略大小写、下划线和连字符）
+type PluginLister interface {
+	ListPlugins() []string
+}
+
+// BatchReloader 支持批量重新加载的管理器（可选）
+// 管理器实现该接口时，同一批次中需要重新加载的插件连同其依赖方按依赖顺序重新加载；
+// 否则只逐个重新加载发生变更的插件
+type BatchReloader interface {
+	ReloadPlugins(names, dependentsOnly []string) *core.ReloadBatch
+}
+
+// 批次中插件的处理动作
+const (
+	ActionReload     = "reload"     // 重新加载已注册的插件
+	ActionLoad       = "load"       // 加载并注册新插件
+	ActionReplace    = "replace"    // 以新编译的产物替换已注册的插件
+	ActionUnregister = "unregister" // 插件文件删除后注销插件
+)
+
+// maxDebounceRounds 持续有文件变更时，最早的变更最多等待的防抖间隔数
+const maxDebounceRounds = 5
+
+// errPluginFileNotFound 插件目录中的.go文件没有对应的编译产物
+var errPluginFileNotFound = errors.New("插件编译文件不存在")
+
+// BatchResult 批次中一个插件的处理结果
+type BatchResult struct {
+	Plugin string `json:"plugin"`
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"` // 重新加载的原因，如依赖的插件已重新加载
+	Error  string `json:"error,omitempty"`
+}
+
+// Batch 防抖间隔内合并处理的一批文件变更及其结果
+type Batch struct {
+	Targets    []string      `json:"targets"` // 本批次处理的插件文件、清单或源码目录
+	Results    []BatchResult `json:"results"` // 各插件的处理结果，按执行顺序排列
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+
+	reload   []string // 需要原地重新加载的插件
+	replaced []string // 已替换为新实例的插件，只需重新加载其依赖方
+}
+
+// Err 合并批次中各插件的错误，全部成功时返回nil
+func (b *Batch) Err() error {
+	var errs []error
+	for _, result := range b.Results {
+		if result.Error != "" {
+			errs = append(errs, fmt.Errorf("插件 '%s' %s失败: %s", result.Plugin, result.Action, result.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record 记录插件的处理结果
+func (b *Batch) record(plugin, action string, err error) {
+	result := BatchResult{Plugin: plugin, Action: action}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	b.Results = append(b.Results, result)
+}
+
+// requestReload 将插件加入批次末尾的重新加载，同一插件的多个文件变更只重新加载一次
+func (b *Batch) requestReload(name string) {
+	for _, existing := range b.reload {
+		if existing == name {
+			return
+		}
+	}
+	b.reload = append(b.reload, name)
+}
+
 // PluginWatcher 插件文件监控器
+// 递归监控插件目录，将文件变更映射到插件：插件目录下的<名称>.go、带plugin.json的插件目录（任意层级），
+// 以及没有清单的源码目录（如features/Note，按目录名匹配已注册的插件）。
+// 防抖间隔内的变更合并为一批处理，同一插件的多个文件变更只处理一次
 type PluginWatcher struct {
 	watcher      *fsnotify.Watcher
 	pluginDir    string
 	manager      PluginManager
 	loader       *loader.PluginLoader
+	builder      *loader.PluginBuilder // 为空时不编译源码，只加载已有的编译产物
 	logger       *pkg.Logger
 	mu           sync.RWMutex
-	watchedFiles map[string]time.Time
+	watchedFiles map[string]time.Time // 已处理的路径 -> 处理时的最新修改时间
+	manifestMu   sync.Mutex
+	manifests    map[string]string // 清单路径 -> 插件名称，用于清单删除后注销插件
 	scanInterval time.Duration
+	debounce     time.Duration
 	running      bool
 	stopChan     chan struct{}
-	processChan  chan string
+
+	pendingMu    sync.Mutex
+	pending      map[string]bool // 防抖间隔内待处理的路径
+	pendingSince time.Time       // 最早一个待处理变更的时间
+	kick         chan struct{}
+
+	batchMu   sync.Mutex // 串行化批次的处理
+	lastBatch *Batch
 }
 
 // NewPluginWatcher 创建插件监控器
@@ -59,21 +158,32 @@ func NewPluginWatcher(pluginDir string, manager PluginManager, logger *pkg.Logge
 	if config.Config.Plugins.ScanInterval > 0 {
 		scanInterval = config.Config.Plugins.ScanInterval
 	}
+	// 防抖间隔，默认为500毫秒
+	debounce := 500
+	if config.Config.Plugins.Debounce > 0 {
+		debounce = config.Config.Plugins.Debounce
+	}
 
 	// 创建插件加载器
 	pluginLoader := loader.NewPluginLoader(logger)
 
 	pw := &PluginWatcher{
 		watcher:      watcher,
-		pluginDir:    pluginDir,
+		pluginDir:    filepath.Clean(pluginDir),
 		manager:      manager,
 		loader:       pluginLoader,
 		logger:       logger,
 		watchedFiles: make(map[string]time.Time),
+		manifests:    make(map[string]string),
 		scanInterval: time.Duration(scanInterval) * time.Second,
+		debounce:     time.Duration(debounce) * time.Millisecond,
 		running:      false,
 		stopChan:     make(chan struct{}),
-		processChan:  make(chan string, 100),
+		pending:      make(map[string]bool),
+		kick:         make(chan struct{}, 1),
+	}
+	if config.Config.Plugins.Build.Enabled {
+		pw.builder = loader.NewPluginBuilder(loader.BuildConfigFromConfig(), logger)
 	}
 
 	// 确保插件目录存在
@@ -85,6 +195,23 @@ func NewPluginWatcher(pluginDir string, manager PluginManager, logger *pkg.Logge
 	return pw, nil
 }
 
+// Builder 返回插件源码编译器，未启用源码编译时返回nil
+func (pw *PluginWatcher) Builder() *loader.PluginBuilder {
+	return pw.builder
+}
+
+// SetBuilder 设置插件源码编译器，为nil时关闭源码编译，应在Start之前调用
+func (pw *PluginWatcher) SetBuilder(builder *loader.PluginBuilder) {
+	pw.builder = builder
+}
+
+// LastBatch 返回最近处理完成的一批变更，尚未处理过时返回nil
+func (pw *PluginWatcher) LastBatch() *Batch {
+	pw.mu.RLock()
+	defer pw.mu.RUnlock()
+	return pw.lastBatch
+}
+
 // Start 启动监控器
 func (pw *PluginWatcher) Start() error {
 	pw.mu.Lock()
@@ -99,11 +226,8 @@ func (pw *PluginWatcher) Start() error {
 	go pw.watchLoop()
 	// 启动定期扫描（额外保障）
 	go pw.scanLoop()
-	// 启动文件处理队列
-	go pw.processQueue()
-
-	// 初始扫描插件目录
-	pw.scanPluginDir()
+	// 启动防抖和批次处理
+	go pw.debounceLoop()
 
 	// 添加插件目录到监控
 	if err := pw.watcher.Add(pw.pluginDir); err != nil {
@@ -112,6 +236,9 @@ func (pw *PluginWatcher) Start() error {
 		return fmt.Errorf("添加插件目录到监控失败: %w", err)
 	}
 
+	// 初始扫描插件目录，同时将各级子目录加入监控
+	pw.scanPluginDir()
+
 	pw.logger.Debug("插件文件监控器已启动", zap.String("pluginDir", pw.pluginDir))
 	return nil
 }
@@ -141,30 +268,28 @@ func (pw *PluginWatcher) watchLoop() {
 				return
 			}
 
-			// 忽略临时文件和目录
-			if isTempFile(event.Name) || isDirectory(event.Name) {
+			// 忽略只修改权限的事件、临时文件和隐藏目录（如编译产物目录.build）
+			if event.Op == fsnotify.Chmod || pw.ignored(event.Name) {
 				continue
 			}
 
-			// 只处理.go文件
-			if filepath.Ext(event.Name) != ".go" {
+			// 新建的子目录递归加入监控，其中已有的文件（如整体复制进来的插件目录）一并处理
+			if isDirectory(event.Name) {
+				if event.Op&fsnotify.Create != 0 {
+					pw.watchTree(event.Name)
+				}
+				continue
+			}
+
+			target, ok := pw.eventTarget(event.Name)
+			if !ok {
 				continue
 			}
 
 			pw.logger.Debug("检测到文件变更",
 				zap.String("path", event.Name),
 				zap.String("event", event.Op.String()))
-
-			// 防抖处理（避免短时间内多次触发）
-			pw.mu.Lock()
-			pw.watchedFiles[event.Name] = time.Now()
-			pw.mu.Unlock()
-
-			// 将文件加入处理队列（带延迟）
-			go func(path string) {
-				time.Sleep(500 * time.Millisecond)
-				pw.processChan <- path
-			}(event.Name)
+			pw.enqueue(target)
 
 		case err, ok := <-pw.watcher.Errors:
 			if !ok {
@@ -194,32 +319,43 @@ func (pw *PluginWatcher) scanLoop() {
 	}
 }
 
-// processQueue 处理文件变更队列
-func (pw *PluginWatcher) processQueue() {
+// enqueue 将待处理的路径加入当前批次，并重新开始防抖计时
+func (pw *PluginWatcher) enqueue(target string) {
+	pw.pendingMu.Lock()
+	if len(pw.pending) == 0 {
+		pw.pendingSince = time.Now()
+	}
+	pw.pending[target] = true
+	pw.pendingMu.Unlock()
+
+	select {
+	case pw.kick <- struct{}{}:
+	default:
+	}
+}
+
+// debounceLoop 防抖间隔内没有新的变更后处理当前批次
+// 持续有变更时，最早的变更最多等待maxDebounceRounds个防抖间隔
+func (pw *PluginWatcher) debounceLoop() {
+	timer := time.NewTimer(pw.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
 	for {
 		select {
-		case path := <-pw.processChan:
-			// 再次检查文件是否存在（可能已被删除）
-			if _, err := os.Stat(path); os.IsNotExist(err) {
-				pw.handlePluginRemoval(path)
-				continue
-			}
-
-			// 检查文件修改时间，避免重复处理
-			pw.mu.RLock()
-			lastProcessed, exists := pw.watchedFiles[path]
-			pw.mu.RUnlock()
+		case <-pw.kick:
+			pw.pendingMu.Lock()
+			waited := time.Since(pw.pendingSince)
+			pw.pendingMu.Unlock()
 
-			fileInfo, err := os.Stat(path)
-			if err != nil {
-				pw.logger.Error("获取文件信息失败", zap.String("path", path), zap.Error(err))
-				continue
+			delay := pw.debounce
+			if remaining := maxDebounceRounds*pw.debounce - waited; remaining < delay {
+				delay = max(remaining, 0)
 			}
+			timer.Reset(delay)
 
-			// 如果是新文件或文件确实被修改了
-			if !exists || fileInfo.ModTime().After(lastProcessed) {
-				pw.handlePluginChange(path)
-			}
+		case <-timer.C:
+			pw.flush()
 
 		case <-pw.stopChan:
 			return
@@ -227,107 +363,634 @@ func (pw *PluginWatcher) processQueue() {
 	}
 }
 
-// scanPluginDir 扫描插件目录
-func (pw *PluginWatcher) scanPluginDir() {
-	files, err := ioutil.ReadDir(pw.pluginDir)
-	if err != nil {
-		pw.logger.Error("扫描插件目录失败", zap.Error(err))
+// flush 取出待处理的路径并作为一批处理
+func (pw *PluginWatcher) flush() {
+	pw.pendingMu.Lock()
+	targets := make([]string, 0, len(pw.pending))
+	for target := range pw.pending {
+		targets = append(targets, target)
+	}
+	pw.pending = make(map[string]bool)
+	pw.pendingMu.Unlock()
+
+	if len(targets) == 0 {
 		return
 	}
+	sort.Strings(targets)
+	pw.processBatch(targets)
+}
 
-	currentFiles := make(map[string]bool)
+// processBatch 处理一批变更：依次处理各路径的新增、修改或删除，
+// 最后统一重新加载受影响的插件及其依赖方，返回合并的结果
+func (pw *PluginWatcher) processBatch(targets []string) *Batch {
+	pw.batchMu.Lock()
+	defer pw.batchMu.Unlock()
 
-	for _, file := range files {
-		if file.IsDir() || filepath.Ext(file.Name()) != ".go" {
+	batch := &Batch{Targets: targets, StartedAt: time.Now()}
+	for _, target := range targets {
+		if !unitExists(target) {
+			pw.removeTarget(batch, target)
 			continue
 		}
+		pw.changeTarget(batch, target)
+		pw.markProcessed(target)
+	}
+	pw.reloadBatch(batch)
+	batch.FinishedAt = time.Now()
 
-		path := filepath.Join(pw.pluginDir, file.Name())
-		currentFiles[path] = true
+	pw.mu.Lock()
+	pw.lastBatch = batch
+	pw.mu.Unlock()
+	pw.logBatch(batch)
+	return batch
+}
 
-		// 检查是否是新文件
-		pw.mu.RLock()
-		_, exists := pw.watchedFiles[path]
-		pw.mu.RUnlock()
+// handlePluginChange 立即处理单个插件文件、清单或源码目录的变更（不经过防抖），文件已删除时注销插件
+func (pw *PluginWatcher) handlePluginChange(path string) *Batch {
+	return pw.processBatch([]string{path})
+}
+
+// scanPluginDir 递归扫描插件目录：将子目录加入监控，并将监控遗漏的新增、修改和删除加入批次
+// 首次发现的没有清单的源码目录只记录修改时间，这些插件随服务编译，无需在启动时重新加载
+func (pw *PluginWatcher) scanPluginDir() {
+	current := make(map[string]time.Time)
+	err := filepath.WalkDir(pw.pluginDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == pw.pluginDir {
+				return err
+			}
+			return nil
+		}
+		if path == pw.pluginDir {
+			return nil
+		}
+		if pw.ignored(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			pw.watchDir(path)
+			return nil
+		}
 
-		if !exists {
-			pw.logger.Debug("发现新的插件文件", zap.String("path", path))
-			pw.processChan <- path
+		target, ok := pw.eventTarget(path)
+		if !ok {
+			return nil
 		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().After(current[target]) {
+			current[target] = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		pw.logger.Error("扫描插件目录失败", zap.Error(err))
+		return
 	}
 
-	// 检查是否有文件被删除
+	var queue []string
 	pw.mu.Lock()
-	for path := range pw.watchedFiles {
-		if !currentFiles[path] {
-			pw.logger.Debug("检测到插件文件被删除", zap.String("path", path))
-			pw.handlePluginRemoval(path)
-			delete(pw.watchedFiles, path)
+	for target, modTime := range current {
+		lastProcessed, exists := pw.watchedFiles[target]
+		switch {
+		case !exists && isSourceDirTarget(target):
+			pw.watchedFiles[target] = modTime
+		case !exists:
+			pw.logger.Debug("发现新的插件文件", zap.String("path", target))
+			queue = append(queue, target)
+		case modTime.After(lastProcessed):
+			pw.logger.Debug("扫描发现插件文件变更", zap.String("path", target))
+			queue = append(queue, target)
+		}
+	}
+	// 检查是否有文件被删除
+	for target := range pw.watchedFiles {
+		if _, exists := current[target]; !exists {
+			pw.logger.Debug("检测到插件文件被删除", zap.String("path", target))
+			queue = append(queue, target)
 		}
 	}
 	pw.mu.Unlock()
+
+	for _, target := range queue {
+		pw.enqueue(target)
+	}
 }
 
-// handlePluginChange 处理插件文件变更
-func (pw *PluginWatcher) handlePluginChange(path string) {
-	// 简化处理，打印日志并调用插件管理器的重载方法
+// changeTarget 处理插件文件、清单或源码目录的新增和修改
+func (pw *PluginWatcher) changeTarget(batch *Batch, target string) {
+	switch {
+	case filepath.Base(target) == core.ManifestFileName:
+		pw.changeManifest(batch, target)
+	case isSourceDirTarget(target):
+		pw.changeSourceDir(batch, target)
+	default:
+		pw.changePluginFile(batch, target)
+	}
+}
+
+// changePluginFile 处理插件目录下<名称>.go文件的变更
+func (pw *PluginWatcher) changePluginFile(batch *Batch, path string) {
 	pluginName := getPluginNameFromPath(path)
 
 	pw.logger.Debug("处理插件文件变更",
-			zap.String("path", path),
-			zap.String("pluginName", pluginName))
+		zap.String("path", path),
+		zap.String("pluginName", pluginName))
 
-	// 检查插件是否已注册
+	if !config.Config.Plugins.HotReload {
+		pw.logger.Debug("热重载功能已禁用", zap.String("pluginName", pluginName))
+		return
+	}
+
+	// 启用源码编译时编译插件并加载产物，已注册的插件由新产物替换
+	if pw.builder != nil {
+		pw.buildAndLoad(batch, loader.BuildSource{
+			Name:  pluginName,
+			Dir:   filepath.Dir(path),
+			Files: []string{filepath.Base(path)},
+		}, nil)
+		return
+	}
+
+	// 已注册的插件在批次末尾重新加载
 	if _, exists := pw.manager.GetPlugin(pluginName); exists {
-		// 重新加载现有插件
-		if config.Config.Plugins.HotReload {
-			if err := pw.manager.ReloadPlugin(pluginName); err != nil {
-				pw.logger.Error("重新加载插件失败",
-					zap.String("pluginName", pluginName),
+		batch.requestReload(pluginName)
+		return
+	}
+
+	pw.logger.Debug("发现新插件，尝试动态加载", zap.String("pluginName", pluginName))
+	if err := pw.tryLoadNewPlugin(pluginName); !errors.Is(err, errPluginFileNotFound) {
+		batch.record(pluginName, ActionLoad, err)
+	}
+}
+
+// changeSourceDir 处理没有清单的源码目录的变更，目录对应的插件在批次末尾重新加载
+func (pw *PluginWatcher) changeSourceDir(batch *Batch, dir string) {
+	pluginName := pw.dirPluginName(dir)
+	if pluginName == "" {
+		pw.logger.Debug("源码目录没有对应的已注册插件，跳过", zap.String("dir", dir))
+		return
+	}
+
+	pw.logger.Debug("处理插件源码目录变更",
+		zap.String("dir", dir),
+		zap.String("pluginName", pluginName))
+
+	if !config.Config.Plugins.HotReload {
+		pw.logger.Debug("热重载功能已禁用", zap.String("pluginName", pluginName))
+		return
+	}
+	batch.requestReload(pluginName)
+}
+
+// changeManifest 处理插件清单或插件目录中文件的变更
+// 清单无效或与已注册插件的名称、版本不一致时不加载插件
+func (pw *PluginWatcher) changeManifest(batch *Batch, path string) {
+	manifest, err := core.LoadPluginManifest(path)
+	if err == nil {
+		err = manifest.Validate()
+	}
+	if err != nil {
+		pluginName := filepath.Base(filepath.Dir(path))
+		pw.logger.Error("插件清单无效，跳过加载",
+			zap.String("path", path),
+			zap.Error(err))
+		metrics.RecordPluginError(pluginName, "invalid_manifest")
+		batch.record(pluginName, ActionLoad, fmt.Errorf("插件清单无效: %w", err))
+		return
+	}
+
+	pw.manifestMu.Lock()
+	pw.manifests[path] = manifest.Name
+	pw.manifestMu.Unlock()
+
+	pw.logger.Debug("处理插件清单变更",
+		zap.String("path", path),
+		zap.String("pluginName", manifest.Name))
+
+	if !config.Config.Plugins.HotReload {
+		pw.logger.Debug("热重载功能已禁用", zap.String("pluginName", manifest.Name))
+		return
+	}
+
+	// 插件目录中有源码时编译后加载产物，新产物按清单校验，清单中的版本可以与运行中的插件不同
+	if dir := filepath.Dir(path); pw.builder != nil && loader.HasGoSources(dir) {
+		pw.buildAndLoad(batch, loader.BuildSource{
+			Name:    manifest.Name,
+			Dir:     dir,
+			Tags:    manifest.BuildTags,
+			Version: manifest.Version,
+		}, manifest)
+		return
+	}
+
+	// 已注册的插件：清单与运行中的插件一致时在批次末尾重新加载
+	if plugin, exists := pw.manager.GetPlugin(manifest.Name); exists {
+		if corePlugin, ok := plugin.(core.Plugin); ok {
+			if err := manifest.CheckPlugin(corePlugin); err != nil {
+				pw.logger.Error("插件清单与已注册插件不一致，跳过重新加载",
+					zap.String("pluginName", manifest.Name),
 					zap.Error(err))
-				metrics.RecordPluginError(pluginName, "hot_reload_failed")
-			} else {
-				pw.logger.Debug("插件已成功重新加载", zap.String("pluginName", pluginName))
-				metrics.RecordPluginReload(pluginName, true)
+				metrics.RecordPluginError(manifest.Name, "manifest_mismatch")
+				batch.record(manifest.Name, ActionReload, err)
+				return
 			}
-		} else {
-			pw.logger.Debug("热重载功能已禁用", zap.String("pluginName", pluginName))
 		}
-	} else {
-		// 注册新插件
-		if config.Config.Plugins.HotReload {
-			pw.logger.Debug("发现新插件，尝试动态加载", zap.String("pluginName", pluginName))
-			pw.tryLoadNewPlugin(pluginName)
+		batch.requestReload(manifest.Name)
+		return
+	}
+
+	// 新插件：按清单加载并校验后注册
+	pluginInstance, _, err := pw.loader.LoadPluginFromManifest(path)
+	if err != nil {
+		pw.logger.Error("按清单加载插件失败",
+			zap.String("pluginName", manifest.Name),
+			zap.Error(err))
+		metrics.RecordPluginError(manifest.Name, "dynamic_load_failed")
+		batch.record(manifest.Name, ActionLoad, err)
+		return
+	}
+	if err := pw.register(pluginInstance, manifest); err != nil {
+		pw.logger.Error("注册插件失败",
+			zap.String("pluginName", manifest.Name),
+			zap.Error(err))
+		metrics.RecordPluginError(manifest.Name, "hot_register_failed")
+		pw.loader.UnloadPlugin(manifest.Name)
+		batch.record(manifest.Name, ActionLoad, err)
+		return
+	}
+
+	batch.record(manifest.Name, ActionLoad, nil)
+	pw.logger.Debug("插件已按清单加载并注册", zap.String("pluginName", manifest.Name))
+}
+
+// register 注册插件，管理器支持时连同清单一起注册
+func (pw *PluginWatcher) register(plugin core.Plugin, manifest *core.PluginManifest) error {
+	if registrar, ok := pw.manager.(ManifestRegistrar); ok && manifest != nil {
+		return registrar.RegisterWithManifest(plugin, manifest)
+	}
+	return pw.manager.Register(plugin)
+}
+
+// buildAndLoad 编译插件源码并加载产物，插件已注册时以新产物的实例替换
+// 编译、加载或注册失败时正在运行的插件不受影响，结果记录在编译状态和批次中；
+// 替换成功后依赖该插件的插件在批次末尾重新加载
+func (pw *PluginWatcher) buildAndLoad(batch *Batch, source loader.BuildSource, manifest *core.PluginManifest) {
+	action := ActionLoad
+	if _, exists := pw.manager.GetPlugin(source.Name); exists {
+		action = ActionReplace
+	}
+
+	result, err := pw.builder.Build(source)
+	if err != nil {
+		// 编译错误已由编译器记录到编译状态、日志和指标中
+		batch.record(source.Name, action, fmt.Errorf("编译失败: %w", err))
+		return
+	}
+
+	pluginInstance, err := pw.loader.LoadPlugin(result.Artifact, source.Name)
+	if err != nil {
+		pw.logger.Error("加载插件编译产物失败",
+			zap.String("pluginName", source.Name),
+			zap.String("artifact", result.Artifact),
+			zap.Error(err))
+		metrics.RecordPluginError(source.Name, "dynamic_load_failed")
+		pw.builder.RecordLoad(source.Name, result.Version, err)
+		batch.record(source.Name, action, err)
+		return
+	}
+
+	err = pw.replace(pluginInstance, manifest)
+	pw.builder.RecordLoad(source.Name, result.Version, err)
+	batch.record(source.Name, action, err)
+	if err != nil {
+		pw.logger.Error("注册插件编译产物失败",
+			zap.String("pluginName", source.Name),
+			zap.String("version", result.Version),
+			zap.Error(err))
+		return
+	}
+	if action == ActionReplace {
+		batch.replaced = append(batch.replaced, source.Name)
+	}
+
+	pw.logger.Debug("插件已编译并加载",
+		zap.String("pluginName", source.Name),
+		zap.String("version", result.Version))
+}
+
+// replace 注册新加载的插件实例，已注册同名插件时先注销旧实例，新实例注册失败时重新注册旧实例
+func (pw *PluginWatcher) replace(plugin core.Plugin, manifest *core.PluginManifest) error {
+	name := plugin.Name()
+	previous, exists := pw.manager.GetPlugin(name)
+	if !exists {
+		if err := pw.register(plugin, manifest); err != nil {
+			metrics.RecordPluginError(name, "hot_register_failed")
+			pw.loader.UnloadPlugin(name)
+			return err
+		}
+		return nil
+	}
+
+	if err := pw.manager.Unregister(name); err != nil {
+		metrics.RecordPluginReload(name, false)
+		return fmt.Errorf("注销旧版本插件失败: %w", err)
+	}
+	if err := pw.register(plugin, manifest); err != nil {
+		metrics.RecordPluginReload(name, false)
+		if restoreErr := pw.manager.Register(previous); restoreErr != nil {
+			return fmt.Errorf("注册新版本插件失败: %w，恢复旧版本也失败: %v", err, restoreErr)
+		}
+		return fmt.Errorf("注册新版本插件失败，已恢复旧版本: %w", err)
+	}
+	metrics.RecordPluginReload(name, true)
+	return nil
+}
+
+// reloadBatch 重新加载批次中发生变更的插件，管理器支持时连同依赖方按依赖顺序重新加载
+func (pw *PluginWatcher) reloadBatch(batch *Batch) {
+	if len(batch.reload) == 0 && len(batch.replaced) == 0 {
+		return
+	}
+
+	if reloader, ok := pw.manager.(BatchReloader); ok {
+		result := reloader.ReloadPlugins(batch.reload, batch.replaced)
+		for _, step := range result.Steps {
+			batch.Results = append(batch.Results, BatchResult{
+				Plugin: step.Plugin,
+				Action: ActionReload,
+				Reason: step.Reason,
+				Error:  step.Error,
+			})
+			if step.Status == core.ReloadFailed {
+				metrics.RecordPluginError(step.Plugin, "hot_reload_failed")
+			}
+		}
+		return
+	}
+
+	// 管理器不支持批量重新加载时逐个重新加载发生变更的插件
+	for _, name := range batch.reload {
+		err := pw.manager.ReloadPlugin(name)
+		if err != nil {
+			pw.logger.Error("重新加载插件失败",
+				zap.String("pluginName", name),
+				zap.Error(err))
+			metrics.RecordPluginError(name, "hot_reload_failed")
 		} else {
-			pw.logger.Debug("热重载功能已禁用，无法加载新插件", zap.String("pluginName", pluginName))
+			metrics.RecordPluginReload(name, true)
 		}
+		batch.record(name, ActionReload, err)
+	}
+}
+
+// logBatch 记录批次的合并结果
+func (pw *PluginWatcher) logBatch(batch *Batch) {
+	if len(batch.Results) == 0 {
+		return
 	}
+	fields := []zap.Field{
+		zap.Strings("targets", batch.Targets),
+		zap.Any("results", batch.Results),
+		zap.Duration("duration", batch.FinishedAt.Sub(batch.StartedAt)),
+	}
+	if err := batch.Err(); err != nil {
+		pw.logger.Warn("插件变更批次处理完成，部分插件失败", append(fields, zap.Error(err))...)
+		return
+	}
+	pw.logger.Info("插件变更批次处理完成", fields...)
+}
 
-	// 更新文件处理时间
+// removeTarget 处理插件文件、清单或源码目录的删除，注销对应的插件
+func (pw *PluginWatcher) removeTarget(batch *Batch, path string) {
 	pw.mu.Lock()
-	pw.watchedFiles[path] = time.Now()
+	delete(pw.watchedFiles, path)
 	pw.mu.Unlock()
-}
 
-// handlePluginRemoval 处理插件文件删除
-func (pw *PluginWatcher) handlePluginRemoval(path string) {
-	pluginName := getPluginNameFromPath(path)
+	pluginName := pw.pluginNameForPath(path)
 
 	pw.logger.Debug("处理插件文件删除",
-			zap.String("path", path),
-			zap.String("pluginName", pluginName))
+		zap.String("path", path),
+		zap.String("pluginName", pluginName))
 
 	// 检查插件是否已注册
-	if _, exists := pw.manager.GetPlugin(pluginName); exists {
-		// 注销插件
-		if err := pw.manager.Unregister(pluginName); err != nil {
-			pw.logger.Error("注销插件失败",
-				zap.String("pluginName", pluginName),
-				zap.Error(err))
-		} else {
-			pw.logger.Debug("插件已成功注销", zap.String("pluginName", pluginName))
+	if pluginName == "" {
+		return
+	}
+	if _, exists := pw.manager.GetPlugin(pluginName); !exists {
+		return
+	}
+
+	// 注销插件
+	err := pw.manager.Unregister(pluginName)
+	if err != nil {
+		pw.logger.Error("注销插件失败",
+			zap.String("pluginName", pluginName),
+			zap.Error(err))
+	} else {
+		pw.logger.Debug("插件已成功注销", zap.String("pluginName", pluginName))
+	}
+	batch.record(pluginName, ActionUnregister, err)
+}
+
+// markProcessed 记录路径处理时的最新修改时间，定期扫描据此发现监控遗漏的变更
+func (pw *PluginWatcher) markProcessed(target string) {
+	modTime := pw.targetModTime(target)
+	pw.mu.Lock()
+	pw.watchedFiles[target] = modTime
+	pw.mu.Unlock()
+}
+
+// targetModTime 返回映射到该路径的全部文件中最新的修改时间
+func (pw *PluginWatcher) targetModTime(target string) time.Time {
+	if filepath.Ext(target) == ".go" {
+		if info, err := os.Stat(target); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+
+	root := target
+	if filepath.Base(target) == core.ManifestFileName {
+		root = filepath.Dir(target)
+	}
+	var latest time.Time
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil
+		}
+		if pw.ignored(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
+		if d.IsDir() {
+			return nil
+		}
+		if mapped, ok := pw.eventTarget(path); !ok || mapped != target {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest
+}
+
+// pluginNameForPath 获取路径对应的插件名称
+// 插件清单使用清单中声明的名称（未成功解析过时使用目录名），源码目录按目录名匹配已注册的插件，其他文件使用文件名
+func (pw *PluginWatcher) pluginNameForPath(path string) string {
+	if isSourceDirTarget(path) {
+		return pw.dirPluginName(path)
+	}
+	if filepath.Base(path) != core.ManifestFileName {
+		return getPluginNameFromPath(path)
 	}
+
+	pw.manifestMu.Lock()
+	defer pw.manifestMu.Unlock()
+	if name, exists := pw.manifests[path]; exists {
+		delete(pw.manifests, path)
+		return name
+	}
+	return filepath.Base(filepath.Dir(path))
+}
+
+// dirPluginName 返回源码目录对应的已注册插件，没有时返回空字符串
+// 目录名与插件名称相同，或忽略大小写、下划线和连字符后相同（如FormatConverter对应format_converter）
+func (pw *PluginWatcher) dirPluginName(dir string) string {
+	base := filepath.Base(dir)
+	if _, exists := pw.manager.GetPlugin(base); exists {
+		return base
+	}
+	lister, ok := pw.manager.(PluginLister)
+	if !ok {
+		return ""
+	}
+	names := lister.ListPlugins()
+	sort.Strings(names)
+	for _, name := range names {
+		if normalizePluginName(name) == normalizePluginName(base) {
+			return name
+		}
+	}
+	return ""
+}
+
+// eventTarget 将文件映射为待处理的路径
+// 带清单的插件目录（包括其子目录）中的文件映射为该目录的清单；插件目录下的.go文件映射为自身；
+// 没有清单的子目录中的.go文件映射为所在目录。测试文件不触发处理
+func (pw *PluginWatcher) eventTarget(path string) (string, bool) {
+	if filepath.Base(path) == core.ManifestFileName {
+		return path, true
+	}
+	if strings.HasSuffix(path, "_test.go") {
+		return "", false
+	}
+	dir := filepath.Dir(path)
+	if dir == pw.pluginDir {
+		if filepath.Ext(path) == ".go" {
+			return path, true
+		}
+		return "", false
+	}
+	if manifestDir := pw.manifestDirOf(dir); manifestDir != "" {
+		return filepath.Join(manifestDir, core.ManifestFileName), true
+	}
+	if filepath.Ext(path) == ".go" {
+		return dir, true
+	}
+	return "", false
+}
+
+// manifestDirOf 从dir向上查找带清单的插件目录（不含插件根目录），没有时返回空字符串
+func (pw *PluginWatcher) manifestDirOf(dir string) string {
+	for dir != pw.pluginDir && strings.HasPrefix(dir, pw.pluginDir+string(filepath.Separator)) {
+		if _, err := os.Stat(filepath.Join(dir, core.ManifestFileName)); err == nil {
+			return dir
+		}
+		dir = filepath.Dir(dir)
+	}
+	return ""
+}
+
+// ignored 判断是否忽略该路径：临时文件、插件目录之外以及以.开头的文件或目录（如编译产物目录.build）
+func (pw *PluginWatcher) ignored(path string) bool {
+	if isTempFile(path) {
+		return true
+	}
+	rel, err := filepath.Rel(pw.pluginDir, path)
+	if err != nil {
+		return true
+	}
+	if rel == "." {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// watchTree 将目录及其各级子目录加入监控，并处理其中已有的文件
+func (pw *PluginWatcher) watchTree(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if pw.ignored(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			pw.watchDir(path)
+			return nil
+		}
+		if target, ok := pw.eventTarget(path); ok {
+			pw.enqueue(target)
+		}
+		return nil
+	})
+}
+
+// watchDir 将目录加入监控
+func (pw *PluginWatcher) watchDir(dir string) {
+	if err := pw.watcher.Add(dir); err != nil {
+		pw.logger.Warn("添加插件目录到监控失败", zap.String("dir", dir), zap.Error(err))
+	}
+}
+
+// isSourceDirTarget 判断路径是否为没有清单的源码目录
+func isSourceDirTarget(target string) bool {
+	return filepath.Base(target) != core.ManifestFileName && filepath.Ext(target) != ".go"
+}
+
+// unitExists 判断待处理的路径是否仍然存在，源码目录需要仍有.go文件
+func unitExists(target string) bool {
+	info, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return loader.HasGoSources(target)
+	}
+	return true
+}
+
+// normalizePluginName 忽略大小写、下划线和连字符，用于目录名与插件名称的匹配
+func normalizePluginName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
 }
 
 // getPluginNameFromPath 从文件路径中提取插件名称
@@ -353,16 +1016,16 @@ func isDirectory(path string) bool {
 	return fileInfo.IsDir()
 }
 
-// tryLoadNewPlugin 尝试动态加载新插件
-func (pw *PluginWatcher) tryLoadNewPlugin(pluginName string) {
+// tryLoadNewPlugin 尝试动态加载新插件，没有对应的编译产物时返回errPluginFileNotFound
+func (pw *PluginWatcher) tryLoadNewPlugin(pluginName string) error {
 	// 检查.so文件是否存在
 	soPath := loader.GetPluginPath(pw.pluginDir, pluginName)
 	if _, err := os.Stat(soPath); os.IsNotExist(err) {
 		pw.logger.Debug("插件编译文件不存在，跳过加载",
-				zap.String("pluginName", pluginName),
-				zap.String("expectedPath", soPath))
+			zap.String("pluginName", pluginName),
+			zap.String("expectedPath", soPath))
 		metrics.RecordPluginError(pluginName, "plugin_file_not_found")
-		return
+		return errPluginFileNotFound
 	}
 
 	// 尝试加载插件
@@ -372,47 +1035,28 @@ func (pw *PluginWatcher) tryLoadNewPlugin(pluginName string) {
 			zap.String("pluginName", pluginName),
 			zap.Error(err))
 		metrics.RecordPluginError(pluginName, "dynamic_load_failed")
-		return
+		return err
 	}
 
 	// 注册插件
-	if err := pw.manager.Register(pluginInstance); err != nil {
+	if err := pw.register(pluginInstance, nil); err != nil {
 		pw.logger.Error("注册插件失败",
 			zap.String("pluginName", pluginName),
 			zap.Error(err))
 		metrics.RecordPluginError(pluginName, "hot_register_failed")
 		// 加载失败，卸载插件
 		pw.loader.UnloadPlugin(pluginName)
-		return
+		return err
 	}
 
 	pw.logger.Debug("插件已成功动态加载并注册", zap.String("pluginName", pluginName))
+	return nil
 }
 
 // PluginManifest 插件清单结构，用于描述插件信息
-type PluginManifest struct {
-	Name              string   `json:"name"`
-	Description       string   `json:"description"`
-	Version           string   `json:"version"`
-	Author            string   `json:"author"`
-	Dependencies      []string `json:"dependencies"`
-	Conflicts         []string `json:"conflicts"`
-	EntryPoint        string   `json:"entry_point"`
-	BuildTags         []string `json:"build_tags"`
-	RequiredGoVersion string   `json:"required_go_version"`
-}
+type PluginManifest = core.PluginManifest
 
 // LoadPluginManifest 加载插件清单文件
 func LoadPluginManifest(manifestPath string) (*PluginManifest, error) {
-	data, err := ioutil.ReadFile(manifestPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取插件清单失败: %w", err)
-	}
-
-	manifest := &PluginManifest{}
-	if err := json.Unmarshal(data, manifest); err != nil {
-		return nil, fmt.Errorf("解析插件清单失败: %w", err)
-	}
-
-	return manifest, nil
+	return core.LoadPluginManifest(manifestPath)
 }
@@ -10,6 +10,7 @@ import (
 
 	"weave/config"
 	"weave/pkg"
+	"weave/plugins/core"
 	"weave/plugins/loader"
 )
 
@@ -181,7 +182,7 @@ func TestHandlePluginRemovalUnregisters(t *testing.T) {
 		t.Fatalf("new watcher error: %v", err)
 	}
 	path := filepath.Join(d, "gone.go")
-	pw.handlePluginRemoval(path)
+	pw.handlePluginChange(path)
 	if len(sm.unregistered) != 1 || sm.unregistered[0] != "gone" {
 		t.Fatalf("expected 'gone' to be unregistered, got %#v", sm.unregistered)
 	}
@@ -375,8 +376,8 @@ func TestPluginWatcher_StopConcurrency(t *testing.T) {
 	t.Log("Concurrency test completed without errors")
 }
 
-// TestProcessQueue_FileDeleted 测试文件在队列处理前被删除的情况
-func TestProcessQueue_FileDeleted(t *testing.T) {
+// TestProcessBatch_FileDeleted 测试文件在批次处理前被删除的情况
+func TestProcessBatch_FileDeleted(t *testing.T) {
 	d := t.TempDir()
 	manager := newStubManager()
 	logger := pkg.GetLogger()
@@ -409,11 +410,11 @@ func TestProcessQueue_FileDeleted(t *testing.T) {
 		t.Fatalf("remove temp file error: %v", err)
 	}
 
-	// 将文件路径发送到处理队列
-	pw.processChan <- tempFile
-
-	// 等待处理完成
-	time.Sleep(100 * time.Millisecond)
+	// 处理包含该文件的批次
+	batch := pw.processBatch([]string{tempFile})
+	if len(batch.Results) != 1 || batch.Results[0].Action != ActionUnregister {
+		t.Fatalf("expected unregister result, got %+v", batch.Results)
+	}
 
 	// 验证插件已从manager中注销
 	_, exists := manager.plugins[pluginName]
@@ -440,9 +441,9 @@ func TestHandlePluginRemoval_UnregisterError(t *testing.T) {
 		t.Fatalf("new watcher error: %v", err)
 	}
 
-	// 调用handlePluginRemoval
+	// 处理已删除的插件文件
 	path := filepath.Join(d, pluginName+".go")
-	pw.handlePluginRemoval(path)
+	pw.handlePluginChange(path)
 
 	// 验证文件从watchedFiles中移除（这里我们只是确保测试能通过，因为我们没有直接访问watchedFiles的方式）
 	// 主要目的是覆盖错误处理路径
@@ -467,3 +468,223 @@ func TestScanPluginDir_EmptyDir(t *testing.T) {
 		t.Fatalf("expected no registered plugins, got %#v", manager.registered)
 	}
 }
+
+// writeManifest 在插件目录下写入plugin.json，返回清单路径
+func writeManifest(t *testing.T, root, dir, content string) string {
+	t.Helper()
+	pluginDir := filepath.Join(root, dir)
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatalf("mkdir plugin dir: %v", err)
+	}
+	path := filepath.Join(pluginDir, core.ManifestFileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	return path
+}
+
+// TestScanPluginDir_DiscoversManifestDirs 测试扫描发现带清单的插件目录
+func TestScanPluginDir_DiscoversManifestDirs(t *testing.T) {
+	d := t.TempDir()
+	pw, err := NewPluginWatcher(d, newStubManager(), pkg.GetLogger())
+	if err != nil {
+		t.Fatalf("new watcher error: %v", err)
+	}
+	manifestPath := writeManifest(t, d, "alpha", `{"name": "alpha", "version": "1.0.0"}`)
+	// 没有清单的目录不是插件目录
+	if err := os.MkdirAll(filepath.Join(d, "assets"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	pw.scanPluginDir()
+
+	if len(pw.pending) != 1 || !pw.pending[manifestPath] {
+		t.Fatalf("expected only manifest %s queued, got %v", manifestPath, pw.pending)
+	}
+}
+
+// TestHandleManifestChange_InvalidManifest 测试无效清单不会触发加载
+func TestHandleManifestChange_InvalidManifest(t *testing.T) {
+	d := t.TempDir()
+	sm := newStubManager()
+	sm.plugins["bad"] = true
+	pw, err := NewPluginWatcher(d, sm, pkg.GetLogger())
+	if err != nil {
+		t.Fatalf("new watcher error: %v", err)
+	}
+	config.Config.Plugins.HotReload = true
+
+	pw.handlePluginChange(writeManifest(t, d, "bad", `{"name": "bad", "version": "abc"}`))
+	pw.handlePluginChange(writeManifest(t, d, "broken", `{`))
+	if len(sm.reloaded) != 0 || len(sm.registered) != 0 {
+		t.Fatalf("expected invalid manifests ignored, got reloaded=%v registered=%v", sm.reloaded, sm.registered)
+	}
+}
+
+// TestHandleManifestChange_ReloadAndRemoval 测试清单变更重新加载插件，删除清单时按清单声明的名称注销
+func TestHandleManifestChange_ReloadAndRemoval(t *testing.T) {
+	d := t.TempDir()
+	sm := newStubManager()
+	sm.plugins["declared"] = true
+	pw, err := NewPluginWatcher(d, sm, pkg.GetLogger())
+	if err != nil {
+		t.Fatalf("new watcher error: %v", err)
+	}
+	config.Config.Plugins.HotReload = true
+
+	path := writeManifest(t, d, "folder", `{"name": "declared", "version": "1.0.0"}`)
+	pw.handlePluginChange(path)
+	if len(sm.reloaded) != 1 || sm.reloaded[0] != "declared" {
+		t.Fatalf("expected 'declared' reloaded, got %v", sm.reloaded)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+	pw.handlePluginChange(path)
+	if len(sm.unregistered) != 1 || sm.unregistered[0] != "declared" {
+		t.Fatalf("expected 'declared' unregistered, got %v", sm.unregistered)
+	}
+}
+
+// TestHandlePluginChange_BuildFailureKeepsPlugin 测试编译失败时记录编译状态，已注册的插件保持运行
+func TestHandlePluginChange_BuildFailureKeepsPlugin(t *testing.T) {
+	d := t.TempDir()
+	goPath := filepath.Join(d, "hot.go")
+	if err := os.WriteFile(goPath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("write go error: %v", err)
+	}
+	sm := newStubManager()
+	sm.plugins["hot"] = true
+	pw, err := NewPluginWatcher(d, sm, pkg.GetLogger())
+	if err != nil {
+		t.Fatalf("new watcher error: %v", err)
+	}
+	config.Config.Plugins.HotReload = true
+	// 不存在的go命令，编译必然失败
+	pw.SetBuilder(loader.NewPluginBuilder(loader.BuildConfig{
+		Toolchain: filepath.Join(d, "missing-go"),
+		OutputDir: filepath.Join(d, ".build"),
+	}, pkg.GetLogger()))
+
+	pw.handlePluginChange(goPath)
+
+	status, ok := pw.Builder().Status("hot")
+	if !ok || status.Status != loader.BuildFailed || status.Error == "" {
+		t.Fatalf("expected failed build status, got %+v", status)
+	}
+	if len(sm.reloaded) != 0 || len(sm.unregistered) != 0 || len(sm.registered) != 0 {
+		t.Fatalf("expected running plugin untouched, got reloaded=%v unregistered=%v registered=%v", sm.reloaded, sm.unregistered, sm.registered)
+	}
+}
+
+// listingManager 支持按目录名匹配已注册插件的stubManager
+type listingManager struct {
+	*stubManager
+	mu sync.Mutex
+}
+
+func (lm *listingManager) ListPlugins() []string {
+	names := make([]string, 0, len(lm.plugins))
+	for name := range lm.plugins {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (lm *listingManager) ReloadPlugin(name string) error {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	return lm.stubManager.ReloadPlugin(name)
+}
+
+func (lm *listingManager) reloadedPlugins() []string {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	return append([]string(nil), lm.reloaded...)
+}
+
+// TestEventTarget_RecursiveMapping 测试子目录中的文件映射到插件文件、清单或源码目录
+func TestEventTarget_RecursiveMapping(t *testing.T) {
+	d := t.TempDir()
+	pw, err := NewPluginWatcher(d, newStubManager(), pkg.GetLogger())
+	if err != nil {
+		t.Fatalf("new watcher error: %v", err)
+	}
+	manifestPath := writeManifest(t, d, "alpha", `{"name": "alpha", "version": "1.0.0"}`)
+	noteDir := filepath.Join(d, "features", "Note")
+
+	cases := []struct {
+		path   string
+		target string
+	}{
+		{filepath.Join(d, "hot.go"), filepath.Join(d, "hot.go")},
+		{filepath.Join(d, "hot.so"), ""},
+		{filepath.Join(d, "alpha", "web", "index.html"), manifestPath},
+		{filepath.Join(d, "alpha", "alpha.go"), manifestPath},
+		{filepath.Join(noteDir, "note.go"), noteDir},
+		{filepath.Join(noteDir, "handler.go"), noteDir},
+		{filepath.Join(noteDir, "note_test.go"), ""},
+		{filepath.Join(noteDir, "README.md"), ""},
+	}
+	for _, tc := range cases {
+		target, ok := pw.eventTarget(tc.path)
+		if ok != (tc.target != "") || target != tc.target {
+			t.Errorf("eventTarget(%s) = %q, %v; want %q", tc.path, target, ok, tc.target)
+		}
+	}
+	if !pw.ignored(filepath.Join(d, ".build", "alpha", "alpha.so")) {
+		t.Errorf("expected build output directory ignored")
+	}
+}
+
+// TestWatcher_CoalescesDirectoryChanges 测试同一源码目录中多个文件的变更合并为一次重新加载
+func TestWatcher_CoalescesDirectoryChanges(t *testing.T) {
+	config.Config.Plugins.HotReload = true
+	config.Config.Plugins.Debounce = 100
+	defer func() { config.Config.Plugins.Debounce = 0 }()
+
+	d := t.TempDir()
+	noteDir := filepath.Join(d, "features", "Note")
+	if err := os.MkdirAll(noteDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := []string{filepath.Join(noteDir, "note.go"), filepath.Join(noteDir, "handler.go")}
+	for _, path := range files {
+		if err := os.WriteFile(path, []byte("package note\n"), 0644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+	}
+
+	lm := &listingManager{stubManager: newStubManager()}
+	lm.plugins["note"] = true
+	pw, err := NewPluginWatcher(d, lm, pkg.GetLogger())
+	if err != nil {
+		t.Fatalf("new watcher error: %v", err)
+	}
+	if err := pw.Start(); err != nil {
+		t.Fatalf("start watcher error: %v", err)
+	}
+	defer pw.Stop()
+
+	// 启动时发现的源码目录随服务编译，不重新加载
+	time.Sleep(300 * time.Millisecond)
+	if reloaded := lm.reloadedPlugins(); len(reloaded) != 0 {
+		t.Fatalf("expected no reload on initial scan, got %v", reloaded)
+	}
+
+	for _, path := range files {
+		if err := os.WriteFile(path, []byte("package note\n// change\n"), 0644); err != nil {
+			t.Fatalf("rewrite source: %v", err)
+		}
+	}
+	time.Sleep(600 * time.Millisecond)
+
+	if reloaded := lm.reloadedPlugins(); len(reloaded) != 1 || reloaded[0] != "note" {
+		t.Fatalf("expected a single reload of 'note', got %v", reloaded)
+	}
+	batch := pw.LastBatch()
+	if batch == nil || len(batch.Targets) != 1 || batch.Targets[0] != noteDir || batch.Err() != nil {
+		t.Fatalf("unexpected last batch: %+v", batch)
+	}
+}
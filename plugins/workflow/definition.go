@@ -0,0 +1,287 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 工作流定义的限制
+const (
+	MaxNodes           = 100              // 单个工作流的最大节点数
+	MaxRetries         = 10               // 单个节点的最大重试次数
+	DefaultTimeout     = 10 * time.Minute // 工作流未声明超时时的整体超时
+	DefaultMaxParallel = 10               // 扇出节点未声明并发数时的最大并发
+)
+
+// nodeIDPattern 节点ID只能包含字母、数字、下划线和中划线，以便在模板中引用
+var nodeIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// Definition 工作流定义
+type Definition struct {
+	Timeout string                 `json:"timeout,omitempty" yaml:"timeout"` // 整体超时，如"5m"，默认10分钟
+	Nodes   []Node                 `json:"nodes" yaml:"nodes"`
+	Output  map[string]interface{} `json:"output,omitempty" yaml:"output"` // 运行输出模板，未声明时输出末端节点的输出
+
+	timeout time.Duration
+}
+
+// Node 工作流节点，每个节点调用一次插件的Execute（扇出节点对每一项调用一次）
+type Node struct {
+	ID          string                 `json:"id" yaml:"id"`
+	Plugin      string                 `json:"plugin" yaml:"plugin"`
+	Params      map[string]interface{} `json:"params,omitempty" yaml:"params"`             // 参数模板，字符串中的{{ 表达式 }}在执行前替换
+	DependsOn   []string               `json:"depends_on,omitempty" yaml:"depends_on"`     // 依赖的节点，全部结束后才执行
+	When        string                 `json:"when,omitempty" yaml:"when"`                 // 执行条件，不满足时跳过该节点
+	ForEach     string                 `json:"for_each,omitempty" yaml:"for_each"`         // 扇出：表达式结果为数组时对每一项执行一次
+	MaxParallel int                    `json:"max_parallel,omitempty" yaml:"max_parallel"` // 扇出的最大并发数
+	Retries     int                    `json:"retries,omitempty" yaml:"retries"`           // 失败后的重试次数
+	RetryDelay  string                 `json:"retry_delay,omitempty" yaml:"retry_delay"`   // 重试间隔，如"2s"
+	Timeout     string                 `json:"timeout,omitempty" yaml:"timeout"`           // 单次执行超时，未声明时使用插件的执行超时
+
+	retryDelay time.Duration
+	timeout    time.Duration
+}
+
+// ParseDefinition 解析YAML或JSON格式的工作流定义并校验
+func ParseDefinition(source string) (*Definition, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("工作流定义不能为空")
+	}
+
+	// YAML兼容JSON，统一按YAML解析后转换为JSON结构，保证嵌套的map键为字符串
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(source), &raw); err != nil {
+		return nil, fmt.Errorf("解析工作流定义失败: %w", err)
+	}
+	data, err := json.Marshal(normalizeYAML(raw))
+	if err != nil {
+		return nil, fmt.Errorf("解析工作流定义失败: %w", err)
+	}
+
+	var def Definition
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("解析工作流定义失败: %w", err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// normalizeYAML 将yaml.v2解析出的map[interface{}]interface{}转换为map[string]interface{}
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return result
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Validate 校验工作流定义：节点ID唯一、依赖存在且无环、时间格式正确、表达式只引用上游节点
+func (d *Definition) Validate() error {
+	if len(d.Nodes) == 0 {
+		return fmt.Errorf("工作流至少需要一个节点")
+	}
+	if len(d.Nodes) > MaxNodes {
+		return fmt.Errorf("工作流节点数不能超过 %d", MaxNodes)
+	}
+
+	timeout, err := parseDuration("timeout", d.Timeout)
+	if err != nil {
+		return err
+	}
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	d.timeout = timeout
+
+	index := make(map[string]int, len(d.Nodes))
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		if !nodeIDPattern.MatchString(node.ID) {
+			return fmt.Errorf("第 %d 个节点的ID '%s' 无效，只能包含字母、数字、下划线和中划线，且以字母开头", i+1, node.ID)
+		}
+		if _, exists := index[node.ID]; exists {
+			return fmt.Errorf("节点ID '%s' 重复", node.ID)
+		}
+		index[node.ID] = i
+
+		if node.Plugin == "" {
+			return fmt.Errorf("节点 '%s' 未指定插件", node.ID)
+		}
+		if node.Retries < 0 || node.Retries > MaxRetries {
+			return fmt.Errorf("节点 '%s' 的重试次数必须在 0-%d 之间", node.ID, MaxRetries)
+		}
+		if node.MaxParallel < 0 {
+			return fmt.Errorf("节点 '%s' 的并发数不能为负数", node.ID)
+		}
+		if node.MaxParallel > 0 && node.ForEach == "" {
+			return fmt.Errorf("节点 '%s' 未声明for_each，不能设置max_parallel", node.ID)
+		}
+		if node.retryDelay, err = parseDuration(fmt.Sprintf("节点 '%s' 的retry_delay", node.ID), node.RetryDelay); err != nil {
+			return err
+		}
+		if node.timeout, err = parseDuration(fmt.Sprintf("节点 '%s' 的timeout", node.ID), node.Timeout); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range d.Nodes {
+		for _, dep := range node.DependsOn {
+			if _, exists := index[dep]; !exists {
+				return fmt.Errorf("节点 '%s' 依赖的节点 '%s' 不存在", node.ID, dep)
+			}
+			if dep == node.ID {
+				return fmt.Errorf("节点 '%s' 不能依赖自身", node.ID)
+			}
+		}
+	}
+	if cycle := d.findCycle(index); cycle != nil {
+		return fmt.Errorf("工作流存在循环依赖: %s", strings.Join(cycle, " -> "))
+	}
+
+	// 表达式只能引用上游节点，否则执行时引用的节点可能尚未结束
+	for _, node := range d.Nodes {
+		upstream := d.upstream(node.ID, index)
+		check := func(field, expr string) error {
+			for _, ref := range nodeRefs(expr) {
+				if _, exists := index[ref]; !exists {
+					return fmt.Errorf("节点 '%s' 的%s引用了不存在的节点 '%s'", node.ID, field, ref)
+				}
+				if !upstream[ref] {
+					return fmt.Errorf("节点 '%s' 的%s引用了节点 '%s'，需要在depends_on中直接或间接依赖该节点", node.ID, field, ref)
+				}
+			}
+			return nil
+		}
+		if err := check("when", node.When); err != nil {
+			return err
+		}
+		if err := check("for_each", node.ForEach); err != nil {
+			return err
+		}
+		for _, expr := range templateExprs(node.Params) {
+			if err := check("params", expr); err != nil {
+				return err
+			}
+		}
+	}
+	for _, expr := range templateExprs(d.Output) {
+		for _, ref := range nodeRefs(expr) {
+			if _, exists := index[ref]; !exists {
+				return fmt.Errorf("output引用了不存在的节点 '%s'", ref)
+			}
+		}
+	}
+	return nil
+}
+
+// parseDuration 解析时间配置，空字符串表示未设置
+func parseDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%s '%s' 无效，应为如 30s、5m 的时间长度", field, value)
+	}
+	return duration, nil
+}
+
+// findCycle 查找循环依赖，返回环上的节点ID
+func (d *Definition) findCycle(index map[string]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(d.Nodes))
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range d.Nodes[index[id]].DependsOn {
+			switch state[dep] {
+			case visiting:
+				for i, p := range path {
+					if p == dep {
+						return append(append([]string{}, path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+
+	for _, node := range d.Nodes {
+		if state[node.ID] == unvisited {
+			if cycle := visit(node.ID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// upstream 返回节点直接或间接依赖的全部节点
+func (d *Definition) upstream(id string, index map[string]int) map[string]bool {
+	result := make(map[string]bool)
+	stack := append([]string{}, d.Nodes[index[id]].DependsOn...)
+	for len(stack) > 0 {
+		dep := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if result[dep] {
+			continue
+		}
+		result[dep] = true
+		stack = append(stack, d.Nodes[index[dep]].DependsOn...)
+	}
+	return result
+}
+
+// sinks 返回没有被其他节点依赖的节点ID
+func (d *Definition) sinks() []string {
+	depended := make(map[string]bool)
+	for _, node := range d.Nodes {
+		for _, dep := range node.DependsOn {
+			depended[dep] = true
+		}
+	}
+	var result []string
+	for _, node := range d.Nodes {
+		if !depended[node.ID] {
+			result = append(result, node.ID)
+		}
+	}
+	return result
+}
@@ -0,0 +1,530 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"weave/pkg"
+	"weave/plugins/core"
+
+	"go.uber.org/zap"
+)
+
+// Status 工作流运行和节点的状态
+type Status string
+
+const (
+	StatusRunning   Status = "running"   // 执行中
+	StatusSucceeded Status = "succeeded" // 执行成功
+	StatusFailed    Status = "failed"    // 执行失败
+	StatusSkipped   Status = "skipped"   // 节点条件不满足，已跳过
+	StatusCanceled  Status = "canceled"  // 其他节点失败或运行超时，节点未执行或被中断
+)
+
+// errUpstreamFailed 运行中其他节点失败时取消执行上下文的原因
+var errUpstreamFailed = errors.New("其他节点执行失败，运行已终止")
+
+// Run 工作流的一次运行
+type Run struct {
+	ID         uint                   `json:"id"`
+	WorkflowID uint                   `json:"workflow_id"`
+	TenantID   uint                   `json:"tenant_id"`
+	UserID     uint                   `json:"user_id"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Status     Status                 `json:"status"`
+	Input      map[string]interface{} `json:"input"`
+	Output     interface{}            `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Steps      []*Step                `json:"steps"`
+}
+
+// Step 节点在一次运行中的执行记录
+type Step struct {
+	ID         uint        `json:"id"`
+	RunID      uint        `json:"run_id"`
+	NodeID     string      `json:"node_id"`
+	Plugin     string      `json:"plugin"`
+	Status     Status      `json:"status"`
+	Params     interface{} `json:"params,omitempty"` // 渲染后的参数，扇出节点为各项参数的数组
+	Output     interface{} `json:"output,omitempty"` // 插件返回值，扇出节点为各项返回值的数组
+	Error      string      `json:"error,omitempty"`
+	Attempts   int         `json:"attempts"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Executor 执行插件的接口，由core.PluginManager实现
+type Executor interface {
+	ExecutePluginContext(ctx context.Context, name string, params map[string]interface{}) (interface{}, error)
+}
+
+// RunStore 运行记录的持久化存储
+type RunStore interface {
+	// CreateRun 保存新运行记录并填充ID
+	CreateRun(ctx context.Context, run *Run) error
+	// SaveStep 保存节点执行记录，ID为0时新建并填充ID
+	SaveStep(ctx context.Context, step *Step) error
+	// FinishRun 保存运行结果
+	FinishRun(ctx context.Context, run *Run) error
+}
+
+// Engine 工作流执行引擎
+// 按依赖关系并发执行节点，每个节点通过Executor调用插件，执行过程逐步写入RunStore
+type Engine struct {
+	executor Executor
+	store    RunStore
+}
+
+// NewEngine 创建工作流执行引擎
+func NewEngine(executor Executor, store RunStore) *Engine {
+	return &Engine{executor: executor, store: store}
+}
+
+// execution 一次运行的执行状态
+type execution struct {
+	engine *Engine
+	def    *Definition
+	run    *Run
+	index  map[string]int
+
+	mu    sync.Mutex
+	nodes map[string]interface{} // 已结束节点的状态和输出，供表达式引用
+	steps map[string]*Step
+}
+
+// nodeResult 节点执行结束的通知
+type nodeResult struct {
+	id     string
+	status Status
+	err    error
+}
+
+// Run 执行工作流并等待结束
+// 调用方的用户、租户和请求ID从上下文的RequestMeta中读取并传给各节点；
+// 节点失败不会返回错误，而是体现在运行记录的状态中，只有保存运行记录失败时返回错误
+func (e *Engine) Run(ctx context.Context, workflowID uint, def *Definition, input map[string]interface{}) (*Run, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if input == nil {
+		input = make(map[string]interface{})
+	}
+	normalizedInput, err := normalize(input)
+	if err != nil {
+		return nil, fmt.Errorf("运行输入无法序列化: %w", err)
+	}
+
+	meta, _ := core.RequestMetaFromContext(ctx)
+	run := &Run{
+		WorkflowID: workflowID,
+		TenantID:   meta.TenantID,
+		UserID:     meta.UserID,
+		RequestID:  meta.RequestID,
+		Status:     StatusRunning,
+		Input:      input,
+		StartedAt:  time.Now(),
+		Steps:      make([]*Step, 0, len(def.Nodes)),
+	}
+	if err := e.store.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	x := &execution{
+		engine: e,
+		def:    def,
+		run:    run,
+		index:  make(map[string]int, len(def.Nodes)),
+		nodes:  make(map[string]interface{}, len(def.Nodes)),
+		steps:  make(map[string]*Step, len(def.Nodes)),
+	}
+	for i, node := range def.Nodes {
+		x.index[node.ID] = i
+	}
+
+	timeout := def.timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	runCtx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	defer cancelTimeout()
+	runCtx, cancel := context.WithCancelCause(runCtx)
+	defer cancel(nil)
+
+	failure := x.schedule(runCtx, cancel, normalizedInput)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	for _, node := range def.Nodes {
+		if step, ok := x.steps[node.ID]; ok {
+			run.Steps = append(run.Steps, step)
+		}
+	}
+	if failure != nil {
+		run.Status = StatusFailed
+		run.Error = failure.Error()
+	} else {
+		output, err := x.output(normalizedInput)
+		if err != nil {
+			run.Status = StatusFailed
+			run.Error = err.Error()
+		} else {
+			run.Status = StatusSucceeded
+			run.Output = output
+		}
+	}
+
+	// 调用方上下文已结束时仍需保存运行结果
+	if err := e.store.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		pkg.Warn("保存工作流运行结果失败", zap.Uint("workflow_id", workflowID), zap.Uint("run_id", run.ID), zap.Error(err))
+	}
+
+	fields := []zap.Field{
+		zap.Uint("workflow_id", workflowID),
+		zap.Uint("run_id", run.ID),
+		zap.String("status", string(run.Status)),
+		zap.Duration("duration", finishedAt.Sub(run.StartedAt)),
+	}
+	if run.Status == StatusFailed {
+		pkg.Warn("工作流运行失败", append(fields, zap.String("error", run.Error))...)
+	} else {
+		pkg.Info("工作流运行结束", fields...)
+	}
+	return run, nil
+}
+
+// schedule 按依赖关系调度节点，返回导致运行失败的错误
+// 节点的依赖全部结束（成功或跳过）后开始执行；任一节点失败后不再启动新节点，并取消执行中的节点
+func (x *execution) schedule(ctx context.Context, cancel context.CancelCauseFunc, input interface{}) error {
+	pending := make(map[string]int, len(x.def.Nodes))
+	dependents := make(map[string][]string, len(x.def.Nodes))
+	for _, node := range x.def.Nodes {
+		pending[node.ID] = len(node.DependsOn)
+		for _, dep := range node.DependsOn {
+			dependents[dep] = append(dependents[dep], node.ID)
+		}
+	}
+
+	results := make(chan nodeResult, len(x.def.Nodes))
+	started := make(map[string]bool, len(x.def.Nodes))
+	inFlight := 0
+	launch := func(id string) {
+		started[id] = true
+		inFlight++
+		go func() {
+			status, err := x.runNode(ctx, x.def.Nodes[x.index[id]], input)
+			results <- nodeResult{id: id, status: status, err: err}
+		}()
+	}
+
+	for _, node := range x.def.Nodes {
+		if pending[node.ID] == 0 {
+			launch(node.ID)
+		}
+	}
+
+	var failure error
+	for inFlight > 0 {
+		result := <-results
+		inFlight--
+
+		if result.status == StatusFailed && failure == nil {
+			failure = fmt.Errorf("节点 '%s' 执行失败: %w", result.id, result.err)
+			if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, errUpstreamFailed) {
+				failure = fmt.Errorf("工作流执行超时或被取消: %w", cause)
+			}
+			cancel(errUpstreamFailed)
+		}
+		if failure != nil {
+			continue
+		}
+		for _, next := range dependents[result.id] {
+			pending[next]--
+			if pending[next] == 0 {
+				launch(next)
+			}
+		}
+	}
+
+	// 运行失败时未启动的节点记录为已取消
+	if failure != nil {
+		now := time.Now()
+		for _, node := range x.def.Nodes {
+			if started[node.ID] {
+				continue
+			}
+			step := &Step{
+				RunID:      x.run.ID,
+				NodeID:     node.ID,
+				Plugin:     node.Plugin,
+				Status:     StatusCanceled,
+				Error:      errUpstreamFailed.Error(),
+				FinishedAt: &now,
+			}
+			x.saveStep(step)
+			x.mu.Lock()
+			x.steps[node.ID] = step
+			x.mu.Unlock()
+		}
+	}
+	return failure
+}
+
+// runNode 执行单个节点并记录执行过程
+func (x *execution) runNode(ctx context.Context, node Node, input interface{}) (Status, error) {
+	now := time.Now()
+	step := &Step{
+		RunID:     x.run.ID,
+		NodeID:    node.ID,
+		Plugin:    node.Plugin,
+		Status:    StatusRunning,
+		StartedAt: &now,
+	}
+	x.mu.Lock()
+	x.steps[node.ID] = step
+	x.mu.Unlock()
+
+	s := x.scope(input)
+	output, err := x.execute(ctx, node, step, s)
+
+	finishedAt := time.Now()
+	x.mu.Lock()
+	step.FinishedAt = &finishedAt
+	switch {
+	case errors.Is(err, errConditionFalse):
+		step.Status = StatusSkipped
+		step.Error = err.Error()
+		err = nil
+	case err != nil && errors.Is(context.Cause(ctx), errUpstreamFailed):
+		step.Status = StatusCanceled
+		step.Error = err.Error()
+	case err != nil:
+		step.Status = StatusFailed
+		step.Error = err.Error()
+	default:
+		step.Status = StatusSucceeded
+		step.Output = output
+	}
+	x.nodes[node.ID] = map[string]interface{}{"status": string(step.Status), "output": step.Output}
+	status := step.Status
+	x.mu.Unlock()
+
+	x.saveStep(step)
+	return status, err
+}
+
+// errConditionFalse 节点的执行条件不满足
+var errConditionFalse = errors.New("执行条件不满足")
+
+// execute 计算条件、渲染参数并调用插件，扇出节点对每一项调用一次
+func (x *execution) execute(ctx context.Context, node Node, step *Step, s scope) (interface{}, error) {
+	if node.When != "" {
+		ok, err := evalCondition(node.When, s)
+		if err != nil {
+			return nil, fmt.Errorf("计算执行条件失败: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errConditionFalse, node.When)
+		}
+	}
+
+	if node.ForEach == "" {
+		params, err := renderParams(node.Params, s)
+		if err != nil {
+			return nil, err
+		}
+		x.mu.Lock()
+		step.Params = params
+		x.mu.Unlock()
+		x.saveStep(step)
+		return x.call(ctx, node, step, params)
+	}
+
+	items, err := evalItems(node.ForEach, s)
+	if err != nil {
+		return nil, err
+	}
+	paramsList := make([]interface{}, len(items))
+	for i, item := range items {
+		itemScope := make(scope, len(s)+2)
+		for key, value := range s {
+			itemScope[key] = value
+		}
+		itemScope["item"] = item
+		itemScope["index"] = float64(i)
+		params, err := renderParams(node.Params, itemScope)
+		if err != nil {
+			return nil, fmt.Errorf("渲染第 %d 项的参数失败: %w", i, err)
+		}
+		paramsList[i] = params
+	}
+	x.mu.Lock()
+	step.Params = paramsList
+	x.mu.Unlock()
+	x.saveStep(step)
+
+	return x.fanOut(ctx, node, step, paramsList)
+}
+
+// fanOut 并发执行扇出节点的各项，任一项失败时取消其余项，输出按项的顺序排列
+func (x *execution) fanOut(ctx context.Context, node Node, step *Step, paramsList []interface{}) (interface{}, error) {
+	maxParallel := node.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = DefaultMaxParallel
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	outputs := make([]interface{}, len(paramsList))
+	semaphore := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, params := range paramsList {
+		wg.Add(1)
+		go func(i int, params map[string]interface{}) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-semaphore }()
+
+			output, err := x.call(ctx, node, step, params)
+			if err != nil {
+				cancel(fmt.Errorf("第 %d 项执行失败: %w", i, err))
+				return
+			}
+			outputs[i] = output
+		}(i, params.(map[string]interface{}))
+	}
+	wg.Wait()
+
+	if cause := context.Cause(ctx); cause != nil {
+		return nil, cause
+	}
+	return outputs, nil
+}
+
+// call 调用插件，失败后按节点配置重试
+func (x *execution) call(ctx context.Context, node Node, step *Step, params map[string]interface{}) (interface{}, error) {
+	var lastErr error
+	for attempt := 0; attempt <= node.Retries; attempt++ {
+		if attempt > 0 && node.retryDelay > 0 {
+			timer := time.NewTimer(node.retryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, lastErr
+			case <-timer.C:
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, context.Cause(ctx)
+		}
+
+		x.mu.Lock()
+		step.Attempts++
+		x.mu.Unlock()
+
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if node.timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, node.timeout)
+		}
+		result, err := x.engine.executor.ExecutePluginContext(callCtx, node.Plugin, params)
+		cancel()
+		if err == nil {
+			output, err := normalize(result)
+			if err != nil {
+				return nil, fmt.Errorf("插件返回值无法序列化: %w", err)
+			}
+			return output, nil
+		}
+		lastErr = err
+		if attempt < node.Retries {
+			pkg.Warn("工作流节点执行失败，准备重试",
+				zap.Uint("run_id", x.run.ID),
+				zap.String("node", node.ID),
+				zap.Int("attempt", attempt+1),
+				zap.Error(err))
+		}
+	}
+	return nil, lastErr
+}
+
+// scope 构建节点表达式可引用的数据快照
+func (x *execution) scope(input interface{}) scope {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	nodes := make(map[string]interface{}, len(x.nodes))
+	for id, state := range x.nodes {
+		nodes[id] = state
+	}
+	return scope{"input": input, "nodes": nodes}
+}
+
+// output 生成运行输出：声明了output模板时按模板渲染，否则为末端节点的输出
+func (x *execution) output(input interface{}) (interface{}, error) {
+	s := x.scope(input)
+	if x.def.Output != nil {
+		output, err := render(x.def.Output, s)
+		if err != nil {
+			return nil, fmt.Errorf("渲染运行输出失败: %w", err)
+		}
+		return output, nil
+	}
+	output := make(map[string]interface{})
+	for _, id := range x.def.sinks() {
+		if state, ok := s["nodes"].(map[string]interface{})[id].(map[string]interface{}); ok {
+			output[id] = state["output"]
+		}
+	}
+	return output, nil
+}
+
+// saveStep 保存节点执行记录，失败时只记录日志
+func (x *execution) saveStep(step *Step) {
+	x.mu.Lock()
+	snapshot := *step
+	x.mu.Unlock()
+
+	if err := x.engine.store.SaveStep(context.Background(), &snapshot); err != nil {
+		pkg.Warn("保存工作流节点执行记录失败", zap.Uint("run_id", x.run.ID), zap.String("node", step.NodeID), zap.Error(err))
+		return
+	}
+	x.mu.Lock()
+	step.ID = snapshot.ID
+	x.mu.Unlock()
+}
+
+// renderParams 渲染节点参数
+func renderParams(params map[string]interface{}, s scope) (map[string]interface{}, error) {
+	if params == nil {
+		return make(map[string]interface{}), nil
+	}
+	rendered, err := render(params, s)
+	if err != nil {
+		return nil, fmt.Errorf("渲染参数失败: %w", err)
+	}
+	return rendered.(map[string]interface{}), nil
+}
+
+// normalize 将插件返回值转换为JSON结构，便于在表达式中按路径读取和持久化
+func normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
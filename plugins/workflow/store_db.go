@@ -0,0 +1,246 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"weave/models"
+	"weave/pkg"
+
+	"gorm.io/gorm"
+)
+
+// DBRunStore 基于数据库的运行记录存储（workflow_runs、workflow_steps表）
+// DB为空时使用pkg.DB
+type DBRunStore struct {
+	DB *gorm.DB
+}
+
+// NewDBRunStore 创建数据库运行记录存储
+func NewDBRunStore(db *gorm.DB) *DBRunStore {
+	return &DBRunStore{DB: db}
+}
+
+// db 返回绑定上下文的数据库连接
+func (s *DBRunStore) db(ctx context.Context) (*gorm.DB, error) {
+	db := s.DB
+	if db == nil {
+		db = pkg.DB
+	}
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化，无法保存工作流运行记录")
+	}
+	return db.WithContext(context.WithoutCancel(ctx)), nil
+}
+
+// CreateRun 保存新运行记录
+func (s *DBRunStore) CreateRun(ctx context.Context, run *Run) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	input, err := marshalJSON(run.Input)
+	if err != nil {
+		return err
+	}
+	record := &models.WorkflowRun{
+		WorkflowID: run.WorkflowID,
+		TenantID:   run.TenantID,
+		UserID:     run.UserID,
+		RequestID:  run.RequestID,
+		Status:     string(run.Status),
+		Input:      input,
+		StartedAt:  run.StartedAt,
+	}
+	if err := db.Create(record).Error; err != nil {
+		return fmt.Errorf("保存工作流 %d 的运行记录失败: %w", run.WorkflowID, err)
+	}
+	run.ID = record.ID
+	return nil
+}
+
+// SaveStep 保存节点执行记录
+func (s *DBRunStore) SaveStep(ctx context.Context, step *Step) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	record, err := stepToRecord(step)
+	if err != nil {
+		return err
+	}
+	if err := db.Save(record).Error; err != nil {
+		return fmt.Errorf("保存节点 '%s' 的执行记录失败: %w", step.NodeID, err)
+	}
+	step.ID = record.ID
+	return nil
+}
+
+// FinishRun 保存运行结果
+func (s *DBRunStore) FinishRun(ctx context.Context, run *Run) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	output := ""
+	if run.Output != nil {
+		if output, err = marshalJSON(run.Output); err != nil {
+			return err
+		}
+	}
+	err = db.Model(&models.WorkflowRun{}).
+		Where("id = ?", run.ID).
+		Updates(map[string]interface{}{
+			"status":      string(run.Status),
+			"output":      output,
+			"error":       run.Error,
+			"finished_at": run.FinishedAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("保存工作流运行 %d 的结果失败: %w", run.ID, err)
+	}
+	return nil
+}
+
+// GetRun 读取运行记录及其节点执行记录，tenantID不为0时只读取该租户的记录
+func GetRun(db *gorm.DB, workflowID, runID, tenantID uint) (*Run, error) {
+	query := db.Where("id = ? AND workflow_id = ?", runID, workflowID)
+	if tenantID != 0 {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	var record models.WorkflowRun
+	if err := query.First(&record).Error; err != nil {
+		return nil, err
+	}
+	run, err := recordToRun(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []models.WorkflowStep
+	if err := db.Where("run_id = ?", runID).Order("id").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+	for i := range steps {
+		step, err := recordToStep(&steps[i])
+		if err != nil {
+			return nil, err
+		}
+		run.Steps = append(run.Steps, step)
+	}
+	return run, nil
+}
+
+// ListRuns 按开始时间倒序列出工作流的运行记录（不含节点执行记录）
+func ListRuns(db *gorm.DB, workflowID, tenantID uint, limit int) ([]*Run, error) {
+	query := db.Where("workflow_id = ?", workflowID).Order("id DESC")
+	if tenantID != 0 {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var records []models.WorkflowRun
+	if err := query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	runs := make([]*Run, 0, len(records))
+	for i := range records {
+		run, err := recordToRun(&records[i])
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// stepToRecord 将节点执行记录转换为数据库记录
+func stepToRecord(step *Step) (*models.WorkflowStep, error) {
+	record := &models.WorkflowStep{
+		ID:         step.ID,
+		RunID:      step.RunID,
+		NodeID:     step.NodeID,
+		Plugin:     step.Plugin,
+		Status:     string(step.Status),
+		Error:      step.Error,
+		Attempts:   step.Attempts,
+		StartedAt:  step.StartedAt,
+		FinishedAt: step.FinishedAt,
+	}
+	var err error
+	if step.Params != nil {
+		if record.Params, err = marshalJSON(step.Params); err != nil {
+			return nil, err
+		}
+	}
+	if step.Output != nil {
+		if record.Output, err = marshalJSON(step.Output); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// recordToRun 将数据库记录转换为运行记录
+func recordToRun(record *models.WorkflowRun) (*Run, error) {
+	run := &Run{
+		ID:         record.ID,
+		WorkflowID: record.WorkflowID,
+		TenantID:   record.TenantID,
+		UserID:     record.UserID,
+		RequestID:  record.RequestID,
+		Status:     Status(record.Status),
+		Error:      record.Error,
+		StartedAt:  record.StartedAt,
+		FinishedAt: record.FinishedAt,
+		Steps:      []*Step{},
+	}
+	if err := unmarshalJSON(record.Input, &run.Input); err != nil {
+		return nil, fmt.Errorf("解析工作流运行 %d 的输入失败: %w", record.ID, err)
+	}
+	if err := unmarshalJSON(record.Output, &run.Output); err != nil {
+		return nil, fmt.Errorf("解析工作流运行 %d 的输出失败: %w", record.ID, err)
+	}
+	return run, nil
+}
+
+// recordToStep 将数据库记录转换为节点执行记录
+func recordToStep(record *models.WorkflowStep) (*Step, error) {
+	step := &Step{
+		ID:         record.ID,
+		RunID:      record.RunID,
+		NodeID:     record.NodeID,
+		Plugin:     record.Plugin,
+		Status:     Status(record.Status),
+		Error:      record.Error,
+		Attempts:   record.Attempts,
+		StartedAt:  record.StartedAt,
+		FinishedAt: record.FinishedAt,
+	}
+	if err := unmarshalJSON(record.Params, &step.Params); err != nil {
+		return nil, fmt.Errorf("解析节点 '%s' 的参数失败: %w", record.NodeID, err)
+	}
+	if err := unmarshalJSON(record.Output, &step.Output); err != nil {
+		return nil, fmt.Errorf("解析节点 '%s' 的输出失败: %w", record.NodeID, err)
+	}
+	return step, nil
+}
+
+// marshalJSON 序列化为JSON字符串
+func marshalJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("序列化失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalJSON 解析JSON字符串，空字符串时不做处理
+func unmarshalJSON(data string, target interface{}) error {
+	if data == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(data), target)
+}
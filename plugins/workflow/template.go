@@ -0,0 +1,318 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templatePattern 参数中的模板表达式，如 {{ nodes.fetch.output.url }}
+var templatePattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// nodeRefPattern 表达式中对节点的引用
+var nodeRefPattern = regexp.MustCompile(`\bnodes\.([A-Za-z][A-Za-z0-9_-]*)`)
+
+// comparisonOperators 条件表达式支持的比较运算符，按长度优先匹配
+var comparisonOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// scope 表达式求值时可引用的数据
+// input为运行输入，nodes为已结束节点的状态和输出，扇出节点执行时还可以引用item和index
+type scope map[string]interface{}
+
+// templateExprs 收集参数中的全部模板表达式
+func templateExprs(value interface{}) []string {
+	var exprs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			for _, match := range templatePattern.FindAllStringSubmatch(v, -1) {
+				exprs = append(exprs, match[1])
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(value)
+	return exprs
+}
+
+// nodeRefs 返回表达式引用的节点ID
+func nodeRefs(expr string) []string {
+	var refs []string
+	for _, match := range nodeRefPattern.FindAllStringSubmatch(expr, -1) {
+		refs = append(refs, match[1])
+	}
+	return refs
+}
+
+// render 渲染参数模板
+// 字符串只包含一个表达式时保留表达式结果的类型，否则将结果拼接为字符串
+func render(value interface{}, s scope) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if match := templatePattern.FindStringSubmatchIndex(v); match != nil && match[0] == 0 && match[1] == len(v) {
+			return s.lookup(v[match[2]:match[3]])
+		}
+		var renderErr error
+		result := templatePattern.ReplaceAllStringFunc(v, func(m string) string {
+			value, err := s.lookup(templatePattern.FindStringSubmatch(m)[1])
+			if err != nil {
+				renderErr = err
+				return ""
+			}
+			return stringify(value)
+		})
+		return result, renderErr
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			rendered, err := render(item, s)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = rendered
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := render(item, s)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = rendered
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+// stringify 将表达式结果拼接到字符串中，非字符串按JSON格式输出
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// lookup 按路径读取数据，如 input.user.name、nodes.fetch.output.items.0
+// 路径的根必须存在，中间的键不存在时返回nil
+func (s scope) lookup(path string) (interface{}, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("表达式不能为空")
+	}
+	segments := strings.Split(path, ".")
+	current, exists := s[segments[0]]
+	if !exists {
+		return nil, fmt.Errorf("表达式 '%s' 无效，只能引用 input、nodes、item 或 index", path)
+	}
+	for _, segment := range segments[1:] {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[segment]
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, nil
+			}
+			current = v[i]
+		default:
+			return nil, nil
+		}
+	}
+	return current, nil
+}
+
+// unwrapExpr 去掉条件和扇出表达式外层可选的{{ }}
+func unwrapExpr(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if match := templatePattern.FindStringSubmatch(expr); match != nil && match[0] == expr {
+		return match[1]
+	}
+	return expr
+}
+
+// evalCondition 计算节点的执行条件
+// 支持单个操作数（按真值判断，可用!取反）或两个操作数的比较，操作数为路径或JSON字面量（字符串也可使用单引号）
+func evalCondition(expr string, s scope) (bool, error) {
+	expr = unwrapExpr(expr)
+	if expr == "" {
+		return true, nil
+	}
+
+	for _, op := range comparisonOperators {
+		if i := indexOutsideQuotes(expr, op); i > 0 {
+			left, err := s.operand(expr[:i])
+			if err != nil {
+				return false, err
+			}
+			right, err := s.operand(expr[i+len(op):])
+			if err != nil {
+				return false, err
+			}
+			return compare(left, right, op)
+		}
+	}
+
+	if negated, ok := strings.CutPrefix(expr, "!"); ok {
+		value, err := s.operand(negated)
+		if err != nil {
+			return false, err
+		}
+		return !truthy(value), nil
+	}
+	value, err := s.operand(expr)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// indexOutsideQuotes 查找不在引号内的运算符位置
+func indexOutsideQuotes(expr, op string) int {
+	var quote rune
+	for i, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case strings.HasPrefix(expr[i:], op):
+			// 避免将>=、<=、!=中的字符识别为单字符运算符
+			if (op == ">" || op == "<") && strings.HasPrefix(expr[i+1:], "=") {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// operand 解析条件表达式的操作数
+func (s scope) operand(token string) (interface{}, error) {
+	token = strings.TrimSpace(token)
+	if len(token) >= 2 && token[0] == '\'' && token[len(token)-1] == '\'' {
+		return token[1 : len(token)-1], nil
+	}
+	var literal interface{}
+	if err := json.Unmarshal([]byte(token), &literal); err == nil {
+		return literal, nil
+	}
+	return s.lookup(token)
+}
+
+// compare 比较两个操作数，数字按数值比较，其他类型只支持相等判断
+func compare(left, right interface{}, op string) (bool, error) {
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if lok && rok {
+		switch op {
+		case "==":
+			return lf == rf, nil
+		case "!=":
+			return lf != rf, nil
+		case ">":
+			return lf > rf, nil
+		case ">=":
+			return lf >= rf, nil
+		case "<":
+			return lf < rf, nil
+		case "<=":
+			return lf <= rf, nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return reflect.DeepEqual(left, right), nil
+	case "!=":
+		return !reflect.DeepEqual(left, right), nil
+	}
+	if ls, ok := left.(string); ok {
+		if rs, ok := right.(string); ok {
+			switch op {
+			case ">":
+				return ls > rs, nil
+			case ">=":
+				return ls >= rs, nil
+			case "<":
+				return ls < rs, nil
+			case "<=":
+				return ls <= rs, nil
+			}
+		}
+	}
+	return false, fmt.Errorf("无法使用 %s 比较 %v 和 %v", op, left, right)
+}
+
+// toFloat 将JSON数字转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// truthy 判断值是否为真：nil、false、0、空字符串、"false"和空集合为假
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false"
+	case float64:
+		return v != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+// evalItems 计算扇出表达式，结果必须为数组
+func evalItems(expr string, s scope) ([]interface{}, error) {
+	value, err := s.lookup(unwrapExpr(expr))
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("for_each表达式 '%s' 的结果不是数组", expr)
+	}
+}
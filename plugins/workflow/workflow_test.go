@@ -0,0 +1,349 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"weave/plugins/core"
+)
+
+// fakeExecutor 按插件名称调用测试函数的执行器
+type fakeExecutor struct {
+	mu      sync.Mutex
+	plugins map[string]func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+	calls   map[string]int
+}
+
+func newFakeExecutor() *fakeExecutor {
+	return &fakeExecutor{
+		plugins: make(map[string]func(ctx context.Context, params map[string]interface{}) (interface{}, error)),
+		calls:   make(map[string]int),
+	}
+}
+
+func (e *fakeExecutor) ExecutePluginContext(ctx context.Context, name string, params map[string]interface{}) (interface{}, error) {
+	e.mu.Lock()
+	fn, ok := e.plugins[name]
+	e.calls[name]++
+	e.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("插件 '%s' 不存在", name)
+	}
+	return fn(ctx, params)
+}
+
+func (e *fakeExecutor) callCount(name string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls[name]
+}
+
+// memoryRunStore 内存中的运行记录存储
+type memoryRunStore struct {
+	mu     sync.Mutex
+	runs   map[uint]Run
+	steps  map[uint]Step
+	nextID uint
+}
+
+func newMemoryRunStore() *memoryRunStore {
+	return &memoryRunStore{runs: make(map[uint]Run), steps: make(map[uint]Step)}
+}
+
+func (s *memoryRunStore) CreateRun(ctx context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	run.ID = s.nextID
+	s.runs[run.ID] = *run
+	return nil
+}
+
+func (s *memoryRunStore) SaveStep(ctx context.Context, step *Step) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if step.ID == 0 {
+		s.nextID++
+		step.ID = s.nextID
+	}
+	s.steps[step.ID] = *step
+	return nil
+}
+
+func (s *memoryRunStore) FinishRun(ctx context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = *run
+	return nil
+}
+
+// savedStep 返回节点最后保存的执行记录
+func (s *memoryRunStore) savedStep(runID uint, nodeID string) (Step, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, step := range s.steps {
+		if step.RunID == runID && step.NodeID == nodeID {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+func mustParse(t *testing.T, source string) *Definition {
+	t.Helper()
+	def, err := ParseDefinition(source)
+	if err != nil {
+		t.Fatalf("parse definition: %v", err)
+	}
+	return def
+}
+
+func stepsByNode(run *Run) map[string]*Step {
+	steps := make(map[string]*Step, len(run.Steps))
+	for _, step := range run.Steps {
+		steps[step.NodeID] = step
+	}
+	return steps
+}
+
+func TestParseDefinition(t *testing.T) {
+	yamlDef := mustParse(t, `
+timeout: 1m
+nodes:
+  - id: fetch
+    plugin: fetcher
+    params:
+      url: "{{ input.url }}"
+    retries: 2
+    retry_delay: 10ms
+  - id: store
+    plugin: storer
+    depends_on: [fetch]
+    when: nodes.fetch.output.status == 200
+`)
+	if yamlDef.timeout != time.Minute || yamlDef.Nodes[0].retryDelay != 10*time.Millisecond {
+		t.Fatalf("durations not parsed: %+v", yamlDef)
+	}
+
+	jsonDef := mustParse(t, `{"nodes": [{"id": "a", "plugin": "p", "params": {"nested": {"n": 1}}}]}`)
+	if _, ok := jsonDef.Nodes[0].Params["nested"].(map[string]interface{}); !ok {
+		t.Fatalf("nested params not normalized: %#v", jsonDef.Nodes[0].Params)
+	}
+
+	invalid := map[string]string{
+		"empty":        `nodes: []`,
+		"unknown key":  `{"nodes": [{"id": "a", "plugin": "p", "dependsOn": ["b"]}]}`,
+		"duplicate id": `{"nodes": [{"id": "a", "plugin": "p"}, {"id": "a", "plugin": "p"}]}`,
+		"missing dep":  `{"nodes": [{"id": "a", "plugin": "p", "depends_on": ["b"]}]}`,
+		"cycle": `{"nodes": [{"id": "a", "plugin": "p", "depends_on": ["c"]}, {"id": "b", "plugin": "p", "depends_on": ["a"]},
+			{"id": "c", "plugin": "p", "depends_on": ["b"]}]}`,
+		"not upstream":   `{"nodes": [{"id": "a", "plugin": "p"}, {"id": "b", "plugin": "p", "params": {"x": "{{ nodes.a.output }}"}}]}`,
+		"bad duration":   `{"nodes": [{"id": "a", "plugin": "p", "timeout": "soon"}]}`,
+		"too many tries": `{"nodes": [{"id": "a", "plugin": "p", "retries": 11}]}`,
+	}
+	for name, source := range invalid {
+		if _, err := ParseDefinition(source); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestEngineRunsDAG(t *testing.T) {
+	executor := newFakeExecutor()
+	executor.plugins["list"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"files": []string{"a.txt", "b.txt", "c.txt"}, "count": 3}, nil
+	}
+	executor.plugins["size"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return len(params["file"].(string)) * int(params["scale"].(float64)), nil
+	}
+	executor.plugins["echo"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params, nil
+	}
+	store := newMemoryRunStore()
+	engine := NewEngine(executor, store)
+
+	def := mustParse(t, `
+nodes:
+  - id: list
+    plugin: list
+  - id: sizes
+    plugin: size
+    depends_on: [list]
+    for_each: nodes.list.output.files
+    max_parallel: 2
+    params:
+      file: "{{ item }}"
+      scale: "{{ input.scale }}"
+  - id: many
+    plugin: echo
+    depends_on: [list]
+    when: nodes.list.output.count > 2
+    params:
+      label: "{{ input.name }} has {{ nodes.list.output.count }} files"
+  - id: few
+    plugin: echo
+    depends_on: [list]
+    when: "nodes.list.output.count <= 2"
+  - id: join
+    plugin: echo
+    depends_on: [sizes, many, few]
+    params:
+      sizes: "{{ nodes.sizes.output }}"
+      few: "{{ nodes.few.status }}"
+      label: "{{ nodes.many.output.label }}"
+output:
+  total: "{{ nodes.join.output.sizes }}"
+`)
+
+	ctx := core.WithRequestMeta(context.Background(), core.RequestMeta{UserID: 7, TenantID: 3, RequestID: "req-1"})
+	run, err := engine.Run(ctx, 1, def, map[string]interface{}{"scale": 2, "name": "inbox"})
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if run.Status != StatusSucceeded || run.TenantID != 3 || run.UserID != 7 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+
+	steps := stepsByNode(run)
+	if len(steps) != 5 {
+		t.Fatalf("expected 5 steps, got %d", len(steps))
+	}
+	if steps["few"].Status != StatusSkipped || steps["many"].Status != StatusSucceeded {
+		t.Fatalf("unexpected branch statuses: few=%s many=%s", steps["few"].Status, steps["many"].Status)
+	}
+	if steps["sizes"].Attempts != 3 || len(steps["sizes"].Params.([]interface{})) != 3 {
+		t.Fatalf("unexpected fan-out step: %+v", steps["sizes"])
+	}
+
+	join := steps["join"].Output.(map[string]interface{})
+	if join["few"] != "skipped" || join["label"] != "inbox has 3 files" {
+		t.Fatalf("unexpected join output: %#v", join)
+	}
+	total := run.Output.(map[string]interface{})["total"].([]interface{})
+	if len(total) != 3 || total[0] != float64(10) || total[2] != float64(10) {
+		t.Fatalf("fan-out outputs should keep item order and type, got %#v", total)
+	}
+
+	saved, ok := store.savedStep(run.ID, "join")
+	if !ok || saved.Status != StatusSucceeded || saved.ID == 0 {
+		t.Fatalf("step history not saved: %+v", saved)
+	}
+	if store.runs[run.ID].Status != StatusSucceeded {
+		t.Fatalf("run result not saved: %+v", store.runs[run.ID])
+	}
+}
+
+func TestEngineRetriesAndFailure(t *testing.T) {
+	executor := newFakeExecutor()
+	var flakyCalls int
+	executor.plugins["flaky"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		flakyCalls++
+		if flakyCalls < 3 {
+			return nil, errors.New("temporary failure")
+		}
+		return "ok", nil
+	}
+	executor.plugins["broken"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("always fails")
+	}
+	executor.plugins["slow"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	executor.plugins["echo"] = func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params, nil
+	}
+	engine := NewEngine(executor, newMemoryRunStore())
+
+	t.Run("retry", func(t *testing.T) {
+		def := mustParse(t, `{"nodes": [{"id": "a", "plugin": "flaky", "retries": 2, "retry_delay": "1ms"}]}`)
+		run, err := engine.Run(context.Background(), 1, def, nil)
+		if err != nil {
+			t.Fatalf("run error: %v", err)
+		}
+		if run.Status != StatusSucceeded || run.Steps[0].Attempts != 3 {
+			t.Fatalf("expected success after 3 attempts, got %s after %d: %s", run.Status, run.Steps[0].Attempts, run.Error)
+		}
+		if run.Output.(map[string]interface{})["a"] != "ok" {
+			t.Fatalf("unexpected output: %#v", run.Output)
+		}
+	})
+
+	t.Run("failure cancels the rest", func(t *testing.T) {
+		def := mustParse(t, `
+nodes:
+  - id: broken
+    plugin: broken
+    retries: 1
+  - id: slow
+    plugin: slow
+  - id: after
+    plugin: echo
+    depends_on: [broken]
+`)
+		run, err := engine.Run(context.Background(), 2, def, nil)
+		if err != nil {
+			t.Fatalf("run error: %v", err)
+		}
+		if run.Status != StatusFailed || !strings.Contains(run.Error, "broken") || !strings.Contains(run.Error, "always fails") {
+			t.Fatalf("unexpected run result: %s %s", run.Status, run.Error)
+		}
+		steps := stepsByNode(run)
+		if steps["broken"].Status != StatusFailed || steps["broken"].Attempts != 2 {
+			t.Fatalf("unexpected failed step: %+v", steps["broken"])
+		}
+		if steps["slow"].Status != StatusCanceled || steps["after"].Status != StatusCanceled {
+			t.Fatalf("expected remaining nodes canceled, got slow=%s after=%s", steps["slow"].Status, steps["after"].Status)
+		}
+		if executor.callCount("echo") != 0 {
+			t.Fatalf("downstream node should not run after failure")
+		}
+	})
+
+	t.Run("node timeout", func(t *testing.T) {
+		def := mustParse(t, `{"nodes": [{"id": "slow", "plugin": "slow", "timeout": "20ms"}]}`)
+		start := time.Now()
+		run, err := engine.Run(context.Background(), 3, def, nil)
+		if err != nil {
+			t.Fatalf("run error: %v", err)
+		}
+		if run.Status != StatusFailed || time.Since(start) > time.Second {
+			t.Fatalf("expected node timeout, got %s after %v", run.Status, time.Since(start))
+		}
+	})
+}
+
+func TestEvalCondition(t *testing.T) {
+	s := scope{
+		"input": map[string]interface{}{"env": "prod", "count": float64(3), "tags": []interface{}{"a"}},
+		"nodes": map[string]interface{}{},
+	}
+	cases := map[string]bool{
+		"input.env == 'prod'":   true,
+		`input.env != "prod"`:   false,
+		"input.count >= 3":      true,
+		"input.count < 3":       false,
+		"input.tags":            true,
+		"!input.missing":        true,
+		"{{ input.count > 1 }}": true,
+		"input.env == 'a == b'": false,
+		"input.tags.0 == 'a'":   true,
+	}
+	for expr, expected := range cases {
+		got, err := evalCondition(expr, s)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %v, got %v", expr, expected, got)
+		}
+	}
+	if _, err := evalCondition("secrets.token", s); err == nil {
+		t.Errorf("expected error for unknown root")
+	}
+}
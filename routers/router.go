@@ -6,6 +6,8 @@ import (
 	"weave/middleware"
 	"weave/pkg"
 	"weave/pkg/metrics"
+	"weave/pkg/openapi"
+	"weave/plugins/core"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
@@ -46,6 +48,15 @@ func SetupRouter() *gin.Engine {
 		registry.MustRegister(metrics.PluginMemoryUsage)
 		// 注册插件重载指标
 		registry.MustRegister(metrics.PluginReloads)
+		// 注册插件分版本调用指标
+		registry.MustRegister(metrics.PluginVersionRequests)
+		registry.MustRegister(metrics.PluginVersionDuration)
+		// 注册插件异步任务指标
+		registry.MustRegister(metrics.PluginJobs)
+		registry.MustRegister(metrics.PluginJobDuration)
+		// 注册插件编译指标
+		registry.MustRegister(metrics.PluginBuilds)
+		registry.MustRegister(metrics.PluginBuildDuration)
 
 		// 使用自定义registry创建handler
 		handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
@@ -56,6 +67,12 @@ func SetupRouter() *gin.Engine {
 		handler.ServeHTTP(c.Writer, c.Request)
 	})
 
+	// OpenAPI文档及文档页面，运行时由核心路由和插件路由生成
+	openAPICtrl := controllers.NewOpenAPIController(router)
+	router.GET("/openapi.json", openAPICtrl.GetSpec)
+	router.GET("/docs", openAPICtrl.GetDocsPage)
+	router.GET(openapi.DocsScriptPath, openAPICtrl.GetDocsScript)
+
 	// 启动指标更新器，每30秒更新一次系统指标
 	mm.StartMetricsUpdater(30 * time.Second)
 
@@ -178,14 +195,61 @@ func SetupRouter() *gin.Engine {
 				plugins.GET("/", pluginCtrl.GetAllPlugins)
 				// 获取插件状态
 				plugins.GET("/:name/status", pluginCtrl.GetPluginStatus)
+				// 获取插件状态转换历史
+				plugins.GET("/:name/history", pluginCtrl.GetPluginHistory)
+				// 获取插件依赖图
+				plugins.GET("/dependency-graph", pluginCtrl.GetDependencyGraph)
+
+				// 插件管理接口只允许拥有租户管理员角色的用户访问
+				admin := plugins.Group("", core.GlobalPluginManager.RequireRoles(core.AdminRole))
 				// 启用插件
-				plugins.POST("/:name/enable", pluginCtrl.EnablePlugin)
+				admin.POST("/:name/enable", pluginCtrl.EnablePlugin)
 				// 禁用插件
-				plugins.POST("/:name/disable", pluginCtrl.DisablePlugin)
+				admin.POST("/:name/disable", pluginCtrl.DisablePlugin)
 				// 重载插件
-				plugins.POST("/:name/reload", pluginCtrl.ReloadPlugin)
-				// 获取插件依赖图
-				plugins.GET("/dependency-graph", pluginCtrl.GetDependencyGraph)
+				admin.POST("/:name/reload", pluginCtrl.ReloadPlugin)
+				// 获取和更新插件配置
+				admin.GET("/:name/config", pluginCtrl.GetPluginConfig)
+				admin.PUT("/:name/config", pluginCtrl.UpdatePluginConfig)
+				// 灰度发布：部署、查看状态、调整流量策略、提升或回滚灰度版本
+				admin.POST("/:name/canary", pluginCtrl.DeployPluginCanary)
+				admin.GET("/:name/canary", pluginCtrl.GetPluginCanary)
+				admin.PUT("/:name/canary/policy", pluginCtrl.UpdateCanaryPolicy)
+				admin.POST("/:name/canary/promote", pluginCtrl.PromoteCanary)
+				admin.POST("/:name/canary/rollback", pluginCtrl.RollbackCanary)
+				// 异步任务：提交、查询进度和结果、取消
+				// 任务直接调用插件的Execute，不经过插件路由声明的角色和权限校验，因此同样只允许租户管理员使用
+				admin.POST("/:name/jobs", pluginCtrl.EnqueuePluginJob)
+				admin.GET("/:name/jobs", pluginCtrl.ListPluginJobs)
+				admin.GET("/:name/jobs/:id", pluginCtrl.GetPluginJob)
+				admin.POST("/:name/jobs/:id/cancel", pluginCtrl.CancelPluginJob)
+				// 插件源码编译状态
+				admin.GET("/builds", pluginCtrl.GetPluginBuilds)
+				admin.GET("/:name/build", pluginCtrl.GetPluginBuild)
+				// 插件仓库：搜索、安装、升级、回滚和卸载
+				admin.GET("/registry", pluginCtrl.SearchPluginRegistry)
+				admin.GET("/installed", pluginCtrl.GetInstalledPlugins)
+				admin.POST("/install", pluginCtrl.InstallPlugin)
+				admin.POST("/:name/upgrade", pluginCtrl.UpgradePlugin)
+				admin.POST("/:name/rollback", pluginCtrl.RollbackPlugin)
+				admin.POST("/:name/uninstall", pluginCtrl.UninstallPlugin)
+			}
+
+			// 工作流路由：运行时同步执行全部节点，节点自身的超时和重试由工作流定义控制，
+			// 因此不使用插件路由的重试和超时中间件。
+			// 节点与异步任务一样直接调用插件的Execute，不经过插件路由的角色和权限校验，因此只允许租户管理员访问
+			workflows := api.Group("/workflows", core.GlobalPluginManager.RequireRoles(core.AdminRole))
+			{
+				workflowCtrl := &controllers.WorkflowController{}
+				workflows.GET("/", workflowCtrl.GetWorkflows)
+				workflows.POST("/", workflowCtrl.CreateWorkflow)
+				workflows.GET("/:id", workflowCtrl.GetWorkflow)
+				workflows.PUT("/:id", workflowCtrl.UpdateWorkflow)
+				workflows.DELETE("/:id", workflowCtrl.DeleteWorkflow)
+				// 运行工作流，查看运行记录和各节点的执行过程
+				workflows.POST("/:id/run", workflowCtrl.RunWorkflow)
+				workflows.GET("/:id/runs", workflowCtrl.GetWorkflowRuns)
+				workflows.GET("/:id/runs/:run_id", workflowCtrl.GetWorkflowRun)
 			}
 
 			// 负载均衡管理路由
@@ -218,7 +282,7 @@ func SetupRouter() *gin.Engine {
 			"version":             "1.0.0",
 			"api_base":            "/api/v1",
 			"health_check":        "/health",
-			"available_endpoints": []string{"/api/v1/users", "/api/v1/tools", "/api/v1/plugins", "/health"},
+			"available_endpoints": []string{"/api/v1/users", "/api/v1/tools", "/api/v1/plugins", "/api/v1/workflows", "/health"},
 		})
 	})
 
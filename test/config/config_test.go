@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"weave/config"
 )
@@ -50,6 +51,11 @@ func TestDefaultConfig(t *testing.T) {
 	if config.Config.CSRF.Enabled != true {
 		t.Errorf("Expected default CSRF.Enabled to be true, got %v", config.Config.CSRF.Enabled)
 	}
+
+	// 默认不授予任何角色插件权限
+	if len(config.Config.Plugins.RolePermissions) != 0 {
+		t.Errorf("Expected no default role permissions, got %v", config.Config.Plugins.RolePermissions)
+	}
 }
 
 // TestLoadConfigFromEnv 测试从环境变量加载配置
@@ -225,3 +231,138 @@ func TestCSRFConfigLoading(t *testing.T) {
 		})
 	}
 }
+
+// TestLoadPluginSettings 测试从配置文件加载插件配置段
+func TestLoadPluginSettings(t *testing.T) {
+	resetEnvVars()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := `
+plugins:
+  dir: ` + dir + `
+  scanInterval: 10
+  debounce: 200
+  processes:
+    - name: echo
+      path: ./bin/echo
+      args: ["--verbose"]
+  limits:
+    sample_optimized:
+      maxConcurrentExecutions: 2
+      queueLength: 4
+      queueTimeout: 3
+  trust:
+    publicKeys: ["11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="]
+    devMode: true
+  rolePermissions:
+    member: ["notes:read", "notes:write"]
+    team:owner: ["notes:read"]
+  jobs:
+    workers: 2
+    retention: 48
+  stateSync:
+    redis: true
+    pollInterval: 30
+  build:
+    enabled: true
+    toolchain: /usr/local/go/bin/go
+    flags: ["-trimpath", "-race"]
+  registry:
+    url: https://plugins.example.com/weave
+    keep: 5
+  storage:
+    backend: db
+  sample_optimized:
+    greeting: "Hi"
+    api_key: "old-value"
+  settings:
+    sample_optimized:
+      api_key: "secret-value"
+    storage:
+      bucket: plugin-owned
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	os.Setenv("CONFIG_PATH", configPath)
+	os.Setenv("DB_USERNAME", "user")
+	os.Setenv("DB_PASSWORD", "pass")
+	os.Setenv("JWT_SECRET", "jwt")
+	defer func() {
+		os.Unsetenv("CONFIG_PATH")
+		resetEnvVars()
+	}()
+
+	if err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+
+	if config.Config.Plugins.ScanInterval != 10 {
+		t.Errorf("Expected scan interval 10, got %d", config.Config.Plugins.ScanInterval)
+	}
+	if config.Config.Plugins.Debounce != 200 {
+		t.Errorf("Expected debounce 200, got %d", config.Config.Plugins.Debounce)
+	}
+
+	processes := config.Config.Plugins.Processes
+	if len(processes) != 1 || processes[0].Name != "echo" || processes[0].Path != "./bin/echo" || len(processes[0].Args) != 1 {
+		t.Errorf("Unexpected process plugins: %+v", processes)
+	}
+
+	limits := config.Config.Plugins.Limits["sample_optimized"]
+	if limits.MaxConcurrentExecutions != 2 || limits.QueueLength != 4 || limits.QueueTimeout != 3 {
+		t.Errorf("Unexpected plugin limits: %+v", limits)
+	}
+
+	trust := config.Config.Plugins.Trust
+	if len(trust.PublicKeys) != 1 || !trust.DevMode {
+		t.Errorf("Unexpected plugin trust policy: %+v", trust)
+	}
+
+	rolePermissions := config.Config.Plugins.RolePermissions
+	if len(rolePermissions["member"]) != 2 || len(rolePermissions["team:owner"]) != 1 || rolePermissions["owner"] != nil {
+		t.Errorf("Expected configured role permissions to replace defaults, got %v", rolePermissions)
+	}
+
+	jobs := config.Config.Plugins.Jobs
+	if jobs.Workers != 2 || jobs.Retention != 48 || jobs.MaxAttempts != 3 {
+		t.Errorf("Unexpected plugin job queue config: %+v", jobs)
+	}
+
+	stateSync := config.Config.Plugins.StateSync
+	if !stateSync.Enabled || !stateSync.Redis || stateSync.PollInterval != 30 || stateSync.Channel != "weave:plugin_state" {
+		t.Errorf("Unexpected plugin state sync config: %+v", stateSync)
+	}
+
+	build := config.Config.Plugins.Build
+	if !build.Enabled || build.Toolchain != "/usr/local/go/bin/go" || len(build.Flags) != 2 || build.OutputDir != "./plugins/.build" || build.Keep != 3 {
+		t.Errorf("Unexpected plugin build config: %+v", build)
+	}
+
+	registry := config.Config.Plugins.Registry
+	if registry.URL != "https://plugins.example.com/weave" || registry.InstallDir != "./plugins/.installed" || registry.Timeout != 60 || registry.Keep != 5 {
+		t.Errorf("Unexpected plugin registry config: %+v", registry)
+	}
+
+	// 管理器配置项不会被视为插件配置段，与管理器配置项同名的插件通过settings读取配置
+	if len(config.Config.Plugins.Settings) != 2 || config.Config.Plugins.Settings["jobs"] != nil || config.Config.Plugins.Settings["settings"] != nil {
+		t.Errorf("Expected manager config keys to be excluded from plugin settings, got %v", config.Config.Plugins.Settings)
+	}
+	if config.Config.Plugins.Storage.Backend != "db" || config.Config.Plugins.Settings["storage"]["bucket"] != "plugin-owned" {
+		t.Errorf("Expected plugin named storage to get its own settings, got %v", config.Config.Plugins.Settings["storage"])
+	}
+
+	// plugins.<插件名>与plugins.settings.<插件名>逐项合并，settings中的配置项优先
+	settings := config.Config.Plugins.Settings["sample_optimized"]
+	if settings["greeting"] != "Hi" || settings["api_key"] != "secret-value" {
+		t.Errorf("Unexpected plugin settings: %v", settings)
+	}
+
+	sanitized := config.SanitizeConfig()["Plugins"].(map[string]interface{})["Settings"].(map[string]interface{})
+	masked := sanitized["sample_optimized"].(map[string]interface{})
+	if masked["api_key"] != "***" || masked["greeting"] != "Hi" {
+		t.Errorf("Expected sensitive plugin settings to be masked, got %v", masked)
+	}
+}
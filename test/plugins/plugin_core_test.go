@@ -387,3 +387,17 @@ func TestWatcherSkipLoadWithoutSO(t *testing.T) {
 		t.Fatalf("expected no dynamic register without .so, got %#v", sm.registered)
 	}
 }
+
+func TestDeployCanaryRejectsManifestOutsidePluginDir(t *testing.T) {
+	_ = pkg.InitLogger(pkg.DefaultOptions())
+	previous := config.Config.Plugins.Dir
+	config.Config.Plugins.Dir = t.TempDir()
+	t.Cleanup(func() { config.Config.Plugins.Dir = previous })
+
+	for _, manifest := range []string{"", "../plugin.json", "/etc/plugin.json", "a/../../plugin.json"} {
+		err := plugins.DeployCanary("note", manifest, core.CanaryPolicy{})
+		if pkg.GetHTTPStatus(err) != http.StatusBadRequest {
+			t.Fatalf("expected manifest %q rejected with 400, got %v", manifest, err)
+		}
+	}
+}